@@ -0,0 +1,100 @@
+package gobalt
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// Priority is how much of a BandwidthPool a download gets compared to the others running at the same time.
+// Any positive value works: a download with priority 8 gets twice the bandwidth of one with priority 4.
+type Priority int
+
+const (
+	PriorityBackground  Priority = 1  //Archive jobs and other downloads nobody is waiting for.
+	PriorityNormal      Priority = 4  //Default priority.
+	PriorityInteractive Priority = 16 //Downloads a user is waiting for, like a bot command.
+)
+
+// BandwidthPool shares a download rate between every download using it (see WithBandwidth()), in proportion to their Priority.
+// Shares are recomputed as downloads start and finish, so a single download gets the whole rate. It's safe for concurrent use.
+type BandwidthPool struct {
+	rate int64 //Bytes per second.
+
+	mu     sync.Mutex
+	weight int //Sum of the priorities of the running downloads.
+}
+
+// NewBandwidthPool creates a BandwidthPool limiting the downloads to bytesPerSecond in total.
+func NewBandwidthPool(bytesPerSecond int64) *BandwidthPool {
+	return &BandwidthPool{rate: max(bytesPerSecond, 1)}
+}
+
+// WithBandwidth makes the download share the rate of pool with the other downloads using it, with the given priority.
+func WithBandwidth(pool *BandwidthPool, priority Priority) DownloadOption {
+	return func(c *downloadConfig) {
+		c.bandwidth, c.priority = pool, max(priority, 1)
+	}
+}
+
+// join adds a download with priority to the pool, leave must be called once it's done.
+func (p *BandwidthPool) join(priority Priority) (leave func()) {
+	p.mu.Lock()
+	p.weight += int(priority)
+	p.mu.Unlock()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			p.mu.Lock()
+			p.weight -= int(priority)
+			p.mu.Unlock()
+		})
+	}
+}
+
+// share returns the current rate of a download with priority, in bytes per second.
+func (p *BandwidthPool) share(priority Priority) float64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return float64(p.rate) * float64(priority) / float64(max(p.weight, int(priority)))
+}
+
+// throttledWriter slows writes down to the share of its download in a BandwidthPool.
+type throttledWriter struct {
+	ctx      context.Context
+	w        io.Writer
+	pool     *BandwidthPool
+	priority Priority
+	next     time.Time //When the next write may start.
+}
+
+// limitBandwidth wraps w to follow the bandwidth pool of the download, if any. leave must be called once the download is done.
+func (c *downloadConfig) limitBandwidth(ctx context.Context, w io.Writer) (limited io.Writer, leave func()) {
+	if c.bandwidth == nil {
+		return w, func() {}
+	}
+	return &throttledWriter{ctx: ctx, w: w, pool: c.bandwidth, priority: c.priority}, c.bandwidth.join(c.priority)
+}
+
+func (t *throttledWriter) Write(b []byte) (int, error) {
+	written := 0
+	for len(b) > 0 {
+		chunk := b[:min(len(b), 16<<10)]
+		if err := SystemClock.Sleep(t.ctx, time.Until(t.next)); err != nil {
+			return written, err
+		}
+		n, err := t.w.Write(chunk)
+		written += n
+		if err != nil {
+			return written, err
+		}
+		wait := time.Duration(float64(n) / t.pool.share(t.priority) * float64(time.Second))
+		if now := time.Now(); t.next.Before(now) {
+			t.next = now
+		}
+		t.next = t.next.Add(wait)
+		b = b[n:]
+	}
+	return written, nil
+}
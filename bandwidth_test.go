@@ -0,0 +1,45 @@
+package gobalt
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBandwidthPriority(t *testing.T) {
+	media := bytes.Repeat([]byte("x"), 100<<10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(media)
+	}))
+	defer server.Close()
+
+	pool := NewBandwidthPool(400 << 10)
+	start := time.Now()
+	var wg sync.WaitGroup
+	elapsed := make(map[Priority]time.Duration)
+	var mu sync.Mutex
+	for _, priority := range []Priority{PriorityBackground, 3 * PriorityBackground} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := New().DownloadTo(context.Background(), server.URL, io.Discard, WithBandwidth(pool, priority)); err != nil {
+				t.Error(err)
+			}
+			mu.Lock()
+			elapsed[priority] = time.Since(start)
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	//The fast download gets 300 KiB/s, so it takes ~0.33s. Both together move 200 KiB at 400 KiB/s, ~0.5s.
+	fast, slow := elapsed[3*PriorityBackground], elapsed[PriorityBackground]
+	if fast >= slow || slow < 400*time.Millisecond || slow > 2*time.Second {
+		t.Fatalf("unexpected durations: high priority %v, low priority %v", fast, slow)
+	}
+}
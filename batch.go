@@ -0,0 +1,233 @@
+package gobalt
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// batchCount numbers the batches, to tell their jobs apart in pprof labels.
+var batchCount atomic.Int64
+
+// BatchOptions changes how DownloadBatch() behaves.
+type BatchOptions struct {
+	Dir         string           //Directory files are saved to. Default: the current directory.
+	Concurrency int              //How many items are downloaded at the same time. Default: 4.
+	Numbered    bool             //Prefix filenames with the position of the item, like "01 - Title.mp3", so they keep the original order.
+	Download    []DownloadOption //Options used for every download, see SaveTo().
+
+	//Tagging of audio items (Mode: Audio), so music players group the files as an album. See WriteTags().
+	TagAudio bool   //Write the track number and the fields below to every audio file.
+	Album    string //Album name, usually the playlist title (see GetYoutubePlaylistTitle()).
+	Artist   string //Artist of every track, and album artist. If empty, the artist sent by cobalt is kept.
+	Cover    bool   //Embed the thumbnail of each item as cover art, when one can be found (see FetchCover()).
+
+	Progress func(BatchProgress) //Called when an item starts, progresses or finishes, one call at a time. It should return quickly.
+
+	Metadata []map[string]string //Metadata of the items, by position in the list (see WithMetadata()). Can be shorter than the list.
+}
+
+// BatchProgress is the state of a running batch, see BatchOptions.Progress.
+type BatchProgress struct {
+	Items     []ItemProgress //Every item, in the original order.
+	Running   int            //Items being downloaded.
+	Succeeded int
+	Failed    int
+	Done      int64 //Bytes downloaded so far, by every item.
+}
+
+// ItemProgress is the state of one item of a batch.
+type ItemProgress struct {
+	Url      string
+	Name     string    //Name of the saved file, once the item finished.
+	Started  time.Time //When the item started, zero while it's queued.
+	Finished bool
+	Err      error //Why the item failed, if it finished.
+	Done     int64 //Bytes downloaded so far.
+	Total    int64 //Size of the file, 0 if unknown.
+
+	Metadata map[string]string //Metadata of the item, from BatchOptions.Metadata.
+}
+
+// BatchEntry is the result of one item of a batch.
+type BatchEntry struct {
+	Index  int             //Position of the item in the list given to DownloadBatch(), starting at 0.
+	Url    string          //Media url of the item.
+	Result *DownloadResult //Saved file, <NIL> if the item failed.
+	Err    error           //Why the item failed, <NIL> if it succeeded.
+
+	Metadata map[string]string //Metadata of the item, from BatchOptions.Metadata.
+}
+
+// BatchReport is the result of DownloadBatch(). Entries are in the original order of the items, whatever order they finished in.
+type BatchReport struct {
+	Entries   []BatchEntry
+	Succeeded int
+	Failed    int
+}
+
+// DownloadBatch(ctx, items, opts) sends every item to cobalt and saves the files, opts.Concurrency at a time.
+// Failed items don't stop the others, check the report.
+func DownloadBatch(ctx context.Context, items []Settings, opts BatchOptions) *BatchReport {
+	return defaultClient().DownloadBatch(ctx, items, opts)
+}
+
+// DownloadBatch(ctx, items, opts) is the same as DownloadBatch(), using c.
+func (c *Cobalt) DownloadBatch(ctx context.Context, items []Settings, opts BatchOptions) *BatchReport {
+	report := &BatchReport{Entries: make([]BatchEntry, len(items))}
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = 4
+	}
+	dir := opts.Dir
+	if dir == "" {
+		dir = "."
+	}
+	width := max(len(strconv.Itoa(len(items))), 2)
+
+	batch := batchCount.Add(1)
+	progress := newBatchTracker(items, opts)
+	limit := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, item := range items {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			entry := &report.Entries[i]
+			entry.Index, entry.Url = i, item.Url
+			if i < len(opts.Metadata) {
+				entry.Metadata = opts.Metadata[i]
+			}
+			select {
+			case limit <- struct{}{}:
+			case <-ctx.Done():
+				entry.Err = ctx.Err()
+				return
+			}
+			defer func() { <-limit }()
+
+			downloadOpts := opts.Download
+			if opts.Numbered {
+				downloadOpts = append(downloadOpts[:len(downloadOpts):len(downloadOpts)], withFilenamePrefix(fmt.Sprintf("%0*d - ", width, i+1)))
+			}
+			if entry.Metadata != nil {
+				downloadOpts = append(downloadOpts[:len(downloadOpts):len(downloadOpts)], WithMetadata(entry.Metadata))
+			}
+			if progress != nil {
+				progress.start(i)
+				defer func() { progress.finish(i, entry.Result, entry.Err) }()
+				downloadOpts = append(downloadOpts[:len(downloadOpts):len(downloadOpts)], WithProgress(func(done, total int64) { progress.update(i, done, total) }))
+			}
+			c.withJobLabels(ctx, fmt.Sprintf("batch-%v/%v", batch, i+1), item.Url, func(ctx context.Context) {
+				entry.Result, entry.Err = c.SaveMedia(ctx, item, dir, downloadOpts...)
+				if entry.Err == nil && opts.TagAudio && item.Mode == Audio {
+					entry.Err = c.tagItem(ctx, opts, item, entry.Result, i+1, len(items))
+				}
+			})
+		}()
+	}
+	wg.Wait()
+
+	for _, entry := range report.Entries {
+		if entry.Err != nil {
+			report.Failed++
+		} else {
+			report.Succeeded++
+		}
+	}
+	return report
+}
+
+// batchTracker keeps the BatchProgress of a batch and reports it to fn.
+type batchTracker struct {
+	mu    sync.Mutex
+	state BatchProgress
+	fn    func(BatchProgress)
+}
+
+// newBatchTracker returns <NIL> if there's no fn, so batches without progress don't pay for it.
+func newBatchTracker(items []Settings, opts BatchOptions) *batchTracker {
+	if opts.Progress == nil {
+		return nil
+	}
+	t := &batchTracker{fn: opts.Progress, state: BatchProgress{Items: make([]ItemProgress, len(items))}}
+	for i, item := range items {
+		t.state.Items[i].Url = item.Url
+		if i < len(opts.Metadata) {
+			t.state.Items[i].Metadata = opts.Metadata[i]
+		}
+	}
+	return t
+}
+
+func (t *batchTracker) start(i int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.state.Items[i].Started = time.Now()
+	t.state.Running++
+	t.report()
+}
+
+func (t *batchTracker) update(i int, done, total int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	item := &t.state.Items[i]
+	t.state.Done += done - item.Done
+	item.Done, item.Total = done, total
+	t.report()
+}
+
+func (t *batchTracker) finish(i int, result *DownloadResult, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	item := &t.state.Items[i]
+	item.Finished, item.Err = true, err
+	if result != nil {
+		item.Name = filepath.Base(result.Path)
+	}
+	t.state.Running--
+	if err != nil {
+		t.state.Failed++
+	} else {
+		t.state.Succeeded++
+	}
+	t.report()
+}
+
+// report calls fn with a copy of the state, t.mu must be held so calls don't overlap.
+func (t *batchTracker) report() {
+	state := t.state
+	state.Items = slices.Clone(t.state.Items)
+	t.fn(state)
+}
+
+// tagItem writes the album tags of track number track to a downloaded audio item.
+func (c *Cobalt) tagItem(ctx context.Context, opts BatchOptions, item Settings, result *DownloadResult, track, total int) error {
+	tags := AudioTags{
+		Album:       opts.Album,
+		Artist:      opts.Artist,
+		AlbumArtist: opts.Artist,
+		Track:       track,
+		TrackTotal:  total,
+	}
+	if opts.Cover {
+		//Items without a thumbnail are still tagged.
+		tags.Cover, _ = c.fetchCover(ctx, coverURLs(item.Url))
+	}
+	if err := WriteTags(ctx, result.Path, tags); err != nil {
+		return fmt.Errorf("failed to tag %v: %w", result.Path, err)
+	}
+	return nil
+}
+
+// withFilenamePrefix adds prefix to the filename chosen by SaveTo(), used by batches to number files.
+func withFilenamePrefix(prefix string) DownloadOption {
+	return func(c *downloadConfig) {
+		c.filenamePrefix = prefix
+	}
+}
@@ -0,0 +1,74 @@
+package gobalt
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDownloadBatchOrder(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/file/"):
+			//The first items finish last.
+			if r.URL.Path == "/file/a" {
+				time.Sleep(50 * time.Millisecond)
+			}
+			w.Write([]byte(r.URL.Path))
+		case r.Method == http.MethodGet:
+			w.Write([]byte(`{"cobalt":{"version":"10.5.0","services":["youtube"]}}`))
+		default:
+			var body map[string]any
+			json.NewDecoder(r.Body).Decode(&body)
+			id := strings.TrimPrefix(body["url"].(string), "https://youtu.be/")
+			if id == "missing" {
+				w.Write([]byte(`{"status":"error","error":{"code":"error.api.content.video.unavailable"}}`))
+				return
+			}
+			w.Write([]byte(`{"status":"tunnel","url":"http://` + r.Host + `/file/` + id + `","filename":"` + id + `.mp3"}`))
+		}
+	}))
+	defer server.Close()
+
+	var items []Settings
+	for _, id := range []string{"a", "b", "missing", "c"} {
+		options := CreateDefaultSettings()
+		options.Url = "https://youtu.be/" + id
+		items = append(items, options)
+	}
+	dir := t.TempDir()
+	var last BatchProgress
+	progress := func(state BatchProgress) { last = state }
+	report := New(WithInstance(server.URL)).DownloadBatch(context.Background(), items, BatchOptions{Dir: dir, Numbered: true, Progress: progress})
+
+	if report.Succeeded != 3 || report.Failed != 1 {
+		t.Fatalf("unexpected report %+v", report)
+	}
+	for i, want := range []string{"01 - a.mp3", "02 - b.mp3", "", "04 - c.mp3"} {
+		entry := report.Entries[i]
+		if entry.Index != i || entry.Url != items[i].Url {
+			t.Fatalf("entry %v is out of order: %+v", i, entry)
+		}
+		if want == "" {
+			if entry.Err == nil {
+				t.Fatal("missing video should fail")
+			}
+			continue
+		}
+		if entry.Err != nil || entry.Result.Path != filepath.Join(dir, want) {
+			t.Fatalf("entry %v: got %+v, want %v", i, entry, want)
+		}
+		if _, err := os.Stat(entry.Result.Path); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if last.Succeeded != 3 || last.Failed != 1 || last.Running != 0 || last.Items[0].Name != "01 - a.mp3" {
+		t.Fatalf("unexpected last progress %+v", last)
+	}
+}
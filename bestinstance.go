@@ -0,0 +1,81 @@
+package gobalt
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// InstanceCriteria tells SelectBestInstance() which instances can be chosen.
+type InstanceCriteria struct {
+	Sources     InstanceSources //Where the candidates are found. Default: the InstanceTrackers.
+	Service     string          //Service the instance must support, like "youtube" (see DetectService()). Empty means any.
+	MinScore    int             //Lowest tracker score accepted, from 0 to 100.
+	MinTrust    int             //Lowest tracker trust accepted.
+	AllowAuth   bool            //Also accept instances that need an api key, e.g. when the client has one for them.
+	Concurrency int             //How many instances are probed at the same time. Default: 8.
+	SetDefault  bool            //Use the chosen instance for the next requests of the client (or CobaltApi, for the package-level function).
+}
+
+// SelectBestInstance(ctx, criteria) fetches the instances of criteria.Sources, probes the ones that fit the criteria
+// in parallel (see EnrichInstances()), and returns the best one. Instances are ranked by their measured latency,
+// weighted by their tracker score: an instance with score 100 counts its latency as is, one with score 0 twice.
+// Returns ErrNoUsableInstance if no instance fits.
+func SelectBestInstance(ctx context.Context, criteria InstanceCriteria) (*Selection, error) {
+	selection, err := defaultClient().selectBestInstance(ctx, criteria)
+	if err == nil && criteria.SetDefault {
+		CobaltApi = selection.Instance.API
+	}
+	return selection, err
+}
+
+// SelectBestInstance(ctx, criteria) is the same as SelectBestInstance(), using c. With criteria.SetDefault, the requests
+// sent by c afterwards go to the chosen instance, so don't call it while c is being used by other goroutines.
+func (c *Cobalt) SelectBestInstance(ctx context.Context, criteria InstanceCriteria) (*Selection, error) {
+	selection, err := c.selectBestInstance(ctx, criteria)
+	if err == nil && criteria.SetDefault {
+		c.api = selection.Instance.API
+	}
+	return selection, err
+}
+
+func (c *Cobalt) selectBestInstance(ctx context.Context, criteria InstanceCriteria) (*Selection, error) {
+	list, err := c.GetInstances(ctx, criteria.Sources)
+	if err != nil {
+		return nil, err
+	}
+	//Leave out what the tracker already rules out, so they aren't probed for nothing.
+	list = slices.DeleteFunc(slices.Clone(list), func(tracker InstanceInfo) bool {
+		return !tracker.Online.API || tracker.Score < criteria.MinScore || tracker.Trust < criteria.MinTrust
+	})
+	concurrency := criteria.Concurrency
+	if concurrency <= 0 {
+		concurrency = 8
+	}
+	candidates := c.EnrichInstances(ctx, list, concurrency).Filter(func(instance Instance) bool {
+		return instance.Online &&
+			(criteria.AllowAuth || !instance.AuthRequired) &&
+			(criteria.Service == "" || slices.Contains(instance.Services, criteria.Service))
+	})
+	if len(candidates) == 0 {
+		return nil, ErrNoUsableInstance
+	}
+
+	best := slices.MinFunc(candidates, func(a, b Instance) int {
+		return cmp.Compare(rankLatency(a), rankLatency(b))
+	})
+	return &Selection{
+		Instance: best,
+		RTT:      best.Latency,
+		Reason: fmt.Sprintf("best latency and score of %v instances (%v, score %v)",
+			len(candidates), best.Latency.Round(time.Millisecond), best.Tracker.Score),
+	}, nil
+}
+
+// rankLatency is the latency of instance, made worse the lower its tracker score is.
+func rankLatency(instance Instance) time.Duration {
+	score := min(max(instance.Tracker.Score, 0), 100)
+	return instance.Latency * time.Duration(200-score) / 100
+}
@@ -0,0 +1,59 @@
+package gobalt
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSelectBestInstance(t *testing.T) {
+	tiktok := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"cobalt":{"version":"10.5.0","services":["tiktok"]}}`))
+	}))
+	defer tiktok.Close()
+	lowScore := fakeCobalt(t, `{}`)
+	youtube := fakeCobalt(t, `{}`)
+	tracker := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[]`))
+	}))
+	defer tracker.Close()
+
+	client := New(WithInstance("http://127.0.0.1:1"))
+	selection, err := client.SelectBestInstance(context.Background(), InstanceCriteria{
+		Sources: InstanceSources{Trackers: []string{tracker.URL}, Static: []InstanceInfo{
+			{API: tiktok.URL, Score: 100, Online: OnlineStatus{API: true}},
+			{API: lowScore.URL, Score: 10, Online: OnlineStatus{API: true}},
+			{API: youtube.URL, Score: 90, Online: OnlineStatus{API: true}},
+		}},
+		Service:    "youtube",
+		MinScore:   50,
+		SetDefault: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if selection.Instance.API != youtube.URL+"/" && selection.Instance.API != youtube.URL {
+		t.Fatalf("expected %v, got %+v", youtube.URL, selection.Instance)
+	}
+	if client.api != selection.Instance.API {
+		t.Fatalf("the client should now use %v, got %v", selection.Instance.API, client.api)
+	}
+
+	_, err = client.SelectBestInstance(context.Background(), InstanceCriteria{
+		Sources: InstanceSources{Trackers: []string{tracker.URL}, Static: []InstanceInfo{{API: tiktok.URL, Online: OnlineStatus{API: true}}}},
+		Service: "youtube",
+	})
+	if err != ErrNoUsableInstance {
+		t.Fatalf("expected ErrNoUsableInstance, got %v", err)
+	}
+}
+
+func TestRankLatency(t *testing.T) {
+	trusted := Instance{Latency: 100 * time.Millisecond, Tracker: InstanceInfo{Score: 100}}
+	unknown := Instance{Latency: 60 * time.Millisecond, Tracker: InstanceInfo{Score: 0}}
+	if rankLatency(trusted) >= rankLatency(unknown) {
+		t.Fatalf("a well scored instance should win over a slightly faster unknown one")
+	}
+}
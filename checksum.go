@@ -0,0 +1,131 @@
+package gobalt
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"strings"
+)
+
+// Checksum algorithms supported by WithChecksum() and VerifyChecksum().
+const (
+	ChecksumSHA256 = "sha256"
+	ChecksumMD5    = "md5"
+)
+
+// ErrChecksumMismatch is returned by VerifyChecksum() when the file doesn't have the expected checksum.
+var ErrChecksumMismatch = errors.New("checksum mismatch")
+
+// WithChecksum makes Download() and DownloadTo() compute the checksum of the file with algorithm (ChecksumSHA256 or ChecksumMD5)
+// while it's downloaded, and return it in DownloadResult.Checksum as "algorithm:hex", e.g. to deduplicate or validate archived media.
+// Files continued from an earlier download or fetched in segments (see WithSegments()) are read again once complete instead.
+func WithChecksum(algorithm string) DownloadOption {
+	return func(c *downloadConfig) {
+		c.checksum = algorithm
+	}
+}
+
+// newHash returns the hash of a checksum algorithm.
+func newHash(algorithm string) (hash.Hash, error) {
+	switch strings.ToLower(algorithm) {
+	case ChecksumSHA256:
+		return sha256.New(), nil
+	case ChecksumMD5:
+		return md5.New(), nil
+	}
+	return nil, fmt.Errorf("unknown checksum algorithm %q", algorithm)
+}
+
+// startChecksum prepares the hash of the download, if WithChecksum() was used.
+func (c *downloadConfig) startChecksum() (err error) {
+	if c.checksum == "" {
+		return nil
+	}
+	c.hash, err = newHash(c.checksum)
+	return err
+}
+
+// hashWriter returns a writer hashing what is written to w at offset in the file, when it follows what was hashed so far.
+func (c *downloadConfig) hashWriter(w io.Writer, offset int64) io.Writer {
+	if c.hash == nil {
+		return w
+	}
+	if offset == 0 {
+		c.hash.Reset()
+		c.hashed = 0
+	}
+	if c.hashed != offset {
+		return w
+	}
+	return &hashingWriter{w: w, config: c}
+}
+
+type hashingWriter struct {
+	w      io.Writer
+	config *downloadConfig
+}
+
+func (h *hashingWriter) Write(b []byte) (int, error) {
+	n, err := h.w.Write(b)
+	h.config.hash.Write(b[:n])
+	h.config.hashed += int64(n)
+	return n, err
+}
+
+// finishChecksum returns the checksum of the downloaded file, reading it from path if some of it wasn't hashed while downloading.
+func (c *downloadConfig) finishChecksum(path string, size int64) (string, error) {
+	if c.hash == nil {
+		return "", nil
+	}
+	if c.hashed != size {
+		return FileChecksum(path, c.checksum)
+	}
+	return strings.ToLower(c.checksum) + ":" + hex.EncodeToString(c.hash.Sum(nil)), nil
+}
+
+// FileChecksum(path, algorithm) returns the checksum of the file at path as "algorithm:hex", like DownloadResult.Checksum.
+func FileChecksum(path, algorithm string) (string, error) {
+	h, err := newHash(algorithm)
+	if err != nil {
+		return "", err
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	if _, err := io.Copy(h, file); err != nil {
+		return "", err
+	}
+	return strings.ToLower(algorithm) + ":" + hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// VerifyChecksum(path, expected) checks that the file at path has the checksum expected, as "algorithm:hex" (like DownloadResult.Checksum)
+// or just the hex digest, whose length tells the algorithm. Returns an error wrapping ErrChecksumMismatch if it doesn't.
+func VerifyChecksum(path, expected string) error {
+	algorithm, digest, found := strings.Cut(expected, ":")
+	if !found {
+		digest = expected
+		switch len(digest) {
+		case sha256.Size * 2:
+			algorithm = ChecksumSHA256
+		case md5.Size * 2:
+			algorithm = ChecksumMD5
+		default:
+			return fmt.Errorf("can't tell the algorithm of checksum %q", expected)
+		}
+	}
+	actual, err := FileChecksum(path, algorithm)
+	if err != nil {
+		return err
+	}
+	if _, actualDigest, _ := strings.Cut(actual, ":"); !strings.EqualFold(actualDigest, digest) {
+		return fmt.Errorf("%w: %v is %v, expected %v", ErrChecksumMismatch, path, actual, expected)
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package gobalt
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDownloadChecksum(t *testing.T) {
+	media := bytes.Repeat([]byte("media"), 1000)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "video.mp4", time.Time{}, bytes.NewReader(media))
+	}))
+	defer server.Close()
+	sha := sha256.Sum256(media)
+	sum := md5.Sum(media)
+	client := New()
+
+	tests := []struct {
+		name     string
+		opts     []DownloadOption
+		checksum string
+	}{
+		{"sha256", []DownloadOption{WithChecksum(ChecksumSHA256)}, "sha256:" + hex.EncodeToString(sha[:])},
+		{"md5", []DownloadOption{WithChecksum(ChecksumMD5)}, "md5:" + hex.EncodeToString(sum[:])},
+		{"segments", []DownloadOption{WithChecksum(ChecksumSHA256), WithSegments(3, 0)}, "sha256:" + hex.EncodeToString(sha[:])},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "video.mp4")
+			result, err := client.Download(context.Background(), server.URL, path, test.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Checksum != test.checksum {
+				t.Fatalf("expected checksum %v, got %v", test.checksum, result.Checksum)
+			}
+			if err := VerifyChecksum(path, result.Checksum); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+
+	var buf bytes.Buffer
+	result, err := client.DownloadTo(context.Background(), server.URL, &buf, WithChecksum(ChecksumSHA256))
+	if err != nil || result.Checksum != tests[0].checksum {
+		t.Fatalf("unexpected checksum of a stream %v, %v", result, err)
+	}
+	if _, err := client.Download(context.Background(), server.URL, t.TempDir()+"/a", WithChecksum("crc")); err == nil {
+		t.Fatal("unknown algorithms should fail")
+	}
+}
+
+func TestVerifyChecksum(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(path, []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyChecksum(path, "5d41402abc4b2a76b9719d911017c592"); err != nil {
+		t.Fatalf("md5 given as plain hex should match: %v", err)
+	}
+	if err := VerifyChecksum(path, "sha256:"+hex.EncodeToString(make([]byte, 32))); !errors.Is(err, ErrChecksumMismatch) {
+		t.Fatalf("expected ErrChecksumMismatch, got %v", err)
+	}
+}
@@ -0,0 +1,224 @@
+package gobalt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// CobaltClient is what a cobalt client can do. *Cobalt implements it, so your application can depend on this
+// interface instead, and use a mock in unit tests or wrap it (e.g. with a caching decorator).
+type CobaltClient interface {
+	Run(ctx context.Context, options Settings) (*CobaltResponse, error)                              //Sends the request to the instance, see Run().
+	ServerInfo(ctx context.Context) (*ServerInfo, error)                                             //Gets the instance information, see CobaltServerInfo().
+	Download(ctx context.Context, url, path string, opts ...DownloadOption) (*DownloadResult, error) //Saves a file to disk, see Download().
+}
+
+var _ CobaltClient = (*Cobalt)(nil)
+
+// API is another name for CobaltClient.
+type API = CobaltClient
+
+// Cobalt is a client for a cobalt instance. Unlike the package-level functions (which use CobaltApi, ApiKey, Client and Language),
+// each Cobalt has its own instance, api key and HTTP client, so you can talk to several instances at the same time. Create one with New().
+type Cobalt struct {
+	api             string
+	apiKey          string
+	language        string
+	httpClient      *http.Client
+	serverInfoCache *ServerInfoCache
+	instanceCache   *InstanceListCache
+	sessions        *Sessions
+	turnstile       TurnstileProvider
+	serviceCheck    bool
+	noHealthCheck   bool
+	middleware      []Middleware
+	logger          *slog.Logger
+	logLevels       LogLevels
+	retry           RetryPolicy
+	downloadRetry   DownloadRetryPolicy
+	clock           Clock
+	onWarning       func(Warning)
+	metrics         Metrics
+	watches         []func(*Cobalt) //Started by New() once every option is applied, see WithServerInfoWatch().
+	serviceSettings map[string][]ServiceSettings
+	failover        *failover
+	rateLimiter     *RateLimiter
+}
+
+// Logger is used by the package-level functions, and by clients created without WithLogger(), to tell what gobalt is doing,
+// like request fields left out because the instance doesn't support them. By default nothing is logged.
+var Logger = discardLogger
+
+var discardLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+// Option changes a setting of a Cobalt client, see New().
+type Option func(*Cobalt)
+
+// New creates a Cobalt client. Options that are not set use the current value of the package-level variables:
+// CobaltApi, ApiKey, Language and Client.
+//
+// Example: gobalt.New(gobalt.WithInstance("https://my.instance"), gobalt.WithAPIKey("key"))
+func New(opts ...Option) *Cobalt {
+	client := defaultClient()
+	for _, opt := range opts {
+		opt(client)
+	}
+	for _, watch := range client.watches {
+		watch(client)
+	}
+	return client
+}
+
+// defaultClient returns a client using the package-level variables, used by the package-level functions like Run().
+func defaultClient() *Cobalt {
+	logger := Logger
+	if logger == nil {
+		logger = discardLogger
+	}
+	return &Cobalt{
+		api:             CobaltApi,
+		apiKey:          ApiKey,
+		language:        Language,
+		httpClient:      &Client,
+		serverInfoCache: DefaultServerInfoCache,
+		instanceCache:   DefaultInstanceListCache,
+		sessions:        DefaultSessions,
+		logger:          logger,
+		logLevels:       DefaultLogLevels,
+		clock:           SystemClock,
+		metrics:         nopMetrics{},
+	}
+}
+
+// WithInstance sets the url of the cobalt api the client talks to.
+func WithInstance(api string) Option {
+	return func(c *Cobalt) {
+		c.api = api
+	}
+}
+
+// WithAPIKey sets the api key sent to the instance.
+func WithAPIKey(key string) Option {
+	return func(c *Cobalt) {
+		c.apiKey = key
+	}
+}
+
+// WithLanguage sets the language sent to the instance as Accept-Language, and used by (*Cobalt).ResolveError().
+func WithLanguage(language string) Option {
+	return func(c *Cobalt) {
+		c.language = language
+	}
+}
+
+// WithHTTPClient sets the HTTP client used for every request, including downloads. Downloads don't use its Timeout as a limit
+// for the whole transfer: it's how long they wait for the response headers, and for more bytes once the body started.
+func WithHTTPClient(client *http.Client) Option {
+	return func(c *Cobalt) {
+		c.httpClient = client
+	}
+}
+
+// WithLogger sets the logger used by the client. Default is Logger.
+func WithLogger(logger *slog.Logger) Option {
+	return func(c *Cobalt) {
+		if logger == nil {
+			logger = discardLogger
+		}
+		c.logger = logger
+	}
+}
+
+// LogLevels sets the level of each kind of message logged by a client, see WithLogLevels().
+type LogLevels struct {
+	Request slog.Level //Requests sent to cobalt (url, instance) and their answer (status, service, duration).
+	Retry   slog.Level //Requests sent again because of a transient failure (attempt, wait, error), see WithRetry().
+	Failure slog.Level //Requests that failed for good (url, instance, error code).
+}
+
+// DefaultLogLevels are the LogLevels of clients created without WithLogLevels().
+var DefaultLogLevels = LogLevels{Request: slog.LevelDebug, Retry: slog.LevelInfo, Failure: slog.LevelWarn}
+
+// WithLogLevels sets the level of each kind of message logged by the client, e.g. to see every request at Info level.
+// Every level of levels is used, start from DefaultLogLevels to only change some of them.
+func WithLogLevels(levels LogLevels) Option {
+	return func(c *Cobalt) {
+		c.logLevels = levels
+	}
+}
+
+// WithTransportOptions makes the client use its own HTTP client, with a transport tuned by opts. See TransportOptions.
+func WithTransportOptions(opts TransportOptions) Option {
+	return func(c *Cobalt) {
+		c.httpClient = &http.Client{
+			Timeout:   c.httpClient.Timeout,
+			Transport: NewTransport(opts),
+		}
+	}
+}
+
+// Instance returns the url of the cobalt api used by the client.
+func (c *Cobalt) Instance() string {
+	return c.api
+}
+
+// Logger returns the logger used by the client, see WithLogger().
+func (c *Cobalt) Logger() *slog.Logger {
+	return c.logger
+}
+
+// Run(ctx, gobalt.Settings) sends the request to the instance of the client, see Run().
+// Failed requests are retried following the RetryPolicy of the client, see WithRetry(), then sent to other instances
+// if failover is enabled, see WithFailover().
+// RequestOptions attached to ctx override the client settings for this request, see ContextWithRequestOptions().
+func (c *Cobalt) Run(ctx context.Context, options Settings) (*CobaltResponse, error) {
+	c, ctx, cancel := c.forRequest(ctx)
+	defer cancel()
+	var media *CobaltResponse
+	onRetry := func(attempt int, wait time.Duration, err error) {
+		c.logger.Log(ctx, c.logLevels.Retry, "retrying cobalt request", "url", options.Url, "instance", c.api, "attempt", attempt+1, "wait", wait, "error", err)
+		if opts, _ := RequestOptionsFromContext(ctx); opts.OnRetry != nil {
+			opts.OnRetry(attempt, wait, err)
+		}
+	}
+	//The budget covers the retries and the failover after them.
+	budgetCtx := ctx
+	if c.retry.Budget > 0 {
+		var cancelBudget context.CancelFunc
+		budgetCtx, cancelBudget = context.WithTimeout(ctx, c.retry.Budget)
+		defer cancelBudget()
+	}
+	err := c.retry.retry(budgetCtx, c.clock, onRetry, func(ctx context.Context) (err error) {
+		start := c.clock.Now()
+		media, err = c.run(ctx, c.api, options)
+		c.metrics.RequestFinished(instanceLabel(c.api), MetricCode(err), c.clock.Now().Sub(start))
+		return err
+	})
+	if err != nil && c.failover != nil && shouldFailover(err) && !errors.Is(err, ErrRetryBudgetExhausted) {
+		media, err = c.runFailover(budgetCtx, options, err)
+		if err != nil && budgetCtx.Err() != nil && ctx.Err() == nil {
+			err = fmt.Errorf("%w during failover: %w", ErrRetryBudgetExhausted, err)
+		}
+	}
+	if err != nil {
+		c.logger.Log(ctx, c.logLevels.Failure, "cobalt request failed", "url", options.Url, "instance", c.api, "code", errorCode(err), "error", err)
+	}
+	return media, err
+}
+
+// ServerInfo(ctx) returns the information of the instance of the client, from the server info cache when possible.
+func (c *Cobalt) ServerInfo(ctx context.Context) (*ServerInfo, error) {
+	c, ctx, cancel := c.forRequest(ctx)
+	defer cancel()
+	return c.serverInfoCache.get(ctx, c, c.api)
+}
+
+// ResolveError(error) returns a human-readable error message from the error code, in the language of the client. See ResolveError().
+func (c *Cobalt) ResolveError(code error) string {
+	return resolveError(c.language, code)
+}
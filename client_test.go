@@ -0,0 +1,115 @@
+package gobalt
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestClientsAreIndependent(t *testing.T) {
+	var mu sync.Mutex
+	keys := map[string]string{}
+	newServer := func(name string) *httptest.Server {
+		fake := fakeCobalt(t, `{"status":"tunnel","url":"https://example.com/`+name+`"}`)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodPost {
+				mu.Lock()
+				keys[name] = r.Header.Get("Authorization")
+				mu.Unlock()
+			}
+			fake.Config.Handler.ServeHTTP(w, r)
+		}))
+		t.Cleanup(server.Close)
+		return server
+	}
+
+	first := New(WithInstance(newServer("first").URL), WithAPIKey("first-key"))
+	second := New(WithInstance(newServer("second").URL), WithAPIKey("second-key"))
+
+	options := CreateDefaultSettings()
+	options.Url = "https://youtu.be/dQw4w9WgXcQ"
+	var wg sync.WaitGroup
+	for _, client := range []CobaltClient{first, second} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := client.Run(context.Background(), options); err != nil {
+				t.Errorf("run failed: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if keys["first"] != "Api-Key first-key" || keys["second"] != "Api-Key second-key" {
+		t.Fatalf("each instance should receive the key of its client, got %v", keys)
+	}
+}
+
+func TestContextVariantsAreCanceled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("no request should be sent with a canceled context")
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := CobaltServerInfoContext(ctx, server.URL); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if _, err := ProcessMediaContext(ctx, server.URL); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestClientPlaylistUsesItsHTTPClient(t *testing.T) {
+	var hosts []string
+	transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		hosts = append(hosts, req.URL.Host)
+		body := `["https://youtu.be/a","https://youtu.be/b"]`
+		if req.URL.Host == "www.youtube.com" {
+			body = `{"title":"Mix"}`
+		}
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(body)), Request: req}, nil
+	})
+	client := New(WithHTTPClient(&http.Client{Transport: transport}))
+
+	playlist := "https://www.youtube.com/playlist?list=PL1234567890"
+	list, err := client.YoutubePlaylist(context.Background(), playlist)
+	if err != nil || len(list) != 2 {
+		t.Fatalf("unexpected playlist %v, %v", list, err)
+	}
+	if title, err := client.YoutubePlaylistTitle(context.Background(), playlist); err != nil || title != "Mix" {
+		t.Fatalf("unexpected title %q, %v", title, err)
+	}
+	if len(hosts) != 2 {
+		t.Fatalf("every request should go through the client transport, got %v", hosts)
+	}
+}
+
+func TestLogLevels(t *testing.T) {
+	server, _ := flakyCobalt(t, 1)
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelInfo}))
+	levels := DefaultLogLevels
+	levels.Request = slog.LevelInfo
+	client := New(WithInstance(server.URL), WithLogger(logger), WithLogLevels(levels), WithRetry(RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond}))
+
+	options := CreateDefaultSettings()
+	options.Url = "https://youtu.be/dQw4w9WgXcQ"
+	if _, err := client.Run(context.Background(), options); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`msg="sending cobalt request"`, `code=error.api.capacity`, `msg="retrying cobalt request"`, `attempt=2`, `status=tunnel`} {
+		if !strings.Contains(logs.String(), want) {
+			t.Errorf("logs don't have %v:\n%v", want, logs.String())
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package gobalt
+
+import (
+	"context"
+	"time"
+)
+
+// Clock tells the time and waits. gobalt uses it for retry backoff and cache expiration, so tests can replace it
+// with a fake clock and fast-forward time instead of sleeping. See WithClock().
+type Clock interface {
+	Now() time.Time
+	//Sleep waits for d, or until ctx is done (returning ctx.Err()).
+	Sleep(ctx context.Context, d time.Duration) error
+}
+
+// SystemClock is the real clock, used by default.
+var SystemClock Clock = systemClock{}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) Sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// WithClock sets the clock used by the client for retries. Caches have their own Clock field.
+func WithClock(clock Clock) Option {
+	return func(c *Cobalt) {
+		if clock == nil {
+			clock = SystemClock
+		}
+		c.clock = clock
+	}
+}
+
+// orSystemClock returns clock, or SystemClock if it's nil.
+func orSystemClock(clock Clock) Clock {
+	if clock == nil {
+		return SystemClock
+	}
+	return clock
+}
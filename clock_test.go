@@ -0,0 +1,90 @@
+package gobalt
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeClock is a Clock where sleeping only moves the time forward.
+type fakeClock struct {
+	mu    sync.Mutex
+	now   time.Time
+	slept []time.Duration
+}
+
+func (f *fakeClock) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+func (f *fakeClock) Sleep(ctx context.Context, d time.Duration) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = f.now.Add(d)
+	f.slept = append(f.slept, d)
+	return ctx.Err()
+}
+
+func TestFakeClockRetry(t *testing.T) {
+	server, requests := flakyCobalt(t, 1000)
+	options := CreateDefaultSettings()
+	options.Url = "https://youtu.be/dQw4w9WgXcQ"
+
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	policy := RetryPolicy{MaxAttempts: 100, InitialBackoff: time.Minute, MaxBackoff: time.Hour, Budget: 2 * time.Hour}
+	client := New(WithInstance(server.URL), WithRetry(policy), WithClock(clock))
+
+	start := time.Now()
+	_, err := client.Run(context.Background(), options)
+	if !errors.Is(err, ErrRetryBudgetExhausted) {
+		t.Fatalf("expected ErrRetryBudgetExhausted, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("the fake clock should not sleep for real, took %v", elapsed)
+	}
+	//1+2+4+8+16+32 minutes fit in 2 hours, the next wait (64 minutes) doesn't.
+	want := []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute, 8 * time.Minute, 16 * time.Minute, 32 * time.Minute}
+	if len(clock.slept) != len(want) || requests.Load() != int32(len(want)+1) {
+		t.Fatalf("expected waits %v, got %v (%v requests)", want, clock.slept, requests.Load())
+	}
+	for i := range want {
+		if clock.slept[i] != want[i] {
+			t.Fatalf("expected waits %v, got %v", want, clock.slept)
+		}
+	}
+}
+
+func TestFakeClockServerInfoCache(t *testing.T) {
+	var fetches atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		w.Write([]byte(`{"cobalt":{"version":"10.5.0","services":["youtube"]}}`))
+	}))
+	defer server.Close()
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	cache := &ServerInfoCache{TTL: time.Hour, MaxStale: time.Minute, Clock: clock}
+	client := New()
+
+	for range 2 {
+		if _, err := cache.get(context.Background(), client, server.URL); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if fetches.Load() != 1 {
+		t.Fatalf("the second call should be cached, got %v fetches", fetches.Load())
+	}
+	clock.Sleep(context.Background(), 2*time.Hour)
+	if _, err := cache.get(context.Background(), client, server.URL); err != nil {
+		t.Fatal(err)
+	}
+	if fetches.Load() != 2 {
+		t.Fatalf("the value should expire once the fake clock moved past the TTL, got %v fetches", fetches.Load())
+	}
+}
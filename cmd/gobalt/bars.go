@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lostdusty/gobalt/v2"
+)
+
+// barWidth is the number of cells of a progress bar.
+const barWidth = 24
+
+// barProgress draws the downloads of a batch as stacked progress bars (name, percent, speed, ETA) with a summary line,
+// redrawing them in place with ANSI escape codes. It's fed by gobalt.BatchOptions.Progress.
+type barProgress struct {
+	mu       sync.Mutex
+	w        io.Writer
+	started  time.Time
+	last     time.Time
+	lines    int //Lines drawn by the last render, moved back over by the next one.
+	finished int //Items finished at the last render.
+}
+
+func newBarProgress(w io.Writer) *barProgress {
+	return &barProgress{w: w, started: time.Now()}
+}
+
+// update is a gobalt.BatchOptions.Progress function, it redraws at most every progressInterval, and always when an item finishes.
+func (b *barProgress) update(state gobalt.BatchProgress) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := time.Now()
+	finished := state.Succeeded + state.Failed
+	if now.Sub(b.last) < progressInterval && finished == b.finished {
+		return
+	}
+	b.last, b.finished = now, finished
+	b.render(state, now)
+}
+
+func (b *barProgress) render(state gobalt.BatchProgress, now time.Time) {
+	var out strings.Builder
+	if b.lines > 0 {
+		fmt.Fprintf(&out, "\x1b[%dA", b.lines)
+	}
+	b.lines = 0
+	for _, item := range state.Items {
+		if item.Started.IsZero() {
+			continue
+		}
+		out.WriteString("\x1b[2K")
+		out.WriteString(itemLine(item, now))
+		out.WriteByte('\n')
+		b.lines++
+	}
+	speed := float64(state.Done) / max(now.Sub(b.started).Seconds(), 0.001)
+	fmt.Fprintf(&out, "\x1b[2K%v/%v done, %v failed, %v running, %v/s\n",
+		state.Succeeded, len(state.Items), state.Failed, state.Running, formatBytes(int64(speed)))
+	b.lines++
+	io.WriteString(b.w, out.String())
+}
+
+// itemLine formats the progress bar of one item.
+func itemLine(item gobalt.ItemProgress, now time.Time) string {
+	name := item.Name
+	if name == "" {
+		name = path.Base(item.Url)
+	}
+	name = truncate(name, 30)
+	switch {
+	case item.Err != nil:
+		return fmt.Sprintf("%-30v failed: %v", name, item.Err)
+	case item.Finished:
+		return fmt.Sprintf("%-30v [%v] 100%% %v", name, strings.Repeat("=", barWidth), formatBytes(item.Done))
+	}
+
+	elapsed := max(now.Sub(item.Started).Seconds(), 0.001)
+	speed := float64(item.Done) / elapsed
+	if item.Total <= 0 {
+		return fmt.Sprintf("%-30v [%v] %v %v/s", name, strings.Repeat("?", barWidth), formatBytes(item.Done), formatBytes(int64(speed)))
+	}
+	ratio := min(float64(item.Done)/float64(item.Total), 1)
+	filled := int(ratio * barWidth)
+	bar := strings.Repeat("=", filled) + strings.Repeat(" ", barWidth-filled)
+	eta := "--:--"
+	if speed > 0 {
+		left := time.Duration(float64(item.Total-item.Done) / speed * float64(time.Second))
+		eta = fmt.Sprintf("%02d:%02d", int(left.Minutes()), int(left.Seconds())%60)
+	}
+	return fmt.Sprintf("%-30v [%v] %3.0f%% %v/s ETA %v", name, bar, ratio*100, formatBytes(int64(speed)), eta)
+}
+
+// truncate shortens s to n characters, ending it with "…" if it was cut.
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n-1]) + "…"
+}
+
+// formatBytes formats a size like "1.5 MiB".
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%v B", n)
+	}
+	value, exp := float64(n)/unit, 0
+	for value >= unit && exp < 4 {
+		value /= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", value, "KMGTP"[exp])
+}
+
+// isTerminal reports if w is a terminal, where progress bars can be drawn.
+func isTerminal(w io.Writer) bool {
+	file, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
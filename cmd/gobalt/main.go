@@ -0,0 +1,368 @@
+// Command gobalt downloads media from the command line using a cobalt instance.
+//
+// Usage:
+//
+//	gobalt get [flags] URL...  Downloads the media at each URL, several at the same time. Also named download.
+//	gobalt info [flags]        Shows information about the instance.
+//	gobalt instances [flags]   Lists public cobalt instances.
+//	gobalt serve [flags]       Serves the gobalt REST api (see package server) and a status page.
+//
+// Every subcommand accepts --json, which prints machine-readable results on stdout (human text always goes to stderr).
+// get draws progress bars on stderr when it's a terminal (or with --progress bars). It also accepts --progress json, which
+// prints the progress as newline-delimited JSON events on stdout; with --json, the result is then printed as a single line after them.
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/lostdusty/gobalt/v2"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// command is a subcommand of the CLI, it returns the value printed with --json.
+type command func(ctx context.Context, cli *cli, args []string) (any, error)
+
+var commands = map[string]command{
+	"get":       getCommand,
+	"download":  getCommand,
+	"info":      infoCommand,
+	"instances": instancesCommand,
+	"serve":     serveCommand,
+}
+
+// cli holds the flags shared by every subcommand, and where to write output.
+type cli struct {
+	stdout   io.Writer
+	stderr   io.Writer
+	json     bool
+	compact  bool //Print the --json result on a single line, so stdout stays newline-delimited JSON.
+	instance string
+	apiKey   string
+	cache    string //File where instance lists are cached between runs, see gobalt.InstanceListCache.
+	flags    *flag.FlagSet
+}
+
+// run runs the CLI with args (without the program name) and returns the exit code.
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 || commands[args[0]] == nil {
+		fmt.Fprintln(stderr, "usage: gobalt <get|download|info|instances|serve> [flags]")
+		return 2
+	}
+	name := args[0]
+	c := &cli{stdout: stdout, stderr: stderr, flags: flag.NewFlagSet(name, flag.ContinueOnError)}
+	c.flags.SetOutput(stderr)
+	c.flags.BoolVar(&c.json, "json", false, "print machine-readable results on stdout")
+	c.flags.StringVar(&c.instance, "instance", gobalt.CobaltApi, "url of the cobalt instance")
+	c.flags.StringVar(&c.apiKey, "api-key", cmp.Or(os.Getenv("GOBALT_API_KEY"), gobalt.ApiKey), "api key of the instance (default: GOBALT_API_KEY, or COBALT_API_KEY)")
+	c.flags.StringVar(&c.cache, "instance-cache", defaultInstanceCache(), "file where instance lists are cached between runs, empty to disable")
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	result, err := commands[name](ctx, c, args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+	//Commands can return a result with an error when part of the work failed, like a batch.
+	if c.json && result != nil {
+		encoder := json.NewEncoder(stdout)
+		if !c.compact {
+			encoder.SetIndent("", "  ")
+		}
+		encoder.Encode(result)
+	}
+	if err != nil {
+		c.fail(err)
+		return 1
+	}
+	return 0
+}
+
+// client creates the gobalt client from the shared flags.
+func (c *cli) client() *gobalt.Cobalt {
+	opts := []gobalt.Option{gobalt.WithInstance(c.instance), gobalt.WithAPIKey(c.apiKey)}
+	if c.cache != "" {
+		opts = append(opts, gobalt.WithInstanceListCache(gobalt.NewInstanceListCache(10*time.Minute, c.cache)))
+	}
+	return gobalt.New(opts...)
+}
+
+// defaultInstanceCache is the instance cache file in the cache directory of the user, or "" if there's none.
+func defaultInstanceCache() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "gobalt", "instances.json")
+}
+
+// printf writes human text to stderr.
+func (c *cli) printf(format string, a ...any) {
+	fmt.Fprintf(c.stderr, format, a...)
+}
+
+// jsonError is how errors are printed with --json.
+type jsonError struct {
+	Error struct {
+		Code    string `json:"code,omitempty"` //Cobalt error code, like "error.api.link.invalid".
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// fail reports err, with its cobalt error code when it has one.
+func (c *cli) fail(err error) {
+	code := errorCode(err)
+	message := err.Error()
+	if code != "" {
+		message = gobalt.ResolveError(err)
+	}
+	c.printf("error: %v\n", message)
+	if c.json {
+		var out jsonError
+		out.Error.Code = code
+		out.Error.Message = message
+		json.NewEncoder(c.stdout).Encode(out)
+	}
+}
+
+// errorCode returns the code of the gobalt.CobaltError in err, like "error.api.link.invalid", or "" if there's none.
+func errorCode(err error) string {
+	var cobaltErr *gobalt.CobaltError
+	if errors.As(err, &cobaltErr) {
+		return cobaltErr.Code
+	}
+	return ""
+}
+
+// getResult is printed by get with --json.
+type getResult struct {
+	Response *gobalt.CobaltResponse `json:"response"`
+	Path     string                 `json:"path,omitempty"` //Where the file was saved, empty for picker responses.
+	Size     int64                  `json:"size,omitempty"`
+}
+
+func getCommand(ctx context.Context, c *cli, args []string) (any, error) {
+	output := c.flags.String("o", ".", `directory to save the file to, or "-" to write it to stdout`)
+	audio := c.flags.Bool("audio", false, "download only the audio")
+	mute := c.flags.Bool("mute", false, "download the video without audio")
+	quality := c.flags.Int("quality", 0, "video quality, like 720 or 2160 (default 1080)")
+	playlist := c.flags.Bool("playlist", false, "the url is a YouTube playlist, download every video of it")
+	progress := c.flags.String("progress", "", `"bars" to draw progress bars on stderr (default when it's a terminal), "json" to print the progress as newline-delimited JSON events on stdout`)
+	concurrency := c.flags.Int("concurrency", 4, "how many files are downloaded at the same time")
+	history := c.flags.String("history", "", "file where finished downloads are recorded, media already in it is skipped")
+	force := c.flags.Bool("force", false, "download media even if it's already in the --history file")
+	if err := c.flags.Parse(args); err != nil {
+		return nil, err
+	}
+	if *progress != "" && *progress != "json" && *progress != "bars" {
+		return nil, fmt.Errorf("unknown progress format %q", *progress)
+	}
+	if c.flags.NArg() == 0 {
+		return nil, errors.New("usage: gobalt get [flags] URL...")
+	}
+	if *output == "-" && (c.json || *progress == "json" || c.flags.NArg() > 1 || *playlist) {
+		return nil, errors.New("--json, --progress json, --playlist and several urls can't be used with -o -, stdout is used for the media")
+	}
+	if *playlist && c.flags.NArg() > 1 {
+		return nil, errors.New("--playlist takes a single url")
+	}
+	if *progress == "" && *output != "-" && isTerminal(c.stderr) {
+		*progress = "bars"
+	}
+
+	var items []gobalt.Settings
+	for _, link := range c.flags.Args() {
+		options := gobalt.CreateDefaultSettings()
+		options.Url = link
+		switch {
+		case *audio:
+			options.Mode = gobalt.Audio
+		case *mute:
+			options.Mode = gobalt.Mute
+		}
+		if *quality > 0 {
+			options.VideoQuality = *quality
+		}
+		items = append(items, options)
+	}
+	var opts []gobalt.DownloadOption
+	if *history != "" {
+		h, err := gobalt.OpenHistoryFile(*history)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, gobalt.WithHistory(h))
+	}
+	if *force {
+		opts = append(opts, gobalt.WithForce())
+	}
+	if *playlist {
+		return c.getPlaylist(ctx, items[0], *output, opts)
+	}
+	if len(items) > 1 {
+		return c.getBatch(ctx, items, *output, *progress, *concurrency, opts)
+	}
+
+	options := items[0]
+	var events *jsonProgress
+	switch *progress {
+	case "json":
+		c.compact = true
+		events = newJSONProgress(c.stdout, options.Url)
+		events.phase("resolving", nil)
+		opts = append(opts, gobalt.WithProgress(events.update))
+	case "bars":
+		bars := newBarProgress(c.stderr)
+		item := gobalt.ItemProgress{Url: options.Url, Started: time.Now()}
+		opts = append(opts, gobalt.WithProgress(func(done, total int64) {
+			item.Done, item.Total = done, total
+			bars.update(gobalt.BatchProgress{Items: []gobalt.ItemProgress{item}, Running: 1, Done: done})
+		}))
+	}
+	result, err := c.get(ctx, options, *output, opts)
+	if events != nil {
+		if err != nil {
+			events.phase("failed", err)
+		} else {
+			events.phase("done", nil)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if result == nil {
+		return nil, nil
+	}
+	return result, nil
+}
+
+// batchItemResult is an item of the result printed by get with several urls and --json.
+type batchItemResult struct {
+	Url   string `json:"url"`
+	Path  string `json:"path,omitempty"`
+	Size  int64  `json:"size,omitempty"`
+	Code  string `json:"code,omitempty"`  //Cobalt error code, if the item failed with one.
+	Error string `json:"error,omitempty"` //Why the item failed.
+}
+
+// getBatch downloads several items at the same time with gobalt.DownloadBatch(), see getCommand.
+func (c *cli) getBatch(ctx context.Context, items []gobalt.Settings, output, progress string, concurrency int, download []gobalt.DownloadOption) (any, error) {
+	opts := gobalt.BatchOptions{Dir: output, Concurrency: concurrency, Download: download}
+	switch progress {
+	case "json":
+		c.compact = true
+		opts.Progress = newJSONBatchProgress(c.stdout, items)
+	case "bars":
+		opts.Progress = newBarProgress(c.stderr).update
+	}
+	report := c.client().DownloadBatch(ctx, items, opts)
+	return c.batchResults(report, progress)
+}
+
+// getPlaylist downloads every video of the playlist at options.Url with gobalt.DownloadPlaylist(), see getCommand.
+// There's no progress: each saved video is printed instead.
+func (c *cli) getPlaylist(ctx context.Context, options gobalt.Settings, output string, opts []gobalt.DownloadOption) (any, error) {
+	report, err := c.client().DownloadPlaylist(ctx, options.Url, output, options, opts...)
+	if err != nil {
+		return nil, err
+	}
+	c.printf("%v\n", report.Summary())
+	return c.batchResults(report.BatchReport, "")
+}
+
+// batchResults prints what happened to each item of report, and returns the result printed with --json.
+func (c *cli) batchResults(report *gobalt.BatchReport, progress string) (any, error) {
+	results := make([]batchItemResult, len(report.Entries))
+	for i, entry := range report.Entries {
+		results[i].Url = entry.Url
+		if entry.Err != nil {
+			c.printf("failed %v: %v\n", entry.Url, entry.Err)
+			results[i].Code, results[i].Error = errorCode(entry.Err), entry.Err.Error()
+			continue
+		}
+		results[i].Path, results[i].Size = entry.Result.Path, entry.Result.Size
+		if progress != "bars" {
+			c.printf("saved %v (%v bytes)\n", entry.Result.Path, entry.Result.Size)
+		}
+	}
+	if report.Failed > 0 {
+		return results, fmt.Errorf("%v of %v downloads failed", report.Failed, len(report.Entries))
+	}
+	return results, nil
+}
+
+// get sends options to cobalt and saves the media in output, see getCommand.
+func (c *cli) get(ctx context.Context, options gobalt.Settings, output string, opts []gobalt.DownloadOption) (*getResult, error) {
+	media, err := c.client().Run(ctx, options)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &getResult{Response: media}
+	if media.Status == "picker" {
+		c.printf("%v has several media, download them one by one:\n", options.Url)
+		for _, item := range *media.Picker {
+			c.printf("  %v %v\n", item.Type, item.URL)
+		}
+		return result, nil
+	}
+	if output == "-" {
+		streamed, err := media.StreamTo(ctx, c.stdout, opts...)
+		if err != nil {
+			return nil, err
+		}
+		c.printf("wrote %v bytes to stdout\n", streamed.Size)
+		return nil, nil
+	}
+	saved, err := media.SaveToContext(ctx, output, opts...)
+	if errors.Is(err, gobalt.ErrAlreadyDownloaded) {
+		c.printf("skipped %v, it was already downloaded (use --force to download it again)\n", options.Url)
+		return result, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	result.Path, result.Size = saved.Path, saved.Size
+	c.printf("saved %v (%v bytes)\n", saved.Path, saved.Size)
+	return result, nil
+}
+
+func infoCommand(ctx context.Context, c *cli, args []string) (any, error) {
+	if err := c.flags.Parse(args); err != nil {
+		return nil, err
+	}
+	info, err := c.client().ServerInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c.printf("%v: cobalt %v\nservices: %v\n", c.instance, info.Cobalt.Version, strings.Join(info.Cobalt.Services, ", "))
+	return info, nil
+}
+
+func instancesCommand(ctx context.Context, c *cli, args []string) (any, error) {
+	if err := c.flags.Parse(args); err != nil {
+		return nil, err
+	}
+	instances, err := c.client().Instances(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, instance := range instances {
+		c.printf("%v (cobalt %v, score %v)\n", instance.API, instance.Version, instance.Score)
+	}
+	return instances, nil
+}
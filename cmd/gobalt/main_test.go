@@ -0,0 +1,296 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/lostdusty/gobalt/v2"
+	gobaltserver "github.com/lostdusty/gobalt/v2/server"
+)
+
+// fakeCobalt is a fake cobalt instance, that answers every request for media with response.
+func fakeCobalt(t *testing.T, response func(host string) string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/file":
+			w.Write([]byte("media"))
+		case r.Method == http.MethodGet:
+			w.Write([]byte(`{"cobalt":{"version":"10.5.0","services":["youtube"]}}`))
+		default:
+			w.Write([]byte(response(r.Host)))
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestGetJSON(t *testing.T) {
+	server := fakeCobalt(t, func(host string) string {
+		return `{"status":"tunnel","url":"http://` + host + `/file","filename":"video.mp4"}`
+	})
+	dir := t.TempDir()
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"get", "--json", "--instance", server.URL, "-o", dir, "https://youtu.be/dQw4w9WgXcQ"}, &stdout, &stderr)
+	if code != 0 {
+		t.Fatalf("exit code %v, stderr: %v", code, stderr.String())
+	}
+	var result getResult
+	if err := json.Unmarshal(stdout.Bytes(), &result); err != nil {
+		t.Fatalf("stdout is not json: %v\n%v", err, stdout.String())
+	}
+	if result.Path != filepath.Join(dir, "video.mp4") || result.Size != 5 || result.Response.Status != "tunnel" {
+		t.Fatalf("unexpected result %+v", result)
+	}
+	if data, _ := os.ReadFile(result.Path); string(data) != "media" {
+		t.Fatalf("file was not saved, got %q", data)
+	}
+}
+
+func TestErrorJSON(t *testing.T) {
+	server := fakeCobalt(t, func(string) string {
+		return `{"status":"error","error":{"code":"error.api.content.video.unavailable"}}`
+	})
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"get", "--json", "--instance", server.URL, "https://youtu.be/dQw4w9WgXcQ"}, &stdout, &stderr)
+	if code != 1 {
+		t.Fatalf("expected exit code 1, got %v", code)
+	}
+	var result jsonError
+	if err := json.Unmarshal(stdout.Bytes(), &result); err != nil {
+		t.Fatalf("stdout is not json: %v\n%v", err, stdout.String())
+	}
+	if result.Error.Code != "error.api.content.video.unavailable" || result.Error.Message == "" {
+		t.Fatalf("unexpected error %+v", result)
+	}
+	if stderr.Len() == 0 {
+		t.Fatal("the error should also be printed on stderr")
+	}
+}
+
+func TestServeMux(t *testing.T) {
+	cobalt := fakeCobalt(t, func(host string) string {
+		return `{"status":"redirect","url":"http://` + host + `/file","filename":"video.mp4"}`
+	})
+	c := &cli{instance: cobalt.URL}
+	server := httptest.NewServer(newServeMux(gobaltserver.New(c.client(), "")))
+	defer server.Close()
+
+	res, err := http.Get(server.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	page, _ := io.ReadAll(res.Body)
+	res.Body.Close()
+	if !strings.Contains(string(page), "cobalt 10.5.0") {
+		t.Fatalf("status page should show the instance version, got %s", page)
+	}
+
+	res, err = http.Post(server.URL+"/api/download", "application/json", strings.NewReader(`{"url":"https://youtu.be/dQw4w9WgXcQ"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("api should be served under /api/, got %v", res.Status)
+	}
+}
+
+func TestGetToStdout(t *testing.T) {
+	server := fakeCobalt(t, func(host string) string {
+		return `{"status":"tunnel","url":"http://` + host + `/file","filename":"video.mp4"}`
+	})
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"get", "--instance", server.URL, "-o", "-", "https://youtu.be/dQw4w9WgXcQ"}, &stdout, &stderr)
+	if code != 0 {
+		t.Fatalf("exit code %v, stderr: %v", code, stderr.String())
+	}
+	if stdout.String() != "media" {
+		t.Fatalf("stdout should only have the media, got %q", stdout.String())
+	}
+}
+
+func TestProgressJSON(t *testing.T) {
+	server := fakeCobalt(t, func(host string) string {
+		return `{"status":"tunnel","url":"http://` + host + `/file","filename":"video.mp4"}`
+	})
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"get", "--json", "--progress", "json", "--instance", server.URL, "-o", t.TempDir(), "https://youtu.be/dQw4w9WgXcQ"}, &stdout, &stderr)
+	if code != 0 {
+		t.Fatalf("exit code %v, stderr: %v", code, stderr.String())
+	}
+	var phases []string
+	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	for _, line := range lines[:len(lines)-1] {
+		var event progressEvent
+		if err := json.Unmarshal([]byte(line), &event); err != nil {
+			t.Fatalf("invalid event %q: %v", line, err)
+		}
+		if event.Job != "youtube:dQw4w9WgXcQ" {
+			t.Fatalf("unexpected job id %q", event.Job)
+		}
+		phases = append(phases, event.Phase)
+	}
+	if strings.Join(phases, ",") != "resolving,downloading,done" {
+		t.Fatalf("unexpected phases %v", phases)
+	}
+	var result getResult
+	if err := json.Unmarshal([]byte(lines[len(lines)-1]), &result); err != nil || result.Size != 5 {
+		t.Fatalf("last line should be the result, got %q", lines[len(lines)-1])
+	}
+}
+
+func TestGetSeveralWithBars(t *testing.T) {
+	var requests atomic.Int64
+	server := fakeCobalt(t, func(host string) string {
+		//Every item needs its own filename, or they would be saved over each other.
+		return fmt.Sprintf(`{"status":"tunnel","url":"http://%v/file","filename":"video%v.mp4"}`, host, requests.Add(1))
+	})
+	dir := t.TempDir()
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"get", "--json", "--progress", "bars", "--instance", server.URL, "-o", dir,
+		"https://youtu.be/dQw4w9WgXcQ", "https://youtu.be/jNQXAC9IVRw"}, &stdout, &stderr)
+	if code != 0 {
+		t.Fatalf("exit code %v, stderr: %v", code, stderr.String())
+	}
+	var results []batchItemResult
+	if err := json.Unmarshal(stdout.Bytes(), &results); err != nil || len(results) != 2 {
+		t.Fatalf("unexpected result %v: %v", stdout.String(), err)
+	}
+	if !strings.Contains(stderr.String(), "2/2 done, 0 failed") {
+		t.Fatalf("the summary line should be drawn, got %q", stderr.String())
+	}
+}
+
+func TestSdNotify(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notify")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Skipf("unix sockets are not available: %v", err)
+	}
+	defer conn.Close()
+	t.Setenv("NOTIFY_SOCKET", path)
+
+	if err := sdNotify("READY=1"); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 64)
+	n, err := conn.Read(buf)
+	if err != nil || string(buf[:n]) != "READY=1" {
+		t.Fatalf("unexpected notification %q, %v", buf[:n], err)
+	}
+	t.Setenv("WATCHDOG_USEC", "2000000")
+	if interval := watchdogInterval(); interval != time.Second {
+		t.Fatalf("expected the watchdog to be pinged every second, got %v", interval)
+	}
+}
+
+func TestDownloadQuality(t *testing.T) {
+	var quality atomic.Value
+	server := fakeCobalt(t, func(host string) string {
+		return `{"status":"tunnel","url":"http://` + host + `/file","filename":"video.mp4"}`
+	})
+	//Look at the requests sent to the fake instance.
+	handler := server.Config.Handler
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			var body map[string]any
+			data, _ := io.ReadAll(r.Body)
+			json.Unmarshal(data, &body)
+			quality.Store(body["videoQuality"])
+			r.Body = io.NopCloser(bytes.NewReader(data))
+		}
+		handler.ServeHTTP(w, r)
+	})
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"download", "--quality", "720", "--instance", server.URL, "-o", t.TempDir(), "https://youtu.be/dQw4w9WgXcQ"}, &stdout, &stderr)
+	if code != 0 {
+		t.Fatalf("exit code %v, stderr: %v", code, stderr.String())
+	}
+	if q := quality.Load(); q != "720" {
+		t.Fatalf("expected quality 720 to be sent, got %v", q)
+	}
+}
+
+func TestAPIKeyDefault(t *testing.T) {
+	var authorization atomic.Value
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			w.Write([]byte(`{"cobalt":{"version":"10.5.0","services":["youtube"]}}`))
+			return
+		}
+		authorization.Store(r.Header.Get("Authorization"))
+		w.Write([]byte(`{"status":"error","error":{"code":"error.api.link.invalid"}}`))
+	}))
+	defer server.Close()
+
+	saved := gobalt.ApiKey
+	defer func() { gobalt.ApiKey = saved }()
+	gobalt.ApiKey = "from-cobalt-api-key"
+	t.Setenv("GOBALT_API_KEY", "")
+	var stdout, stderr bytes.Buffer
+	run([]string{"get", "--instance", server.URL, "https://youtu.be/dQw4w9WgXcQ"}, &stdout, &stderr)
+	if got := authorization.Load(); got != "Api-Key from-cobalt-api-key" {
+		t.Fatalf("the key of the library (COBALT_API_KEY) should be used by default, got %q", got)
+	}
+
+	t.Setenv("GOBALT_API_KEY", "from-gobalt-api-key")
+	run([]string{"get", "--instance", server.URL, "https://youtu.be/dQw4w9WgXcQ"}, &stdout, &stderr)
+	if got := authorization.Load(); got != "Api-Key from-gobalt-api-key" {
+		t.Fatalf("GOBALT_API_KEY should win, got %q", got)
+	}
+}
+
+func TestGetHistory(t *testing.T) {
+	var downloads atomic.Int32
+	server := fakeCobalt(t, func(host string) string {
+		return `{"status":"tunnel","url":"http://` + host + `/file","filename":"video.mp4"}`
+	})
+	handler := server.Config.Handler
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/file" {
+			downloads.Add(1)
+		}
+		handler.ServeHTTP(w, r)
+	})
+	dir := t.TempDir()
+	history := filepath.Join(dir, "history.json")
+
+	get := func(flags ...string) string {
+		var stdout, stderr bytes.Buffer
+		args := append([]string{"get", "--instance", server.URL, "--history", history, "-o", dir}, flags...)
+		if code := run(append(args, "https://youtu.be/dQw4w9WgXcQ"), &stdout, &stderr); code != 0 {
+			t.Fatalf("exit code %v, stderr: %v", code, stderr.String())
+		}
+		return stderr.String()
+	}
+	get()
+	if out := get(); !strings.Contains(out, "skipped") || downloads.Load() != 1 {
+		t.Fatalf("the second download should be skipped, got %v downloads and %q", downloads.Load(), out)
+	}
+	get("--audio")
+	if downloads.Load() != 2 {
+		t.Fatalf("the audio of a downloaded video should be downloaded, got %v downloads", downloads.Load())
+	}
+	get("--force")
+	if downloads.Load() != 3 {
+		t.Fatalf("--force should download the media again, got %v downloads", downloads.Load())
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/lostdusty/gobalt/v2"
+)
+
+// progressEvent is a line of --progress json output.
+type progressEvent struct {
+	Job     string  `json:"job"`               //Id of the download, the media key of its url (see gobalt.MediaKey()).
+	Phase   string  `json:"phase"`             //"resolving", "downloading", "done" or "failed".
+	Bytes   int64   `json:"bytes"`             //Bytes downloaded so far.
+	Total   int64   `json:"total,omitempty"`   //Size of the file, if known.
+	Percent float64 `json:"percent,omitempty"` //Progress from 0 to 100, if the size is known.
+	Speed   float64 `json:"speed"`             //Average speed in bytes per second.
+	Error   string  `json:"error,omitempty"`   //Why the download failed.
+}
+
+// How often "downloading" events are written.
+const progressInterval = 250 * time.Millisecond
+
+// jsonProgress writes the progress of a download as newline-delimited JSON events.
+type jsonProgress struct {
+	mu      sync.Mutex
+	encoder *json.Encoder
+	event   progressEvent
+	started time.Time
+	last    time.Time
+}
+
+func newJSONProgress(w io.Writer, link string) *jsonProgress {
+	return &jsonProgress{encoder: json.NewEncoder(w), event: progressEvent{Job: gobalt.MediaKey(link)}}
+}
+
+// phase writes an event for a new phase.
+func (p *jsonProgress) phase(phase string, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.event.Phase = phase
+	if err != nil {
+		p.event.Error = err.Error()
+	}
+	p.write()
+}
+
+// update is a gobalt.ProgressFunc, it writes "downloading" events at most every progressInterval.
+func (p *jsonProgress) update(done, total int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now()
+	if p.started.IsZero() {
+		p.started = now
+	}
+	p.event.Phase, p.event.Bytes, p.event.Total = "downloading", done, total
+	if total > 0 {
+		p.event.Percent = float64(done) * 100 / float64(total)
+	}
+	if elapsed := now.Sub(p.started).Seconds(); elapsed > 0 {
+		p.event.Speed = float64(done) / elapsed
+	}
+	if now.Sub(p.last) >= progressInterval || (total > 0 && done >= total) {
+		p.last = now
+		p.write()
+	}
+}
+
+func (p *jsonProgress) write() {
+	p.encoder.Encode(p.event)
+}
+
+// newJSONBatchProgress returns a gobalt.BatchOptions.Progress function writing the events of every item of a batch.
+func newJSONBatchProgress(w io.Writer, items []gobalt.Settings) func(gobalt.BatchProgress) {
+	jobs := make([]*jsonProgress, len(items))
+	for i, item := range items {
+		jobs[i] = newJSONProgress(w, item.Url)
+	}
+	previous := make([]gobalt.ItemProgress, len(items))
+	return func(state gobalt.BatchProgress) {
+		for i, item := range state.Items {
+			before := previous[i]
+			if !item.Started.IsZero() && before.Started.IsZero() {
+				jobs[i].phase("resolving", nil)
+			}
+			if item.Done != before.Done {
+				jobs[i].update(item.Done, item.Total)
+			}
+			if item.Finished && !before.Finished {
+				if item.Err != nil {
+					jobs[i].phase("failed", item.Err)
+				} else {
+					jobs[i].phase("done", nil)
+				}
+			}
+		}
+		copy(previous, state.Items)
+	}
+}
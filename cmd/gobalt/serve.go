@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"html/template"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/lostdusty/gobalt/v2/server"
+)
+
+// statusPage is shown at / by gobalt serve.
+var statusPage = template.Must(template.New("status").Parse(`<!doctype html>
+<title>gobalt</title>
+<h1>gobalt</h1>
+<p>Instance: {{.Instance}} {{if .Version}}(cobalt {{.Version}}){{else}}(offline: {{.Error}}){{end}}</p>
+<p>Running since {{.Started.Format "2006-01-02 15:04:05"}}.</p>
+<p>Api: <code>POST /api/download</code>, <code>GET /api/instances</code>, <code>GET /api/status</code>, <code>/api/jobs</code>, <code>GET /healthz</code>.</p>
+`))
+
+// newServeMux returns the handler of gobalt serve: the REST api under /api/, the status page at / and
+// the health check at /healthz.
+func newServeMux(api *server.Handler) *http.ServeMux {
+	client := api.Client
+	started := time.Now()
+	mux := http.NewServeMux()
+	mux.Handle("/api/", http.StripPrefix("/api", api))
+	mux.HandleFunc("GET /healthz", api.Healthz)
+	mux.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
+		page := struct {
+			Instance, Version, Error string
+			Started                  time.Time
+		}{Instance: client.Instance(), Started: started}
+		if info, err := client.ServerInfo(r.Context()); err == nil {
+			page.Version = info.Cobalt.Version
+		} else {
+			page.Error = err.Error()
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		statusPage.Execute(w, page)
+	})
+	return mux
+}
+
+// serveCommand runs the REST api until ctx is done. It has no --json result, it only prints the address it listens on.
+// Under systemd (Type=notify), it reports when it's ready and stopping, and pings the watchdog if WatchdogSec= is set.
+func serveCommand(ctx context.Context, c *cli, args []string) (any, error) {
+	listen := c.flags.String("listen", ":8080", "address to listen on")
+	output := c.flags.String("o", "", "directory downloads are saved to, if empty only the cobalt response is returned")
+	if err := c.flags.Parse(args); err != nil {
+		return nil, err
+	}
+
+	listener, err := net.Listen("tcp", *listen)
+	if err != nil {
+		return nil, err
+	}
+	api := server.New(c.client(), *output)
+	httpServer := &http.Server{Handler: newServeMux(api), ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		httpServer.Shutdown(shutdown)
+		//Jobs still running are paused, keeping their partial files.
+		api.Shutdown(shutdown)
+	}()
+	c.printf("listening on http://%v\n", listener.Addr())
+	if err := sdNotify("READY=1"); err != nil {
+		c.printf("failed to notify systemd: %v\n", err)
+	}
+	if interval := watchdogInterval(); interval > 0 {
+		go watchdog(ctx, interval)
+	}
+	err = httpServer.Serve(listener)
+	sdNotify("STOPPING=1")
+	if !errors.Is(err, http.ErrServerClosed) {
+		return nil, err
+	}
+	return nil, nil
+}
+
+// watchdog pings the systemd watchdog every interval until ctx is done.
+func watchdog(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			sdNotify("WATCHDOG=1")
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package gobalt
+
+import (
+	"errors"
+	"strings"
+)
+
+// Classes of errors, to check a failure with errors.Is() instead of comparing codes:
+//
+//	if errors.Is(err, gobalt.ErrRateLimited) { ... }
+//
+// A CobaltError matches the class of its code, and an InvalidResponseError matches ErrNetwork.
+var (
+	ErrRateLimited        = errors.New("rate limited")                      //error.api.rate_exceeded and error.api.fetch.rate.
+	ErrAuthRequired       = errors.New("authentication required")           //error.api.auth.*, the instance needs a valid api key or token.
+	ErrUnsupportedService = errors.New("service not supported")             //error.api.service.* and error.api.link.unsupported.
+	ErrInvalidLink        = errors.New("invalid link")                      //error.api.link.invalid.
+	ErrContentUnavailable = errors.New("content unavailable")               //error.api.content.*, the media can't be downloaded by any instance.
+	ErrFetchFailed        = errors.New("failed to fetch the media")         //error.api.fetch.* and error.api.youtube.*, the instance couldn't get the media from the service.
+	ErrServerError        = errors.New("instance failed")                   //error.api.capacity, error.api.generic and error.api.unknown_response.
+	ErrNetwork            = errors.New("failed to reach the cobalt server") //error.net.*, the instance is unreachable or answered with something invalid.
+)
+
+// errorClasses maps code prefixes to the classes they belong to. A code can be in more than one class.
+var errorClasses = []struct {
+	prefix string
+	class  error
+}{
+	{"error.api.rate_exceeded", ErrRateLimited},
+	{"error.api.fetch.rate", ErrRateLimited},
+	{"error.api.auth.", ErrAuthRequired},
+	{"error.api.service.", ErrUnsupportedService},
+	{"error.api.link.unsupported", ErrUnsupportedService},
+	{"error.api.link.invalid", ErrInvalidLink},
+	{"error.api.content.", ErrContentUnavailable},
+	{"error.api.fetch.", ErrFetchFailed},
+	{"error.api.youtube.", ErrFetchFailed},
+	{"error.api.capacity", ErrServerError},
+	{"error.api.generic", ErrServerError},
+	{"error.api.unknown_response", ErrServerError},
+	{"error.net.", ErrNetwork},
+}
+
+// CobaltError is an error with a cobalt error code, answered by an instance (like "error.api.link.invalid")
+// or found while talking to it (like "error.net.failed"). Use errors.As() to get it, or errors.Is() with the classes
+// above (ErrRateLimited, ErrAuthRequired...) to branch on the kind of failure.
+//
+// Its message is the code, followed by Err if there's one, so it still works with ResolveError() and string comparisons.
+type CobaltError struct {
+	Code         string  //Cobalt error code, like "error.api.link.invalid".
+	Context      Context //Context sent by cobalt with the error, zero if there's none.
+	HumanMessage string  //Description of the code in the language of the client, see ResolveError(). Empty if the code is unknown.
+	Instance     string  //Instance that failed, if known.
+	RequestID    string  //Id of the failed request sent by the instance (X-Request-Id or CF-Ray header), if any.
+	Err          error   //Error behind the code, if any (e.g. the connection error of "error.net.failed").
+}
+
+func (e *CobaltError) Error() string {
+	if e.Err != nil {
+		return e.Code + ": " + e.Err.Error()
+	}
+	return e.Code
+}
+
+func (e *CobaltError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports if target is the class of the code, see ErrRateLimited and the others.
+func (e *CobaltError) Is(target error) bool {
+	return isErrorClass(e.Code, target)
+}
+
+// Is reports if target is ErrNetwork, invalid responses are "error.net.invalid_response".
+func (e *InvalidResponseError) Is(target error) bool {
+	return target == ErrNetwork
+}
+
+func isErrorClass(code string, target error) bool {
+	for _, class := range errorClasses {
+		if class.class == target && strings.HasPrefix(code, class.prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// newCobaltError creates a CobaltError for code, described in language.
+func newCobaltError(language, code, instance string, err error) *CobaltError {
+	human, _ := errDescription(language, code)
+	return &CobaltError{Code: code, HumanMessage: human, Instance: instance, Err: err}
+}
@@ -0,0 +1,46 @@
+package gobalt
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestCobaltError(t *testing.T) {
+	server := fakeCobalt(t, `{"status":"error","error":{"code":"error.api.fetch.rate","context":{"service":"youtube"}}}`)
+	options := CreateDefaultSettings()
+	options.Url = "https://youtu.be/dQw4w9WgXcQ"
+	_, err := New(WithInstance(server.URL)).Run(context.Background(), options)
+
+	var cobaltErr *CobaltError
+	if !errors.As(err, &cobaltErr) {
+		t.Fatalf("expected a CobaltError, got %#v", err)
+	}
+	if cobaltErr.Code != "error.api.fetch.rate" || cobaltErr.Context.Service != "youtube" || cobaltErr.HumanMessage == "" {
+		t.Fatalf("got unexpected error %+v", cobaltErr)
+	}
+	if err.Error() != "error.api.fetch.rate" {
+		t.Fatalf("the message should stay the code, got %q", err)
+	}
+	if !errors.Is(err, ErrRateLimited) || !errors.Is(err, ErrFetchFailed) || errors.Is(err, ErrInvalidLink) {
+		t.Fatalf("error.api.fetch.rate is in the wrong classes")
+	}
+}
+
+func TestErrorClasses(t *testing.T) {
+	for code, class := range map[string]error{
+		"error.api.auth.jwt.missing":          ErrAuthRequired,
+		"error.api.link.invalid":              ErrInvalidLink,
+		"error.api.link.unsupported":          ErrUnsupportedService,
+		"error.api.content.video.unavailable": ErrContentUnavailable,
+		"error.api.capacity":                  ErrServerError,
+		"error.net.failed":                    ErrNetwork,
+	} {
+		if !errors.Is(&CobaltError{Code: code}, class) {
+			t.Errorf("%v should be %v", code, class)
+		}
+	}
+	if !errors.Is(&InvalidResponseError{}, ErrNetwork) {
+		t.Errorf("invalid responses should be ErrNetwork")
+	}
+}
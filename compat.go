@@ -0,0 +1,221 @@
+package gobalt
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/mcuadros/go-version"
+)
+
+// fieldAvailability says which cobalt versions understand a request field.
+type fieldAvailability struct {
+	since string //First version that knows the field, "" means every v10+ version.
+	until string //First version that doesn't know the field anymore, "" means it's still supported.
+}
+
+// Feature is something only some cobalt versions can do, see ServerInfo.Supports().
+type Feature string
+
+const (
+	FeatureYoutubeHLS      Feature = "youtubeHLS"      //Downloading YouTube videos from HLS streams, see Settings.YoutubeHLS.
+	FeatureBrowserDub      Feature = "browserDub"      //Picking the dubbed audio from Accept-Language, see Settings.YoutubeDubbedAudio.
+	FeatureBetterAudio     Feature = "betterAudio"     //Picking the best YouTube audio track, see Settings.YoutubeBetterAudio.
+	FeatureSubtitles       Feature = "subtitles"       //Adding subtitles to videos, see Settings.SubtitleLanguage.
+	FeatureLocalProcessing Feature = "localProcessing" //Answering with the parts of the media to process on the client (status local-processing).
+)
+
+// features says which cobalt v10+ versions have each Feature. v7 instances have none of them.
+var features = map[Feature]fieldAvailability{
+	FeatureYoutubeHLS:      {since: "10.2.0"},
+	FeatureBrowserDub:      {until: "10.5.0"},
+	FeatureBetterAudio:     {since: "10.9.0"},
+	FeatureSubtitles:       {since: "11.0.0"},
+	FeatureLocalProcessing: {since: "11.0.0"},
+}
+
+// requestFields lists the request fields (by their json name) that are not understood by every cobalt v10+ version.
+// Fields not listed here are always sent.
+var requestFields = map[string]fieldAvailability{
+	"subtitleLang":          features[FeatureSubtitles],
+	"youtubeHLS":            features[FeatureYoutubeHLS],
+	"youtubeBetterAudio":    features[FeatureBetterAudio],
+	"youtubeDubBrowserLang": features[FeatureBrowserDub],
+	"localProcessing":       features[FeatureLocalProcessing],
+}
+
+// Supports(feature) reports if the instance has feature, from its cobalt version. When the version is unknown (e.g. the
+// health check is disabled, see WithHealthCheck()) every feature is assumed to be there.
+func (s *ServerInfo) Supports(feature Feature) bool {
+	availability, ok := features[feature]
+	if !ok || isLegacyVersion(s.Cobalt.Version) {
+		return false
+	}
+	return availability.supportedBy(s.Cobalt.Version)
+}
+
+// Features() returns the features of the instance, sorted. See Supports().
+func (s *ServerInfo) Features() []Feature {
+	var supported []Feature
+	for feature := range features {
+		if s.Supports(feature) {
+			supported = append(supported, feature)
+		}
+	}
+	sort.Slice(supported, func(i, j int) bool { return supported[i] < supported[j] })
+	return supported
+}
+
+// wireName is the name a request field has in the cobalt versions set by fieldAvailability.
+type wireName struct {
+	fieldAvailability
+	name string
+}
+
+// requestRenames lists request fields (by their json name in Settings) that are called differently by some cobalt versions.
+// The first wireName that matches the server version is used, fields without a match keep their Settings name.
+var requestRenames = map[string][]wireName{
+	"twitterGif": {{fieldAvailability{since: "10.5.0"}, "convertGif"}},
+	"tiktokH265": {{fieldAvailability{since: "10.5.0"}, "allowH265"}},
+	//Older names (like filenamePattern for filenameStyle) are cobalt v7 ones, sent by runLegacy().
+}
+
+// UnsupportedParameterError is returned by Run() when a setting you changed from its default isn't supported by the
+// cobalt version of the instance, instead of the server silently ignoring it.
+// Settings left at their default value are just not sent to instances that don't support them.
+type UnsupportedParameterError struct {
+	Instance string //Url of the instance.
+	Version  string //Cobalt version of the instance.
+	Field    string //Name of the request field, like "subtitleLang".
+	Requires string //Versions that support the field, like ">= 11.0.0".
+}
+
+func (e *UnsupportedParameterError) Error() string {
+	return fmt.Sprintf("instance %v (v%v) does not support %v; requires %v", e.Instance, e.Version, e.Field, e.Requires)
+}
+
+// requirement describes the versions that support the field, like ">= 10.2.0" or "< 10.5.0".
+func (f fieldAvailability) requirement() string {
+	switch {
+	case f.since != "" && f.until != "":
+		return fmt.Sprintf(">= %v and < %v", f.since, f.until)
+	case f.since != "":
+		return ">= " + f.since
+	case f.until != "":
+		return "< " + f.until
+	}
+	return "any version"
+}
+
+// supportedBy reports if the field is understood by cobalt serverVersion. Unknown versions support everything.
+func (f fieldAvailability) supportedBy(serverVersion string) bool {
+	if serverVersion == "" {
+		return true
+	}
+	if f.since != "" && version.Compare(serverVersion, f.since, "<") {
+		return false
+	}
+	if f.until != "" && version.Compare(serverVersion, f.until, ">=") {
+		return false
+	}
+	return true
+}
+
+// unsupportedFields returns the json names of the request fields cobalt serverVersion doesn't know, sorted.
+func unsupportedFields(serverVersion string) []string {
+	var fields []string
+	for field, availability := range requestFields {
+		if !availability.supportedBy(serverVersion) {
+			fields = append(fields, field)
+		}
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// renamedFields returns the fields that must be sent with another name to cobalt serverVersion, as Settings name -> wire name.
+// Nothing is renamed for unknown versions.
+func renamedFields(serverVersion string) map[string]string {
+	if serverVersion == "" {
+		return nil
+	}
+	renames := make(map[string]string)
+	for field, names := range requestRenames {
+		for _, name := range names {
+			if name.supportedBy(serverVersion) {
+				renames[field] = name.name
+				break
+			}
+		}
+	}
+	return renames
+}
+
+// shapeRequest encodes options for an instance running cobalt serverVersion, leaving out the fields it doesn't know
+// (instead of relying on the server to ignore them) and using the field names it expects.
+// Returns the body and the names of the fields left out that didn't have their default value, worth warning about.
+//
+// If a field that the instance doesn't know was changed from its default value, an *UnsupportedParameterError is returned instead.
+func shapeRequest(options Settings, instance, serverVersion string) (*pooledBody, []string, error) {
+	unsupported := unsupportedFields(serverVersion)
+	renames := renamedFields(serverVersion)
+	if len(unsupported) == 0 && len(renames) == 0 {
+		body, err := encodeJSON(options)
+		return body, nil, err
+	}
+
+	encoded, err := json.Marshal(options)
+	if err != nil {
+		return nil, nil, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(encoded, &fields); err != nil {
+		return nil, nil, err
+	}
+	defaults := defaultRequestFields()
+	var changed []string
+	for _, field := range unsupported {
+		value, ok := fields[field]
+		if ok && isSetByUser(value, defaults[field]) {
+			return nil, nil, &UnsupportedParameterError{
+				Instance: instance,
+				Version:  serverVersion,
+				Field:    field,
+				Requires: requestFields[field].requirement(),
+			}
+		}
+		if ok && !bytes.Equal(value, defaults[field]) {
+			changed = append(changed, field)
+		}
+		delete(fields, field)
+	}
+	for field, name := range renames {
+		if value, ok := fields[field]; ok {
+			delete(fields, field)
+			fields[name] = value
+		}
+	}
+	body, err := encodeJSON(fields)
+	return body, changed, err
+}
+
+// defaultRequestFields returns the encoded fields of CreateDefaultSettings(), by json name.
+func defaultRequestFields() map[string]json.RawMessage {
+	encoded, _ := json.Marshal(CreateDefaultSettings())
+	var fields map[string]json.RawMessage
+	json.Unmarshal(encoded, &fields)
+	return fields
+}
+
+// isSetByUser reports if an encoded value was changed from its default to something meaningful (not empty, false or zero).
+func isSetByUser(value, defaultValue json.RawMessage) bool {
+	if bytes.Equal(value, defaultValue) {
+		return false
+	}
+	switch string(value) {
+	case `""`, "false", "0", `"0"`, "null":
+		return false
+	}
+	return true
+}
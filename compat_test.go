@@ -0,0 +1,194 @@
+package gobalt
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// versionedCobalt is a fake instance running cobalt serverVersion, that saves the body of the last POST request.
+func versionedCobalt(t *testing.T, serverVersion string, body *map[string]any) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			w.Write([]byte(`{"cobalt":{"version":"` + serverVersion + `","services":["youtube"]}}`))
+			return
+		}
+		json.NewDecoder(r.Body).Decode(body)
+		w.Write([]byte(`{"status":"tunnel","url":"https://example.com/tunnel"}`))
+	}))
+	t.Cleanup(server.Close)
+	//Test servers may get the port of an earlier one, don't let it answer with the cached version of another.
+	t.Cleanup(func() { DefaultServerInfoCache.Forget(server.URL) })
+	return server
+}
+
+func TestVersionAwareRequestShaping(t *testing.T) {
+	options := CreateDefaultSettings()
+	options.Url = "https://youtu.be/dQw4w9WgXcQ"
+
+	var logs bytes.Buffer
+	var body map[string]any
+	var handled []Warning
+	old := New(WithInstance(versionedCobalt(t, "10.0.3", &body).URL), WithLogger(slog.New(slog.NewTextHandler(&logs, nil))),
+		WithWarningHandler(func(w Warning) { handled = append(handled, w) }))
+	media, err := old.Run(context.Background(), options)
+	if err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	if len(media.Warnings) != 0 || len(handled) != 0 {
+		t.Fatalf("fields left with their default value should be dropped silently, got %v", media.Warnings)
+	}
+	if _, ok := body["youtubeHLS"]; ok {
+		t.Fatal("youtubeHLS should not be sent to cobalt 10.0.3")
+	}
+	if _, ok := body["youtubeDubBrowserLang"]; !ok {
+		t.Fatal("youtubeDubBrowserLang should be sent to cobalt 10.0.3")
+	}
+
+	//Turning HLS off is harmless for an instance without HLS, but it's worth telling.
+	noHLS := options
+	noHLS.YoutubeHLS = false
+	media, err = old.Run(context.Background(), noHLS)
+	if err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	if len(media.Warnings) != 1 || media.Warnings[0].Code != WarningFieldsDropped || len(handled) != 1 || len(media.Warnings[0].Fields) != 1 {
+		t.Fatalf("expected a fields_dropped warning for youtubeHLS, got %v (handler: %v)", media.Warnings, handled)
+	}
+	if !strings.Contains(logs.String(), "youtubeHLS") {
+		t.Fatalf("expected the dropped field to be logged, got %q", logs.String())
+	}
+
+	body = nil
+	recent := New(WithInstance(versionedCobalt(t, "10.7.1", &body).URL))
+	if _, err := recent.Run(context.Background(), options); err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	if _, ok := body["youtubeDubBrowserLang"]; ok {
+		t.Fatal("youtubeDubBrowserLang should not be sent to cobalt 10.7.1")
+	}
+	if body["youtubeHLS"] != true || body["url"] != options.Url {
+		t.Fatalf("other fields should be kept, got %v", body)
+	}
+}
+
+func TestRenamedRequestFields(t *testing.T) {
+	options := CreateDefaultSettings()
+	options.Url = "https://x.com/user/status/1"
+	options.TwitterConvertGif = false
+
+	var body map[string]any
+	client := New(WithInstance(versionedCobalt(t, "10.6.0", &body).URL))
+	if _, err := client.Run(context.Background(), options); err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	if _, ok := body["twitterGif"]; ok {
+		t.Fatal("twitterGif should be sent as convertGif to cobalt 10.6.0")
+	}
+	if body["convertGif"] != false || body["allowH265"] != false || body["filenameStyle"] != "basic" {
+		t.Fatalf("fields were not renamed correctly, got %v", body)
+	}
+
+	body = nil
+	client = New(WithInstance(versionedCobalt(t, "10.1.0", &body).URL))
+	if _, err := client.Run(context.Background(), options); err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	if body["twitterGif"] != false || body["tiktokH265"] != false {
+		t.Fatalf("older versions should receive the old names, got %v", body)
+	}
+}
+
+func TestUnsupportedParameter(t *testing.T) {
+	options := CreateDefaultSettings()
+	options.Url = "https://www.youtube.com/watch?v=dQw4w9WgXcQ"
+	options.SubtitleLanguage = "pt"
+
+	var body map[string]any
+	server := versionedCobalt(t, "10.0.3", &body)
+	_, err := New(WithInstance(server.URL)).Run(context.Background(), options)
+	var unsupported *UnsupportedParameterError
+	if !errors.As(err, &unsupported) {
+		t.Fatalf("expected an UnsupportedParameterError, got %v", err)
+	}
+	want := "instance " + server.URL + " (v10.0.3) does not support subtitleLang; requires >= 11.0.0"
+	if err.Error() != want {
+		t.Fatalf("got error %q, want %q", err, want)
+	}
+	if body != nil {
+		t.Fatal("the request should not have been sent")
+	}
+
+	//Fields left at their default value are still just dropped.
+	options.SubtitleLanguage = ""
+	if _, err := New(WithInstance(server.URL)).Run(context.Background(), options); err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+}
+
+func TestSubtitlesAndBetterAudio(t *testing.T) {
+	options := CreateDefaultSettings()
+	options.Url = "https://www.youtube.com/watch?v=dQw4w9WgXcQ"
+	options.SubtitleLanguage = "pt"
+	options.YoutubeBetterAudio = true
+
+	var body map[string]any
+	if _, err := New(WithInstance(versionedCobalt(t, "10.9.2", &body).URL)).Run(context.Background(), options); err == nil {
+		t.Fatal("subtitleLang should not be supported by cobalt 10.9.2")
+	}
+	options.SubtitleLanguage = ""
+	if _, err := New(WithInstance(versionedCobalt(t, "10.9.2", &body).URL)).Run(context.Background(), options); err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	if body["youtubeBetterAudio"] != true {
+		t.Fatalf("youtubeBetterAudio should be sent to cobalt 10.9.2, got %v", body)
+	}
+
+	media := new(CobaltResponse)
+	response := `{"status":"local-processing","tunnel":["https://cobalt.invalid/video","https://cobalt.invalid/audio","https://cobalt.invalid/subs"],"output":{"type":"video/mp4","filename":"video.mp4","subtitles":true}}`
+	if err := json.Unmarshal([]byte(response), media); err != nil {
+		t.Fatal(err)
+	}
+	if got := media.SubtitlesURL(); got != "https://cobalt.invalid/subs" {
+		t.Fatalf("got subtitles url %q", got)
+	}
+	if _, ok := media.Extra["tunnel"]; ok {
+		t.Fatal("tunnel should not be an extra field")
+	}
+	media.Output.Subtitles = false
+	if got := media.SubtitlesURL(); got != "" {
+		t.Fatalf("expected no subtitles url, got %q", got)
+	}
+}
+
+func TestFeatures(t *testing.T) {
+	tests := []struct {
+		version string
+		want    []Feature
+	}{
+		{"7.15", nil},
+		{"10.0.3", []Feature{FeatureBrowserDub}},
+		{"10.9.2", []Feature{FeatureBetterAudio, FeatureYoutubeHLS}},
+		{"11.2", []Feature{FeatureBetterAudio, FeatureLocalProcessing, FeatureSubtitles, FeatureYoutubeHLS}},
+	}
+	for _, test := range tests {
+		info := &ServerInfo{Cobalt: CobaltServerInformation{Version: test.version}}
+		if got := info.Features(); !slices.Equal(got, test.want) {
+			t.Errorf("features of cobalt %v: got %v, want %v", test.version, got, test.want)
+		}
+	}
+	if unknown := (&ServerInfo{}); !unknown.Supports(FeatureSubtitles) {
+		t.Error("an unknown version should be assumed to support everything")
+	}
+	if (&ServerInfo{}).Supports("teleport") {
+		t.Error("unknown features should not be supported")
+	}
+}
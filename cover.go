@@ -0,0 +1,109 @@
+package gobalt
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	_ "image/png"
+	"io"
+	"net/http"
+)
+
+// MaxCoverSize is the largest width or height of cover art made by NormalizeCover(), in pixels.
+const MaxCoverSize = 1000
+
+// ErrNoCover is returned by FetchCover() when no thumbnail could be found for a link.
+var ErrNoCover = errors.New("no cover art found")
+
+// NormalizeCover(data) converts a JPEG or PNG image to a JPEG that fits in MaxCoverSize x MaxCoverSize,
+// which every music player can show as cover art.
+func NormalizeCover(data []byte) ([]byte, error) {
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("invalid cover art: %w", err)
+	}
+	img = shrinkImage(img, MaxCoverSize)
+	var out bytes.Buffer
+	if err := jpeg.Encode(&out, img, &jpeg.Options{Quality: 90}); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// shrinkImage scales img down (keeping its aspect ratio) so its sides are at most size, averaging the source pixels.
+func shrinkImage(img image.Image, size int) image.Image {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width <= size && height <= size {
+		return img
+	}
+	newWidth, newHeight := size, max(height*size/width, 1)
+	if height > width {
+		newWidth, newHeight = max(width*size/height, 1), size
+	}
+
+	out := image.NewRGBA(image.Rect(0, 0, newWidth, newHeight))
+	for y := 0; y < newHeight; y++ {
+		y0, y1 := bounds.Min.Y+y*height/newHeight, bounds.Min.Y+max((y+1)*height/newHeight, y*height/newHeight+1)
+		for x := 0; x < newWidth; x++ {
+			x0, x1 := bounds.Min.X+x*width/newWidth, bounds.Min.X+max((x+1)*width/newWidth, x*width/newWidth+1)
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					pr, pg, pb, pa := img.At(sx, sy).RGBA()
+					r, g, b, a, n = r+uint64(pr), g+uint64(pg), b+uint64(pb), a+uint64(pa), n+1
+				}
+			}
+			out.SetRGBA64(x, y, color.RGBA64{uint16(r / n), uint16(g / n), uint16(b / n), uint16(a / n)})
+		}
+	}
+	return out
+}
+
+// coverURLs returns the thumbnails to try as cover art for a media link, best first.
+func coverURLs(link string) []string {
+	service, id, ok := MediaID(link)
+	if !ok || service != "youtube" {
+		return nil
+	}
+	return []string{
+		"https://i.ytimg.com/vi/" + id + "/maxresdefault.jpg",
+		"https://i.ytimg.com/vi/" + id + "/hqdefault.jpg",
+	}
+}
+
+// FetchCover(ctx, link) downloads the thumbnail of a media link (only YouTube for now) and normalizes it with NormalizeCover().
+func FetchCover(ctx context.Context, link string) ([]byte, error) {
+	return defaultClient().fetchCover(ctx, coverURLs(link))
+}
+
+// fetchCover returns the first of urls that can be downloaded, normalized.
+func (c *Cobalt) fetchCover(ctx context.Context, urls []string) ([]byte, error) {
+	for _, url := range urls {
+		res, err := c.genericHttpRequest(ctx, url, http.MethodGet, nil)
+		if err != nil {
+			continue
+		}
+		data, err := io.ReadAll(io.LimitReader(res.Body, 10<<20))
+		res.Body.Close()
+		if err != nil {
+			continue
+		}
+		if cover, err := NormalizeCover(data); err == nil {
+			return cover, nil
+		}
+	}
+	return nil, ErrNoCover
+}
+
+// id3Picture encodes an ID3 APIC frame with a JPEG front cover.
+func id3Picture(cover []byte) []byte {
+	data := []byte{0}
+	data = append(data, "image/jpeg"...)
+	data = append(data, 0, 3, 0) //End of mime type, front cover, empty description.
+	return append(data, cover...)
+}
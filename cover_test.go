@@ -0,0 +1,40 @@
+package gobalt
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+func TestNormalizeCover(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 2000, 1000))
+	for y := 0; y < 1000; y++ {
+		for x := 0; x < 2000; x++ {
+			src.Set(x, y, color.RGBA{200, 10, 10, 255})
+		}
+	}
+	var encoded bytes.Buffer
+	png.Encode(&encoded, src)
+
+	cover, err := NormalizeCover(encoded.Bytes())
+	if err != nil {
+		t.Fatalf("normalize failed: %v", err)
+	}
+	img, err := jpeg.Decode(bytes.NewReader(cover))
+	if err != nil {
+		t.Fatalf("cover is not a jpeg: %v", err)
+	}
+	if img.Bounds().Dx() != 1000 || img.Bounds().Dy() != 500 {
+		t.Fatalf("cover should be 1000x500, got %v", img.Bounds())
+	}
+	if r, _, _, _ := img.At(500, 250).RGBA(); r>>8 < 180 {
+		t.Fatalf("colors changed while scaling, got red %v", r>>8)
+	}
+
+	if _, err := NormalizeCover([]byte("not an image")); err == nil {
+		t.Fatal("invalid images should fail")
+	}
+}
@@ -0,0 +1,119 @@
+package gobalt
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+)
+
+// How many bytes of the body are kept in InvalidResponseError.Snippet.
+const snippetLength = 200
+
+// InvalidResponseError is returned when an instance answers with something that isn't a valid cobalt response,
+// like an HTML page from a proxy, a Cloudflare challenge or truncated JSON. It explains what was received, and a guess of why.
+//
+// Its error message starts with "error.net.invalid_response", so ResolveError() still works with it.
+type InvalidResponseError struct {
+	Instance    string //Url of the instance that sent the response.
+	StatusCode  int    //HTTP status code of the response.
+	ContentType string //Content-Type of the response.
+	Snippet     string //First bytes of the body.
+	Cause       string //Best guess of what went wrong.
+	Err         error  //Error found while decoding the response, if any.
+}
+
+func (e *InvalidResponseError) Error() string {
+	return fmt.Sprintf("error.net.invalid_response: %v (instance: %v, status: %v, content-type: %q, body: %q)",
+		e.Cause, e.Instance, e.StatusCode, e.ContentType, e.Snippet)
+}
+
+func (e *InvalidResponseError) Unwrap() error {
+	return e.Err
+}
+
+// diagnoseResponse creates an InvalidResponseError for a response that couldn't be used, guessing the cause from its content.
+func diagnoseResponse(instance string, res *http.Response, body []byte, err error) *InvalidResponseError {
+	diagnostic := &InvalidResponseError{
+		Instance:    instance,
+		StatusCode:  res.StatusCode,
+		ContentType: res.Header.Get("Content-Type"),
+		Snippet:     snippet(body),
+		Err:         err,
+	}
+	diagnostic.Cause = guessCause(res, body, err)
+	return diagnostic
+}
+
+// guessCause tries to explain why a response isn't a valid cobalt response.
+func guessCause(res *http.Response, body []byte, err error) string {
+	lower := bytes.ToLower(body)
+	isHTML := strings.Contains(res.Header.Get("Content-Type"), "text/html") ||
+		bytes.HasPrefix(bytes.TrimSpace(lower), []byte("<!doctype html")) ||
+		bytes.HasPrefix(bytes.TrimSpace(lower), []byte("<html"))
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+
+	switch {
+	case res.Header.Get("Cf-Mitigated") == "challenge" || bytes.Contains(lower, []byte("challenge-platform")) || bytes.Contains(lower, []byte("just a moment...")):
+		return "the instance is behind a Cloudflare challenge, which gobalt can't solve, try another instance"
+	case res.StatusCode == http.StatusBadGateway || res.StatusCode == http.StatusServiceUnavailable || res.StatusCode == http.StatusGatewayTimeout:
+		return "the instance (or the proxy in front of it) is down or overloaded, try again later"
+	case isHTML && (res.StatusCode == http.StatusForbidden || res.StatusCode == http.StatusUnauthorized):
+		return "a proxy or firewall in front of the instance blocked the request"
+	case isHTML:
+		return "got an HTML page instead of JSON, the url is probably a cobalt frontend or a proxy error page instead of the api"
+	case len(bytes.TrimSpace(body)) == 0:
+		return "the instance sent an empty response"
+	case errors.As(err, &syntaxErr) && strings.Contains(syntaxErr.Error(), "unexpected end"):
+		return "the response was cut in the middle, the connection probably dropped"
+	case errors.As(err, &syntaxErr):
+		return "the response is not valid JSON"
+	case errors.As(err, &typeErr):
+		return fmt.Sprintf("the field %q has an unexpected type, is this a cobalt v10+ instance?", typeErr.Field)
+	default:
+		return "the response doesn't look like a cobalt api response, is this a cobalt v10+ instance?"
+	}
+}
+
+// snippet returns the first bytes of body, cut at a valid utf-8 boundary.
+func snippet(body []byte) string {
+	if len(body) <= snippetLength {
+		return string(body)
+	}
+	cut := body[:snippetLength]
+	for len(cut) > 0 && !utf8.Valid(cut) {
+		cut = cut[:len(cut)-1]
+	}
+	return string(cut) + "..."
+}
+
+// validate checks if a decoded cobalt response has the fields its status requires.
+func (c *CobaltResponse) validate() error {
+	switch c.Status {
+	case "error":
+		if c.Error == nil || c.Error.Code == "" {
+			return errors.New("error response without an error code")
+		}
+	case "tunnel", "redirect":
+		if c.URL == "" {
+			return fmt.Errorf("%v response without an url", c.Status)
+		}
+	case "local-processing":
+		if len(c.Tunnel) == 0 {
+			return errors.New("local-processing response without tunnels")
+		}
+	case "picker":
+		if c.Picker == nil || len(*c.Picker) == 0 {
+			return errors.New("picker response without items")
+		}
+	case "":
+		return errors.New("response without a status")
+	default:
+		return fmt.Errorf("unknown status %q", c.Status)
+	}
+	return nil
+}
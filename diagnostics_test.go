@@ -0,0 +1,59 @@
+package gobalt
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestInvalidResponseDiagnostics(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      int
+		contentType string
+		body        string
+		cause       string
+	}{
+		{"cloudflare", http.StatusForbidden, "text/html", `<!DOCTYPE html><title>Just a moment...</title>`, "Cloudflare challenge"},
+		{"frontend", http.StatusOK, "text/html", `<html><body>cobalt</body></html>`, "HTML page instead of JSON"},
+		{"truncated", http.StatusOK, "application/json", `{"status":"tunnel","url":"htt`, "cut in the middle"},
+		{"bad gateway", http.StatusBadGateway, "text/plain", `bad gateway`, "down or overloaded"},
+		{"unknown shape", http.StatusOK, "application/json", `{"hello":"world"}`, "doesn't look like a cobalt api response"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", test.contentType)
+				w.WriteHeader(test.status)
+				w.Write([]byte(test.body))
+			}))
+			defer server.Close()
+
+			_, err := CobaltServerInfo(server.URL)
+			var invalid *InvalidResponseError
+			if !errors.As(err, &invalid) {
+				t.Fatalf("expected an InvalidResponseError, got %v", err)
+			}
+			if !strings.Contains(invalid.Cause, test.cause) || invalid.StatusCode != test.status || invalid.Snippet != test.body {
+				t.Fatalf("got unexpected diagnostic: %+v", invalid)
+			}
+			if !strings.HasPrefix(ResolveError(err), ErrDescriptions["error.net.invalid_response"]) {
+				t.Fatalf("ResolveError didn't recognize the code: %v", ResolveError(err))
+			}
+		})
+	}
+}
+
+func TestRunInvalidResponse(t *testing.T) {
+	server := fakeCobalt(t, `{"status":"error"}`)
+	options := CreateDefaultSettings()
+	options.Url = "https://youtu.be/dQw4w9WgXcQ"
+	_, err := RunOn(context.Background(), server.URL, options)
+	var invalid *InvalidResponseError
+	if !errors.As(err, &invalid) {
+		t.Fatalf("expected an InvalidResponseError for an error without code, got %v", err)
+	}
+}
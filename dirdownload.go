@@ -0,0 +1,46 @@
+package gobalt
+
+import "context"
+
+// DownloadToDir(ctx, link, dir) does the whole pipeline in one call: it checks link, sends it to cobalt with the default
+// settings, and saves every file of the answer to dir (one for tunnels and redirects, all of them for pickers, like a
+// carousel of photos, named by position and type like "01_photo.jpg"). Files are written to .part files first and get
+// sanitized names. It returns the saved paths, including the ones saved before an error.
+//
+// With WithHistory(), a picker counts as a single media: it's skipped as a whole, and recorded once every item is saved.
+func DownloadToDir(ctx context.Context, link, dir string, opts ...DownloadOption) (paths []string, err error) {
+	return defaultClient().DownloadToDir(ctx, link, dir, opts...)
+}
+
+// DownloadToDir(ctx, link, dir) is the same as DownloadToDir(), using c.
+func (c *Cobalt) DownloadToDir(ctx context.Context, link, dir string, opts ...DownloadOption) (paths []string, err error) {
+	normalized, err := NormalizeMediaURL(link)
+	if err != nil {
+		return nil, err
+	}
+	if DetectService(normalized) == "" {
+		c.logger.Debug("unknown service, sending the link to cobalt anyway", "url", normalized)
+	}
+	options := CreateDefaultSettings()
+	options.Url = normalized
+	media, err := c.Run(ctx, options)
+	if err != nil {
+		return nil, err
+	}
+	if media.Status != "picker" {
+		result, err := media.SaveToContext(ctx, dir, opts...)
+		if err != nil {
+			return nil, err
+		}
+		return []string{result.Path}, nil
+	}
+
+	//Pickers are saved like DownloadAllPicker() does, so a link gets the same file names from both.
+	results, err := media.DownloadAllPickerContext(ctx, dir, WithPickerDownload(opts...))
+	for _, result := range results {
+		if result.Result != nil {
+			paths = append(paths, result.Result.Path)
+		}
+	}
+	return paths, err
+}
@@ -0,0 +1,46 @@
+package gobalt
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDownloadToDirPicker(t *testing.T) {
+	var api *httptest.Server
+	api = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path != "/":
+			w.Write([]byte("file " + r.URL.Path))
+		case r.Method == http.MethodGet:
+			w.Write([]byte(`{"cobalt":{"version":"10.5.0","services":["instagram"]}}`))
+		default:
+			w.Write([]byte(`{"status":"picker","picker":[
+				{"type":"photo","url":"` + api.URL + `/a.webp?sig=1"},
+				{"type":"video","url":"` + api.URL + `/stream"}
+			]}`))
+		}
+	}))
+	defer api.Close()
+
+	dir := t.TempDir()
+	history := NewMemoryHistory()
+	client := New(WithInstance(api.URL))
+	paths, err := client.DownloadToDir(context.Background(), "https://www.instagram.com/p/C1a2B3c/", dir, WithHistory(history))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{filepath.Join(dir, "01_photo.webp"), filepath.Join(dir, "02_video.mp4")}
+	if len(paths) != 2 || paths[0] != want[0] || paths[1] != want[1] {
+		t.Fatalf("expected %v, got %v", want, paths)
+	}
+	if data, _ := os.ReadFile(paths[1]); string(data) != "file /stream" {
+		t.Fatalf("unexpected content %q", data)
+	}
+	if _, err := client.DownloadToDir(context.Background(), "https://www.instagram.com/p/C1a2B3c/", dir, WithHistory(history)); err == nil {
+		t.Fatal("the picker should be skipped once it's in the history")
+	}
+}
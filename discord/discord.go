@@ -0,0 +1,133 @@
+// Package discord uploads files downloaded with gobalt to a Discord webhook.
+//
+// Discord refuses attachments over the upload limit of the server, so pass Webhook.PreCheck() to the download:
+// files that are too big are skipped before any byte is downloaded.
+//
+//	hook := discord.NewWebhook("https://discord.com/api/webhooks/...")
+//	result, err := media.SaveTo(dir, gobalt.WithPreCheck(hook.PreCheck()), gobalt.WithPostDownloadHook(hook.Hook()))
+package discord
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/lostdusty/gobalt/v2"
+)
+
+// DefaultMaxSize is the attachment limit of Discord servers without boosts, in bytes.
+const DefaultMaxSize = 10 << 20
+
+// ErrTooLarge is returned by Upload() for files bigger than Webhook.MaxSize.
+var ErrTooLarge = errors.New("file is too large for discord")
+
+// Webhook uploads files to a Discord webhook. Create one with NewWebhook().
+type Webhook struct {
+	URL      string       //Webhook url, like "https://discord.com/api/webhooks/<id>/<token>".
+	Client   *http.Client //HTTP client used for uploads. Default: http.DefaultClient.
+	MaxSize  int64        //Largest file uploaded, in bytes. Default: DefaultMaxSize.
+	Username string       //Overrides the name of the webhook, optional.
+	Content  string       //Message sent with the file, optional.
+}
+
+// NewWebhook creates a Webhook that uploads to url, with the default size limit.
+func NewWebhook(url string) *Webhook {
+	return &Webhook{URL: url}
+}
+
+// PreCheck returns a gobalt.PreCheck that skips files that are too big to be uploaded, see gobalt.WithPreCheck().
+func (w *Webhook) PreCheck() gobalt.PreCheck {
+	return gobalt.MaxSize(w.maxSize())
+}
+
+// Hook returns a gobalt.PostDownloadHook that uploads every downloaded file, see gobalt.WithPostDownloadHook().
+func (w *Webhook) Hook() gobalt.PostDownloadHook {
+	return func(ctx context.Context, result *gobalt.DownloadResult) error {
+		return w.Upload(ctx, result.Path)
+	}
+}
+
+// Upload(ctx, path) uploads the file at path.
+func (w *Webhook) Upload(ctx context.Context, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	if info.Size() > w.maxSize() {
+		return fmt.Errorf("%w: %v has %v bytes, the limit is %v bytes", ErrTooLarge, filepath.Base(path), info.Size(), w.maxSize())
+	}
+	return w.UploadReader(ctx, filepath.Base(path), file)
+}
+
+// UploadReader(ctx, name, r) uploads the content of r as a file called name, e.g. to send a stream without saving it first.
+// At most MaxSize bytes are read, bigger streams fail with ErrTooLarge.
+func (w *Webhook) UploadReader(ctx context.Context, name string, r io.Reader) error {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	payload, err := json.Marshal(struct {
+		Username string `json:"username,omitempty"`
+		Content  string `json:"content,omitempty"`
+	}{w.Username, w.Content})
+	if err != nil {
+		return err
+	}
+	if err := form.WriteField("payload_json", string(payload)); err != nil {
+		return err
+	}
+	part, err := form.CreateFormFile("files[0]", name)
+	if err != nil {
+		return err
+	}
+	written, err := io.Copy(part, io.LimitReader(r, w.maxSize()+1))
+	if err != nil {
+		return err
+	}
+	if written > w.maxSize() {
+		return fmt.Errorf("%w: %v is bigger than %v bytes", ErrTooLarge, name, w.maxSize())
+	}
+	if err := form.Close(); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	res, err := w.client().Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		message, _ := io.ReadAll(io.LimitReader(res.Body, 512))
+		return fmt.Errorf("discord answered %v: %s", res.Status, bytes.TrimSpace(message))
+	}
+	return nil
+}
+
+func (w *Webhook) maxSize() int64 {
+	if w.MaxSize <= 0 {
+		return DefaultMaxSize
+	}
+	return w.MaxSize
+}
+
+func (w *Webhook) client() *http.Client {
+	if w.Client == nil {
+		return http.DefaultClient
+	}
+	return w.Client
+}
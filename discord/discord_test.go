@@ -0,0 +1,45 @@
+package discord
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestUpload(t *testing.T) {
+	var uploaded, payload string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		file, header, err := r.FormFile("files[0]")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		data, _ := io.ReadAll(file)
+		uploaded = header.Filename + ":" + string(data)
+		payload = r.FormValue("payload_json")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "video.mp4")
+	os.WriteFile(path, []byte("not really a video"), 0o644)
+
+	hook := &Webhook{URL: server.URL, Content: "here it is"}
+	if err := hook.Upload(context.Background(), path); err != nil {
+		t.Fatalf("upload failed: %v", err)
+	}
+	if uploaded != "video.mp4:not really a video" || !strings.Contains(payload, "here it is") {
+		t.Fatalf("unexpected upload %q with payload %q", uploaded, payload)
+	}
+
+	hook.MaxSize = 4
+	if err := hook.Upload(context.Background(), path); !errors.Is(err, ErrTooLarge) {
+		t.Fatalf("expected ErrTooLarge, got %v", err)
+	}
+}
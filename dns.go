@@ -0,0 +1,128 @@
+package gobalt
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"time"
+)
+
+// HostResolver looks up the IP addresses of a host. *net.Resolver implements it.
+type HostResolver interface {
+	LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error)
+}
+
+// DNSCache is a caching resolver that can be used by Client (see TransportOptions.DNSCache), so instance hostnames
+// aren't resolved again for every API call and download. It's safe for concurrent use.
+//
+// Go's resolver doesn't expose record TTLs, so entries are kept for TTL. If a lookup fails (e.g. flaky DNS),
+// an expired entry is still used for up to StaleTTL.
+type DNSCache struct {
+	TTL      time.Duration //How long a lookup is cached. Default: 1 minute.
+	StaleTTL time.Duration //How long an expired entry can still be used when a new lookup fails. Default: 10 minutes.
+	Resolver HostResolver  //Resolver used for lookups. Default: net.DefaultResolver.
+	Dialer   *net.Dialer   //Dialer used to connect to the resolved addresses. Default: a net.Dialer with a 30 seconds timeout.
+	Clock    Clock         //Clock used to expire entries. Default: SystemClock.
+
+	mu      sync.Mutex
+	entries map[string]dnsEntry
+}
+
+type dnsEntry struct {
+	addrs   []net.IPAddr
+	expires time.Time
+}
+
+// NewDNSCache creates a DNSCache with the given TTL, using the default resolver.
+func NewDNSCache(ttl time.Duration) *DNSCache {
+	return &DNSCache{TTL: ttl}
+}
+
+// LookupIPAddr returns the addresses of host, from the cache when possible.
+func (c *DNSCache) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	now := orSystemClock(c.Clock).Now()
+	c.mu.Lock()
+	entry, ok := c.entries[host]
+	c.mu.Unlock()
+	if ok && now.Before(entry.expires) {
+		return entry.addrs, nil
+	}
+
+	addrs, err := c.resolver().LookupIPAddr(ctx, host)
+	if err != nil || len(addrs) == 0 {
+		if ok && now.Before(entry.expires.Add(c.staleTTL())) {
+			return entry.addrs, nil
+		}
+		if err == nil {
+			err = &net.DNSError{Err: "no addresses found", Name: host, IsNotFound: true}
+		}
+		return nil, err
+	}
+
+	c.mu.Lock()
+	if c.entries == nil {
+		c.entries = make(map[string]dnsEntry)
+	}
+	c.entries[host] = dnsEntry{addrs: addrs, expires: now.Add(c.ttl())}
+	c.mu.Unlock()
+	return addrs, nil
+}
+
+// DialContext connects to address (host:port) using the cached addresses of host, trying each address until one works.
+// It can be used as http.Transport.DialContext.
+func (c *DNSCache) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+	dialer := c.Dialer
+	if dialer == nil {
+		dialer = &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	}
+	if net.ParseIP(host) != nil {
+		return dialer.DialContext(ctx, network, address)
+	}
+
+	addrs, err := c.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	var errs []error
+	for _, addr := range addrs {
+		conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(addr.String(), port))
+		if err == nil {
+			return conn, nil
+		}
+		errs = append(errs, err)
+	}
+	return nil, errors.Join(errs...)
+}
+
+// Flush removes every cached entry.
+func (c *DNSCache) Flush() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = nil
+}
+
+func (c *DNSCache) resolver() HostResolver {
+	if c.Resolver == nil {
+		return net.DefaultResolver
+	}
+	return c.Resolver
+}
+
+func (c *DNSCache) ttl() time.Duration {
+	if c.TTL <= 0 {
+		return time.Minute
+	}
+	return c.TTL
+}
+
+func (c *DNSCache) staleTTL() time.Duration {
+	if c.StaleTTL <= 0 {
+		return 10 * time.Minute
+	}
+	return c.StaleTTL
+}
@@ -0,0 +1,67 @@
+package gobalt
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+type fakeResolver struct {
+	lookups int
+	err     error
+}
+
+func (r *fakeResolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	r.lookups++
+	if r.err != nil {
+		return nil, r.err
+	}
+	return []net.IPAddr{{IP: net.ParseIP("127.0.0.1")}}, nil
+}
+
+func TestDNSCache(t *testing.T) {
+	resolver := &fakeResolver{}
+	cache := &DNSCache{TTL: time.Hour, Resolver: resolver}
+	for range 3 {
+		if _, err := cache.LookupIPAddr(context.Background(), "cobalt.example"); err != nil {
+			t.Fatalf("lookup failed: %v", err)
+		}
+	}
+	if resolver.lookups != 1 {
+		t.Fatalf("expected 1 lookup, got %v", resolver.lookups)
+	}
+
+	//An expired entry is still used when the resolver fails.
+	cache.TTL = time.Nanosecond
+	cache.Flush()
+	cache.LookupIPAddr(context.Background(), "cobalt.example")
+	resolver.err = errors.New("dns is down")
+	if _, err := cache.LookupIPAddr(context.Background(), "cobalt.example"); err != nil {
+		t.Fatalf("expected the stale entry to be used, got %v", err)
+	}
+}
+
+func TestDNSCacheTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+	_, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+
+	resolver := &fakeResolver{}
+	client := &http.Client{Transport: NewTransport(TransportOptions{DNSCache: &DNSCache{Resolver: resolver}})}
+	target := url.URL{Scheme: "http", Host: net.JoinHostPort("cobalt.example", port)}
+	res, err := client.Get(target.String())
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	res.Body.Close()
+	if resolver.lookups != 1 {
+		t.Fatalf("expected the transport to use the cache, got %v lookups", resolver.lookups)
+	}
+}
@@ -0,0 +1,356 @@
+package gobalt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	neturl "net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// DownloadResult contains information about a finished download.
+type DownloadResult struct {
+	Path    string //Where the file was saved.
+	Size    int64  //How many bytes were written.
+	URL     string //Url the file was downloaded from, usually a cobalt tunnel.
+	Source  string //Original media url (e.g. the YouTube link), if known. See WithSource().
+	Service string //Service of the original media url, if known. See DetectService().
+
+	Checksum string //Checksum of the file as "algorithm:hex", see WithChecksum().
+
+	Metadata map[string]string //Metadata of the caller, see WithMetadata().
+}
+
+// DownloadOption changes how Download() behaves.
+type DownloadOption func(*downloadConfig)
+
+type downloadConfig struct {
+	source    string
+	mode      downloadMode //Mode the media was requested with, part of its history key.
+	hooks     []PostDownloadHook
+	filter    Filter
+	history   History
+	force     bool
+	preChecks []PreCheck
+	fileMode  os.FileMode
+	dirMode   os.FileMode
+	owner     *fileOwner
+	scanners  []Scanner
+
+	filenamePrefix string
+	localTranscode bool
+	progress       []ProgressFunc
+	hostLimiter    *HostLimiter
+	bandwidth      *BandwidthPool
+	priority       Priority
+	refreshTunnel  func(ctx context.Context) (string, error)
+	metadata       map[string]string
+
+	segments         int   //Connections used to download big files, see WithSegments().
+	minSegmentedSize int64 //Smallest file downloaded with segments.
+
+	checksum string    //Checksum algorithm, see WithChecksum().
+	hash     hash.Hash //Hash of the bytes downloaded so far, in order.
+	hashed   int64     //How many bytes of the file were hashed.
+
+	collision   CollisionPolicy //What to do when the file already exists, see WithCollisionPolicy().
+	resumeStore ResumeStore     //Where resume states are kept, nil means FileResumeStore. See WithResumeStore().
+	onRetry     []RetryFunc     //Called before the download is retried, see WithRetryHook().
+
+	nameFromResponse bool   //The filename is taken from the Content-Disposition of the response, see Download().
+	responseName     string //Filename sent by the server, when nameFromResponse is set.
+}
+
+func newDownloadConfig(opts []DownloadOption) *downloadConfig {
+	config := &downloadConfig{}
+	for _, opt := range opts {
+		opt(config)
+	}
+	return config
+}
+
+// withRequest sets the source of the download and the mode it was requested with, from the settings sent to cobalt.
+func withRequest(options Settings) DownloadOption {
+	return func(c *downloadConfig) {
+		c.source, c.mode = options.Url, options.Mode
+	}
+}
+
+// WithSource tells Download() which media url (e.g. the YouTube link sent to Run()) the file comes from.
+// It's used to fill DownloadResult.Source and DownloadResult.Service.
+func WithSource(link string) DownloadOption {
+	return func(c *downloadConfig) {
+		c.source = link
+	}
+}
+
+// ErrPaused is returned by Download() when its context was canceled with ErrPaused as cause (see context.WithCancelCause()).
+// Unlike other errors, the partial file and its ResumeState are kept. downloader.Manager.Shutdown() pauses downloads this way.
+var ErrPaused = errors.New("download paused")
+
+// PausedError is the error of a paused Download(), telling where the partial file was kept. It wraps ErrPaused.
+type PausedError struct {
+	State *ResumeState //State of the partial file, also kept by the ResumeStore of the download (see WithResumeStore()).
+}
+
+func (e *PausedError) Error() string {
+	return fmt.Sprintf("%v: %v bytes saved to %v", ErrPaused, e.State.Size, e.State.PartPath)
+}
+
+func (e *PausedError) Unwrap() error {
+	return ErrPaused
+}
+
+// ErrSkipped is returned (wrapped with the reason) when a Filter decided to not download the media.
+var ErrSkipped = errors.New("download skipped")
+
+// Filter decides if a media should be downloaded, after Run() but before any byte of the file is fetched.
+// info is fetched with a HEAD (or ranged GET) request to the media url, fields the server didn't send are left empty.
+// Return true and a reason to skip the download, e.g. for files that are too big, of the wrong type or too long.
+type Filter func(resp *CobaltResponse, info MediaInfo) (skip bool, reason string)
+
+// WithFilter sets a Filter that is checked by SaveTo() before downloading.
+func WithFilter(filter Filter) DownloadOption {
+	return func(c *downloadConfig) {
+		c.filter = filter
+	}
+}
+
+// StreamTo(ctx, w) writes the media of this response to w instead of saving it, see DownloadTo().
+func (c *CobaltResponse) StreamTo(ctx context.Context, w io.Writer, opts ...DownloadOption) (*DownloadResult, error) {
+	if c.URL == "" {
+		return nil, fmt.Errorf("response with status %v has no url to download", c.Status)
+	}
+	opts = append([]DownloadOption{withRequest(c.request)}, opts...)
+	return c.cobalt().DownloadTo(ctx, c.URL, w, opts...)
+}
+
+// SaveTo(dir) downloads the media of this response to dir, using the filename provided by cobalt.
+// Returns an error wrapping ErrSkipped if a Filter rejected the media, see WithFilter().
+func (c *CobaltResponse) SaveTo(dir string, opts ...DownloadOption) (*DownloadResult, error) {
+	return c.SaveToContext(context.Background(), dir, opts...)
+}
+
+// SaveToContext(ctx, dir) is the same as SaveTo(), but the download is canceled when ctx is done.
+func (c *CobaltResponse) SaveToContext(ctx context.Context, dir string, opts ...DownloadOption) (*DownloadResult, error) {
+	if c.Status == "local-processing" {
+		return nil, fmt.Errorf("response with status local-processing has no single file to download, see SaveParts() and ProcessLocally()")
+	}
+	if c.URL == "" {
+		return nil, fmt.Errorf("response with status %v has no url to download", c.Status)
+	}
+	request := c.request
+	refresh := withTunnelRefresh(func(ctx context.Context) (string, error) {
+		media, err := c.cobalt().Run(ctx, request)
+		if err != nil {
+			return "", err
+		}
+		if media.URL == "" {
+			return "", fmt.Errorf("cobalt answered with status %v instead of a new tunnel", media.Status)
+		}
+		return media.URL, nil
+	})
+	opts = append([]DownloadOption{withRequest(c.request), refresh}, opts...)
+	config := newDownloadConfig(opts)
+	if err := config.checkHistory(); err != nil {
+		return nil, err
+	}
+
+	if config.filter != nil {
+		info := MediaInfo{Name: c.Filename}
+		if head, err := c.cobalt().probeMedia(ctx, c.URL); err == nil {
+			info = *head
+		}
+		if skip, reason := config.filter(c, info); skip {
+			return nil, fmt.Errorf("%w: %v", ErrSkipped, reason)
+		}
+	}
+
+	filename := cleanPathSegment(c.Filename)
+	if filename == "" {
+		//Let Download() name the file from the Content-Disposition of the tunnel.
+		return c.cobalt().Download(ctx, c.URL, dir+string(filepath.Separator), opts...)
+	}
+	filename = config.filenamePrefix + filename
+	return c.cobalt().Download(ctx, c.URL, filepath.Join(dir, filename), opts...)
+}
+
+// cobalt returns the client that made the request, or the default client for responses created by hand.
+func (c *CobaltResponse) cobalt() *Cobalt {
+	if c.client == nil {
+		return defaultClient()
+	}
+	return c.client
+}
+
+// Download(url, path) saves the file at url (like CobaltResponse.URL) to path, and returns information about it.
+func Download(url, path string, opts ...DownloadOption) (*DownloadResult, error) {
+	return DownloadContext(context.Background(), url, path, opts...)
+}
+
+// DownloadContext(ctx, url, path) is the same as Download(), but the download is canceled when ctx is done.
+func DownloadContext(ctx context.Context, url, path string, opts ...DownloadOption) (*DownloadResult, error) {
+	return defaultClient().Download(ctx, url, path, opts...)
+}
+
+// Download(ctx, url, path) saves the file at url (like CobaltResponse.URL) to path using the HTTP client of c, and returns information about it.
+// If a partial file of the same media was left at path (by a paused or interrupted download, see ResumeState), the download
+// continues from where it stopped when the server supports Range requests, and starts again otherwise.
+// If path is a directory (it exists, or ends with a path separator), the file is saved in it with the name sent by the server
+// in Content-Disposition, or the last segment of url. DownloadResult.Path tells where the file ended up.
+func (c *Cobalt) Download(ctx context.Context, url, path string, opts ...DownloadOption) (*DownloadResult, error) {
+	config := newDownloadConfig(opts)
+	if isDirPath(path) {
+		config.nameFromResponse = true
+		path = filepath.Join(path, config.filenamePrefix+urlFilename(url))
+	}
+	if err := config.checkHistory(); err != nil {
+		return nil, err
+	}
+	if err := config.runPreChecks(ctx, c, url); err != nil {
+		return nil, err
+	}
+	if err := config.startChecksum(); err != nil {
+		return nil, err
+	}
+	if !config.nameFromResponse && config.collision != CollisionNumber {
+		//Don't download a file that won't be kept.
+		if _, err := config.resolveCollision(path); err != nil {
+			return nil, err
+		}
+	}
+
+	//Write to a .part file, described by a resume state, and only move it to path once complete.
+	//A partial file left by an earlier download of the same media is continued, see resumePart().
+	if err := config.makeParentDirs(path); err != nil {
+		return nil, err
+	}
+	state, file, err := resumePart(config.resumeStore, url, config.source, path)
+	if err != nil {
+		return nil, err
+	}
+	err = config.setFilePermissions(state.PartPath)
+	if err == nil {
+		err = c.fetchSegmented(ctx, config, state, file)
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = config.runScanners(ctx, state.PartPath)
+	}
+	var checksum string
+	if err == nil {
+		checksum, err = config.finishChecksum(state.PartPath, state.Size)
+	}
+	if name := cleanPathSegment(config.responseName); err == nil && name != "" {
+		path = filepath.Join(filepath.Dir(path), config.filenamePrefix+name)
+	}
+	if err == nil {
+		path, err = config.resolveCollision(path)
+	}
+	if err == nil {
+		err = os.Rename(state.PartPath, path)
+	}
+	if err != nil && errors.Is(context.Cause(ctx), ErrPaused) {
+		//Keep the partial file and its state, so the download can be continued later.
+		if saveErr := state.Save(); saveErr == nil {
+			return nil, &PausedError{State: state}
+		}
+	}
+	if err != nil {
+		state.Remove()
+		return nil, err
+	}
+	state.resumeStore().DeleteResumeState(state.Path)
+	written := state.Size
+
+	result := &DownloadResult{
+		Path:     path,
+		Size:     written,
+		URL:      state.URL,
+		Source:   config.source,
+		Service:  DetectService(config.source),
+		Checksum: checksum,
+		Metadata: config.metadata,
+	}
+
+	if err := config.recordHistory(result); err != nil {
+		return result, err
+	}
+
+	if err := config.runHooks(ctx, result); err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+// DownloadTo(ctx, url, w) writes the file at url to w instead of saving it, e.g. to stream it to stdout or to an upload.
+// Pre-checks are run as usual, but history, scanners, file modes and hooks only apply to files saved by Download(). DownloadResult.Path is empty.
+func DownloadTo(ctx context.Context, url string, w io.Writer, opts ...DownloadOption) (*DownloadResult, error) {
+	return defaultClient().DownloadTo(ctx, url, w, opts...)
+}
+
+// DownloadTo(ctx, url, w) writes the file at url to w using the HTTP client of c, see DownloadTo().
+func (c *Cobalt) DownloadTo(ctx context.Context, url string, w io.Writer, opts ...DownloadOption) (*DownloadResult, error) {
+	config := newDownloadConfig(opts)
+	if err := config.startChecksum(); err != nil {
+		return nil, err
+	}
+	res, err := c.openDownload(ctx, url, config)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	limited, leave := config.limitBandwidth(ctx, w)
+	defer leave()
+	written, err := io.Copy(config.trackProgress(config.hashWriter(limited, 0), 0, res.ContentLength), res.Body)
+	c.metrics.BytesDownloaded(hostOf(url), written)
+	checksum, _ := config.finishChecksum("", written)
+	result := &DownloadResult{
+		Size:     written,
+		URL:      url,
+		Source:   config.source,
+		Service:  DetectService(config.source),
+		Checksum: checksum,
+		Metadata: config.metadata,
+	}
+	return result, err
+}
+
+// isDirPath reports if path names a directory: it ends with a separator, or is an existing directory.
+func isDirPath(path string) bool {
+	if strings.HasSuffix(path, "/") || strings.HasSuffix(path, string(filepath.Separator)) {
+		return true
+	}
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+// urlFilename returns a filename for the file at link, from the last segment of its path. Used until the server sends a better one.
+func urlFilename(link string) string {
+	parsed, err := neturl.Parse(link)
+	if err != nil {
+		return "download"
+	}
+	if name := cleanPathSegment(path.Base(parsed.Path)); name != "" {
+		return name
+	}
+	return "download"
+}
+
+// openDownload runs the pre-checks and starts the download of url, the caller must close the response body.
+func (c *Cobalt) openDownload(ctx context.Context, url string, config *downloadConfig) (*http.Response, error) {
+	if err := config.runPreChecks(ctx, c, url); err != nil {
+		return nil, err
+	}
+
+	return c.requestDownload(ctx, url, config, 0, "")
+}
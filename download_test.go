@@ -0,0 +1,121 @@
+package gobalt
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDownload(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("not really a video"))
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "video.mp4")
+	result, err := Download(server.URL, path, WithSource("https://youtu.be/dQw4w9WgXcQ"))
+	if err != nil {
+		t.Fatalf("download failed: %v", err)
+	}
+	if result.Size != 18 || result.Service != "youtube" {
+		t.Fatalf("got unexpected result %+v", result)
+	}
+	if content, _ := os.ReadFile(path); string(content) != "not really a video" {
+		t.Fatalf("got unexpected file content %q", content)
+	}
+}
+
+func TestDownloadBadStatus(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "video.mp4")
+	if _, err := Download(server.URL, path); err == nil {
+		t.Fatal("expected an error for a 404 response")
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatal("no file should be created when the download fails")
+	}
+}
+
+func TestSaveToFilter(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "video/mp4")
+		w.Write([]byte("a video that is way too big"))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	resp := &CobaltResponse{Status: "tunnel", URL: server.URL, Filename: "big.mp4"}
+	tooBig := WithFilter(func(resp *CobaltResponse, info MediaInfo) (bool, string) {
+		return info.Size > 10, "file is bigger than 10 bytes"
+	})
+	if _, err := resp.SaveTo(dir, tooBig); !errors.Is(err, ErrSkipped) {
+		t.Fatalf("expected ErrSkipped, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "big.mp4")); !os.IsNotExist(err) {
+		t.Fatal("skipped media should not be downloaded")
+	}
+
+	result, err := resp.SaveTo(dir)
+	if err != nil || result.Path != filepath.Join(dir, "big.mp4") {
+		t.Fatalf("expected the file to be saved, got %+v (err: %v)", result, err)
+	}
+}
+
+func TestDownloadTo(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("not really a video"))
+	}))
+	defer server.Close()
+
+	var buf bytes.Buffer
+	result, err := DownloadTo(context.Background(), server.URL, &buf, WithSource("https://youtu.be/dQw4w9WgXcQ"))
+	if err != nil {
+		t.Fatalf("download failed: %v", err)
+	}
+	if buf.String() != "not really a video" || result.Size != int64(buf.Len()) || result.Service != "youtube" || result.Path != "" {
+		t.Fatalf("unexpected result %+v with %q", result, buf.String())
+	}
+}
+
+func TestDownloadProgress(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("not really a video"))
+	}))
+	defer server.Close()
+
+	var done, total int64
+	_, err := Download(server.URL, filepath.Join(t.TempDir(), "video.mp4"), WithProgress(func(d, t int64) { done, total = d, t }))
+	if err != nil {
+		t.Fatalf("download failed: %v", err)
+	}
+	if done != 18 || total != 18 {
+		t.Fatalf("progress should end at 18/18, got %v/%v", done, total)
+	}
+}
+
+func TestDownloadToDirUsesContentDisposition(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/named" {
+			w.Header().Set("Content-Disposition", `attachment; filename*=UTF-8''Caf%C3%A9.mp4`)
+		}
+		w.Write([]byte("media"))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	result, err := Download(server.URL+"/named", dir)
+	if err != nil || result.Path != filepath.Join(dir, "Café.mp4") {
+		t.Fatalf("expected the Content-Disposition name, got %+v, %v", result, err)
+	}
+	result, err = Download(server.URL+"/tunnel/file.webm", dir+string(filepath.Separator))
+	if err != nil || result.Path != filepath.Join(dir, "file.webm") {
+		t.Fatalf("expected the url name, got %+v, %v", result, err)
+	}
+}
@@ -0,0 +1,119 @@
+package downloader
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sync"
+
+	"github.com/lostdusty/gobalt/v2"
+)
+
+// StoredJob is the part of a Job that a JobStore keeps. Job.Options can't be saved, ManagerOptions.Options
+// are applied to restored jobs instead.
+type StoredJob struct {
+	ID       string          `json:"id"`       //Id of the job in the Manager that submitted it, see JobHandle.
+	Settings gobalt.Settings `json:"settings"` //Request sent to cobalt.
+	Dir      string          `json:"dir"`      //Directory where the media is saved.
+	Priority gobalt.Priority `json:"priority"` //Share of the bandwidth of the job, see Job.
+
+	Metadata map[string]string `json:"metadata,omitempty"` //Metadata of the caller, see Job.
+}
+
+// JobStore keeps the queue of a Manager, so jobs that didn't finish (because the process stopped or was paused
+// by Manager.Shutdown()) are submitted again by the next NewManager(). See ManagerOptions.Store.
+type JobStore interface {
+	SaveJob(job StoredJob) error       //SaveJob saves a submitted job, replacing any job with the same ID.
+	DeleteJob(id string) error         //DeleteJob removes a job that finished or failed.
+	PendingJobs() ([]StoredJob, error) //PendingJobs returns the saved jobs, in the order they were saved.
+}
+
+// restore submits again the jobs left in the store by a previous Manager. They keep their ids, so each one is saved
+// over its old entry and the queue is never missing from the store, even if the process stops while restoring it.
+func (m *Manager) restore() {
+	pending, err := m.options.Store.PendingJobs()
+	if err != nil {
+		m.client.Logger().Warn("failed to load the pending jobs", "error", err)
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, stored := range pending {
+		//New jobs get ids after the restored ones.
+		var n int
+		if _, err := fmt.Sscanf(stored.ID, "job-%d", &n); err == nil && n > m.jobs {
+			m.jobs = n
+		}
+	}
+	for _, stored := range pending {
+		job := Job{Settings: stored.Settings, Dir: stored.Dir, Priority: stored.Priority, Metadata: stored.Metadata}
+		if _, err := m.submit(stored.ID, job); err != nil {
+			m.client.Logger().Warn("failed to restore a pending job", "job", stored.ID, "error", err)
+		}
+	}
+}
+
+// FileJobStore is a JobStore keeping the queue in a JSON file, for programs that don't need a database (see the sqlitestore package).
+// Create one with OpenJobStoreFile().
+type FileJobStore struct {
+	mu   sync.Mutex
+	path string
+	jobs []StoredJob
+}
+
+// OpenJobStoreFile loads the jobs saved in the JSON file at path, creating an empty store if the file doesn't exist.
+// Every change writes the whole queue back to the file.
+func OpenJobStoreFile(path string) (*FileJobStore, error) {
+	store := &FileJobStore{path: path}
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(content, &store.jobs); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+func (s *FileJobStore) SaveJob(job StoredJob) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if i := slices.IndexFunc(s.jobs, func(j StoredJob) bool { return j.ID == job.ID }); i >= 0 {
+		s.jobs[i] = job
+	} else {
+		s.jobs = append(s.jobs, job)
+	}
+	return s.save()
+}
+
+func (s *FileJobStore) DeleteJob(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.jobs = slices.DeleteFunc(s.jobs, func(j StoredJob) bool { return j.ID == id })
+	return s.save()
+}
+
+func (s *FileJobStore) PendingJobs() ([]StoredJob, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.jobs), nil
+}
+
+// save writes the queue to its file, s.mu must be held.
+func (s *FileJobStore) save() error {
+	content, err := json.MarshalIndent(s.jobs, "", "  ")
+	if err != nil {
+		return err
+	}
+	//Write to a temporary file first, so a crash can't leave a half written queue behind.
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, content, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
@@ -0,0 +1,419 @@
+// Package downloader runs gobalt downloads in the background: a Manager queues jobs with a bounded concurrency,
+// can pause, resume and cancel them, and keeps its queue in a JobStore so it survives restarts.
+//
+//	manager := downloader.NewManager(client, downloader.ManagerOptions{Concurrency: 3})
+//	job, err := manager.Submit(downloader.Job{Settings: settings, Dir: "downloads"})
+package downloader
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/lostdusty/gobalt/v2"
+)
+
+// ErrManagerClosed is returned by Manager.Submit() once Shutdown() was called.
+var ErrManagerClosed = errors.New("download manager is shut down")
+
+// ErrJobCanceled is returned by JobHandle.Result() for jobs stopped with Cancel().
+var ErrJobCanceled = errors.New("job canceled")
+
+// JobState tells where a job of a Manager is, see JobHandle.Status().
+type JobState string
+
+const (
+	JobQueued   JobState = "queued"   //Waiting for a free slot.
+	JobRunning  JobState = "running"  //Requesting or downloading the media.
+	JobPaused   JobState = "paused"   //Stopped by Pause() or Manager.Shutdown(), the partial file is kept, see Manager.Resume().
+	JobDone     JobState = "done"     //Saved.
+	JobFailed   JobState = "failed"   //Stopped by an error.
+	JobCanceled JobState = "canceled" //Stopped by Cancel(), the partial file is removed.
+)
+
+// JobStatus is a snapshot of a job, see JobHandle.Status().
+type JobStatus struct {
+	ID      string   //Id of the job.
+	State   JobState //Where the job is.
+	Written int64    //Bytes of the file saved so far.
+	Total   int64    //Size of the file, 0 if unknown.
+	Err     error    //Why the job failed, was paused or canceled.
+}
+
+// ManagerOptions configures a Manager, see NewManager().
+type ManagerOptions struct {
+	Concurrency int                     //How many jobs run at the same time. Default: 2.
+	MaxPerHost  int                     //How many files are downloaded at the same time from a single host, see gobalt.HostLimiter. 0 means no limit.
+	Bandwidth   *gobalt.BandwidthPool   //Rate shared by the jobs following their gobalt.Priority, see gobalt.WithBandwidth(). nil means no limit.
+	History     gobalt.History          //History used by every job, flushed by Shutdown() if it has a Flush() error method. Optional.
+	Options     []gobalt.DownloadOption //Download options applied to every job, before the options of the job.
+	Store       JobStore                //Where the queue is saved, so unfinished jobs are restored by the next NewManager(). Optional.
+	Events      ManagerEvents           //Called as the jobs go on. Optional.
+}
+
+// ManagerEvents are called by a Manager as its jobs go on, so an application can update a UI or send notifications
+// without polling JobHandle.Status(). Every field is optional. They're called from the goroutine of the job, so they
+// must be safe for concurrent use and return quickly.
+type ManagerEvents struct {
+	OnQueued    func(job *JobHandle)                                             //The job was submitted (or resumed, or restored from the store).
+	OnStarted   func(job *JobHandle)                                             //The job got a slot and starts.
+	OnProgress  func(job *JobHandle, written, total int64)                       //Bytes of the file were saved, see gobalt.ProgressFunc.
+	OnRetry     func(job *JobHandle, attempt int, wait time.Duration, err error) //The cobalt request or the download failed and is sent again.
+	OnCompleted func(job *JobHandle, result *gobalt.DownloadResult)              //The media was saved.
+	OnFailed    func(job *JobHandle, err error)                                  //The job failed, or was paused (err wraps gobalt.ErrPaused) or canceled (err wraps ErrJobCanceled).
+}
+
+// Job is a media to download with a Manager.
+type Job struct {
+	Settings gobalt.Settings         //Request sent to cobalt.
+	Dir      string                  //Directory where the media is saved.
+	Options  []gobalt.DownloadOption //Download options of this job, see gobalt.Cobalt.SaveMedia().
+	Priority gobalt.Priority         //Share of ManagerOptions.Bandwidth this job gets. Default: gobalt.PriorityNormal.
+
+	Metadata map[string]string //Metadata of the caller, kept by the store and passed to the download, see gobalt.WithMetadata().
+}
+
+// JobHandle follows a Job submitted to a Manager.
+type JobHandle struct {
+	ID  string //Unique id of the job in its Manager, like "job-1". Also used as pprof label, see runtime/pprof.
+	Job Job
+
+	done    chan struct{}
+	result  *gobalt.DownloadResult
+	err     error
+	cancel  context.CancelCauseFunc
+	manager *Manager
+
+	mu       sync.Mutex
+	state    JobState
+	written  int64
+	total    int64
+	canceled bool //Cancel() was called, the job must not end up paused.
+}
+
+// Done is closed once the job finished, failed or was paused.
+func (h *JobHandle) Done() <-chan struct{} {
+	return h.done
+}
+
+// Result waits for the job and returns its result. Jobs stopped by Shutdown() or Pause() return an error wrapping gobalt.ErrPaused,
+// jobs stopped by Cancel() one wrapping ErrJobCanceled.
+func (h *JobHandle) Result() (*gobalt.DownloadResult, error) {
+	<-h.done
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.result, h.err
+}
+
+// Status returns where the job is and how much of it was downloaded. It doesn't wait.
+func (h *JobHandle) Status() JobStatus {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	status := JobStatus{ID: h.ID, State: h.state, Written: h.written, Total: h.total}
+	if h.state == JobFailed || h.state == JobPaused || h.state == JobCanceled {
+		status.Err = h.err
+	}
+	return status
+}
+
+// Pause stops the job, keeping its partial file and its place in the store. Continue it with Manager.Resume().
+func (h *JobHandle) Pause() {
+	h.cancel(gobalt.ErrPaused)
+}
+
+// Cancel stops the job for good, removing its partial file and its place in the store. Paused jobs are canceled right away.
+func (h *JobHandle) Cancel() {
+	h.mu.Lock()
+	h.canceled = true
+	paused := h.state == JobPaused
+	h.mu.Unlock()
+	h.cancel(ErrJobCanceled)
+	if paused {
+		h.manager.discard(h)
+	}
+}
+
+func (h *JobHandle) setState(state JobState) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.state = state
+}
+
+func (h *JobHandle) progress(written, total int64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.written, h.total = written, total
+}
+
+// Manager runs download jobs in the background with a limited concurrency, and can be shut down cleanly
+// (e.g. on SIGTERM) with Shutdown(). Create one with NewManager().
+type Manager struct {
+	client  *gobalt.Cobalt
+	options ManagerOptions
+	slots   chan struct{}
+
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+	wg     sync.WaitGroup
+
+	mu      sync.Mutex
+	closed  bool
+	jobs    int                   //Jobs submitted so far, used for ids.
+	handles map[string]*JobHandle //Jobs queued, running or paused, by id.
+}
+
+// NewManager creates a Manager sending requests with client (nil means gobalt.New()).
+// If opts.Store is set, the jobs it still has are submitted again.
+func NewManager(client *gobalt.Cobalt, opts ManagerOptions) *Manager {
+	if client == nil {
+		client = gobalt.New()
+	}
+	if opts.Concurrency < 1 {
+		opts.Concurrency = 2
+	}
+	if opts.History != nil {
+		opts.Options = append([]gobalt.DownloadOption{gobalt.WithHistory(opts.History)}, opts.Options...)
+	}
+	if opts.MaxPerHost > 0 {
+		opts.Options = append([]gobalt.DownloadOption{gobalt.WithHostLimit(gobalt.NewHostLimiter(opts.MaxPerHost))}, opts.Options...)
+	}
+	m := &Manager{client: client, options: opts, slots: make(chan struct{}, opts.Concurrency), handles: make(map[string]*JobHandle)}
+	m.ctx, m.cancel = context.WithCancelCause(context.Background())
+	if opts.Store != nil {
+		m.restore()
+	}
+	return m
+}
+
+// Submit queues job, it starts as soon as there's a free slot. Returns ErrManagerClosed after Shutdown(),
+// or the error of ManagerOptions.Store if the job couldn't be saved.
+func (m *Manager) Submit(job Job) (*JobHandle, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return nil, ErrManagerClosed
+	}
+	m.jobs++
+	return m.submit(fmt.Sprintf("job-%v", m.jobs), job)
+}
+
+// Resume submits again a job stopped by Pause(), it continues from its partial file. Returns the new handle of the job.
+func (m *Manager) Resume(id string) (*JobHandle, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return nil, ErrManagerClosed
+	}
+	handle, ok := m.handles[id]
+	if !ok || handle.Status().State != JobPaused {
+		return nil, fmt.Errorf("no paused job %v", id)
+	}
+	return m.submit(id, handle.Job)
+}
+
+// Job returns the job with id, if it's queued, running or paused.
+func (m *Manager) Job(id string) (*JobHandle, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	handle, ok := m.handles[id]
+	return handle, ok
+}
+
+// Jobs returns the jobs queued, running or paused, sorted by id. Finished jobs are only known by their handle.
+func (m *Manager) Jobs() []*JobHandle {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	handles := make([]*JobHandle, 0, len(m.handles))
+	for _, handle := range m.handles {
+		handles = append(handles, handle)
+	}
+	slices.SortFunc(handles, func(a, b *JobHandle) int {
+		return cmp.Or(cmp.Compare(len(a.ID), len(b.ID)), cmp.Compare(a.ID, b.ID))
+	})
+	return handles
+}
+
+// submit saves and starts job with id. m.mu must be held.
+func (m *Manager) submit(id string, job Job) (*JobHandle, error) {
+	handle := &JobHandle{ID: id, Job: job, done: make(chan struct{}), state: JobQueued, manager: m}
+	if m.options.Store != nil {
+		err := m.options.Store.SaveJob(StoredJob{ID: handle.ID, Settings: job.Settings, Dir: job.Dir, Priority: job.Priority, Metadata: job.Metadata})
+		if err != nil {
+			return nil, fmt.Errorf("failed to save the job: %w", err)
+		}
+	}
+	var ctx context.Context
+	ctx, handle.cancel = context.WithCancelCause(m.ctx)
+	m.handles[id] = handle
+	m.wg.Add(1)
+	go m.run(ctx, handle)
+	return handle, nil
+}
+
+func (m *Manager) run(ctx context.Context, handle *JobHandle) {
+	defer m.wg.Done()
+	defer close(handle.done)
+	defer handle.cancel(nil)
+	events := m.options.Events
+	if events.OnQueued != nil {
+		events.OnQueued(handle)
+	}
+	select {
+	case m.slots <- struct{}{}:
+		defer func() { <-m.slots }()
+	case <-ctx.Done():
+		//Queued jobs stay in the store when the manager stopped or the job was paused.
+		handle.err = context.Cause(ctx)
+		m.finish(handle)
+		return
+	}
+	handle.setState(JobRunning)
+	if events.OnStarted != nil {
+		events.OnStarted(handle)
+	}
+	opts := append(m.options.Options[:len(m.options.Options):len(m.options.Options)], gobalt.WithMetadata(handle.Job.Metadata))
+	opts = append(opts, handle.Job.Options...)
+	opts = append(opts, gobalt.WithProgress(func(written, total int64) {
+		handle.progress(written, total)
+		if events.OnProgress != nil {
+			events.OnProgress(handle, written, total)
+		}
+	}))
+	if events.OnRetry != nil {
+		onRetry := func(attempt int, wait time.Duration, err error) {
+			events.OnRetry(handle, attempt, wait, err)
+		}
+		opts = append(opts, gobalt.WithRetryHook(onRetry))
+		ctx = gobalt.ContextWithRequestOptions(ctx, gobalt.RequestOptions{OnRetry: onRetry})
+	}
+	if m.options.Bandwidth != nil {
+		priority := handle.Job.Priority
+		if priority <= 0 {
+			priority = gobalt.PriorityNormal
+		}
+		opts = append(opts, gobalt.WithBandwidth(m.options.Bandwidth, priority))
+	}
+	handle.err = m.client.DoJob(ctx, handle.ID, handle.Job.Settings.Url, func(ctx context.Context) error {
+		var err error
+		handle.result, err = m.client.SaveMedia(ctx, handle.Job.Settings, handle.Job.Dir, opts...)
+		if err != nil && ctx.Err() != nil && !errors.Is(err, context.Cause(ctx)) {
+			//Tell why the job was stopped, rather than the error it caused.
+			err = fmt.Errorf("%w: %w", context.Cause(ctx), err)
+		}
+		return err
+	})
+	m.finish(handle)
+}
+
+// finish records the final state of a job, and forgets it unless it was paused.
+func (m *Manager) finish(handle *JobHandle) {
+	state := JobDone
+	switch {
+	case errors.Is(handle.err, gobalt.ErrPaused):
+		state = JobPaused
+	case errors.Is(handle.err, ErrJobCanceled):
+		state = JobCanceled
+	case handle.err != nil:
+		state = JobFailed
+	}
+	handle.mu.Lock()
+	if state == JobPaused && handle.canceled {
+		//Canceled while it was being paused.
+		state, handle.err = JobCanceled, fmt.Errorf("%w: %w", ErrJobCanceled, handle.err)
+		removePartial(handle.err)
+	}
+	handle.state = state
+	handle.mu.Unlock()
+	switch events := m.options.Events; {
+	case state == JobDone && events.OnCompleted != nil:
+		events.OnCompleted(handle, handle.result)
+	case state != JobDone && events.OnFailed != nil:
+		events.OnFailed(handle, handle.err)
+	}
+	if state != JobPaused {
+		m.mu.Lock()
+		if m.handles[handle.ID] == handle {
+			delete(m.handles, handle.ID)
+		}
+		m.mu.Unlock()
+	}
+	m.forget(handle)
+}
+
+// discard cancels a paused job: its partial file and resume state are removed, and it's forgotten by the manager and the store.
+// Jobs paused and resumed since then are left alone, they belong to the new handle.
+func (m *Manager) discard(handle *JobHandle) {
+	handle.mu.Lock()
+	if handle.state != JobPaused {
+		handle.mu.Unlock()
+		return
+	}
+	paused := handle.err
+	handle.state, handle.err = JobCanceled, fmt.Errorf("%w: %w", ErrJobCanceled, paused)
+	handle.mu.Unlock()
+
+	m.mu.Lock()
+	current := m.handles[handle.ID] == handle
+	if current {
+		delete(m.handles, handle.ID)
+	}
+	m.mu.Unlock()
+	if !current {
+		return
+	}
+	removePartial(paused)
+	m.forget(handle)
+	if m.options.Events.OnFailed != nil {
+		m.options.Events.OnFailed(handle, handle.err)
+	}
+}
+
+// removePartial deletes the partial file and resume state kept by a paused download, if err tells about one.
+func removePartial(err error) {
+	if paused := (*gobalt.PausedError)(nil); errors.As(err, &paused) {
+		paused.State.Remove()
+	}
+}
+
+// forget removes a job from the store once it's done. Paused jobs are kept, to be restored later.
+func (m *Manager) forget(handle *JobHandle) {
+	if m.options.Store == nil || handle.Status().State == JobPaused {
+		return
+	}
+	if err := m.options.Store.DeleteJob(handle.ID); err != nil {
+		m.client.Logger().Warn("failed to remove a finished job", "job", handle.ID, "error", err)
+	}
+}
+
+// Shutdown stops accepting new jobs and waits for the submitted ones to finish. If ctx is done first, the jobs still
+// running are paused: their partial files and resume states are kept (see gobalt.ErrPaused), and queued jobs are not started.
+// Then the history is flushed. Returns ctx.Err() if jobs had to be paused.
+func (m *Manager) Shutdown(ctx context.Context) error {
+	m.mu.Lock()
+	m.closed = true
+	m.mu.Unlock()
+
+	finished := make(chan struct{})
+	go func() {
+		m.wg.Wait()
+		close(finished)
+	}()
+	var err error
+	select {
+	case <-finished:
+	case <-ctx.Done():
+		err = ctx.Err()
+		m.cancel(gobalt.ErrPaused)
+		<-finished
+	}
+	m.cancel(ErrManagerClosed)
+
+	if flusher, ok := m.options.History.(interface{ Flush() error }); ok {
+		err = errors.Join(err, flusher.Flush())
+	}
+	return err
+}
@@ -0,0 +1,353 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime/pprof"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lostdusty/gobalt/v2"
+)
+
+func TestManagerShutdownPauses(t *testing.T) {
+	started := make(chan struct{})
+	var api *httptest.Server
+	api = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/tunnel":
+			//Send half of the file, then hang until the client gives up.
+			w.Header().Set("Content-Length", "8")
+			w.Write([]byte("1234"))
+			w.(http.Flusher).Flush()
+			close(started)
+			<-r.Context().Done()
+		case r.Method == http.MethodGet:
+			w.Write([]byte(`{"cobalt":{"version":"10.5.0","services":["youtube"]}}`))
+		default:
+			w.Write([]byte(`{"status":"tunnel","url":"` + api.URL + `/tunnel","filename":"video.mp4"}`))
+		}
+	}))
+	defer api.Close()
+
+	dir := t.TempDir()
+	options := gobalt.CreateDefaultSettings()
+	options.Url = "https://youtu.be/dQw4w9WgXcQ"
+	manager := NewManager(gobalt.New(gobalt.WithInstance(api.URL)), ManagerOptions{History: gobalt.NewMemoryHistory()})
+	job, err := manager.Submit(Job{Settings: options, Dir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := manager.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the shutdown to time out, got %v", err)
+	}
+	if _, err := job.Result(); !errors.Is(err, gobalt.ErrPaused) {
+		t.Fatalf("expected the job to be paused, got %v", err)
+	}
+	state, err := gobalt.ReadResumeState(filepath.Join(dir, "video.mp4"))
+	if err != nil || state.Size != 4 || state.TotalSize != 8 {
+		t.Fatalf("unexpected resume state %+v, %v", state, err)
+	}
+	if data, _ := os.ReadFile(state.PartPath); string(data) != "1234" {
+		t.Fatalf("the partial file should be kept, got %q", data)
+	}
+	if _, err := manager.Submit(Job{Settings: options, Dir: dir}); !errors.Is(err, ErrManagerClosed) {
+		t.Fatalf("expected ErrManagerClosed, got %v", err)
+	}
+}
+
+// memoryJobStore is a JobStore for tests.
+type memoryJobStore struct {
+	mu      sync.Mutex
+	jobs    []StoredJob
+	saveErr error //Returned by SaveJob() when set.
+}
+
+func (s *memoryJobStore) SaveJob(job StoredJob) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.saveErr != nil {
+		return s.saveErr
+	}
+	if i := slices.IndexFunc(s.jobs, func(j StoredJob) bool { return j.ID == job.ID }); i >= 0 {
+		s.jobs[i] = job
+	} else {
+		s.jobs = append(s.jobs, job)
+	}
+	return nil
+}
+
+func (s *memoryJobStore) DeleteJob(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.jobs = slices.DeleteFunc(s.jobs, func(job StoredJob) bool { return job.ID == id })
+	return nil
+}
+
+func (s *memoryJobStore) PendingJobs() ([]StoredJob, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.jobs), nil
+}
+
+func TestManagerRestoresStoredJobs(t *testing.T) {
+	var api *httptest.Server
+	api = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/tunnel":
+			w.Write([]byte("media"))
+		case r.Method == http.MethodGet:
+			w.Write([]byte(`{"cobalt":{"version":"10.5.0","services":["youtube"]}}`))
+		default:
+			w.Write([]byte(`{"status":"tunnel","url":"` + api.URL + `/tunnel","filename":"video.mp4"}`))
+		}
+	}))
+	defer api.Close()
+
+	dir := t.TempDir()
+	options := gobalt.CreateDefaultSettings()
+	options.Url = "https://youtu.be/dQw4w9WgXcQ"
+	//A store that can't save doesn't lose the queue.
+	broken := &memoryJobStore{jobs: []StoredJob{{ID: "job-1", Settings: options, Dir: dir}}, saveErr: errors.New("disk full")}
+	NewManager(gobalt.New(gobalt.WithInstance(api.URL)), ManagerOptions{Store: broken}).Shutdown(context.Background())
+	if pending, _ := broken.PendingJobs(); len(pending) != 1 {
+		t.Fatalf("jobs that couldn't be restored should stay in the store, got %+v", pending)
+	}
+
+	store := &memoryJobStore{jobs: []StoredJob{{ID: "job-7", Settings: options, Dir: dir}}}
+	queued := make(chan string, 2)
+	manager := NewManager(gobalt.New(gobalt.WithInstance(api.URL)), ManagerOptions{Store: store, Events: ManagerEvents{OnQueued: func(job *JobHandle) { queued <- job.ID }}})
+	if id := <-queued; id != "job-7" {
+		t.Fatalf("the restored job should keep its id, got %v", id)
+	}
+	next, err := manager.Submit(Job{Settings: options, Dir: t.TempDir()})
+	if err != nil || next.ID != "job-8" {
+		t.Fatalf("new jobs should get ids after the restored ones, got %v, %v", next, err)
+	}
+	if err := manager.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "video.mp4")); err != nil || string(data) != "media" {
+		t.Fatalf("the stored job should have been downloaded, got %q, %v", data, err)
+	}
+	if pending, _ := store.PendingJobs(); len(pending) != 0 {
+		t.Fatalf("finished jobs should be removed from the store, got %+v", pending)
+	}
+}
+
+func TestManagerPauseResumeCancel(t *testing.T) {
+	started := make(chan struct{}, 1)
+	var api *httptest.Server
+	api = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/tunnel" && r.Header.Get("Range") == "bytes=4-":
+			w.Header().Set("Content-Range", "bytes 4-7/8")
+			w.WriteHeader(http.StatusPartialContent)
+			w.Write([]byte("5678"))
+		case r.URL.Path == "/tunnel":
+			//Send half of the file, then hang until the job is stopped.
+			w.Header().Set("ETag", `"v1"`)
+			w.Header().Set("Content-Length", "8")
+			w.Write([]byte("1234"))
+			w.(http.Flusher).Flush()
+			started <- struct{}{}
+			<-r.Context().Done()
+		case r.Method == http.MethodGet:
+			w.Write([]byte(`{"cobalt":{"version":"10.5.0","services":["youtube"]}}`))
+		default:
+			w.Write([]byte(`{"status":"tunnel","url":"` + api.URL + `/tunnel","filename":"video.mp4"}`))
+		}
+	}))
+	defer api.Close()
+
+	dir := t.TempDir()
+	storePath := filepath.Join(t.TempDir(), "jobs.json")
+	store, err := OpenJobStoreFile(storePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	options := gobalt.CreateDefaultSettings()
+	options.Url = "https://youtu.be/dQw4w9WgXcQ"
+	manager := NewManager(gobalt.New(gobalt.WithInstance(api.URL)), ManagerOptions{Store: store})
+	defer manager.Shutdown(context.Background())
+
+	job, err := manager.Submit(Job{Settings: options, Dir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-started
+	for job.Status().Written < 4 {
+		time.Sleep(time.Millisecond)
+	}
+	if state := job.Status().State; state != JobRunning {
+		t.Fatalf("expected the job to run, got %v", state)
+	}
+	job.Pause()
+	if _, err := job.Result(); !errors.Is(err, gobalt.ErrPaused) || job.Status().State != JobPaused {
+		t.Fatalf("expected the job to be paused, got %v, %+v", err, job.Status())
+	}
+	if jobs := manager.Jobs(); len(jobs) != 1 || jobs[0] != job {
+		t.Fatalf("the paused job should still be known, got %v", jobs)
+	}
+	if reopened, _ := OpenJobStoreFile(storePath); reopened == nil || len(reopened.jobs) != 1 {
+		t.Fatal("the paused job should stay in the store file")
+	}
+
+	resumed, err := manager.Resume(job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := resumed.Result(); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "video.mp4")); string(data) != "12345678" {
+		t.Fatalf("the job should continue from its partial file, got %q", data)
+	}
+	if status := resumed.Status(); status.State != JobDone || status.Written != 8 || len(manager.Jobs()) != 0 {
+		t.Fatalf("unexpected status %+v", status)
+	}
+
+	canceled, err := manager.Submit(Job{Settings: options, Dir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-started
+	canceled.Cancel()
+	if _, err := canceled.Result(); !errors.Is(err, ErrJobCanceled) || canceled.Status().State != JobCanceled {
+		t.Fatalf("expected the job to be canceled, got %v", err)
+	}
+	if parts, _ := filepath.Glob(filepath.Join(canceled.Job.Dir, "*")); len(parts) != 0 {
+		t.Fatalf("canceled jobs should leave nothing behind, got %v", parts)
+	}
+	if pending, _ := store.PendingJobs(); len(pending) != 0 {
+		t.Fatalf("finished jobs should be removed from the store, got %+v", pending)
+	}
+
+	//Canceling a paused job removes what pausing kept.
+	paused, err := manager.Submit(Job{Settings: options, Dir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-started
+	for paused.Status().Written < 4 {
+		time.Sleep(time.Millisecond)
+	}
+	paused.Pause()
+	if _, err := paused.Result(); !errors.Is(err, gobalt.ErrPaused) {
+		t.Fatalf("expected the job to be paused, got %v", err)
+	}
+	if parts, _ := filepath.Glob(filepath.Join(paused.Job.Dir, "*")); len(parts) != 2 {
+		t.Fatalf("the paused job should keep its partial file and state, got %v", parts)
+	}
+	paused.Cancel()
+	if _, err := paused.Result(); !errors.Is(err, ErrJobCanceled) || paused.Status().State != JobCanceled {
+		t.Fatalf("expected the paused job to be canceled, got %v, %+v", err, paused.Status())
+	}
+	if parts, _ := filepath.Glob(filepath.Join(paused.Job.Dir, "*")); len(parts) != 0 {
+		t.Fatalf("canceling a paused job should remove its partial file, got %v", parts)
+	}
+	if _, ok := manager.Job(paused.ID); ok {
+		t.Fatal("the canceled job should be forgotten")
+	}
+	if pending, _ := store.PendingJobs(); len(pending) != 0 {
+		t.Fatalf("the canceled job should be removed from the store, got %+v", pending)
+	}
+}
+
+func TestManagerEvents(t *testing.T) {
+	failed := false
+	var api *httptest.Server
+	api = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/tunnel" && !failed:
+			failed = true
+			w.WriteHeader(http.StatusBadGateway)
+		case r.URL.Path == "/tunnel":
+			w.Write([]byte("media"))
+		case r.Method == http.MethodGet:
+			w.Write([]byte(`{"cobalt":{"version":"10.5.0","services":["youtube"]}}`))
+		default:
+			w.Write([]byte(`{"status":"tunnel","url":"` + api.URL + `/tunnel","filename":"video.mp4"}`))
+		}
+	}))
+	defer api.Close()
+
+	var mu sync.Mutex
+	var events []string
+	record := func(event string) {
+		mu.Lock()
+		defer mu.Unlock()
+		if len(events) == 0 || events[len(events)-1] != event {
+			events = append(events, event)
+		}
+	}
+	client := gobalt.New(gobalt.WithInstance(api.URL), gobalt.WithDownloadRetry(gobalt.DownloadRetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond}))
+	manager := NewManager(client, ManagerOptions{Events: ManagerEvents{
+		OnQueued:    func(*JobHandle) { record("queued") },
+		OnStarted:   func(*JobHandle) { record("started") },
+		OnProgress:  func(*JobHandle, int64, int64) { record("progress") },
+		OnRetry:     func(*JobHandle, int, time.Duration, error) { record("retry") },
+		OnCompleted: func(*JobHandle, *gobalt.DownloadResult) { record("completed") },
+		OnFailed:    func(*JobHandle, error) { record("failed") },
+	}})
+	options := gobalt.CreateDefaultSettings()
+	options.Url = "https://youtu.be/dQw4w9WgXcQ"
+	job, err := manager.Submit(Job{Settings: options, Dir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := job.Result(); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"queued", "started", "retry", "progress", "completed"}
+	if !slices.Equal(events, want) {
+		t.Fatalf("expected events %v, got %v", want, events)
+	}
+}
+
+func TestManagerJobLabels(t *testing.T) {
+	var api *httptest.Server
+	api = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/tunnel":
+			w.Write([]byte("media"))
+		case r.Method == http.MethodGet:
+			w.Write([]byte(`{"cobalt":{"version":"10.5.0","services":["youtube"]}}`))
+		default:
+			w.Write([]byte(`{"status":"tunnel","url":"` + api.URL + `/tunnel","filename":"video.mp4"}`))
+		}
+	}))
+	defer api.Close()
+
+	labels := make(map[string]string)
+	scanner := gobalt.ScannerFunc(func(ctx context.Context, path string) error {
+		pprof.ForLabels(ctx, func(key, value string) bool {
+			labels[key] = value
+			return true
+		})
+		return nil
+	})
+	manager := NewManager(gobalt.New(gobalt.WithInstance(api.URL)), ManagerOptions{Options: []gobalt.DownloadOption{gobalt.WithScanner(scanner)}})
+	options := gobalt.CreateDefaultSettings()
+	options.Url = "https://youtu.be/dQw4w9WgXcQ"
+	job, err := manager.Submit(Job{Settings: options, Dir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := job.Result(); err != nil {
+		t.Fatal(err)
+	}
+	if labels["gobalt_job"] != job.ID || labels["gobalt_service"] != "youtube" || labels["gobalt_instance"] != api.URL {
+		t.Fatalf("unexpected labels %v for job %v", labels, job.ID)
+	}
+}
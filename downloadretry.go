@@ -0,0 +1,297 @@
+package gobalt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DownloadRetryPolicy tells Download() how to recover when the transfer of a file fails. Media transfers don't fail like
+// api calls (see RetryPolicy): the connection can be reset in the middle of the file, or the tunnel can answer with an error.
+//
+//   - network errors, transfers cut in the middle and 5xx or 429 answers from the tunnel are retried, continuing
+//     from the bytes already written (with a Range request);
+//   - other 4xx answers usually mean the tunnel expired: the media is requested again from cobalt to get a new tunnel,
+//     and the file is downloaded from the start. This only works for downloads started by SaveTo() or SaveMedia(),
+//     which know the request that made the tunnel.
+//
+// The zero value never retries. Streams (DownloadTo()) are never retried, since what was written can't be taken back.
+type DownloadRetryPolicy struct {
+	MaxAttempts    int           //How many times the transfer is started at most, including the first one. 0 or 1 means no retries.
+	InitialBackoff time.Duration //Wait before the first retry, doubled after each one. Default: 1 second.
+	MaxBackoff     time.Duration //Longest wait between retries. Default: 30 seconds.
+}
+
+// WithDownloadRetry sets the policy used to retry failed file transfers, see DownloadRetryPolicy.
+// It's separate from WithRetry(), which only applies to api calls.
+func WithDownloadRetry(policy DownloadRetryPolicy) Option {
+	return func(c *Cobalt) {
+		c.downloadRetry = policy
+	}
+}
+
+// TunnelStatusError is returned when the server of a file (usually a cobalt tunnel) answers with an unexpected status.
+type TunnelStatusError struct {
+	StatusCode int
+	Status     string
+}
+
+func (e *TunnelStatusError) Error() string {
+	return fmt.Sprintf("download failed with %v", e.Status)
+}
+
+// transferError is a failure while reading the file from the server, as opposed to writing it.
+type transferError struct {
+	written int64
+	err     error
+}
+
+func (e *transferError) Error() string {
+	return fmt.Sprintf("transfer interrupted after %v bytes: %v", e.written, e.err)
+}
+
+func (e *transferError) Unwrap() error {
+	return e.err
+}
+
+// How a failed transfer can be recovered.
+type downloadRecovery int
+
+const (
+	giveUp        downloadRecovery = iota
+	resumeTunnel                   //Continue from the current offset, with the same tunnel.
+	refreshTunnel                  //Ask cobalt for a new tunnel and start again.
+)
+
+// recovery tells what to do after a transfer failed with err.
+func (p DownloadRetryPolicy) recovery(ctx context.Context, err error) downloadRecovery {
+	if ctx.Err() != nil {
+		return giveUp
+	}
+	var status *TunnelStatusError
+	if errors.As(err, &status) {
+		switch {
+		case status.StatusCode >= 500 || status.StatusCode == http.StatusTooManyRequests:
+			return resumeTunnel
+		case status.StatusCode >= 400:
+			return refreshTunnel
+		}
+		return giveUp
+	}
+	var transfer *transferError
+	var network *url.Error
+	if errors.As(err, &transfer) || errors.As(err, &network) {
+		return resumeTunnel
+	}
+	return giveUp
+}
+
+// withTunnelRefresh lets Download() ask cobalt for a new tunnel when the current one stopped working, see DownloadRetryPolicy.
+func withTunnelRefresh(refresh func(ctx context.Context) (string, error)) DownloadOption {
+	return func(c *downloadConfig) {
+		c.refreshTunnel = refresh
+	}
+}
+
+// RetryFunc is called before a failed request or download is sent again, after attempt tries and before waiting wait.
+type RetryFunc func(attempt int, wait time.Duration, err error)
+
+// WithRetryHook calls fn before the download is retried, see DownloadRetryPolicy.
+func WithRetryHook(fn RetryFunc) DownloadOption {
+	return func(c *downloadConfig) {
+		c.onRetry = append(c.onRetry, fn)
+	}
+}
+
+// fetch downloads state.URL to file (the partial file of state), retrying as c.downloadRetry allows. state.Size is kept
+// up to date with what was written, and state.URL changes if a new tunnel was needed. Pre-checks must already be done.
+func (c *Cobalt) fetch(ctx context.Context, config *downloadConfig, state *ResumeState, file *os.File) error {
+	policy := c.downloadRetry
+	for attempt := 1; ; attempt++ {
+		err := c.fetchOnce(ctx, config, state, file)
+		if err == nil {
+			return nil
+		}
+		recovery := policy.recovery(ctx, err)
+		if recovery == giveUp || attempt >= max(policy.MaxAttempts, 1) {
+			return err
+		}
+		if recovery == refreshTunnel {
+			if config.refreshTunnel == nil {
+				return err
+			}
+			tunnel, refreshErr := config.refreshTunnel(ctx)
+			if refreshErr != nil {
+				return fmt.Errorf("%w (and requesting a new tunnel failed: %w)", err, refreshErr)
+			}
+			//A new tunnel may serve a different file (another quality, a new encode), start from scratch.
+			state.URL, state.ETag, state.LastModified = tunnel, "", ""
+			if err := restartPart(file, state); err != nil {
+				return err
+			}
+		}
+		c.logger.Debug("retrying download", "url", state.URL, "attempt", attempt+1, "offset", state.Size, "error", err)
+		wait := RetryPolicy{InitialBackoff: policy.InitialBackoff, MaxBackoff: policy.MaxBackoff}.backoff(attempt)
+		for _, fn := range config.onRetry {
+			fn(attempt, wait, err)
+		}
+		if orSystemClock(c.clock).Sleep(ctx, wait) != nil {
+			return err
+		}
+	}
+}
+
+// fetchOnce requests state.URL from state.Size on, and appends the body to file.
+func (c *Cobalt) fetchOnce(ctx context.Context, config *downloadConfig, state *ResumeState, file *os.File) error {
+	validator := state.ETag
+	if validator == "" {
+		validator = state.LastModified
+	}
+	res, err := c.requestDownload(ctx, state.URL, config, state.Size, validator)
+	var status *TunnelStatusError
+	if errors.As(err, &status) && status.StatusCode == http.StatusRequestedRangeNotSatisfiable && state.Size > 0 && state.Size == state.TotalSize {
+		//The partial file was already complete.
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusOK {
+		//The server sent the whole file, either because nothing was written yet or because it ignored the Range.
+		if err := restartPart(file, state); err != nil {
+			return err
+		}
+		if config.nameFromResponse {
+			if _, params, err := mime.ParseMediaType(res.Header.Get("Content-Disposition")); err == nil {
+				config.responseName = params["filename"]
+			}
+		}
+		fresh := newResumeState(state.URL, state.Source, state.Path, res)
+		state.ETag, state.LastModified, state.TotalSize = fresh.ETag, fresh.LastModified, fresh.TotalSize
+		if err := state.Save(); err != nil {
+			return err
+		}
+	}
+
+	body := &readErrorTracker{r: res.Body}
+	w, leave := config.limitBandwidth(ctx, file)
+	written, err := io.Copy(config.trackProgress(config.hashWriter(w, state.Size), state.Size, state.TotalSize), body)
+	leave()
+	state.Size += written
+	c.metrics.BytesDownloaded(hostOf(state.URL), written)
+	if err != nil && body.err != nil {
+		return &transferError{written: state.Size, err: err}
+	}
+	return err
+}
+
+// requestDownload sends the GET request of a download, asking for the bytes after offset when it's not 0. If validator
+// (an ETag or Last-Modified) is set, the server sends the whole file again if it changed. The caller must close the body.
+func (c *Cobalt) requestDownload(ctx context.Context, url string, config *downloadConfig, offset int64, validator string) (*http.Response, error) {
+	return c.requestRange(ctx, url, config, offset, -1, validator)
+}
+
+// requestRange works like requestDownload(), but asks for the bytes from offset to end (included) when end isn't -1.
+func (c *Cobalt) requestRange(ctx context.Context, url string, config *downloadConfig, offset, end int64, validator string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Add("User-Agent", useragent)
+	ranged := offset > 0 || end >= 0
+	if ranged {
+		if end >= 0 {
+			req.Header.Set("Range", fmt.Sprintf("bytes=%v-%v", offset, end))
+		} else {
+			req.Header.Set("Range", fmt.Sprintf("bytes=%v-", offset))
+		}
+		if validator != "" {
+			req.Header.Set("If-Range", validator)
+		}
+	}
+
+	releaseHost, err := config.limitHost(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	host := hostOf(url)
+	c.metrics.DownloadStarted(host)
+	var once sync.Once
+	release := func() {
+		once.Do(func() {
+			releaseHost()
+			c.metrics.DownloadFinished(host)
+		})
+	}
+
+	res, err := c.doTransfer(req)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	holdUntilClosed(res, release)
+	switch {
+	case res.StatusCode == http.StatusOK:
+		return res, nil
+	case res.StatusCode == http.StatusPartialContent && ranged:
+		if start := rangeStart(res.Header.Get("Content-Range")); start != offset {
+			res.Body.Close()
+			return nil, fmt.Errorf("asked for the file from byte %v, the server sent it from byte %v", offset, start)
+		}
+		return res, nil
+	}
+	res.Body.Close()
+	return nil, &TunnelStatusError{StatusCode: res.StatusCode, Status: res.Status}
+}
+
+// rangeStart gets the first byte from a header like "bytes 100-199/200", returns -1 if it's invalid.
+func rangeStart(header string) int64 {
+	spec, found := strings.CutPrefix(header, "bytes ")
+	start, _, dash := strings.Cut(spec, "-")
+	if !found || !dash {
+		return -1
+	}
+	n, err := strconv.ParseInt(start, 10, 64)
+	if err != nil {
+		return -1
+	}
+	return n
+}
+
+// restartPart empties the partial file, to download the file again from the start.
+func restartPart(file *os.File, state *ResumeState) error {
+	if state.Size == 0 {
+		return nil
+	}
+	state.Size = 0
+	if err := file.Truncate(0); err != nil {
+		return err
+	}
+	_, err := file.Seek(0, io.SeekStart)
+	return err
+}
+
+// readErrorTracker remembers the error returned by r, so a failed copy can tell reading and writing errors apart.
+type readErrorTracker struct {
+	r   io.Reader
+	err error
+}
+
+func (t *readErrorTracker) Read(b []byte) (int, error) {
+	n, err := t.r.Read(b)
+	if err != nil && err != io.EOF {
+		t.err = err
+	}
+	return n, err
+}
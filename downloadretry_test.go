@@ -0,0 +1,79 @@
+package gobalt
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestDownloadRetryResumes(t *testing.T) {
+	var ranges []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ranges = append(ranges, r.Header.Get("Range"))
+		if r.Header.Get("Range") == "" {
+			//Send half of the file, then reset the connection.
+			w.Header().Set("Content-Length", "8")
+			w.Header().Set("ETag", `"v1"`)
+			w.Write([]byte("1234"))
+			w.(http.Flusher).Flush()
+			conn, _, _ := w.(http.Hijacker).Hijack()
+			conn.Close()
+			return
+		}
+		if r.Header.Get("If-Range") != `"v1"` {
+			t.Errorf("the resumed request should check the ETag, got %q", r.Header.Get("If-Range"))
+		}
+		w.Header().Set("Content-Range", "bytes 4-7/8")
+		w.WriteHeader(http.StatusPartialContent)
+		w.Write([]byte("5678"))
+	}))
+	defer server.Close()
+
+	clock := &fakeClock{}
+	client := New(WithDownloadRetry(DownloadRetryPolicy{MaxAttempts: 2}), WithClock(clock))
+	path := filepath.Join(t.TempDir(), "video.mp4")
+	result, err := client.Download(context.Background(), server.URL, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "12345678" || result.Size != 8 {
+		t.Fatalf("unexpected file %q (%v bytes)", data, result.Size)
+	}
+	if strings.Join(ranges, ",") != ",bytes=4-" {
+		t.Fatalf("unexpected requests %q", ranges)
+	}
+}
+
+func TestDownloadRetryRefreshesTunnel(t *testing.T) {
+	var runs atomic.Int64
+	var api *httptest.Server
+	api = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/tunnel/1":
+			http.Error(w, "tunnel expired", http.StatusNotFound)
+		case r.URL.Path == "/tunnel/2":
+			w.Write([]byte("media"))
+		case r.Method == http.MethodGet:
+			w.Write([]byte(`{"cobalt":{"version":"10.5.0","services":["youtube"]}}`))
+		default:
+			w.Write([]byte(`{"status":"tunnel","url":"` + api.URL + `/tunnel/` + string(rune('0'+runs.Add(1))) + `","filename":"video.mp4"}`))
+		}
+	}))
+	defer api.Close()
+
+	client := New(WithInstance(api.URL), WithDownloadRetry(DownloadRetryPolicy{MaxAttempts: 2}), WithClock(&fakeClock{}))
+	options := CreateDefaultSettings()
+	options.Url = "https://youtu.be/dQw4w9WgXcQ"
+	result, err := client.SaveMedia(context.Background(), options, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.URL != api.URL+"/tunnel/2" || runs.Load() != 2 {
+		t.Fatalf("expected a second tunnel to be requested, got %v after %v requests", result.URL, runs.Load())
+	}
+}
@@ -0,0 +1,81 @@
+package gobalt
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"sync"
+)
+
+// Request bodies are encoded into pooled buffers, Run() is called for every media so they're reused instead of allocated every time.
+var bodyPool = sync.Pool{
+	New: func() any {
+		body := &pooledBody{}
+		body.encoder = json.NewEncoder(&body.buf)
+		body.encoder.SetEscapeHTML(false)
+		return body
+	},
+}
+
+// Buffers bigger than this are not put back in the pool, so one huge request doesn't keep memory around forever.
+const maxPooledBufferSize = 64 << 10
+
+// pooledBody is a request body backed by a pooled buffer.
+//
+// The transport may read and close the body after Client.Do() returned (and GetBody can create more readers),
+// so the buffer only goes back to the pool when every reader was closed and release() was called by the request owner.
+type pooledBody struct {
+	buf     bytes.Buffer
+	encoder *json.Encoder
+
+	mu   sync.Mutex
+	refs int
+}
+
+// encodeJSON encodes v to a pooled buffer. Call release() once the request using it is finished.
+func encodeJSON(v any) (*pooledBody, error) {
+	body := bodyPool.Get().(*pooledBody)
+	body.buf.Reset()
+	if err := body.encoder.Encode(v); err != nil {
+		bodyPool.Put(body)
+		return nil, err
+	}
+	body.refs = 1
+	return body, nil
+}
+
+// Len returns the size of the encoded body.
+func (p *pooledBody) Len() int64 {
+	return int64(p.buf.Len())
+}
+
+// Reader returns a new reader over the encoded body, usable as http.Request.Body and in http.Request.GetBody.
+func (p *pooledBody) Reader() io.ReadCloser {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.refs++
+	reader := &pooledReader{body: p}
+	reader.Reset(p.buf.Bytes())
+	return reader
+}
+
+// release drops one reference to the buffer, putting it back in the pool when nobody uses it anymore.
+func (p *pooledBody) release() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.refs--
+	if p.refs == 0 && p.buf.Cap() <= maxPooledBufferSize {
+		bodyPool.Put(p)
+	}
+}
+
+type pooledReader struct {
+	bytes.Reader
+	body *pooledBody
+	once sync.Once
+}
+
+func (r *pooledReader) Close() error {
+	r.once.Do(r.body.release)
+	return nil
+}
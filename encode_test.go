@@ -0,0 +1,61 @@
+package gobalt
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestEncodeJSON(t *testing.T) {
+	options := CreateDefaultSettings()
+	options.Url = "https://www.youtube.com/watch?v=dQw4w9WgXcQ&t=1"
+	body, err := encodeJSON(options)
+	if err != nil {
+		t.Fatalf("failed to encode: %v", err)
+	}
+	first, second := body.Reader(), body.Reader()
+	body.release()
+
+	for _, reader := range []io.ReadCloser{first, second} {
+		var decoded Settings
+		if err := json.NewDecoder(reader).Decode(&decoded); err != nil || decoded != options {
+			t.Fatalf("expected %+v, got %+v (err: %v)", options, decoded, err)
+		}
+		reader.Close()
+		reader.Close() //Closing twice must not release the buffer twice.
+	}
+	if body.refs != 0 {
+		t.Fatalf("expected every reference to be released, got %v", body.refs)
+	}
+}
+
+// The way requests were encoded before buffer pooling, kept to compare in benchmarks.
+func BenchmarkEncodeSettingsMarshal(b *testing.B) {
+	options := CreateDefaultSettings()
+	options.Url = "https://www.youtube.com/watch?v=dQw4w9WgXcQ"
+	b.ReportAllocs()
+	for range b.N {
+		jsonBody, err := json.Marshal(options)
+		if err != nil {
+			b.Fatal(err)
+		}
+		io.Copy(io.Discard, strings.NewReader(string(jsonBody)))
+	}
+}
+
+func BenchmarkEncodeSettingsPooled(b *testing.B) {
+	options := CreateDefaultSettings()
+	options.Url = "https://www.youtube.com/watch?v=dQw4w9WgXcQ"
+	b.ReportAllocs()
+	for range b.N {
+		body, err := encodeJSON(options)
+		if err != nil {
+			b.Fatal(err)
+		}
+		reader := body.Reader()
+		io.Copy(io.Discard, reader)
+		reader.Close()
+		body.release()
+	}
+}
@@ -0,0 +1,64 @@
+package gobalt
+
+import (
+	"errors"
+	"net/http"
+)
+
+// ErrorJSON is a gobalt error in a stable JSON shape, made by ExportError(), so services wrapping gobalt can pass
+// errors to their own frontends without mapping every error type themselves.
+type ErrorJSON struct {
+	Code        string   `json:"code,omitempty"`      //Cobalt error code, like "error.api.link.invalid". Empty for errors without a code.
+	Description string   `json:"description"`         //Human-readable message, see ResolveError().
+	Context     *Context `json:"context,omitempty"`   //Context sent by cobalt with the error, if any.
+	Retryable   bool     `json:"retryable"`           //If sending the same request again may work.
+	Instance    string   `json:"instance,omitempty"`  //Instance that failed, if known.
+	RequestID   string   `json:"requestId,omitempty"` //Id of the failed request sent by the instance (X-Request-Id or CF-Ray header), if any.
+}
+
+// requestIDFrom returns the id the instance (or a proxy in front of it) gave to a request.
+func requestIDFrom(res *http.Response) string {
+	for _, header := range []string{"X-Request-Id", "CF-Ray"} {
+		if id := res.Header.Get(header); id != "" {
+			return id
+		}
+	}
+	return ""
+}
+
+// ExportError(err) describes any error returned by gobalt as an ErrorJSON, with its description in Language.
+func ExportError(err error) *ErrorJSON {
+	return exportError(Language, err)
+}
+
+// ExportError(err) is the same as ExportError(), with the description in the language of the client.
+func (c *Cobalt) ExportError(err error) *ErrorJSON {
+	return exportError(c.language, err)
+}
+
+func exportError(language string, err error) *ErrorJSON {
+	if err == nil {
+		return nil
+	}
+	exported := &ErrorJSON{Description: err.Error(), Retryable: isRetryable(err)}
+	if code := errorCode(err); code != "" {
+		exported.Code = code
+		exported.Description = resolveError(language, err)
+	}
+
+	var cobaltErr *CobaltError
+	var invalid *InvalidResponseError
+	var unsupported *UnsupportedParameterError
+	switch {
+	case errors.As(err, &cobaltErr):
+		exported.Instance, exported.RequestID = cobaltErr.Instance, cobaltErr.RequestID
+		if cobaltErr.Context != (Context{}) {
+			exported.Context = &cobaltErr.Context
+		}
+	case errors.As(err, &invalid):
+		exported.Instance = invalid.Instance
+	case errors.As(err, &unsupported):
+		exported.Instance = unsupported.Instance
+	}
+	return exported
+}
@@ -0,0 +1,39 @@
+package gobalt
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestExportError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			w.Write([]byte(`{"cobalt":{"version":"10.5.0","services":["youtube"]}}`))
+			return
+		}
+		w.Header().Set("X-Request-Id", "abc123")
+		w.Write([]byte(`{"status":"error","error":{"code":"error.api.rate_exceeded","context":{"limit":20}}}`))
+	}))
+	defer server.Close()
+
+	options := CreateDefaultSettings()
+	options.Url = "https://youtu.be/dQw4w9WgXcQ"
+	_, err := New(WithInstance(server.URL)).Run(context.Background(), options)
+	if err == nil || err.Error() != "error.api.rate_exceeded" {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	encoded, _ := json.Marshal(ExportError(err))
+	var exported map[string]any
+	json.Unmarshal(encoded, &exported)
+	if exported["code"] != "error.api.rate_exceeded" || exported["retryable"] != true || exported["instance"] != server.URL ||
+		exported["requestId"] != "abc123" || exported["description"] == "" {
+		t.Fatalf("unexpected export %s", encoded)
+	}
+	if limit := exported["context"].(map[string]any)["limit"]; limit != 20.0 {
+		t.Fatalf("context was not exported, got %s", encoded)
+	}
+}
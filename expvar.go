@@ -0,0 +1,76 @@
+package gobalt
+
+import (
+	"expvar"
+	"sync"
+	"time"
+)
+
+// ExpvarMetrics is a Metrics publishing counters with expvar, for programs already exposing /debug/vars.
+// Create one with NewExpvarMetrics() or use WithExpvar().
+type ExpvarMetrics struct {
+	Requests        *expvar.Int //Requests sent to cobalt.
+	Errors          *expvar.Map //Failed requests, by error code (see MetricCode()).
+	Bytes           *expvar.Int //Bytes of files downloaded.
+	ActiveDownloads *expvar.Int //File transfers in progress.
+	ActiveJobs      *expvar.Int //Jobs running, see DoJob().
+}
+
+var (
+	expvarMu      sync.Mutex
+	expvarMetrics = make(map[string]*ExpvarMetrics)
+)
+
+// NewExpvarMetrics(prefix) publishes the counters as prefix+"requests", prefix+"errors", prefix+"bytes_downloaded",
+// prefix+"active_downloads" and prefix+"active_jobs". expvar names can't be published twice, so clients using the same prefix
+// share the same counters.
+func NewExpvarMetrics(prefix string) *ExpvarMetrics {
+	expvarMu.Lock()
+	defer expvarMu.Unlock()
+	if metrics, ok := expvarMetrics[prefix]; ok {
+		return metrics
+	}
+	metrics := &ExpvarMetrics{
+		Requests:        expvar.NewInt(prefix + "requests"),
+		Errors:          expvar.NewMap(prefix + "errors"),
+		Bytes:           expvar.NewInt(prefix + "bytes_downloaded"),
+		ActiveDownloads: expvar.NewInt(prefix + "active_downloads"),
+		ActiveJobs:      expvar.NewInt(prefix + "active_jobs"),
+	}
+	expvarMetrics[prefix] = metrics
+	return metrics
+}
+
+// WithExpvar makes the client publish its counters with expvar, see NewExpvarMetrics(). Example prefix: "gobalt_".
+func WithExpvar(prefix string) Option {
+	return WithMetrics(NewExpvarMetrics(prefix))
+}
+
+func (m *ExpvarMetrics) RequestFinished(instance, code string, duration time.Duration) {
+	m.Requests.Add(1)
+	if code != "ok" {
+		m.Errors.Add(code, 1)
+	}
+}
+
+func (m *ExpvarMetrics) BytesDownloaded(host string, n int64) {
+	m.Bytes.Add(n)
+}
+
+func (m *ExpvarMetrics) JobStarted() {
+	m.ActiveJobs.Add(1)
+}
+
+func (m *ExpvarMetrics) JobFinished(err error) {
+	m.ActiveJobs.Add(-1)
+}
+
+func (m *ExpvarMetrics) InstanceHealth(instance string, healthy bool) {}
+
+func (m *ExpvarMetrics) DownloadStarted(host string) {
+	m.ActiveDownloads.Add(1)
+}
+
+func (m *ExpvarMetrics) DownloadFinished(host string) {
+	m.ActiveDownloads.Add(-1)
+}
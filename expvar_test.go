@@ -0,0 +1,23 @@
+package gobalt
+
+import (
+	"context"
+	"expvar"
+	"testing"
+)
+
+func TestExpvarMetrics(t *testing.T) {
+	server := fakeCobalt(t, `{"status":"error","error":{"code":"error.api.link.invalid"}}`)
+	client := New(WithInstance(server.URL), WithExpvar("gobalt_test_"))
+	options := CreateDefaultSettings()
+	options.Url = "https://youtu.be/dQw4w9WgXcQ"
+	client.Run(context.Background(), options)
+
+	metrics := NewExpvarMetrics("gobalt_test_")
+	if metrics.Requests.Value() != 1 || metrics.Errors.Get("error.api.link.invalid").String() != "1" {
+		t.Fatalf("unexpected counters: requests %v, errors %v", metrics.Requests, metrics.Errors)
+	}
+	if expvar.Get("gobalt_test_requests") == nil {
+		t.Fatal("the counters should be published")
+	}
+}
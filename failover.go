@@ -0,0 +1,198 @@
+package gobalt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FailoverOptions configures the automatic failover of a client, see WithFailover().
+type FailoverOptions struct {
+	MaxInstances int             //How many other instances are tried at most. Default: 3.
+	Sources      InstanceSources //Where the other instances are found. Default: the InstanceTrackers.
+	ListTTL      time.Duration   //How long the list of instances is kept before it's fetched again. Default: 10 minutes.
+	ProbeTimeout time.Duration   //How long the server info of each other instance may take to arrive. Default: 5 seconds.
+}
+
+// failoverProbes is how many other instances are asked for their server info at the same time.
+const failoverProbes = 4
+
+// failover is the state of the failover of a client: the options and the cached list of instances.
+type failover struct {
+	options FailoverOptions
+
+	mu      sync.Mutex
+	list    CobaltInstance
+	fetched time.Time
+}
+
+// WithFailover makes Run() send the request to other instances when the instance of the client can't handle it
+// (it's down, overloaded, or failed to fetch the media), after the retries of the RetryPolicy. Other instances are taken
+// from opts.Sources, best score first, and the ones whose server info lists the service of the link are tried first.
+// When another instance answered, CobaltResponse.Warnings has a WarningInstanceFallback.
+//
+// Other instances are usually public ones run by strangers, so the requests sent to them don't have the API key of the client.
+// Failover happens within RetryPolicy.Budget, like the retries.
+func WithFailover(opts FailoverOptions) Option {
+	return func(c *Cobalt) {
+		c.failover = &failover{options: opts}
+	}
+}
+
+// shouldFailover reports if a request that failed with err may work on another instance. Errors about the link
+// or the content itself (like error.api.link.invalid or error.api.content.video.unavailable) would fail everywhere.
+func shouldFailover(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var unsupported *UnsupportedParameterError
+	if isRetryable(err) || errors.As(err, &unsupported) {
+		return true
+	}
+	if invalid := (*InvalidResponseError)(nil); errors.As(err, &invalid) {
+		return true
+	}
+	code, _, _ := strings.Cut(err.Error(), ":")
+	for _, prefix := range []string{"error.net.", "error.api.service.", "error.api.fetch.", "error.api.youtube.", "error.api.auth."} {
+		if strings.HasPrefix(code, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// runFailover sends the request to other instances, after it failed with err on the instance of the client.
+// Returns err if no other instance could handle it.
+func (c *Cobalt) runFailover(ctx context.Context, options Settings, err error) (*CobaltResponse, error) {
+	instances, listErr := c.failover.instances(ctx, c)
+	if listErr != nil {
+		c.logger.Warn("failover failed to list instances", "error", listErr)
+		return nil, err
+	}
+	limit := c.failover.options.MaxInstances
+	if limit <= 0 {
+		limit = 3
+	}
+	candidates := c.failover.probe(ctx, c, instances, DetectService(options.Url), limit)
+	//The api key of the client is for its own instance, don't hand it to others.
+	anonymous := *c
+	anonymous.apiKey = ""
+
+	tried := 0
+	for _, api := range candidates {
+		if tried >= limit || ctx.Err() != nil {
+			break
+		}
+		tried++
+		start := c.clock.Now()
+		media, runErr := anonymous.run(ctx, api, options)
+		c.metrics.RequestFinished(instanceLabel(api), MetricCode(runErr), c.clock.Now().Sub(start))
+		if runErr == nil {
+			warning := Warning{
+				Code:     WarningInstanceFallback,
+				Message:  fmt.Sprintf("%v failed (%v), the request was sent to %v", c.api, err, api),
+				Instance: api,
+			}
+			c.warn(warning)
+			media.Warnings = append(media.Warnings, warning)
+			return media, nil
+		}
+		if !shouldFailover(runErr) {
+			return nil, runErr
+		}
+	}
+	return nil, err
+}
+
+// probe asks instances (but the one of c) for their server info, failoverProbes at a time and within ProbeTimeout each, so
+// dead instances don't hold the request. Returns the ones that answered, the ones listing service first, in the order of instances.
+// It stops asking once limit instances listing service answered.
+func (f *failover) probe(ctx context.Context, c *Cobalt, instances CobaltInstance, service string, limit int) []string {
+	timeout := f.options.ProbeTimeout
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	current := instanceKey(c.api)
+	const (
+		unreachable = iota
+		supported
+		unsupported
+	)
+	results := make([]int, len(instances))
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		found int
+	)
+	slots := make(chan struct{}, failoverProbes)
+	for i, instance := range instances {
+		if !instance.Online.API || instanceKey(instance.API) == current {
+			continue
+		}
+		slots <- struct{}{}
+		mu.Lock()
+		enough := found >= limit
+		mu.Unlock()
+		if enough || ctx.Err() != nil {
+			<-slots
+			break
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			probeCtx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+			info, err := c.serverInfoCache.get(probeCtx, c, instance.API)
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err != nil:
+				results[i] = unreachable
+			case supportsService(info, service):
+				results[i] = supported
+				found++
+			default:
+				results[i] = unsupported
+			}
+		}()
+	}
+	wg.Wait()
+
+	//Instances that don't list the service are only tried once the others were.
+	var preferred, others []string
+	for i, result := range results {
+		switch result {
+		case supported:
+			preferred = append(preferred, instances[i].API)
+		case unsupported:
+			others = append(others, instances[i].API)
+		}
+	}
+	return append(preferred, others...)
+}
+
+// instances returns the list of instances to fail over to, fetching it again when it's older than ListTTL.
+func (f *failover) instances(ctx context.Context, c *Cobalt) (CobaltInstance, error) {
+	ttl := f.options.ListTTL
+	if ttl <= 0 {
+		ttl = 10 * time.Minute
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.list != nil && c.clock.Now().Sub(f.fetched) < ttl {
+		return f.list, nil
+	}
+	list, err := c.GetInstances(ctx, f.options.Sources)
+	if err != nil {
+		return nil, err
+	}
+	list = slices.Clone(list)
+	slices.SortStableFunc(list, func(a, b InstanceInfo) int { return b.Score - a.Score })
+	f.list, f.fetched = list, c.clock.Now()
+	return list, nil
+}
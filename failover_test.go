@@ -0,0 +1,166 @@
+package gobalt
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRunFailover(t *testing.T) {
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer down.Close()
+	noYoutube := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			w.Write([]byte(`{"cobalt":{"version":"10.5.0","url":"http://` + r.Host + `/","services":["tiktok"]},"git":{"branch":"main"}}`))
+			return
+		}
+		t.Error("the instance without youtube shouldn't be used")
+		w.Write([]byte(`{"status":"error","error":{"code":"error.api.service.unsupported"}}`))
+	}))
+	defer noYoutube.Close()
+	working := fakeCobalt(t, `{"status":"tunnel","url":"https://example.com/tunnel","filename":"video.mp4"}`)
+
+	var warnings []Warning
+	client := New(
+		WithInstance(down.URL),
+		WithWarningHandler(func(w Warning) {
+			if w.Code == WarningInstanceFallback {
+				warnings = append(warnings, w)
+			}
+		}),
+		WithFailover(FailoverOptions{
+			MaxInstances: 1,
+			Sources: InstanceSources{Trackers: []string{down.URL}, Static: []InstanceInfo{
+				{API: noYoutube.URL, Score: 100, Online: OnlineStatus{API: true}},
+				{API: working.URL, Score: 50, Online: OnlineStatus{API: true}},
+			}},
+		}),
+	)
+	options := CreateDefaultSettings()
+	options.Url = "https://youtu.be/dQw4w9WgXcQ"
+	media, err := client.Run(context.Background(), options)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if media.Filename != "video.mp4" {
+		t.Fatalf("got unexpected response %+v", media)
+	}
+	if len(warnings) != 1 || warnings[0].Code != WarningInstanceFallback || warnings[0].Instance != working.URL {
+		t.Fatalf("expected a fallback warning for %v, got %+v", working.URL, warnings)
+	}
+	if last := media.Warnings[len(media.Warnings)-1]; last.Code != WarningInstanceFallback {
+		t.Fatalf("expected the warning in the response, got %+v", media.Warnings)
+	}
+}
+
+func TestShouldFailover(t *testing.T) {
+	for code, want := range map[string]bool{
+		"error.api.link.invalid":              false,
+		"error.api.content.video.unavailable": false,
+		"error.api.fetch.fail":                true,
+		"error.api.youtube.login":             true,
+		"error.net.generic":                   true,
+	} {
+		if got := shouldFailover(errorString(code)); got != want {
+			t.Errorf("shouldFailover(%v) = %v, want %v", code, got, want)
+		}
+	}
+}
+
+type errorString string
+
+func (e errorString) Error() string { return string(e) }
+
+func TestFailoverWithoutKey(t *testing.T) {
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer down.Close()
+	//A dead instance that never answers its server info must not hold the request.
+	hanging := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		//The server info request is shared, it isn't canceled with the probe.
+		time.Sleep(time.Second)
+	}))
+	defer hanging.Close()
+	var authorization atomic.Value
+	public := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			w.Write([]byte(`{"cobalt":{"version":"10.5.0","services":["youtube"]}}`))
+			return
+		}
+		authorization.Store(r.Header.Get("Authorization"))
+		w.Write([]byte(`{"status":"tunnel","url":"https://example.com/tunnel","filename":"video.mp4"}`))
+	}))
+	defer public.Close()
+	t.Cleanup(func() { DefaultServerInfoCache.Forget(public.URL) })
+
+	client := New(
+		WithInstance(down.URL),
+		WithAPIKey("private-key"),
+		WithFailover(FailoverOptions{
+			ProbeTimeout: 100 * time.Millisecond,
+			Sources: InstanceSources{Trackers: []string{down.URL}, Static: []InstanceInfo{
+				{API: hanging.URL, Score: 100, Online: OnlineStatus{API: true}},
+				{API: public.URL, Score: 50, Online: OnlineStatus{API: true}},
+			}},
+		}),
+	)
+	options := CreateDefaultSettings()
+	options.Url = "https://youtu.be/dQw4w9WgXcQ"
+	start := time.Now()
+	if _, err := client.Run(context.Background(), options); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("the hanging instance held the request for %v", elapsed)
+	}
+	if got := authorization.Load(); got == nil || strings.Contains(got.(string), "private-key") {
+		t.Fatalf("the api key must not be sent to other instances, got %q", got)
+	}
+}
+
+func TestFailoverWithinBudget(t *testing.T) {
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer down.Close()
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			w.Write([]byte(`{"cobalt":{"version":"10.5.0","services":["youtube"]}}`))
+			return
+		}
+		io.Copy(io.Discard, r.Body)
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer slow.Close()
+	t.Cleanup(func() { DefaultServerInfoCache.Forget(slow.URL) })
+
+	client := New(
+		WithInstance(down.URL),
+		WithRetry(RetryPolicy{Budget: 300 * time.Millisecond}),
+		WithFailover(FailoverOptions{Sources: InstanceSources{Trackers: []string{down.URL}, Static: []InstanceInfo{
+			{API: slow.URL, Score: 100, Online: OnlineStatus{API: true}},
+		}}}),
+	)
+	options := CreateDefaultSettings()
+	options.Url = "https://youtu.be/dQw4w9WgXcQ"
+	start := time.Now()
+	_, err := client.Run(context.Background(), options)
+	if !errors.Is(err, ErrRetryBudgetExhausted) {
+		t.Fatalf("expected ErrRetryBudgetExhausted, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("failover ran past the budget, took %v", elapsed)
+	}
+}
@@ -0,0 +1,37 @@
+package gobalt
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// FFmpegPath is the ffmpeg program used when gobalt needs to change a file locally (like tagging formats other than mp3).
+// It's looked up in PATH if it has no path separator.
+var FFmpegPath = "ffmpeg"
+
+// ErrFFmpegNotFound is returned when a feature needs ffmpeg, but FFmpegPath can't be found.
+var ErrFFmpegNotFound = errors.New("ffmpeg not found")
+
+// runFFmpeg runs ffmpeg with args, returning the end of its output as error if it fails.
+func runFFmpeg(ctx context.Context, args ...string) error {
+	program, err := exec.LookPath(FFmpegPath)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrFFmpegNotFound, err)
+	}
+	var output bytes.Buffer
+	cmd := exec.CommandContext(ctx, program, append([]string{"-hide_banner", "-loglevel", "error", "-y"}, args...)...)
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	if err := cmd.Run(); err != nil {
+		message := strings.TrimSpace(output.String())
+		if len(message) > 500 {
+			message = "..." + message[len(message)-500:]
+		}
+		return fmt.Errorf("ffmpeg failed: %w: %v", err, message)
+	}
+	return nil
+}
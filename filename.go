@@ -0,0 +1,127 @@
+package gobalt
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxFilenameBytes is the longest file name most filesystems accept.
+const maxFilenameBytes = 255
+
+// SanitizeFilename(name) makes name safe to use as a file name on this system, see SanitizeFilenameFor().
+func SanitizeFilename(name string) string {
+	return SanitizeFilenameFor(runtime.GOOS, name)
+}
+
+// SanitizeFilenameFor(goos, name) makes name safe to use as a file name on goos (like runtime.GOOS). On windows, characters
+// like \ : * ? " < > | are replaced with _, trailing dots and spaces are removed and device names (CON, NUL, COM1...) get a _ suffix.
+// On darwin / and : are replaced, elsewhere only /. Control characters are always removed, and names are cut to 255 bytes,
+// keeping their extension. Returns "" if nothing is left of name.
+// gobalt names downloads with the windows rules whatever the system is, so files can be copied anywhere.
+func SanitizeFilenameFor(goos, name string) string {
+	invalid := "/"
+	switch goos {
+	case "windows":
+		invalid = `/\:*?"<>|`
+	case "darwin", "ios":
+		invalid = "/:"
+	}
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(invalid, r) {
+			return '_'
+		}
+		if r < 32 || r == 127 {
+			return -1
+		}
+		return r
+	}, name)
+	if goos == "windows" {
+		name = strings.Trim(name, " .")
+		if isReservedWindowsName(name) {
+			base, ext, _ := strings.Cut(name, ".")
+			name = strings.TrimSuffix(base+"_."+ext, ".")
+		}
+	} else if name == "." || name == ".." {
+		return ""
+	}
+	return truncateFilename(name, maxFilenameBytes)
+}
+
+// isReservedWindowsName reports if name (with or without extension) is a device name on windows.
+func isReservedWindowsName(name string) bool {
+	base, _, _ := strings.Cut(strings.ToUpper(name), ".")
+	switch strings.TrimRight(base, " ") {
+	case "CON", "PRN", "AUX", "NUL",
+		"COM1", "COM2", "COM3", "COM4", "COM5", "COM6", "COM7", "COM8", "COM9",
+		"LPT1", "LPT2", "LPT3", "LPT4", "LPT5", "LPT6", "LPT7", "LPT8", "LPT9":
+		return true
+	}
+	return false
+}
+
+// truncateFilename cuts name to limit bytes without splitting a character, keeping its extension if it's short.
+func truncateFilename(name string, limit int) string {
+	if len(name) <= limit {
+		return name
+	}
+	ext := filepath.Ext(name)
+	if len(ext) > limit/4 {
+		ext = ""
+	}
+	base := name[:len(name)-len(ext)]
+	cut := limit - len(ext)
+	for cut > 0 && !utf8.RuneStart(base[cut]) {
+		cut--
+	}
+	return base[:cut] + ext
+}
+
+// CollisionPolicy tells Download() what to do when the file it saves already exists, see WithCollisionPolicy().
+type CollisionPolicy int
+
+const (
+	CollisionOverwrite CollisionPolicy = iota //Replace the existing file, the default.
+	CollisionSkip                             //Keep the existing file, and return an error wrapping ErrSkipped and ErrFileExists.
+	CollisionNumber                           //Save the file with a number added to its name, like "video (1).mp4".
+	CollisionError                            //Keep the existing file, and return an error wrapping ErrFileExists.
+)
+
+// ErrFileExists is returned when the file to save already exists, with CollisionSkip or CollisionError.
+var ErrFileExists = errors.New("file already exists")
+
+// WithCollisionPolicy sets what Download() (and SaveTo(), DownloadBatch(), DownloadPlaylist()...) does when the file already exists.
+// The file is checked before downloading when its name is known, and again before moving the finished download in place.
+func WithCollisionPolicy(policy CollisionPolicy) DownloadOption {
+	return func(c *downloadConfig) {
+		c.collision = policy
+	}
+}
+
+// resolveCollision returns where to save the file meant for path, following the collision policy.
+func (c *downloadConfig) resolveCollision(path string) (string, error) {
+	if c.collision == CollisionOverwrite {
+		return path, nil
+	}
+	if _, err := os.Lstat(path); errors.Is(err, os.ErrNotExist) {
+		return path, nil
+	}
+	switch c.collision {
+	case CollisionSkip:
+		return "", fmt.Errorf("%w: %w: %v", ErrSkipped, ErrFileExists, path)
+	case CollisionError:
+		return "", fmt.Errorf("%w: %v", ErrFileExists, path)
+	}
+	ext := filepath.Ext(path)
+	base := strings.TrimSuffix(path, ext)
+	for i := 1; ; i++ {
+		numbered := fmt.Sprintf("%v (%v)%v", base, i, ext)
+		if _, err := os.Lstat(numbered); errors.Is(err, os.ErrNotExist) {
+			return numbered, nil
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package gobalt
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSanitizeFilenameFor(t *testing.T) {
+	tests := []struct {
+		goos, name, want string
+	}{
+		{"windows", `a:b*c?.mp4`, "a_b_c_.mp4"},
+		{"windows", "CON.mp4", "CON_.mp4"},
+		{"windows", "nul", "nul_"},
+		{"windows", "video. ", "video"},
+		{"linux", "a:b/c\x00.mp4", "a:b_c.mp4"},
+		{"darwin", "a:b.mp4", "a_b.mp4"},
+		{"linux", "..", ""},
+		{"linux", strings.Repeat("é", 200) + ".mp4", strings.Repeat("é", 125) + ".mp4"},
+	}
+	for _, test := range tests {
+		if got := SanitizeFilenameFor(test.goos, test.name); got != test.want {
+			t.Errorf("SanitizeFilenameFor(%q, %q) = %q, want %q", test.goos, test.name, got, test.want)
+		}
+	}
+}
+
+func TestCollisionPolicy(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("new"))
+	}))
+	defer server.Close()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "video.mp4")
+	if err := os.WriteFile(path, []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	client := New()
+
+	if _, err := client.Download(context.Background(), server.URL, path, WithCollisionPolicy(CollisionSkip)); !errors.Is(err, ErrSkipped) || !errors.Is(err, ErrFileExists) {
+		t.Fatalf("expected ErrSkipped and ErrFileExists, got %v", err)
+	}
+	if _, err := client.Download(context.Background(), server.URL, path, WithCollisionPolicy(CollisionError)); errors.Is(err, ErrSkipped) || !errors.Is(err, ErrFileExists) {
+		t.Fatalf("expected ErrFileExists only, got %v", err)
+	}
+	for _, want := range []string{"video (1).mp4", "video (2).mp4"} {
+		result, err := client.Download(context.Background(), server.URL, path, WithCollisionPolicy(CollisionNumber))
+		if err != nil || result.Path != filepath.Join(dir, want) {
+			t.Fatalf("expected %v, got %+v, %v", want, result, err)
+		}
+	}
+	if data, _ := os.ReadFile(path); string(data) != "old" {
+		t.Fatalf("the existing file should be kept, got %q", data)
+	}
+	if _, err := client.Download(context.Background(), server.URL, path); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "new" {
+		t.Fatalf("the existing file should be overwritten by default, got %q", data)
+	}
+}
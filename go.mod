@@ -1,5 +1,10 @@
 module github.com/lostdusty/gobalt/v2
 
-go 1.22
+go 1.22.0
 
-require github.com/mcuadros/go-version v0.0.0-20190830083331-035f6764e8d2
+require (
+	github.com/mcuadros/go-version v0.0.0-20190830083331-035f6764e8d2
+	golang.org/x/net v0.28.0
+)
+
+require golang.org/x/text v0.17.0 // indirect
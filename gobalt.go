@@ -3,6 +3,7 @@
 package gobalt
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -16,8 +17,6 @@ import (
 	"strconv"
 	"strings"
 	"time"
-
-	"github.com/mcuadros/go-version"
 )
 
 var (
@@ -27,6 +26,7 @@ var (
 	} //This allows you to modify the HTTP Client used in requests. This Client will be re-used.
 	useragent = fmt.Sprintf("gobalt/2.0.9 (+https://github.com/lostdusty/gobalt/v2; go/%v; %v/%v)", runtime.Version(), runtime.GOOS, runtime.GOARCH)
 	ApiKey    = os.Getenv("COBALT_API_KEY") //Some instances need an API key to work, set it here. Default is from environment variable `COBALT_API_KEY`.
+	Language  = "en"                        //Language sent to instances as Accept-Language (some localize error details with it), also used by ResolveError(). See ErrDescriptionsByLanguage.
 )
 
 // ServerInfo is the struct used in the function CobaltServerInfo(). It contains two sub-structs: Cobalt and Git
@@ -42,6 +42,8 @@ type CobaltServerInformation struct {
 	StartTime     string   `json:"startTime"`     //Time when the server started in Unix miliseconds.
 	DurationLimit int      `json:"durationLimit"` //Maximum media lenght you can download in seconds. 10800 seconds = 3 hours.
 	Services      []string `json:"services"`      //List of configured/enabled services on the instance.
+
+	TurnstileSitekey string `json:"turnstileSitekey,omitempty"` //Set when the instance uses Turnstile: requests need a session, see Sessions.
 }
 
 // This is ServerInfo.Git struct, it contains informtions about the git commit (from cobalt) the server is using.
@@ -56,21 +58,24 @@ type CobaltGitInformation struct {
 // This function is called before Run() to check if the cobalt server used is reachable.
 // If you can't contact the main server, try using another instance using GetCobaltinstances().
 func CobaltServerInfo(api string) (*ServerInfo, error) {
-	if !strings.HasPrefix(api, "http") {
-		api = "http://" + api
-	}
+	return CobaltServerInfoContext(context.Background(), api)
+}
+
+// CobaltServerInfoContext(ctx, api) is the same as CobaltServerInfo(), but the request is canceled when ctx is done.
+func CobaltServerInfoContext(ctx context.Context, api string) (*ServerInfo, error) {
+	return defaultClient().fetchServerInfo(ctx, api)
+}
+
+// fetchServerInfo asks the instance at api for its server information, without using any cache.
+func (c *Cobalt) fetchServerInfo(ctx context.Context, api string) (*ServerInfo, error) {
 	//Parse url before testing, sanity check
-	parseApiUrl, err := url.Parse(api)
+	apiUrl, err := normalizeInstanceURL(api)
 	if err != nil {
-		return nil, fmt.Errorf("net/url failed to parse provided url, check it and try again (details: %v, url: %v)", err, api)
-	}
-
-	if parseApiUrl.Scheme == "" {
-		parseApiUrl.Scheme = "https"
+		return nil, fmt.Errorf("failed to parse provided url, check it and try again (details: %v, url: %v)", err, api)
 	}
 
 	//Check if the server is reachable
-	res, err := genericHttpRequest(parseApiUrl.String(), http.MethodGet, nil)
+	res, err := c.doHttpRequest(ctx, apiUrl, http.MethodGet, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -83,8 +88,12 @@ func CobaltServerInfo(api string) (*ServerInfo, error) {
 
 	var serverResponse ServerInfo
 	err = json.Unmarshal(jsonbody, &serverResponse)
-	if err != nil {
-		return nil, err
+	if err != nil || res.StatusCode != http.StatusOK || serverResponse.Cobalt.Version == "" {
+		//Cobalt v7 instances answer at /api/serverInfo instead, see legacy.go.
+		if legacy, legacyErr := c.fetchLegacyServerInfo(ctx, apiUrl); legacyErr == nil {
+			return legacy, nil
+		}
+		return nil, diagnoseResponse(apiUrl, res, jsonbody, err)
 	}
 
 	return &serverResponse, nil
@@ -96,21 +105,24 @@ func CobaltServerInfo(api string) (*ServerInfo, error) {
 
 // Struct Settings contains changable options that you can change before download. An URL MUST be set before calling gobalt.Run(Settings).
 type Settings struct {
-	Url                   string       `json:"url"`                   //Any URL from bilibili.com, instagram, pinterest, reddit, rutube, soundcloud, streamable, tiktok, tumblr, twitch clips, twitter/x, vimeo, vine archive, vk or youtube (as long it's configured on the instance).
-	Mode                  downloadMode `json:"downloadMode"`          //Mode to download the videos, either Auto, Audio or Mute. Default: Auto
-	Proxy                 bool         `json:"alwaysProxy"`           //Tunnel downloaded file thru cobalt, bypassing potential restrictions and protecting your identity and privacy. Default: false
-	AudioBitrate          int          `json:"audioBitrate,string"`   //Audio Bitrate settings. Values: 320Kbps, 256Kbps, 128Kbps, 96Kbps, 64Kbps or 8Kbps. Default: 128
-	AudioFormat           audioCodec   `json:"audioFormat"`           //"Best", .mp3, .opus, .ogg or .wav. If not specified will default to "Best".
-	FilenameStyle         pattern      `json:"filenameStyle"`         //"Classic", "Basic", "Pretty" or "Nerdy". Default is "Basic".
-	DisableMetadata       bool         `json:"disableMetadata"`       //Don't include file metadata. Default: false
-	TikTokH265            bool         `json:"tiktokH265"`            //Allows downloading TikTok videos in 1080p at cost of compatibility. Default: false
-	TikTokFullAudio       bool         `json:"tiktokFullAudio"`       //Enables download of original sound used in a TikTok video. Default: false
-	TwitterConvertGif     bool         `json:"twitterGif"`            //Changes whether twitter gifs should be converted to .gif (Twitter gifs are usually looping .mp4s). Default: true
-	VideoQuality          int          `json:"videoQuality,string"`   //144p to 2160p (4K), if not specified will default to 1080p.
-	YoutubeDubbedAudio    bool         `json:"youtubeDubBrowserLang"` //Downloads the YouTube dubbed audio according to the value set in YoutubeDubbedLanguage (and if present). Default is English (US). Follows the ISO 639-1 standard.
-	YoutubeDubbedLanguage string       `json:"youtubeDubLang"`        //Language code to download the dubbed audio, Default is "en".
-	YoutubeHLS            bool         `json:"youtubeHLS"`            //Enables downloading YouTube videos using HLS streams. (Less prone to fail) Default: true
-	YoutubeVideoFormat    videoCodecs  `json:"youtubeVideoCodec"`     //Which video format to download from YouTube, see videoCodecs type for details.
+	Url                   string          `json:"url"`                          //Any URL from bilibili.com, instagram, pinterest, reddit, rutube, soundcloud, streamable, tiktok, tumblr, twitch clips, twitter/x, vimeo, vine archive, vk or youtube (as long it's configured on the instance).
+	Mode                  downloadMode    `json:"downloadMode"`                 //Mode to download the videos, either Auto, Audio or Mute. Default: Auto
+	Proxy                 bool            `json:"alwaysProxy"`                  //Tunnel downloaded file thru cobalt, bypassing potential restrictions and protecting your identity and privacy. Default: false
+	AudioBitrate          int             `json:"audioBitrate,string"`          //Audio Bitrate settings. Values: 320Kbps, 256Kbps, 128Kbps, 96Kbps, 64Kbps or 8Kbps. Default: 128
+	AudioFormat           audioCodec      `json:"audioFormat"`                  //"Best", .mp3, .opus, .ogg or .wav. If not specified will default to "Best".
+	FilenameStyle         pattern         `json:"filenameStyle"`                //"Classic", "Basic", "Pretty" or "Nerdy". Default is "Basic".
+	DisableMetadata       bool            `json:"disableMetadata"`              //Don't include file metadata. Default: false
+	TikTokH265            bool            `json:"tiktokH265"`                   //Allows downloading TikTok videos in 1080p at cost of compatibility. Default: false
+	TikTokFullAudio       bool            `json:"tiktokFullAudio"`              //Enables download of original sound used in a TikTok video. Default: false
+	TwitterConvertGif     bool            `json:"twitterGif"`                   //Changes whether twitter gifs should be converted to .gif (Twitter gifs are usually looping .mp4s). Default: true
+	VideoQuality          int             `json:"videoQuality,string"`          //144p to 2160p (4K), if not specified will default to 1080p.
+	YoutubeDubbedAudio    bool            `json:"youtubeDubBrowserLang"`        //Downloads the YouTube dubbed audio according to the value set in YoutubeDubbedLanguage (and if present). Default is English (US). Follows the ISO 639-1 standard.
+	YoutubeDubbedLanguage string          `json:"youtubeDubLang"`               //Language code to download the dubbed audio, Default is "en".
+	YoutubeHLS            bool            `json:"youtubeHLS"`                   //Enables downloading YouTube videos using HLS streams. (Less prone to fail) Default: true
+	YoutubeVideoFormat    videoCodecs     `json:"youtubeVideoCodec"`            //Which video format to download from YouTube, see videoCodecs type for details.
+	SubtitleLanguage      string          `json:"subtitleLang,omitempty"`       //Language code of the subtitles to add to the video, if the service has them. Requires cobalt 11.0.0 or newer. Default: none.
+	YoutubeBetterAudio    bool            `json:"youtubeBetterAudio,omitempty"` //Prefers the audio track with the best quality on YouTube, when there are several. Requires cobalt 10.9.0 or newer. Default: false
+	LocalProcessing       localProcessing `json:"localProcessing,omitempty"`    //Lets cobalt answer with the parts of the media instead of processing it, see CobaltResponse.ProcessLocally(). Requires cobalt 11.0.0 or newer. Default: disabled
 }
 
 type downloadMode string
@@ -178,16 +190,84 @@ func CreateDefaultSettings() Settings {
 
 // Cobalt response to your request
 type CobaltResponse struct {
-	Status string      `json:"status"` //4 possible status. Error = Something went wrong, see CobaltResponse.Error.Code | Tunnel or Redirect = Everything is right. | Picker = Multiple media, see CobaltResponse.Picker.
-	Picker *[]struct { //This is an array of items, each containing the media type, url to download and thumbnail. May be <NIL> if the status is not picker.
-		Type  string `json:"type"`  //Type of the media, either photo, video or gif
-		URL   string `json:"url"`   //Url to download.
-		Thumb string `json:"thumb"` //Media preview url, optional.
-	} `json:"picker"`
-	URL      string     `json:"url"`      //Returns the download link. If the status is picker this field will be empty. Direct link to a file or a link to cobalt's live render.
-	Filename string     `json:"filename"` //Various text, mostly used for errors.
-	Error    *Error     `json:"error"`    //Error information, may be <NIL> if theres no error.
-	Server   ServerInfo //Server information, see ServerInfo struct.
+	Status   string        `json:"status"`   //5 possible status. Error = Something went wrong, see CobaltResponse.Error.Code | Tunnel or Redirect = Everything is right. | Picker = Multiple media, see CobaltResponse.Picker. | Local-processing = The parts of the media to process yourself, see CobaltResponse.Parts().
+	Picker   *[]PickerItem `json:"picker"`   //This is an array of items, each containing the media type, url to download and thumbnail. May be <NIL> if the status is not picker.
+	URL      string        `json:"url"`      //Returns the download link. If the status is picker this field will be empty. Direct link to a file or a link to cobalt's live render.
+	Filename string        `json:"filename"` //Various text, mostly used for errors.
+	Error    *Error        `json:"error"`    //Error information, may be <NIL> if theres no error.
+	Server   ServerInfo    //Server information of the instance (from the ServerInfoCache), empty if the health check is disabled. See WithHealthCheck().
+
+	//Fields below are only sent by newer instances, they are empty when the instance doesn't provide them.
+	Service string         `json:"service,omitempty"` //Service cobalt resolved the url to, like "youtube".
+	Type    string         `json:"type,omitempty"`    //How cobalt processes the media, like "merge", "mute", "audio", "gif", "remux" or "proxy".
+	Output  *OutputInfo    `json:"output,omitempty"`  //Information about the output file, may be <NIL>.
+	Audio   *AudioInfo     `json:"audio,omitempty"`   //Information about the audio of the output, may be <NIL>.
+	IsHLS   bool           `json:"isHLS,omitempty"`   //If the media comes from a HLS stream.
+	Tunnel  []string       `json:"tunnel,omitempty"`  //Urls of the parts of the media (video, audio, subtitles), sent with status local-processing.
+	Extra   map[string]any `json:"-"`                 //Any other field of the response that gobalt doesn't know yet.
+
+	Instance  string     `json:"-"` //Url of the instance that answered, which may not be the one of the client, see WithFailover().
+	Selection *Selection `json:"-"` //Why the instance was chosen, set by RunNearest(). <NIL> otherwise.
+	Warnings  []Warning  `json:"-"` //Things that didn't go as asked without failing the request, see Warning.
+
+	request Settings //Settings used to request this media, used by helpers like MakeDir().
+	client  *Cobalt  //Client that made the request, used by helpers like SaveTo().
+}
+
+// OutputInfo describes the file cobalt produces, sent by newer instances.
+type OutputInfo struct {
+	Type      string          `json:"type"`                //Mime type of the file, like "video/mp4".
+	Filename  string          `json:"filename"`            //Name of the file.
+	Metadata  *OutputMetadata `json:"metadata,omitempty"`  //Metadata embedded in the file, may be <NIL>.
+	Subtitles bool            `json:"subtitles,omitempty"` //If subtitles are included in the file.
+}
+
+// SubtitlesURL() returns the url of the subtitles of the media, when cobalt sent them as a separate part (see Tunnel and
+// Settings.SubtitleLanguage), or "" if there are none.
+func (c *CobaltResponse) SubtitlesURL() string {
+	if c.Output == nil || !c.Output.Subtitles || len(c.Tunnel) < 2 {
+		return ""
+	}
+	//Subtitles come after the media parts.
+	return c.Tunnel[len(c.Tunnel)-1]
+}
+
+// OutputMetadata is the metadata cobalt embeds in the output file. Every field is optional.
+type OutputMetadata struct {
+	Title       string  `json:"title,omitempty"`
+	Artist      string  `json:"artist,omitempty"` //Usually who uploaded the media.
+	Album       string  `json:"album,omitempty"`
+	AlbumArtist string  `json:"album_artist,omitempty"`
+	Track       string  `json:"track,omitempty"`
+	Date        string  `json:"date,omitempty"`
+	Copyright   string  `json:"copyright,omitempty"`
+	Duration    float64 `json:"duration,omitempty"` //Duration of the media in seconds.
+}
+
+// AudioInfo describes the audio of the output file, sent by newer instances.
+type AudioInfo struct {
+	Copy    bool   `json:"copy"`            //If the audio is copied without re-encoding.
+	Format  string `json:"format"`          //Audio format, like "mp3".
+	Bitrate string `json:"bitrate"`         //Audio bitrate in kbps.
+	Cover   bool   `json:"cover,omitempty"` //If a cover image is embedded.
+}
+
+// knownResponseFields are the json fields decoded into CobaltResponse, everything else goes to CobaltResponse.Extra.
+var knownResponseFields = []string{"status", "picker", "url", "filename", "error", "service", "type", "output", "audio", "isHLS", "tunnel"}
+
+// unknownResponseFields returns the fields of a cobalt response that CobaltResponse doesn't have, or nil if there are none.
+func unknownResponseFields(body []byte) map[string]any {
+	var fields map[string]any
+	if json.Unmarshal(body, &fields) != nil {
+		return nil
+	}
+	for _, known := range knownResponseFields {
+		delete(fields, known)
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+	return fields
 }
 
 type Error struct {
@@ -232,11 +312,21 @@ var (
 	}
 )
 
-// ResolveError(error) returns a human-readable error message from the error code.
+// ResolveError(error) returns a human-readable error message from the error code, in the current Language if there's a translation for it.
 func ResolveError(code error) string {
-	if val, ok := ErrDescriptions[code.Error()]; ok {
+	return resolveError(Language, code)
+}
+
+func resolveError(language string, code error) string {
+	if val, ok := errDescription(language, code.Error()); ok {
 		return fmt.Sprintf("%v (%v)", val, code.Error())
 	}
+	//Errors like "error.net.invalid_response: details" have more information after the code.
+	if prefix, _, found := strings.Cut(code.Error(), ": "); found {
+		if val, ok := errDescription(language, prefix); ok {
+			return fmt.Sprintf("%v (%v)", val, code.Error())
+		}
+	}
 	return code.Error()
 }
 
@@ -248,52 +338,156 @@ type Context struct {
 // Run(gobalt.Settings) sends the request to the provided cobalt api and returns the server response (gobalt.CobaltResponse) and error, use this to download something AFTER setting your desired configuration.
 // Use ErrDescriptions to get a human-readable error message from the error code.
 func Run(options Settings) (*CobaltResponse, error) {
+	return RunContext(context.Background(), options)
+}
+
+// RunContext(ctx, gobalt.Settings) is the same as Run(), but the request is canceled when ctx is done.
+// The instance and api key of RequestOptions attached to ctx (see ContextWithRequestOptions()) win over CobaltApi and ApiKey.
+func RunContext(ctx context.Context, options Settings) (*CobaltResponse, error) {
+	c, ctx, cancel := defaultClient().forRequest(ctx)
+	defer cancel()
+	return c.run(ctx, c.api, options)
+}
+
+// RunOn(ctx, instance, gobalt.Settings) works like Run(), but sends the request to the cobalt api at instance instead of CobaltApi.
+// Use it to target different instances for different requests (e.g. YouTube to your own instance, everything else to a public one), without changing CobaltApi.
+// RequestOptions attached to ctx apply like in RunContext(), except their Instance: instance wins.
+func RunOn(ctx context.Context, instance string, options Settings) (*CobaltResponse, error) {
+	c, ctx, cancel := defaultClient().forRequest(ctx)
+	defer cancel()
+	return c.run(ctx, instance, options)
+}
+
+// run sends the request to the cobalt api at instance, using the api key and HTTP client of c.
+func (c *Cobalt) run(ctx context.Context, instance string, options Settings) (*CobaltResponse, error) {
 	//Check if an url is set.
 	if options.Url == "" {
 		return nil, errors.New("no url was provided to download")
 	}
-
-	//Do a basic check to see if the server is online and handling requests
-	//Also add to CobaltResponse the server information.
-	_, err := CobaltServerInfo(CobaltApi)
+	mediaUrl, err := NormalizeMediaURL(options.Url)
 	if err != nil {
-		return nil, fmt.Errorf("error.net.generic: %v", err)
+		return nil, newCobaltError(c.language, "error.api.link.invalid", "", err)
+	}
+	options.Url = mediaUrl
+	options = c.applyServiceSettings(options)
+	instance, err = normalizeInstanceURL(instance)
+	if err != nil {
+		return nil, fmt.Errorf("invalid instance url: %w", err)
+	}
+
+	//Do a basic check to see if the server is online and handling requests, see DefaultServerInfoCache.
+	//Also add to CobaltResponse the server information. Without the check (see WithHealthCheck()), the version is unknown.
+	info := &ServerInfo{}
+	if !c.noHealthCheck {
+		info, err = c.serverInfoCache.get(ctx, c, instance)
+		c.metrics.InstanceHealth(instance, err == nil)
+		if invalid := (*InvalidResponseError)(nil); errors.As(err, &invalid) {
+			return nil, err
+		}
+		if err != nil {
+			return nil, newCobaltError(c.language, "error.net.generic", instance, err)
+		}
+	}
+	if service := DetectService(options.Url); c.serviceCheck && !supportsService(info, service) {
+		cobaltErr := newCobaltError(c.language, "error.api.service.unsupported", instance, nil)
+		cobaltErr.Context.Service = service
+		return nil, cobaltErr
 	}
 
-	jsonBody, err := json.Marshal(options)
+	if isLegacyVersion(info.Cobalt.Version) {
+		return c.runLegacy(ctx, instance, info, options)
+	}
+
+	//Leave out the fields the instance doesn't know, see requestFields.
+	jsonBody, dropped, err := shapeRequest(options, instance, info.Cobalt.Version)
+	if unsupported := (*UnsupportedParameterError)(nil); errors.As(err, &unsupported) {
+		return nil, err
+	}
 	if err != nil {
-		return nil, fmt.Errorf("error.net.invalid_response")
+		return nil, newCobaltError(c.language, "error.net.invalid_response", instance, nil)
+	}
+	defer jsonBody.release()
+	var warnings []Warning
+	if len(dropped) > 0 {
+		warning := Warning{
+			Code:     WarningFieldsDropped,
+			Message:  fmt.Sprintf("left out request fields cobalt %v doesn't support", info.Cobalt.Version),
+			Instance: instance,
+			Fields:   dropped,
+		}
+		c.warn(warning)
+		warnings = append(warnings, warning)
 	}
 
-	req, err := http.NewRequest(http.MethodPost, CobaltApi, strings.NewReader(string(jsonBody)))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, instance, jsonBody.Reader())
+	if err != nil {
+		return nil, err
+	}
+	req.ContentLength = jsonBody.Len()
+	req.GetBody = func() (io.ReadCloser, error) {
+		return jsonBody.Reader(), nil
+	}
 	req.Header.Add("User-Agent", useragent)
 	req.Header.Add("Accept", "application/json")
 	req.Header.Add("Content-Type", "application/json")
-	req.Header.Add("Authorization", "Api-Key "+ApiKey)
+	authorization, err := c.authorization(ctx, instance, info)
 	if err != nil {
 		return nil, err
 	}
+	req.Header.Add("Authorization", authorization)
+	if c.language != "" {
+		req.Header.Add("Accept-Language", c.language)
+	}
 
-	res, err := Client.Do(req)
+	if c.rateLimiter != nil {
+		if err := c.rateLimiter.Wait(ctx, instance); err != nil {
+			return nil, err
+		}
+	}
+	c.logger.Log(ctx, c.logLevels.Request, "sending cobalt request", "url", options.Url, "instance", instance)
+	start := c.clock.Now()
+	res, err := c.do(req)
 	if err != nil {
-		return nil, fmt.Errorf("error.net.failed")
+		return nil, newCobaltError(c.language, "error.net.failed", instance, err)
 	}
 	defer res.Body.Close()
 
 	jsonbody, err := io.ReadAll(res.Body)
 	if err != nil {
-		return nil, fmt.Errorf("error.net.invalid_response")
+		return nil, diagnoseResponse(instance, res, jsonbody, err)
 	}
 
 	var media CobaltResponse
 	err = json.Unmarshal(jsonbody, &media)
+	if err == nil {
+		err = media.validate()
+	}
 	if err != nil {
-		return nil, fmt.Errorf("error.net.invalid_response")
+		return nil, diagnoseResponse(instance, res, jsonbody, err)
+	}
+	media.Extra = unknownResponseFields(jsonbody)
+	answer := []any{"url", options.Url, "instance", instance, "status", media.Status, "service", media.Service, "duration", c.clock.Now().Sub(start)}
+	if media.Error != nil {
+		answer = append(answer, "code", media.Error.Code)
 	}
+	c.logger.Log(ctx, c.logLevels.Request, "cobalt answered", answer...)
 
 	if media.Status == "error" {
-		return nil, fmt.Errorf("%v", media.Error.Code)
+		cobaltErr := newCobaltError(c.language, media.Error.Code, instance, nil)
+		cobaltErr.Context, cobaltErr.RequestID = media.Error.Context, requestIDFrom(res)
+		if wait := rateLimitWait(cobaltErr); wait > 0 && c.rateLimiter != nil {
+			c.rateLimiter.Tighten(instance, wait)
+		}
+		if c.retryWithSession(ctx, instance, cobaltErr) {
+			return c.run(context.WithValue(ctx, sessionRetryKey{}, true), instance, options)
+		}
+		return nil, cobaltErr
 	}
+	media.request = options
+	media.client = c
+	media.bindPicker()
+	media.Warnings = warnings
+	media.Server, media.Instance = *info, instance
 
 	return &media, nil
 }
@@ -302,8 +496,11 @@ func Run(options Settings) (*CobaltResponse, error) {
 
 //Cobalt response end
 
-// CobaltInstance is a struct that contains information about a cobalt instance.
-type CobaltInstance []struct {
+// CobaltInstance is a list of cobalt instances, as sent by an instance tracker.
+type CobaltInstance []InstanceInfo
+
+// InstanceInfo contains information about a cobalt instance.
+type InstanceInfo struct {
 	API      string       `json:"api"`
 	Branch   string       `json:"branch"`
 	Commit   string       `json:"commit"`
@@ -317,6 +514,7 @@ type CobaltInstance []struct {
 	//Services EnabledServices `json:"services"`
 	Trust   int    `json:"trust"`
 	Version string `json:"version"`
+	Country string `json:"country,omitempty"` //ISO 3166-1 alpha-2 code of where the instance is hosted, if the tracker (or your static list) tells.
 }
 type OnlineStatus struct {
 	API      bool `json:"api"`
@@ -349,35 +547,20 @@ type EnabledServices struct {
 	YoutubeShorts string `json:"youtube_shorts"`
 }
 
-// GetCobaltInstances makes a request to instances.cobalt.best and returns a list of all online cobalt instances.
+// GetCobaltInstances makes a request to the InstanceTrackers (instances.cobalt.best by default) and returns a list of all online cobalt instances.
 func GetCobaltInstances() (CobaltInstance, error) {
-	res, err := genericHttpRequest("https://instances.cobalt.best/api/instances.json", http.MethodGet, nil)
-	if err != nil {
-		return nil, err
-	}
-	defer res.Body.Close()
-
-	jsonbody, err := io.ReadAll(res.Body)
-	if err != nil {
-		return nil, err
-	}
-
-	var listOfCobaltInstances CobaltInstance
-	err = json.Unmarshal(jsonbody, &listOfCobaltInstances)
-	if err != nil {
-		return nil, err
-	}
-
-	parseModernInstances := make(CobaltInstance, 0)
-	for _, v := range listOfCobaltInstances {
-		if version.Compare(v.Version, "10.0.0", ">=") {
-			parseModernInstances = append(parseModernInstances, v)
-		}
+	return GetCobaltInstancesContext(context.Background())
+}
 
-	}
+// GetCobaltInstancesContext(ctx) is the same as GetCobaltInstances(), but the requests are canceled when ctx is done.
+func GetCobaltInstancesContext(ctx context.Context) (CobaltInstance, error) {
+	return defaultClient().Instances(ctx)
+}
 
-	return parseModernInstances, nil
-	//return listOfCobaltInstances, nil
+// Instances(ctx) returns the online cobalt instances listed by the InstanceTrackers, using the HTTP client of c.
+// See GetInstances() to use other sources.
+func (c *Cobalt) Instances(ctx context.Context) (CobaltInstance, error) {
+	return c.GetInstances(ctx, InstanceSources{})
 }
 
 // Deprecated: Cobalt response returns the file name and size.
@@ -390,10 +573,17 @@ type MediaInfo struct {
 // ProcessMedia(url) attempts to fetch the file size, mime type and name.
 // Deprecated: Cobalt response returns the file name and size.
 func ProcessMedia(url string) (*MediaInfo, error) {
-	req, err := genericHttpRequest(url, http.MethodHead, nil)
+	return ProcessMediaContext(context.Background(), url)
+}
+
+// ProcessMediaContext(ctx, url) is the same as ProcessMedia(), but the request is canceled when ctx is done.
+// Deprecated: Cobalt response returns the file name and size.
+func ProcessMediaContext(ctx context.Context, url string) (*MediaInfo, error) {
+	req, err := defaultClient().genericHttpRequest(ctx, url, http.MethodHead, nil)
 	if err != nil {
 		return nil, err
 	}
+	req.Body.Close()
 	_, parsefilename, err := mime.ParseMediaType(req.Header.Get("Content-Disposition"))
 	filename := parsefilename["filename"]
 	if err != nil {
@@ -415,21 +605,67 @@ func ProcessMedia(url string) (*MediaInfo, error) {
 	}, nil
 }
 
+// GetYoutubePlaylistTitle(playlist) returns the title of an Youtube playlist, e.g. to use as album name (see BatchOptions.Album).
+func GetYoutubePlaylistTitle(playlist string) (string, error) {
+	return GetYoutubePlaylistTitleContext(context.Background(), playlist)
+}
+
+// GetYoutubePlaylistTitleContext(ctx, playlist) is the same as GetYoutubePlaylistTitle(), but the request is canceled when ctx is done.
+func GetYoutubePlaylistTitleContext(ctx context.Context, playlist string) (string, error) {
+	return defaultClient().YoutubePlaylistTitle(ctx, playlist)
+}
+
+// YoutubePlaylistTitle(ctx, playlist) returns the title of an Youtube playlist using the HTTP client of c, see GetYoutubePlaylistTitle().
+func (c *Cobalt) YoutubePlaylistTitle(ctx context.Context, playlist string) (string, error) {
+	playlistUrl, err := NormalizeMediaURL(playlist)
+	if err != nil {
+		return "", err
+	}
+
+	res, err := c.genericHttpRequest(ctx, "https://www.youtube.com/oembed?format=json&url="+url.QueryEscape(playlistUrl), http.MethodGet, nil)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+
+	var oembed struct {
+		Title string `json:"title"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&oembed); err != nil {
+		return "", err
+	}
+	if oembed.Title == "" {
+		return "", errors.New("playlist has no title")
+	}
+	return oembed.Title, nil
+}
+
 // This slice will contain urls of Youtube videos
 type Playlist []string
 
 // Function GetYoutubePlaylist(string) gets an Youtube playlist has parameter, and returns a slice []Playlist with the urls of the playlist.
 func GetYoutubePlaylist(playlist string) (Playlist, error) {
+	return GetYoutubePlaylistContext(context.Background(), playlist)
+}
+
+// GetYoutubePlaylistContext(ctx, playlist) is the same as GetYoutubePlaylist(), but the request is canceled when ctx is done.
+func GetYoutubePlaylistContext(ctx context.Context, playlist string) (Playlist, error) {
+	return defaultClient().YoutubePlaylist(ctx, playlist)
+}
+
+// YoutubePlaylist(ctx, playlist) returns the urls of the videos of an Youtube playlist using the HTTP client of c, see GetYoutubePlaylist().
+func (c *Cobalt) YoutubePlaylist(ctx context.Context, playlist string) (Playlist, error) {
 	//Parse param url
-	newYoutubePlaylistUrl, err := url.Parse(playlist)
+	newYoutubePlaylistUrl, err := NormalizeMediaURL(playlist)
 	if err != nil {
 		return nil, err
 	}
 
-	getUrls, err := genericHttpRequest(fmt.Sprintf("https://playlist.kwiatekmiki.pl/api/getvideos?url=%v", newYoutubePlaylistUrl.String()), http.MethodGet, nil)
+	getUrls, err := c.genericHttpRequest(ctx, "https://playlist.kwiatekmiki.pl/api/getvideos?url="+url.QueryEscape(newYoutubePlaylistUrl), http.MethodGet, nil)
 	if err != nil {
 		return nil, err
 	}
+	defer getUrls.Body.Close()
 	if getUrls.StatusCode != 200 {
 		return nil, fmt.Errorf("failed to get playlists: %v", getUrls.Status)
 	}
@@ -449,22 +685,30 @@ func GetYoutubePlaylist(playlist string) (Playlist, error) {
 }
 
 // Function to do generic, less complex http requests, to avoid code repetitions. Internal use of the library only.
-func genericHttpRequest(url, method string, body io.Reader) (*http.Response, error) {
-	request, err := http.NewRequest(method, url, body)
-	request.Header.Add("User-Agent", useragent)
-
-	if err != nil {
-		return nil, err
-	}
-
-	response, err := Client.Do(request)
+func (c *Cobalt) genericHttpRequest(ctx context.Context, url, method string, body io.Reader) (*http.Response, error) {
+	response, err := c.doHttpRequest(ctx, url, method, body)
 	if err != nil {
 		return nil, err
 	}
 
 	if response.StatusCode != 200 {
+		response.Body.Close()
 		return nil, fmt.Errorf("request failed with %v", response.Status)
 	}
 
 	return response, nil
 }
+
+// Same as genericHttpRequest, but doesn't fail on non-200 responses, for callers that want to look at the body of errors.
+func (c *Cobalt) doHttpRequest(ctx context.Context, url, method string, body io.Reader) (*http.Response, error) {
+	request, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Add("User-Agent", useragent)
+	if c.language != "" {
+		request.Header.Add("Accept-Language", c.language)
+	}
+
+	return c.do(request)
+}
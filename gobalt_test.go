@@ -1,11 +1,103 @@
 package gobalt
 
 import (
+	"context"
+	"fmt"
 	"math/rand/v2"
+	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
 )
 
+// fakeCobalt starts a local server that answers like a cobalt instance: server info on GET, and response on POST.
+func fakeCobalt(t *testing.T, response string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.Method == http.MethodGet {
+			w.Write([]byte(`{"cobalt":{"version":"10.5.0","url":"http://` + r.Host + `/","services":["youtube"]},"git":{"branch":"main"}}`))
+			return
+		}
+		w.Write([]byte(response))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestRunOn(t *testing.T) {
+	server := fakeCobalt(t, `{"status":"tunnel","url":"https://example.com/tunnel","filename":"video.mp4"}`)
+	previous := CobaltApi
+	CobaltApi = "http://127.0.0.1:1" //Nothing should be sent here.
+	defer func() { CobaltApi = previous }()
+
+	options := CreateDefaultSettings()
+	options.Url = "https://youtu.be/dQw4w9WgXcQ"
+	media, err := RunOn(context.Background(), server.URL, options)
+	if err != nil {
+		t.Fatalf("RunOn failed: %v", err)
+	}
+	if media.Status != "tunnel" || media.Filename != "video.mp4" {
+		t.Fatalf("got unexpected response %+v", media)
+	}
+
+	failing := fakeCobalt(t, `{"status":"error","error":{"code":"error.api.link.invalid"}}`)
+	if _, err := RunOn(context.Background(), failing.URL, options); err == nil || err.Error() != "error.api.link.invalid" {
+		t.Fatalf("expected error.api.link.invalid, got %v", err)
+	}
+}
+
+func TestNewerResponseFields(t *testing.T) {
+	server := fakeCobalt(t, `{
+		"status": "tunnel",
+		"url": "https://example.com/tunnel",
+		"filename": "song.mp3",
+		"service": "youtube",
+		"type": "audio",
+		"isHLS": true,
+		"output": {
+			"type": "audio/mpeg",
+			"filename": "song.mp3",
+			"metadata": {"title": "Song", "artist": "Singer", "album_artist": "Band", "duration": 212.5},
+			"subtitles": false
+		},
+		"audio": {"copy": false, "format": "mp3", "bitrate": "320", "cover": true},
+		"expiresAt": 1700000000,
+		"queue": {"position": 2}
+	}`)
+	options := CreateDefaultSettings()
+	options.Url = "https://youtu.be/dQw4w9WgXcQ"
+	media, err := RunOn(context.Background(), server.URL, options)
+	if err != nil {
+		t.Fatalf("RunOn failed: %v", err)
+	}
+	if media.Service != "youtube" || media.Type != "audio" || !media.IsHLS {
+		t.Fatalf("unexpected service, type or isHLS in %+v", media)
+	}
+	output := media.Output
+	if output == nil || output.Type != "audio/mpeg" || output.Filename != "song.mp3" || output.Subtitles || output.Metadata == nil {
+		t.Fatalf("unexpected output %+v", output)
+	}
+	if metadata := *output.Metadata; metadata != (OutputMetadata{Title: "Song", Artist: "Singer", AlbumArtist: "Band", Duration: 212.5}) {
+		t.Fatalf("unexpected metadata %+v", metadata)
+	}
+	if media.Audio == nil || *media.Audio != (AudioInfo{Format: "mp3", Bitrate: "320", Cover: true}) {
+		t.Fatalf("unexpected audio %+v", media.Audio)
+	}
+	if len(media.Extra) != 2 || media.Extra["expiresAt"] != float64(1700000000) || fmt.Sprint(media.Extra["queue"]) != "map[position:2]" {
+		t.Fatalf("only the unknown fields should be in Extra, got %v", media.Extra)
+	}
+
+	known := fakeCobalt(t, `{"status":"redirect","url":"https://example.com/file.mp4","filename":"file.mp4"}`)
+	media, err = RunOn(context.Background(), known.URL, options)
+	if err != nil {
+		t.Fatalf("RunOn failed: %v", err)
+	}
+	if media.Extra != nil || media.Output != nil || media.Audio != nil {
+		t.Fatalf("older responses should have no Extra, Output or Audio, got %+v", media)
+	}
+}
+
 func TestCobaltDownload(t *testing.T) {
 	dlTest := CreateDefaultSettings()
 	dlTest.Url = "https://www.youtube.com/watch?v=ud4cyuj2Z3A"
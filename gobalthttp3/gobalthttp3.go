@@ -0,0 +1,20 @@
+// Package gobalthttp3 adds HTTP/3 (QUIC) support to gobalt. It's its own module, so programs that don't need HTTP/3
+// don't depend on quic-go. Import it, then call gobalt.EnableHTTP3():
+//
+//	import _ "github.com/lostdusty/gobalt/v2/gobalthttp3"
+//
+//	if err := gobalt.EnableHTTP3(); err != nil { ... }
+package gobalthttp3
+
+import (
+	"net/http"
+
+	"github.com/lostdusty/gobalt/v2"
+	"github.com/quic-go/quic-go/http3"
+)
+
+func init() {
+	gobalt.RegisterHTTP3(func() http.RoundTripper {
+		return &http3.Transport{}
+	})
+}
@@ -0,0 +1,20 @@
+package gobalthttp3
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/lostdusty/gobalt/v2"
+)
+
+func TestEnableHTTP3(t *testing.T) {
+	previous := gobalt.Client.Transport
+	defer func() { gobalt.Client.Transport = previous }()
+
+	if err := gobalt.EnableHTTP3(); err != nil {
+		t.Fatalf("EnableHTTP3 failed with the package imported: %v", err)
+	}
+	if gobalt.Client.Transport == previous || gobalt.Client.Transport == http.DefaultTransport {
+		t.Fatal("Client should use the HTTP/3 transport")
+	}
+}
@@ -0,0 +1,108 @@
+// Package gobaltprom exports the metrics of gobalt clients to Prometheus.
+//
+//	metrics, err := gobaltprom.New(prometheus.DefaultRegisterer)
+//	client := gobalt.New(gobalt.WithMetrics(metrics))
+package gobaltprom
+
+import (
+	"time"
+
+	"github.com/lostdusty/gobalt/v2"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Collector is a gobalt.Metrics recording to Prometheus collectors. Create one with New().
+type Collector struct {
+	requests   *prometheus.CounterVec
+	duration   *prometheus.HistogramVec
+	bytes      *prometheus.CounterVec
+	activeJobs prometheus.Gauge
+	downloads  *prometheus.GaugeVec
+	jobs       *prometheus.CounterVec
+	health     *prometheus.GaugeVec
+}
+
+var _ gobalt.Metrics = (*Collector)(nil)
+
+// New creates a Collector and registers its metrics on registry:
+//
+//   - gobalt_requests_total{instance, code}: requests sent to cobalt, by result ("ok" or the error code);
+//   - gobalt_request_duration_seconds{instance}: how long cobalt took to answer;
+//   - gobalt_download_bytes_total{host}: bytes downloaded, by tunnel host;
+//   - gobalt_active_downloads{host}: file transfers in progress;
+//   - gobalt_active_jobs: jobs running (see downloader.Manager);
+//   - gobalt_jobs_total{result}: jobs finished, by result ("ok" or the error code);
+//   - gobalt_instance_healthy{instance}: 1 if the last server information check of the instance worked, 0 otherwise.
+func New(registry prometheus.Registerer) (*Collector, error) {
+	c := &Collector{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "gobalt_requests_total",
+			Help: "Requests sent to cobalt instances, by result.",
+		}, []string{"instance", "code"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "gobalt_request_duration_seconds",
+			Help:    "Time taken by cobalt instances to answer.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"instance"}),
+		bytes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "gobalt_download_bytes_total",
+			Help: "Bytes downloaded, by host.",
+		}, []string{"host"}),
+		downloads: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "gobalt_active_downloads",
+			Help: "File transfers in progress, by host.",
+		}, []string{"host"}),
+		activeJobs: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "gobalt_active_jobs",
+			Help: "Download manager jobs running.",
+		}),
+		jobs: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "gobalt_jobs_total",
+			Help: "Download manager jobs finished, by result.",
+		}, []string{"result"}),
+		health: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "gobalt_instance_healthy",
+			Help: "1 if the last health check of the instance worked, 0 otherwise.",
+		}, []string{"instance"}),
+	}
+	for _, collector := range []prometheus.Collector{c.requests, c.duration, c.bytes, c.downloads, c.activeJobs, c.jobs, c.health} {
+		if err := registry.Register(collector); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+func (c *Collector) RequestFinished(instance, code string, duration time.Duration) {
+	c.requests.WithLabelValues(instance, code).Inc()
+	c.duration.WithLabelValues(instance).Observe(duration.Seconds())
+}
+
+func (c *Collector) BytesDownloaded(host string, n int64) {
+	c.bytes.WithLabelValues(host).Add(float64(n))
+}
+
+func (c *Collector) JobStarted() {
+	c.activeJobs.Inc()
+}
+
+func (c *Collector) JobFinished(err error) {
+	c.activeJobs.Dec()
+	c.jobs.WithLabelValues(gobalt.MetricCode(err)).Inc()
+}
+
+func (c *Collector) InstanceHealth(instance string, healthy bool) {
+	value := 0.0
+	if healthy {
+		value = 1
+	}
+	c.health.WithLabelValues(instance).Set(value)
+}
+
+func (c *Collector) DownloadStarted(host string) {
+	c.downloads.WithLabelValues(host).Inc()
+}
+
+func (c *Collector) DownloadFinished(host string) {
+	c.downloads.WithLabelValues(host).Dec()
+}
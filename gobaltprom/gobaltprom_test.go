@@ -0,0 +1,49 @@
+package gobaltprom
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/lostdusty/gobalt/v2"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestCollector(t *testing.T) {
+	var api *httptest.Server
+	api = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/tunnel":
+			w.Write([]byte("12345"))
+		case r.Method == http.MethodGet:
+			w.Write([]byte(`{"cobalt":{"version":"10.5.0","services":["youtube"]}}`))
+		default:
+			w.Write([]byte(`{"status":"tunnel","url":"` + api.URL + `/tunnel","filename":"video.mp4"}`))
+		}
+	}))
+	defer api.Close()
+
+	registry := prometheus.NewRegistry()
+	metrics, err := New(registry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := gobalt.New(gobalt.WithInstance(api.URL), gobalt.WithMetrics(metrics))
+	options := gobalt.CreateDefaultSettings()
+	options.Url = "https://youtu.be/dQw4w9WgXcQ"
+	if _, err := client.SaveMedia(context.Background(), options, t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := testutil.ToFloat64(metrics.requests.WithLabelValues(api.URL, "ok")); got != 1 {
+		t.Fatalf("expected 1 successful request, got %v", got)
+	}
+	if got := testutil.ToFloat64(metrics.bytes.WithLabelValues(api.Listener.Addr().String())); got != 5 {
+		t.Fatalf("expected 5 downloaded bytes, got %v", got)
+	}
+	if got := testutil.ToFloat64(metrics.health.WithLabelValues(api.URL)); got != 1 {
+		t.Fatalf("expected the instance to be healthy, got %v", got)
+	}
+}
@@ -0,0 +1,85 @@
+package gobalttest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/lostdusty/gobalt/v2"
+)
+
+// Tunnel returns a fixture answering link with a tunnel to a file named filename, holding content.
+func Tunnel(link, filename string, content []byte) Fixture {
+	media := "https://cobalt.invalid/tunnel/" + filename
+	response, _ := json.Marshal(map[string]string{"status": "tunnel", "url": media, "filename": filename})
+	return Fixture{URL: link, StatusCode: http.StatusOK, Response: response, Media: map[string][]byte{media: content}}
+}
+
+// PickerItem is an item of a canned picker, see Picker().
+type PickerItem struct {
+	Type      string //Type of the item: photo, video or gif.
+	Content   []byte //Content of the file.
+	Thumbnail []byte //Content of the thumbnail, optional.
+}
+
+// Picker returns a fixture answering link with a picker of items.
+func Picker(link string, items ...PickerItem) Fixture {
+	type item struct {
+		Type  string `json:"type"`
+		URL   string `json:"url"`
+		Thumb string `json:"thumb,omitempty"`
+	}
+	picker := make([]item, len(items))
+	media := make(map[string][]byte)
+	for i, it := range items {
+		picker[i] = item{Type: it.Type, URL: fmt.Sprintf("https://cobalt.invalid/picker/%02d", i)}
+		media[picker[i].URL] = it.Content
+		if it.Thumbnail != nil {
+			picker[i].Thumb = picker[i].URL + "/thumb"
+			media[picker[i].Thumb] = it.Thumbnail
+		}
+	}
+	response, _ := json.Marshal(map[string]any{"status": "picker", "picker": picker})
+	return Fixture{URL: link, StatusCode: http.StatusOK, Response: response, Media: media}
+}
+
+// Error returns a fixture answering link with the cobalt error code, e.g. "error.api.content.video.unavailable".
+func Error(link, code string) Fixture {
+	response, _ := json.Marshal(map[string]any{"status": "error", "error": map[string]string{"code": code}})
+	return Fixture{URL: link, StatusCode: http.StatusBadRequest, Response: response}
+}
+
+// Client is a mock gobalt.CobaltClient for code depending on the interface rather than on *gobalt.Cobalt.
+// Each method calls the matching function field, or fails when it is nil.
+type Client struct {
+	RunFunc        func(ctx context.Context, options gobalt.Settings) (*gobalt.CobaltResponse, error)
+	ServerInfoFunc func(ctx context.Context) (*gobalt.ServerInfo, error)
+	DownloadFunc   func(ctx context.Context, url, path string, opts ...gobalt.DownloadOption) (*gobalt.DownloadResult, error)
+}
+
+var _ gobalt.CobaltClient = (*Client)(nil)
+
+// Run calls c.RunFunc.
+func (c *Client) Run(ctx context.Context, options gobalt.Settings) (*gobalt.CobaltResponse, error) {
+	if c.RunFunc == nil {
+		return nil, fmt.Errorf("gobalttest: Run is not mocked")
+	}
+	return c.RunFunc(ctx, options)
+}
+
+// ServerInfo calls c.ServerInfoFunc.
+func (c *Client) ServerInfo(ctx context.Context) (*gobalt.ServerInfo, error) {
+	if c.ServerInfoFunc == nil {
+		return nil, fmt.Errorf("gobalttest: ServerInfo is not mocked")
+	}
+	return c.ServerInfoFunc(ctx)
+}
+
+// Download calls c.DownloadFunc.
+func (c *Client) Download(ctx context.Context, url, path string, opts ...gobalt.DownloadOption) (*gobalt.DownloadResult, error) {
+	if c.DownloadFunc == nil {
+		return nil, fmt.Errorf("gobalttest: Download is not mocked")
+	}
+	return c.DownloadFunc(ctx, url, path, opts...)
+}
@@ -0,0 +1,70 @@
+package gobalttest
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/lostdusty/gobalt/v2"
+)
+
+func TestCannedFixtures(t *testing.T) {
+	server := NewServer(
+		Tunnel("https://youtu.be/a", "a.mp4", []byte("video")),
+		Picker("https://x.com/b", PickerItem{Type: "photo", Content: []byte("photo"), Thumbnail: []byte("thumb")}),
+		Error("https://youtu.be/c", "error.api.content.video.unavailable"),
+	)
+	defer server.Close()
+	client := gobalt.New(gobalt.WithInstance(server.URL))
+	options := gobalt.CreateDefaultSettings()
+
+	options.Url = "https://youtu.be/a"
+	result, err := client.Run(context.Background(), options)
+	if err != nil || result.Filename != "a.mp4" {
+		t.Fatalf("unexpected tunnel %+v, %v", result, err)
+	}
+	if data := get(t, result.URL); data != "video" {
+		t.Fatalf("the tunnel should serve the content, got %q", data)
+	}
+
+	options.Url = "https://x.com/b"
+	result, err = client.Run(context.Background(), options)
+	if err != nil || result.Picker == nil || len(*result.Picker) != 1 {
+		t.Fatalf("unexpected picker %+v, %v", result, err)
+	}
+	if item := (*result.Picker)[0]; get(t, item.URL) != "photo" || get(t, item.Thumb) != "thumb" {
+		t.Fatalf("the picker should serve its items and thumbnails")
+	}
+
+	options.Url = "https://youtu.be/c"
+	if _, err = client.Run(context.Background(), options); !errors.Is(err, gobalt.ErrContentUnavailable) {
+		t.Fatalf("expected ErrContentUnavailable, got %v", err)
+	}
+}
+
+func TestClient(t *testing.T) {
+	var client gobalt.CobaltClient = &Client{
+		RunFunc: func(ctx context.Context, options gobalt.Settings) (*gobalt.CobaltResponse, error) {
+			return &gobalt.CobaltResponse{Status: "tunnel", URL: options.Url}, nil
+		},
+	}
+	if result, err := client.Run(context.Background(), gobalt.Settings{Url: "https://youtu.be/a"}); err != nil || result.URL != "https://youtu.be/a" {
+		t.Fatalf("unexpected result %+v, %v", result, err)
+	}
+	if _, err := client.ServerInfo(context.Background()); err == nil {
+		t.Fatal("methods not mocked should fail")
+	}
+}
+
+func get(t *testing.T, link string) string {
+	t.Helper()
+	res, err := http.Get(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	data, _ := io.ReadAll(res.Body)
+	return string(data)
+}
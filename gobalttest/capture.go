@@ -0,0 +1,172 @@
+package gobalttest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/lostdusty/gobalt/v2"
+)
+
+// CaptureOptions change how Capture() talks to the real instance.
+type CaptureOptions struct {
+	APIKey       string       //Api key of the instance, if it needs one. It's never saved in the fixture.
+	Client       *http.Client //HTTP client used for every request. Default: http.DefaultClient.
+	MaxMediaSize int64        //How many bytes of each media file are kept, to keep fixtures small. 0 means 1 MiB, -1 means no media at all.
+	Name         string       //Name of the fixture. Default: made from the media link.
+}
+
+// ErrNothingCaptured is returned by Capture() when the instance never answered the media request.
+var ErrNothingCaptured = errors.New("the instance did not answer the media request")
+
+// Capture(ctx, instance, options, opts) sends options to a real cobalt instance and turns its answer into a Fixture: the response
+// as sent by the instance (errors included, they make realistic fixtures too), its server information, and the beginning
+// of every file it links to.
+func Capture(ctx context.Context, instance string, options gobalt.Settings, opts CaptureOptions) (*Fixture, error) {
+	httpClient := opts.Client
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	tap := &captureTransport{next: httpClient.Transport}
+	if tap.next == nil {
+		tap.next = http.DefaultTransport
+	}
+	client := gobalt.New(
+		gobalt.WithInstance(instance),
+		gobalt.WithAPIKey(opts.APIKey),
+		gobalt.WithHTTPClient(&http.Client{Transport: tap, Timeout: httpClient.Timeout}),
+	)
+	_, runErr := client.Run(ctx, options)
+
+	tap.mu.Lock()
+	defer tap.mu.Unlock()
+	if tap.response == nil {
+		if runErr == nil {
+			runErr = ErrNothingCaptured
+		}
+		return nil, fmt.Errorf("capture of %v failed: %w", options.Url, runErr)
+	}
+	fixture := &Fixture{
+		Name:       opts.Name,
+		URL:        options.Url,
+		ServerInfo: tap.serverInfo,
+		StatusCode: tap.status,
+		Response:   tap.response,
+	}
+	if fixture.Name == "" {
+		fixture.Name = fixtureName(options.Url)
+	}
+	if opts.MaxMediaSize < 0 {
+		return fixture, nil
+	}
+	limit := opts.MaxMediaSize
+	if limit == 0 {
+		limit = 1 << 20
+	}
+
+	var body any
+	if err := json.Unmarshal(tap.response, &body); err != nil {
+		return nil, err
+	}
+	for _, link := range linksIn(body, "") {
+		data, err := fetchMedia(ctx, httpClient, link, limit)
+		if err != nil {
+			return nil, fmt.Errorf("failed to capture %v: %w", link, err)
+		}
+		if fixture.Media == nil {
+			fixture.Media = make(map[string][]byte)
+		}
+		fixture.Media[link] = data
+	}
+	return fixture, nil
+}
+
+// captureTransport keeps the raw answers of the instance.
+type captureTransport struct {
+	next http.RoundTripper
+
+	mu         sync.Mutex
+	serverInfo json.RawMessage
+	status     int
+	response   json.RawMessage
+}
+
+func (t *captureTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	res, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	res.Body = io.NopCloser(bytes.NewReader(body))
+
+	if json.Valid(body) {
+		t.mu.Lock()
+		if req.Method == http.MethodPost {
+			t.status, t.response = res.StatusCode, body
+		} else {
+			t.serverInfo = body
+		}
+		t.mu.Unlock()
+	}
+	return res, nil
+}
+
+// linksIn returns the media links in a decoded cobalt response: urls, thumbnails and audio of picker items.
+func linksIn(value any, key string) []string {
+	var links []string
+	switch v := value.(type) {
+	case string:
+		if (key == "url" || key == "thumb" || key == "audio") && (strings.HasPrefix(v, "http://") || strings.HasPrefix(v, "https://")) {
+			links = append(links, v)
+		}
+	case []any:
+		for _, item := range v {
+			links = append(links, linksIn(item, key)...)
+		}
+	case map[string]any:
+		for key, item := range v {
+			links = append(links, linksIn(item, key)...)
+		}
+	}
+	return links
+}
+
+// fetchMedia downloads the first limit bytes of link.
+func fetchMedia(ctx context.Context, client *http.Client, link string, limit int64) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, link, nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK && res.StatusCode != http.StatusPartialContent {
+		return nil, fmt.Errorf("unexpected status %v", res.Status)
+	}
+	return io.ReadAll(io.LimitReader(res.Body, limit))
+}
+
+var unsafeName = regexp.MustCompile(`[^A-Za-z0-9_-]+`)
+
+// fixtureName makes a file name from a media link, like "youtu_be_dQw4w9WgXcQ".
+func fixtureName(link string) string {
+	_, link, _ = strings.Cut(link, "://")
+	name := strings.Trim(unsafeName.ReplaceAllString(strings.TrimPrefix(link, "www."), "_"), "_")
+	if len(name) > 80 {
+		name = name[:80]
+	}
+	return name
+}
@@ -0,0 +1,216 @@
+// Package gobalttest provides a fake cobalt instance for tests, answering with fixtures.
+//
+// Fixtures are made from a real instance with Capture(), so the fake behaves like cobalt really does, and can be captured
+// again when cobalt changes:
+//
+//	fixture, err := gobalttest.Capture(ctx, "https://cobalt.example", settings, gobalttest.CaptureOptions{})
+//	err = fixture.Save("testdata/fixtures")
+//
+// Then in tests:
+//
+//	fixtures, err := gobalttest.LoadFixtures("testdata/fixtures")
+//	server := gobalttest.NewServer(fixtures...)
+//	defer server.Close()
+//	client := gobalt.New(gobalt.WithInstance(server.URL))
+//
+// For simple cases, Tunnel(), Picker() and Error() make canned fixtures without a real instance. Code depending on
+// gobalt.CobaltClient rather than on *gobalt.Cobalt can be given a Client instead, mocking each method with a function.
+package gobalttest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Fixture is a cobalt answer to a media link, with the files it links to.
+type Fixture struct {
+	Name       string            `json:"name"`                 //Name of the fixture, used as file name by Save().
+	URL        string            `json:"url"`                  //Media link the fixture answers to.
+	ServerInfo json.RawMessage   `json:"serverInfo,omitempty"` //Answer of the instance to GET /, optional.
+	StatusCode int               `json:"statusCode"`           //HTTP status of the cobalt answer.
+	Response   json.RawMessage   `json:"response"`             //Body of the cobalt answer.
+	Media      map[string][]byte `json:"media,omitempty"`      //Files linked in Response (tunnels, picker items, thumbnails), by their original url.
+}
+
+// DefaultServerInfo is sent on GET / when no fixture has its own ServerInfo.
+var DefaultServerInfo = json.RawMessage(`{"cobalt":{"version":"10.5.0","url":"","startTime":"0","durationLimit":10800,"services":["youtube","tiktok","twitter","instagram","soundcloud"]},"git":{"branch":"main","commit":"0000000","remote":"imputnet/cobalt"}}`)
+
+// Server is a fake cobalt instance serving fixtures. Links to media in the responses are replaced with links to the server,
+// so downloads work offline too. Create one with NewServer().
+type Server struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	fixtures []Fixture
+}
+
+// NewServer starts a Server answering with fixtures. Call Close() when done.
+func NewServer(fixtures ...Fixture) *Server {
+	s := &Server{fixtures: fixtures}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", s.serveInfo)
+	mux.HandleFunc("POST /{$}", s.serveMedia)
+	mux.HandleFunc("GET /media/{fixture}/{file}", s.serveFile)
+	s.Server = httptest.NewServer(mux)
+	return s
+}
+
+// Add adds a fixture to the server. Fixtures added later win when many answer to the same link.
+func (s *Server) Add(fixture Fixture) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fixtures = append(s.fixtures, fixture)
+}
+
+// find returns the index of the fixture answering to link, or -1.
+func (s *Server) find(link string) (int, Fixture) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := len(s.fixtures) - 1; i >= 0; i-- {
+		if s.fixtures[i].URL == link {
+			return i, s.fixtures[i]
+		}
+	}
+	return -1, Fixture{}
+}
+
+func (s *Server) serveInfo(w http.ResponseWriter, r *http.Request) {
+	info := DefaultServerInfo
+	s.mu.Lock()
+	for _, fixture := range s.fixtures {
+		if len(fixture.ServerInfo) > 0 {
+			info = fixture.ServerInfo
+			break
+		}
+	}
+	s.mu.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(info)
+}
+
+func (s *Server) serveMedia(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		URL string `json:"url"`
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil || request.URL == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"status":"error","error":{"code":"error.api.link.missing"}}`))
+		return
+	}
+	index, fixture := s.find(request.URL)
+	if index < 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"status":"error","error":{"code":"error.api.link.unsupported"}}`))
+		return
+	}
+
+	response := fixture.Response
+	if links := mediaLinks(fixture); len(links) > 0 {
+		var body any
+		if err := json.Unmarshal(fixture.Response, &body); err == nil {
+			body = replaceStrings(body, func(link string) string {
+				if i := slices.Index(links, link); i >= 0 {
+					return fmt.Sprintf("%v/media/%v/%v", s.URL, index, i)
+				}
+				return link
+			})
+			response, _ = json.Marshal(body)
+		}
+	}
+	w.WriteHeader(max(fixture.StatusCode, http.StatusOK))
+	w.Write(response)
+}
+
+func (s *Server) serveFile(w http.ResponseWriter, r *http.Request) {
+	index, err1 := strconv.Atoi(r.PathValue("fixture"))
+	file, err2 := strconv.Atoi(r.PathValue("file"))
+	s.mu.Lock()
+	var fixture Fixture
+	if err1 == nil && index >= 0 && index < len(s.fixtures) {
+		fixture = s.fixtures[index]
+	}
+	s.mu.Unlock()
+	links := mediaLinks(fixture)
+	if err2 != nil || file < 0 || file >= len(links) {
+		http.NotFound(w, r)
+		return
+	}
+	http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(fixture.Media[links[file]]))
+}
+
+// mediaLinks returns the links of the media of fixture, in a stable order.
+func mediaLinks(fixture Fixture) []string {
+	links := make([]string, 0, len(fixture.Media))
+	for link := range fixture.Media {
+		links = append(links, link)
+	}
+	slices.Sort(links)
+	return links
+}
+
+// replaceStrings calls replace on every string in a decoded JSON value.
+func replaceStrings(value any, replace func(string) string) any {
+	switch v := value.(type) {
+	case string:
+		return replace(v)
+	case []any:
+		for i := range v {
+			v[i] = replaceStrings(v[i], replace)
+		}
+	case map[string]any:
+		for key := range v {
+			v[key] = replaceStrings(v[key], replace)
+		}
+	}
+	return value
+}
+
+// Save writes the fixture to dir, as <Name>.json.
+func (f *Fixture) Save(dir string) error {
+	if f.Name == "" {
+		return fmt.Errorf("fixture for %v has no name", f.URL)
+	}
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, f.Name+".json"), data, 0644)
+}
+
+// LoadFixtures reads every fixture saved in dir by Save().
+func LoadFixtures(dir string) ([]Fixture, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	fixtures := make([]Fixture, 0, len(files))
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		var fixture Fixture
+		if err := json.Unmarshal(data, &fixture); err != nil {
+			return nil, fmt.Errorf("invalid fixture %v: %w", file, err)
+		}
+		if fixture.Name == "" {
+			fixture.Name = strings.TrimSuffix(filepath.Base(file), ".json")
+		}
+		fixtures = append(fixtures, fixture)
+	}
+	return fixtures, nil
+}
@@ -0,0 +1,62 @@
+package gobalttest
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/lostdusty/gobalt/v2"
+)
+
+func TestCaptureAndServe(t *testing.T) {
+	media := bytes.Repeat([]byte("media"), 1000)
+	var real *httptest.Server
+	real = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost:
+			w.Write([]byte(`{"status":"tunnel","url":"` + real.URL + `/tunnel?id=1&exp=2","filename":"video.mp4"}`))
+		case r.URL.Path == "/tunnel":
+			w.Write(media)
+		default:
+			w.Write([]byte(`{"cobalt":{"version":"10.9.0","services":["youtube"]}}`))
+		}
+	}))
+	options := gobalt.CreateDefaultSettings()
+	options.Url = "https://youtu.be/dQw4w9WgXcQ"
+
+	fixture, err := Capture(context.Background(), real.URL, options, CaptureOptions{MaxMediaSize: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	real.Close()
+	dir := t.TempDir()
+	if err := fixture.Save(dir); err != nil {
+		t.Fatal(err)
+	}
+	fixtures, err := LoadFixtures(dir)
+	if err != nil || len(fixtures) != 1 || fixtures[0].Name != "youtu_be_dQw4w9WgXcQ" {
+		t.Fatalf("unexpected fixtures %+v, %v", fixtures, err)
+	}
+
+	server := NewServer(fixtures...)
+	defer server.Close()
+	client := gobalt.New(gobalt.WithInstance(server.URL))
+	result, err := client.Run(context.Background(), options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Filename != "video.mp4" {
+		t.Fatalf("unexpected response %+v", result)
+	}
+	res, err := http.Get(result.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	if data, _ := io.ReadAll(res.Body); !bytes.Equal(data, media[:100]) {
+		t.Fatalf("the tunnel should serve the captured media, got %q", data)
+	}
+}
@@ -0,0 +1,168 @@
+package gobalt
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// ErrAlreadyDownloaded is returned (together with ErrSkipped) when the media was found in the download History.
+var ErrAlreadyDownloaded = errors.New("media was already downloaded")
+
+// HistoryEntry is a record of a finished download.
+type HistoryEntry struct {
+	Key    string    `json:"key"`    //Media key (see MediaKey()), followed by the mode of audio and mute downloads, like "youtube:dQw4w9WgXcQ:audio".
+	Source string    `json:"source"` //Link that was requested.
+	Path   string    `json:"path"`   //Where the file was saved.
+	Size   int64     `json:"size"`   //File size in bytes.
+	Time   time.Time `json:"time"`   //When the download finished.
+
+	Metadata map[string]string `json:"metadata,omitempty"` //Metadata of the caller, see WithMetadata().
+}
+
+// History remembers which media were already downloaded, so they can be skipped in later runs,
+// even if they're requested using another link to the same media. See WithHistory().
+type History interface {
+	Lookup(key string) (*HistoryEntry, bool, error) //Lookup returns the entry saved with key, if any.
+	Add(entry HistoryEntry) error                   //Add saves a new entry, replacing any entry with the same key.
+}
+
+// MemoryHistory is a History kept in memory, and optionally saved to a JSON file. It's safe for concurrent use.
+type MemoryHistory struct {
+	mu      sync.Mutex
+	path    string
+	entries map[string]HistoryEntry
+}
+
+// NewMemoryHistory creates an empty History that lives only in memory.
+func NewMemoryHistory() *MemoryHistory {
+	return &MemoryHistory{entries: make(map[string]HistoryEntry)}
+}
+
+// OpenHistoryFile loads the History saved in the JSON file at path, creating an empty one if the file doesn't exist.
+// Every Add() writes the whole history back to the file.
+func OpenHistoryFile(path string) (*MemoryHistory, error) {
+	history := NewMemoryHistory()
+	history.path = path
+
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return history, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []HistoryEntry
+	if err := json.Unmarshal(content, &entries); err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		history.entries[entry.Key] = entry
+	}
+	return history, nil
+}
+
+func (h *MemoryHistory) Lookup(key string) (*HistoryEntry, bool, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	entry, ok := h.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+	return &entry, true, nil
+}
+
+func (h *MemoryHistory) Add(entry HistoryEntry) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.entries[entry.Key] = entry
+	return h.save()
+}
+
+// Flush writes the history to its file again, if it has one. Add() already does it, but Flush() can be used
+// to make sure the file is up to date before exiting, see downloader.Manager.Shutdown().
+func (h *MemoryHistory) Flush() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.save()
+}
+
+// save writes the history to its file, h.mu must be held.
+func (h *MemoryHistory) save() error {
+	if h.path == "" {
+		return nil
+	}
+
+	entries := make([]HistoryEntry, 0, len(h.entries))
+	for _, v := range h.entries {
+		entries = append(entries, v)
+	}
+	content, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	//Write to a temporary file first, so a crash can't leave a half written history behind.
+	tmp := h.path + ".tmp"
+	if err := os.WriteFile(tmp, content, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, h.path)
+}
+
+// WithHistory skips media that is already in history (returning an error wrapping ErrSkipped and ErrAlreadyDownloaded),
+// and records every successful download in it. The media is identified using MediaKey() of the source link (see WithSource())
+// and the mode it was requested with, so downloading the audio of a video doesn't skip the whole video later.
+func WithHistory(history History) DownloadOption {
+	return func(c *downloadConfig) {
+		c.history = history
+	}
+}
+
+// WithForce downloads the media even if it's already in the History, the new download still gets recorded.
+func WithForce() DownloadOption {
+	return func(c *downloadConfig) {
+		c.force = true
+	}
+}
+
+// historyKey returns the key of a download of link in mode. Full downloads (Auto) just use MediaKey(), like older histories.
+func historyKey(link string, mode downloadMode) string {
+	if mode == Audio || mode == Mute {
+		return MediaKey(link) + ":" + string(mode)
+	}
+	return MediaKey(link)
+}
+
+// checkHistory returns an error if the source was already downloaded and WithForce() wasn't used.
+func (c *downloadConfig) checkHistory() error {
+	if c.history == nil || c.force || c.source == "" {
+		return nil
+	}
+	entry, found, err := c.history.Lookup(historyKey(c.source, c.mode))
+	if err != nil {
+		return err
+	}
+	if found {
+		return fmt.Errorf("%w: %w (saved at %v)", ErrSkipped, ErrAlreadyDownloaded, entry.Path)
+	}
+	return nil
+}
+
+// recordHistory saves a finished download in the history, if there's one.
+func (c *downloadConfig) recordHistory(result *DownloadResult) error {
+	if c.history == nil || c.source == "" {
+		return nil
+	}
+	return c.history.Add(HistoryEntry{
+		Key:      historyKey(c.source, c.mode),
+		Source:   c.source,
+		Path:     result.Path,
+		Size:     result.Size,
+		Time:     time.Now(),
+		Metadata: result.Metadata,
+	})
+}
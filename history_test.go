@@ -0,0 +1,77 @@
+package gobalt
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+func TestHistorySkipsKnownMedia(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte("media"))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	historyFile := filepath.Join(dir, "history.json")
+	history, err := OpenHistoryFile(historyFile)
+	if err != nil {
+		t.Fatalf("failed to open history: %v", err)
+	}
+	_, err = Download(server.URL, filepath.Join(dir, "a.mp4"), WithSource("https://youtu.be/dQw4w9WgXcQ"), WithHistory(history))
+	if err != nil {
+		t.Fatalf("first download failed: %v", err)
+	}
+
+	//Reopen the file, so the history is read from disk.
+	history, err = OpenHistoryFile(historyFile)
+	if err != nil {
+		t.Fatalf("failed to reopen history: %v", err)
+	}
+	_, err = Download(server.URL, filepath.Join(dir, "b.mp4"), WithSource("https://www.youtube.com/watch?v=dQw4w9WgXcQ"), WithHistory(history))
+	if !errors.Is(err, ErrAlreadyDownloaded) || !errors.Is(err, ErrSkipped) {
+		t.Fatalf("expected ErrAlreadyDownloaded, got %v", err)
+	}
+	if requests != 1 {
+		t.Fatalf("expected 1 request, got %v", requests)
+	}
+
+	_, err = Download(server.URL, filepath.Join(dir, "c.mp4"), WithSource("https://youtu.be/dQw4w9WgXcQ"), WithHistory(history), WithForce())
+	if err != nil || requests != 2 {
+		t.Fatalf("expected forced download to happen, got %v (requests: %v)", err, requests)
+	}
+}
+
+func TestHistoryKeepsModesApart(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("media"))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	history := NewMemoryHistory()
+	save := func(mode downloadMode, filename string) error {
+		media := &CobaltResponse{Status: "tunnel", URL: server.URL, Filename: filename, request: Settings{Url: "https://youtu.be/dQw4w9WgXcQ", Mode: mode}}
+		_, err := media.SaveTo(dir, WithHistory(history))
+		return err
+	}
+	if err := save(Audio, "audio.mp3"); err != nil {
+		t.Fatal(err)
+	}
+	if err := save(Auto, "video.mp4"); err != nil {
+		t.Fatalf("the audio of a video should not skip the video, got %v", err)
+	}
+	if err := save(Audio, "audio again.mp3"); !errors.Is(err, ErrAlreadyDownloaded) {
+		t.Fatalf("expected ErrAlreadyDownloaded, got %v", err)
+	}
+	if _, found, _ := history.Lookup("youtube:dQw4w9WgXcQ:audio"); !found {
+		t.Fatal("audio downloads should be recorded with their mode")
+	}
+	if _, found, _ := history.Lookup("youtube:dQw4w9WgXcQ"); !found {
+		t.Fatal("full downloads should be recorded with the media key, like older histories")
+	}
+}
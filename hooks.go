@@ -0,0 +1,68 @@
+package gobalt
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// PostDownloadHook is called after each successful download, with information about the saved file.
+// If a hook returns an error, the download result is still returned, along with the error.
+type PostDownloadHook func(ctx context.Context, result *DownloadResult) error
+
+// WithPostDownloadHook adds a function that runs after the file was downloaded. Hooks run in the order they were added.
+func WithPostDownloadHook(hook PostDownloadHook) DownloadOption {
+	return func(c *downloadConfig) {
+		c.hooks = append(c.hooks, hook)
+	}
+}
+
+// WithPostDownloadCommand runs a command after the file was downloaded, useful for triggering transcoders, media servers or notification scripts.
+//
+// The arguments can contain the placeholders {path}, {url}, {source} and {service}, they're replaced by the values of DownloadResult.
+// The same values are also available to the command as the environment variables GOBALT_PATH, GOBALT_URL, GOBALT_SOURCE, GOBALT_SERVICE and GOBALT_SIZE,
+// and the metadata (see WithMetadata()) as GOBALT_META_<KEY>, the key in upper case with other characters than letters and digits replaced by "_".
+//
+// Example: WithPostDownloadCommand("ffmpeg", "-i", "{path}", "{path}.mkv")
+func WithPostDownloadCommand(name string, args ...string) DownloadOption {
+	return WithPostDownloadHook(func(ctx context.Context, result *DownloadResult) error {
+		replacer := strings.NewReplacer(
+			"{path}", result.Path,
+			"{url}", result.URL,
+			"{source}", result.Source,
+			"{service}", result.Service,
+		)
+		expanded := make([]string, len(args))
+		for i, arg := range args {
+			expanded[i] = replacer.Replace(arg)
+		}
+
+		cmd := exec.CommandContext(ctx, name, expanded...)
+		cmd.Env = append(os.Environ(),
+			"GOBALT_PATH="+result.Path,
+			"GOBALT_URL="+result.URL,
+			"GOBALT_SOURCE="+result.Source,
+			"GOBALT_SERVICE="+result.Service,
+			"GOBALT_SIZE="+strconv.FormatInt(result.Size, 10),
+		)
+		cmd.Env = append(cmd.Env, metadataEnv(result.Metadata)...)
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("post-download command %v failed: %w (output: %s)", name, err, strings.TrimSpace(string(output)))
+		}
+		return nil
+	})
+}
+
+// runHooks runs every post-download hook, stopping at the first one that fails.
+func (c *downloadConfig) runHooks(ctx context.Context, result *DownloadResult) error {
+	for _, hook := range c.hooks {
+		if err := hook(ctx, result); err != nil {
+			return err
+		}
+	}
+	return nil
+}
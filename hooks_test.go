@@ -0,0 +1,61 @@
+package gobalt
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestPostDownloadHook(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("media"))
+	}))
+	defer server.Close()
+
+	var got *DownloadResult
+	hookErr := errors.New("hook failed")
+	path := filepath.Join(t.TempDir(), "media.mp3")
+	result, err := Download(server.URL, path, WithPostDownloadHook(func(ctx context.Context, result *DownloadResult) error {
+		got = result
+		return hookErr
+	}))
+	if !errors.Is(err, hookErr) {
+		t.Fatalf("expected the hook error, got %v", err)
+	}
+	if got == nil || result == nil || got.Path != path {
+		t.Fatalf("hook was not called with the download result")
+	}
+}
+
+func TestPostDownloadCommand(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh is not available")
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("media"))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "media.mp3")
+	marker := filepath.Join(dir, "marker")
+	_, err := Download(server.URL, path,
+		WithSource("https://soundcloud.com/artist/track"),
+		WithPostDownloadCommand("sh", "-c", `printf "%s %s" "$GOBALT_SERVICE" "$1" > "$2"`, "sh", "{path}", marker),
+	)
+	if err != nil {
+		t.Fatalf("download failed: %v", err)
+	}
+	content, err := os.ReadFile(marker)
+	if err != nil {
+		t.Fatalf("command did not run: %v", err)
+	}
+	if string(content) != "soundcloud "+path {
+		t.Fatalf("got unexpected command output %q", content)
+	}
+}
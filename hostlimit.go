@@ -0,0 +1,79 @@
+package gobalt
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+)
+
+// HostLimiter caps how many downloads run at the same time from each host, whatever the overall concurrency is.
+// Sharing one between downloads (with WithHostLimit()) keeps a bot from opening too many transfers to a single public instance,
+// which is polite and avoids per-IP throttling. It's safe for concurrent use.
+type HostLimiter struct {
+	perHost int
+
+	mu    sync.Mutex
+	slots map[string]chan struct{}
+}
+
+// NewHostLimiter creates a HostLimiter allowing perHost downloads at the same time from each host (at least 1).
+func NewHostLimiter(perHost int) *HostLimiter {
+	return &HostLimiter{perHost: max(perHost, 1), slots: make(map[string]chan struct{})}
+}
+
+// Acquire waits for a free slot for host, or until ctx is done. release must be called once the download is done.
+func (l *HostLimiter) Acquire(ctx context.Context, host string) (release func(), err error) {
+	l.mu.Lock()
+	slots, ok := l.slots[host]
+	if !ok {
+		slots = make(chan struct{}, l.perHost)
+		l.slots[host] = slots
+	}
+	l.mu.Unlock()
+
+	select {
+	case slots <- struct{}{}:
+		var once sync.Once
+		return func() { once.Do(func() { <-slots }) }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// WithHostLimit makes the download wait for a free slot in limiter for the host of the file url, see HostLimiter.
+// The slot is held until the whole file is transferred.
+func WithHostLimit(limiter *HostLimiter) DownloadOption {
+	return func(c *downloadConfig) {
+		c.hostLimiter = limiter
+	}
+}
+
+// limitHost takes a slot of the host limiter (if any) for link. The returned function releases it.
+func (c *downloadConfig) limitHost(ctx context.Context, link string) (release func(), err error) {
+	if c.hostLimiter == nil {
+		return func() {}, nil
+	}
+	parsed, err := url.Parse(link)
+	if err != nil {
+		return nil, err
+	}
+	return c.hostLimiter.Acquire(ctx, parsed.Host)
+}
+
+// releasingBody releases a host slot when the response body is closed.
+type releasingBody struct {
+	io.ReadCloser
+	release func()
+}
+
+func (b *releasingBody) Close() error {
+	defer b.release()
+	return b.ReadCloser.Close()
+}
+
+// holdUntilClosed makes res release its host slot when its body is closed.
+func holdUntilClosed(res *http.Response, release func()) {
+	res.Body = &releasingBody{ReadCloser: res.Body, release: release}
+}
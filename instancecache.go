@@ -0,0 +1,155 @@
+package gobalt
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// InstanceListCache keeps the lists downloaded from instance trackers by GetInstances(), so failover and instance selection
+// don't fetch the tracker every time. A list older than TTL is fetched again, but if the tracker fails, the old list
+// is still used for up to MaxStale.
+//
+// With a Path, lists are also saved to that file and loaded from it, so short-lived programs (like the CLI) share them
+// between runs and still work when the tracker is briefly unreachable. It's safe for concurrent use.
+type InstanceListCache struct {
+	TTL      time.Duration //How long a list is used before it's fetched again. Default: 10 minutes.
+	MaxStale time.Duration //How long after TTL a list can still be used when its tracker fails. Default: 24 hours.
+	Path     string        //File the lists are saved to, optional.
+	Clock    Clock         //Clock used to expire lists. Default: SystemClock.
+
+	mu      sync.Mutex
+	loaded  bool
+	entries map[string]instanceListEntry
+}
+
+// instanceListEntry is the list of one tracker, as saved in InstanceListCache.Path.
+type instanceListEntry struct {
+	Instances CobaltInstance `json:"instances"`
+	Fetched   time.Time      `json:"fetched"`
+}
+
+// DefaultInstanceListCache is the cache used by clients that weren't given one with WithInstanceListCache(). It's only in memory.
+var DefaultInstanceListCache = &InstanceListCache{TTL: 10 * time.Minute}
+
+// NewInstanceListCache creates an InstanceListCache keeping lists for ttl, saved to path if it's not empty.
+func NewInstanceListCache(ttl time.Duration, path string) *InstanceListCache {
+	return &InstanceListCache{TTL: ttl, Path: path}
+}
+
+// WithInstanceListCache sets the cache of instance lists used by GetInstances(). nil disables it: trackers are fetched every time.
+func WithInstanceListCache(cache *InstanceListCache) Option {
+	return func(c *Cobalt) {
+		c.instanceCache = cache
+	}
+}
+
+// Flush removes every cached list, and the file at Path if there's one.
+func (ic *InstanceListCache) Flush() error {
+	ic.mu.Lock()
+	defer ic.mu.Unlock()
+	ic.entries, ic.loaded = nil, true
+	if ic.Path == "" {
+		return nil
+	}
+	if err := os.Remove(ic.Path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// get returns the list of tracker, fetching it with client if there's no fresh one.
+func (ic *InstanceListCache) get(ctx context.Context, client *Cobalt, tracker string) (CobaltInstance, error) {
+	now := orSystemClock(ic.Clock).Now()
+	ic.mu.Lock()
+	ic.load(client)
+	entry, ok := ic.entries[tracker]
+	ic.mu.Unlock()
+	if ok && now.Sub(entry.Fetched) < ic.ttl() {
+		return entry.Instances, nil
+	}
+
+	instances, err := client.fetchTracker(ctx, tracker)
+	if err != nil {
+		if ok && now.Sub(entry.Fetched) < ic.ttl()+ic.maxStale() {
+			client.logger.Warn("failed to fetch instance tracker, using the cached list", "tracker", tracker, "age", now.Sub(entry.Fetched), "error", err)
+			return entry.Instances, nil
+		}
+		return nil, err
+	}
+
+	ic.mu.Lock()
+	defer ic.mu.Unlock()
+	ic.entries[tracker] = instanceListEntry{Instances: instances, Fetched: now}
+	if err := ic.save(); err != nil {
+		client.logger.Warn("failed to save the instance cache", "path", ic.Path, "error", err)
+	}
+	return instances, nil
+}
+
+// load reads Path the first time the cache is used, ic.mu must be held. A missing or broken file is an empty cache.
+func (ic *InstanceListCache) load(client *Cobalt) {
+	if ic.loaded {
+		return
+	}
+	ic.loaded = true
+	ic.entries = make(map[string]instanceListEntry)
+	if ic.Path == "" {
+		return
+	}
+	content, err := os.ReadFile(ic.Path)
+	if err == nil {
+		err = json.Unmarshal(content, &ic.entries)
+	}
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		client.logger.Warn("failed to read the instance cache", "path", ic.Path, "error", err)
+		ic.entries = make(map[string]instanceListEntry)
+	}
+}
+
+// save writes the cache to Path, ic.mu must be held.
+func (ic *InstanceListCache) save() error {
+	if ic.Path == "" {
+		return nil
+	}
+	content, err := json.Marshal(ic.entries)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(ic.Path), 0o755); err != nil {
+		return err
+	}
+	//Other processes may read or write the same file, so it's replaced at once from a temporary file of our own.
+	tmp, err := os.CreateTemp(filepath.Dir(ic.Path), filepath.Base(ic.Path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), ic.Path)
+}
+
+func (ic *InstanceListCache) ttl() time.Duration {
+	if ic.TTL <= 0 {
+		return 10 * time.Minute
+	}
+	return ic.TTL
+}
+
+func (ic *InstanceListCache) maxStale() time.Duration {
+	if ic.MaxStale <= 0 {
+		return 24 * time.Hour
+	}
+	return ic.MaxStale
+}
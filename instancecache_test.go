@@ -0,0 +1,55 @@
+package gobalt
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestInstanceListCache(t *testing.T) {
+	var requests atomic.Int32
+	var down atomic.Bool
+	tracker := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if down.Load() {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Write([]byte(`[{"api":"cobalt.example.com","version":"10.5.0","score":80}]`))
+	}))
+	defer tracker.Close()
+
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	path := filepath.Join(t.TempDir(), "cache", "instances.json")
+	cache := &InstanceListCache{TTL: time.Minute, MaxStale: time.Hour, Path: path, Clock: clock}
+	client := New(WithInstanceListCache(cache))
+	sources := InstanceSources{Trackers: []string{tracker.URL}}
+	for range 2 {
+		if list, err := client.GetInstances(context.Background(), sources); err != nil || len(list) != 1 {
+			t.Fatalf("unexpected list %v: %v", list, err)
+		}
+	}
+	if requests.Load() != 1 {
+		t.Fatalf("the second call should use the cache, the tracker got %v requests", requests.Load())
+	}
+
+	//Another process reads the file, and keeps the list when the tracker is down.
+	clock.now = clock.now.Add(10 * time.Minute)
+	down.Store(true)
+	other := New(WithInstanceListCache(&InstanceListCache{TTL: time.Minute, MaxStale: time.Hour, Path: path, Clock: clock}))
+	if list, err := other.GetInstances(context.Background(), sources); err != nil || len(list) != 1 {
+		t.Fatalf("the saved list should be used while the tracker is down, got %v: %v", list, err)
+	}
+	if requests.Load() != 2 {
+		t.Fatalf("the expired list should be fetched again first, the tracker got %v requests", requests.Load())
+	}
+
+	clock.now = clock.now.Add(2 * time.Hour)
+	if _, err := other.GetInstances(context.Background(), sources); err == nil {
+		t.Fatalf("lists older than MaxStale should not be used")
+	}
+}
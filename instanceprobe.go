@@ -0,0 +1,105 @@
+package gobalt
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Instance is a cobalt instance with data measured by gobalt itself, which can be trusted more than what trackers say.
+// Create them with ProbeInstance() or EnrichInstances().
+type Instance struct {
+	API          string        //Url of the cobalt api, with its scheme.
+	Tracker      InstanceInfo  //Data sent by the tracker, empty for instances probed directly.
+	Online       bool          //If the instance answered with valid server information.
+	Version      string        //Cobalt version reported by the instance itself.
+	Services     []string      //Services enabled on the instance.
+	Latency      time.Duration //Time taken to answer the server information request.
+	AuthRequired bool          //If the instance refuses requests without an api key (or a turnstile token).
+	ProbedAt     time.Time     //When the instance was probed.
+	Err          string        //Why the probe failed, if it did.
+}
+
+// ProbeInstance(ctx, api) measures a cobalt instance: its version, services and latency, and if it needs authentication.
+// The returned error is also saved in Instance.Err, the Instance is returned either way.
+func ProbeInstance(ctx context.Context, api string) (*Instance, error) {
+	return defaultClient().ProbeInstance(ctx, api)
+}
+
+// ProbeInstance(ctx, api) is the same as ProbeInstance(), using the HTTP client of c. The api key of c is not sent.
+func (c *Cobalt) ProbeInstance(ctx context.Context, api string) (*Instance, error) {
+	instance := &Instance{API: api, ProbedAt: c.clock.Now()}
+	normalized, err := normalizeInstanceURL(api)
+	if err != nil {
+		instance.Err = err.Error()
+		return instance, err
+	}
+	instance.API = normalized
+
+	start := c.clock.Now()
+	info, err := c.fetchServerInfo(ctx, normalized)
+	instance.Latency = c.clock.Now().Sub(start)
+	if err != nil {
+		instance.Err = err.Error()
+		return instance, err
+	}
+	instance.Online, instance.Version, instance.Services = true, info.Cobalt.Version, info.Cobalt.Services
+	instance.AuthRequired = c.needsAuth(ctx, normalized)
+	return instance, nil
+}
+
+// needsAuth sends an empty request without credentials: instances with authentication refuse it before looking at the url.
+func (c *Cobalt) needsAuth(ctx context.Context, api string) bool {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, api, strings.NewReader("{}"))
+	if err != nil {
+		return false
+	}
+	req.Header.Add("User-Agent", useragent)
+	req.Header.Add("Accept", "application/json")
+	req.Header.Add("Content-Type", "application/json")
+	res, err := c.do(req)
+	if err != nil {
+		return false
+	}
+	defer res.Body.Close()
+
+	var answer CobaltResponse
+	if err := json.NewDecoder(io.LimitReader(res.Body, 64<<10)).Decode(&answer); err != nil || answer.Error == nil {
+		return false
+	}
+	return strings.HasPrefix(answer.Error.Code, "error.api.auth.")
+}
+
+// EnrichInstances(ctx, instances, concurrency) probes every tracker entry (concurrency at a time) and returns them
+// as Instance, in the same order. Probes that fail give an Instance with Online false and Err set.
+func EnrichInstances(ctx context.Context, instances CobaltInstance, concurrency int) Instances {
+	return defaultClient().EnrichInstances(ctx, instances, concurrency)
+}
+
+// EnrichInstances(ctx, instances, concurrency) is the same as EnrichInstances(), using c.
+func (c *Cobalt) EnrichInstances(ctx context.Context, instances CobaltInstance, concurrency int) Instances {
+	enriched := make(Instances, len(instances))
+	slots := make(chan struct{}, max(concurrency, 1))
+	var wg sync.WaitGroup
+	for i, tracker := range instances {
+		api := tracker.API
+		if !strings.Contains(api, "://") && tracker.Protocol != "" {
+			api = tracker.Protocol + "://" + api
+		}
+		wg.Add(1)
+		slots <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			probed, _ := c.ProbeInstance(ctx, api)
+			probed.Tracker = tracker
+			enriched[i] = *probed
+		}()
+	}
+	wg.Wait()
+	return enriched
+}
@@ -0,0 +1,37 @@
+package gobalt
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestEnrichInstances(t *testing.T) {
+	open := fakeCobalt(t, `{"status":"error","error":{"code":"error.api.link.missing"}}`)
+	private := fakeCobalt(t, `{"status":"error","error":{"code":"error.api.auth.key.missing"}}`)
+	broken := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("<html>maintenance</html>"))
+	}))
+	defer broken.Close()
+
+	instances := CobaltInstance{
+		{API: strings.TrimPrefix(open.URL, "http://"), Protocol: "http", Version: "7.0.0"},
+		{API: private.URL},
+		{API: broken.URL},
+	}
+	enriched := New().EnrichInstances(context.Background(), instances, 2)
+	if len(enriched) != 3 {
+		t.Fatalf("expected 3 instances, got %v", len(enriched))
+	}
+	if got := enriched[0]; !got.Online || got.Version != "10.5.0" || got.Tracker.Version != "7.0.0" || got.AuthRequired || len(got.Services) != 1 {
+		t.Fatalf("unexpected open instance %+v", got)
+	}
+	if got := enriched[1]; !got.Online || !got.AuthRequired {
+		t.Fatalf("the private instance should require auth, got %+v", got)
+	}
+	if got := enriched[2]; got.Online || got.Err == "" {
+		t.Fatalf("the broken instance should be offline, got %+v", got)
+	}
+}
@@ -0,0 +1,73 @@
+package gobalt
+
+import (
+	"cmp"
+	"slices"
+)
+
+// Instances is a list of instances with chainable helpers to sort and filter it, e.g. to drive an instance picker:
+//
+//	instances.OnlineOnly().WithFrontend().SortByLatency()
+//
+// Every helper returns a new list, the original is left untouched.
+type Instances []Instance
+
+// Instances converts tracker entries to Instances without probing them: Online comes from the tracker, and Latency is unknown.
+// Use EnrichInstances() for measured data.
+func (list CobaltInstance) Instances() Instances {
+	instances := make(Instances, 0, len(list))
+	for _, tracker := range list {
+		instances = append(instances, Instance{
+			API:     tracker.API,
+			Tracker: tracker,
+			Online:  tracker.Online.API,
+			Version: tracker.Version,
+		})
+	}
+	return instances
+}
+
+// Filter returns the instances for which keep returns true.
+func (list Instances) Filter(keep func(Instance) bool) Instances {
+	return slices.DeleteFunc(slices.Clone(list), func(instance Instance) bool {
+		return !keep(instance)
+	})
+}
+
+// OnlineOnly returns the instances whose api is online.
+func (list Instances) OnlineOnly() Instances {
+	return list.Filter(func(instance Instance) bool { return instance.Online })
+}
+
+// WithFrontend returns the instances that also host a web frontend, according to the tracker.
+func (list Instances) WithFrontend() Instances {
+	return list.Filter(func(instance Instance) bool {
+		return instance.Tracker.Frontend != "" && instance.Tracker.Online.Frontend
+	})
+}
+
+// SortByScore returns the instances sorted by tracker score, best first.
+func (list Instances) SortByScore() Instances {
+	sorted := slices.Clone(list)
+	slices.SortStableFunc(sorted, func(a, b Instance) int {
+		return cmp.Compare(b.Tracker.Score, a.Tracker.Score)
+	})
+	return sorted
+}
+
+// SortByLatency returns the instances sorted by measured latency, fastest first. Instances without a measure
+// (offline, or never probed) go last.
+func (list Instances) SortByLatency() Instances {
+	sorted := slices.Clone(list)
+	slices.SortStableFunc(sorted, func(a, b Instance) int {
+		aKnown, bKnown := a.Online && a.Latency > 0, b.Online && b.Latency > 0
+		if aKnown != bKnown {
+			if aKnown {
+				return -1
+			}
+			return 1
+		}
+		return cmp.Compare(a.Latency, b.Latency)
+	})
+	return sorted
+}
@@ -0,0 +1,34 @@
+package gobalt
+
+import (
+	"testing"
+	"time"
+)
+
+func TestInstancesQuery(t *testing.T) {
+	list := Instances{
+		{API: "a", Online: true, Latency: 90 * time.Millisecond, Tracker: InstanceInfo{Score: 50, Frontend: "a.example", Online: OnlineStatus{Frontend: true}}},
+		{API: "b", Online: false, Tracker: InstanceInfo{Score: 100}},
+		{API: "c", Online: true, Latency: 20 * time.Millisecond, Tracker: InstanceInfo{Score: 70}},
+		{API: "d", Online: true, Tracker: InstanceInfo{Score: 90, Frontend: "d.example", Online: OnlineStatus{Frontend: true}}},
+	}
+	apis := func(list Instances) (out string) {
+		for _, instance := range list {
+			out += instance.API
+		}
+		return out
+	}
+
+	if got := apis(list.OnlineOnly().SortByScore()); got != "dca" {
+		t.Fatalf("expected dca, got %v", got)
+	}
+	if got := apis(list.SortByLatency()); got != "cabd" {
+		t.Fatalf("expected cabd, got %v", got)
+	}
+	if got := apis(list.WithFrontend().SortByLatency()); got != "ad" {
+		t.Fatalf("expected ad, got %v", got)
+	}
+	if apis(list) != "abcd" {
+		t.Fatal("the original list should not change")
+	}
+}
@@ -0,0 +1,28 @@
+package gobalt
+
+import (
+	"context"
+	"runtime/pprof"
+)
+
+// withJobLabels runs fn with pprof labels describing a download job (its id, the service of link and the instance of c),
+// so CPU and goroutine profiles of a busy program show which job the work belongs to. Goroutines started by fn inherit them.
+func (c *Cobalt) withJobLabels(ctx context.Context, job, link string, fn func(ctx context.Context)) {
+	pprof.Do(ctx, pprof.Labels(
+		"gobalt_job", job,
+		"gobalt_service", DetectService(link),
+		"gobalt_instance", c.api,
+	), fn)
+}
+
+// DoJob(ctx, id, link, fn) runs fn as the download job id of link: with its pprof labels, and reported to the Metrics of
+// the client (JobStarted(), then JobFinished() with the error of fn). It's what downloader.Manager runs its jobs with.
+func (c *Cobalt) DoJob(ctx context.Context, id, link string, fn func(ctx context.Context) error) error {
+	var err error
+	c.metrics.JobStarted()
+	c.withJobLabels(ctx, id, link, func(ctx context.Context) {
+		err = fn(ctx)
+	})
+	c.metrics.JobFinished(err)
+	return err
+}
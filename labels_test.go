@@ -0,0 +1,34 @@
+package gobalt
+
+import (
+	"context"
+	"errors"
+	"runtime/pprof"
+	"testing"
+)
+
+func TestDoJob(t *testing.T) {
+	metrics := NewExpvarMetrics("gobalt_job_test_")
+	client := New(WithInstance("https://api.example.com"), WithMetrics(metrics))
+	labels := make(map[string]string)
+	failure := errors.New("failed")
+	err := client.DoJob(context.Background(), "job-1", "https://youtu.be/dQw4w9WgXcQ", func(ctx context.Context) error {
+		pprof.ForLabels(ctx, func(key, value string) bool {
+			labels[key] = value
+			return true
+		})
+		if metrics.ActiveJobs.Value() != 1 {
+			t.Errorf("the job should be active while it runs, got %v", metrics.ActiveJobs.Value())
+		}
+		return failure
+	})
+	if err != failure {
+		t.Fatalf("expected the error of the job, got %v", err)
+	}
+	if labels["gobalt_job"] != "job-1" || labels["gobalt_service"] != "youtube" || labels["gobalt_instance"] != "https://api.example.com" {
+		t.Fatalf("unexpected labels %v", labels)
+	}
+	if metrics.ActiveJobs.Value() != 0 {
+		t.Fatalf("the job should be finished, got %v active", metrics.ActiveJobs.Value())
+	}
+}
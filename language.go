@@ -0,0 +1,39 @@
+package gobalt
+
+import "strings"
+
+// ErrDescriptionsByLanguage contains the error description catalogs used by ResolveError(), by language code (like "en" or "pt").
+// English ("en") is ErrDescriptions, and is used for codes a catalog doesn't have. Add your own with RegisterErrDescriptions().
+var ErrDescriptionsByLanguage = map[string]map[string]string{
+	"en": ErrDescriptions,
+}
+
+// RegisterErrDescriptions adds (or replaces) the error descriptions of a language, so ResolveError() uses them when Language matches.
+// Missing codes still fall back to English.
+func RegisterErrDescriptions(language string, descriptions map[string]string) {
+	ErrDescriptionsByLanguage[normalizeLanguage(language)] = descriptions
+}
+
+// errDescription returns the description of code in language, or in English if there's no translation.
+func errDescription(language, code string) (string, bool) {
+	lang := normalizeLanguage(language)
+	candidates := []string{lang}
+	//Try "pt" if there's no catalog for "pt-br".
+	if base, _, found := strings.Cut(lang, "-"); found {
+		candidates = append(candidates, base)
+	}
+	for _, candidate := range candidates {
+		if description, ok := ErrDescriptionsByLanguage[candidate][code]; ok {
+			return description, true
+		}
+	}
+	description, ok := ErrDescriptions[code]
+	return description, ok
+}
+
+// normalizeLanguage turns "pt_BR" or "PT-br" into "pt-br", and drops extra values like in "en-US,en;q=0.9".
+func normalizeLanguage(language string) string {
+	language, _, _ = strings.Cut(language, ",")
+	language, _, _ = strings.Cut(language, ";")
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(language), "_", "-"))
+}
@@ -0,0 +1,51 @@
+package gobalt
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestResolveErrorLanguage(t *testing.T) {
+	previous := Language
+	defer func() { Language = previous }()
+
+	RegisterErrDescriptions("pt", map[string]string{"error.api.link.invalid": "o link é inválido"})
+	defer delete(ErrDescriptionsByLanguage, "pt")
+
+	Language = "pt-BR"
+	if got := ResolveError(errors.New("error.api.link.invalid")); got != "o link é inválido (error.api.link.invalid)" {
+		t.Fatalf("expected the portuguese description, got %v", got)
+	}
+	//Codes without a translation fall back to english.
+	if got := ResolveError(errors.New("error.api.capacity")); got != ErrDescriptions["error.api.capacity"]+" (error.api.capacity)" {
+		t.Fatalf("expected the english description, got %v", got)
+	}
+}
+
+func TestAcceptLanguageHeader(t *testing.T) {
+	previous := Language
+	defer func() { Language = previous }()
+	Language = "de"
+
+	var got []string
+	fake := fakeCobalt(t, `{"status":"tunnel","url":"https://example.com/tunnel"}`)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = append(got, r.Header.Get("Accept-Language"))
+		fake.Config.Handler.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	options := CreateDefaultSettings()
+	options.Url = "https://youtu.be/dQw4w9WgXcQ"
+	if _, err := RunOn(context.Background(), server.URL, options); err != nil {
+		t.Fatalf("RunOn failed: %v", err)
+	}
+	for _, header := range got {
+		if header != "de" {
+			t.Fatalf("expected Accept-Language: de on every request, got %v", got)
+		}
+	}
+}
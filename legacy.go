@@ -0,0 +1,181 @@
+package gobalt
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/mcuadros/go-version"
+)
+
+// Cobalt v7 (and older) instances have another api: requests go to /api/json with other field names, the server info is at
+// /api/serverInfo and responses use other statuses. gobalt detects these instances from their version and translates
+// requests and responses, so Run() works with them like with any other instance.
+
+// isLegacyVersion reports if serverVersion is a cobalt version older than v10, using the /api/json api.
+func isLegacyVersion(serverVersion string) bool {
+	return serverVersion != "" && version.Compare(serverVersion, "10.0.0", "<")
+}
+
+// legacyServerInfo is the server information sent by cobalt v7 at /api/serverInfo.
+type legacyServerInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	Branch    string `json:"branch"`
+	Name      string `json:"name"`
+	URL       string `json:"url"`
+	StartTime string `json:"startTime"`
+}
+
+// fetchLegacyServerInfo asks the instance at api for its server information the cobalt v7 way.
+func (c *Cobalt) fetchLegacyServerInfo(ctx context.Context, api string) (*ServerInfo, error) {
+	res, err := c.doHttpRequest(ctx, strings.TrimSuffix(api, "/")+"/api/serverInfo", http.MethodGet, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	var legacy legacyServerInfo
+	if err := json.NewDecoder(res.Body).Decode(&legacy); err != nil {
+		return nil, err
+	}
+	if res.StatusCode != http.StatusOK || !isLegacyVersion(legacy.Version) {
+		return nil, fmt.Errorf("not a cobalt v7 instance")
+	}
+	return &ServerInfo{
+		Cobalt: CobaltServerInformation{Version: legacy.Version, URL: legacy.URL, StartTime: legacy.StartTime},
+		Git:    CobaltGitInformation{Branch: legacy.Branch, Commit: legacy.Commit},
+	}, nil
+}
+
+// legacyRequest is the body of a cobalt v7 request.
+type legacyRequest struct {
+	Url             string `json:"url"`
+	VideoCodec      string `json:"vCodec"`
+	VideoQuality    string `json:"vQuality"`
+	AudioFormat     string `json:"aFormat"`
+	FilenamePattern string `json:"filenamePattern"`
+	IsAudioOnly     bool   `json:"isAudioOnly"`
+	IsAudioMuted    bool   `json:"isAudioMuted"`
+	IsTTFullAudio   bool   `json:"isTTFullAudio"`
+	DubLang         bool   `json:"dubLang"`
+	DisableMetadata bool   `json:"disableMetadata"`
+	TwitterGif      bool   `json:"twitterGif"`
+	TikTokH265      bool   `json:"tiktokH265"`
+}
+
+// newLegacyRequest translates options to a cobalt v7 request.
+func newLegacyRequest(options Settings) legacyRequest {
+	return legacyRequest{
+		Url:             options.Url,
+		VideoCodec:      string(options.YoutubeVideoFormat),
+		VideoQuality:    strconv.Itoa(options.VideoQuality),
+		AudioFormat:     string(options.AudioFormat),
+		FilenamePattern: string(options.FilenameStyle),
+		IsAudioOnly:     options.Mode == Audio,
+		IsAudioMuted:    options.Mode == Mute,
+		IsTTFullAudio:   options.TikTokFullAudio,
+		DubLang:         options.YoutubeDubbedAudio,
+		DisableMetadata: options.DisableMetadata,
+		TwitterGif:      options.TwitterConvertGif,
+		TikTokH265:      options.TikTokH265,
+	}
+}
+
+// legacyResponse is the answer of a cobalt v7 instance.
+type legacyResponse struct {
+	Status string        `json:"status"` //error, redirect, stream, success, rate-limit or picker.
+	Text   string        `json:"text"`   //Message, in the language of the request. Explains the error of an error response.
+	URL    string        `json:"url"`
+	Picker *[]PickerItem `json:"picker"`
+	Audio  string        `json:"audio"` //Url of the audio of a picker, like the sound of a tiktok slideshow.
+}
+
+// response translates the answer of a cobalt v7 instance to a CobaltResponse.
+func (l legacyResponse) response() CobaltResponse {
+	media := CobaltResponse{URL: l.URL, Picker: l.Picker}
+	if l.Audio != "" {
+		media.Extra = map[string]any{"audio": l.Audio}
+	}
+	switch l.Status {
+	case "stream":
+		media.Status = "tunnel"
+	case "success":
+		//A message and maybe an url, it was used for redirects by some services.
+		media.Status = "redirect"
+	case "rate-limit":
+		media.Status = "error"
+		media.Error = &Error{Code: "error.api.rate_exceeded"}
+	case "error":
+		media.Status = "error"
+		media.Error = &Error{Code: "error.api.generic"}
+	default:
+		media.Status = l.Status
+	}
+	return media
+}
+
+// runLegacy sends options to instance, running cobalt v7, and translates the answer. info is the server info of instance.
+func (c *Cobalt) runLegacy(ctx context.Context, instance string, info *ServerInfo, options Settings) (*CobaltResponse, error) {
+	body, err := json.Marshal(newLegacyRequest(options))
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(instance, "/")+"/api/json", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Add("User-Agent", useragent)
+	req.Header.Add("Accept", "application/json")
+	req.Header.Add("Content-Type", "application/json")
+	if c.language != "" {
+		req.Header.Add("Accept-Language", c.language)
+	}
+
+	if c.rateLimiter != nil {
+		if err := c.rateLimiter.Wait(ctx, instance); err != nil {
+			return nil, err
+		}
+	}
+	c.logger.Log(ctx, c.logLevels.Request, "sending legacy cobalt request", "url", options.Url, "instance", instance, "version", info.Cobalt.Version)
+	res, err := c.do(req)
+	if err != nil {
+		return nil, newCobaltError(c.language, "error.net.failed", instance, err)
+	}
+	defer res.Body.Close()
+
+	jsonbody, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, diagnoseResponse(instance, res, jsonbody, err)
+	}
+	var legacy legacyResponse
+	err = json.Unmarshal(jsonbody, &legacy)
+	media := legacy.response()
+	if err == nil {
+		err = media.validate()
+	}
+	if err != nil {
+		return nil, diagnoseResponse(instance, res, jsonbody, err)
+	}
+	c.logger.Log(ctx, c.logLevels.Request, "cobalt answered", "url", options.Url, "instance", instance, "status", legacy.Status)
+
+	if media.Status == "error" {
+		cobaltErr := newCobaltError(c.language, media.Error.Code, instance, nil)
+		if legacy.Text != "" {
+			//v7 has no error codes, only messages.
+			cobaltErr.HumanMessage = legacy.Text
+		}
+		cobaltErr.RequestID = requestIDFrom(res)
+		return nil, cobaltErr
+	}
+	media.request = options
+	media.client = c
+	media.bindPicker()
+	media.Server, media.Instance = *info, instance
+	return &media, nil
+}
@@ -0,0 +1,71 @@
+package gobalt
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// legacyCobalt is a fake cobalt v7 instance answering response to /api/json, that saves the body of the last request.
+func legacyCobalt(t *testing.T, response string, body *map[string]any) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/serverInfo", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"version":"7.15","commit":"abc123","branch":"current","name":"test","url":"https://co.example.com/","cors":1,"startTime":"1711777798864"}`))
+	})
+	mux.HandleFunc("POST /api/json", func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(body)
+		w.Write([]byte(response))
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	t.Cleanup(func() { DefaultServerInfoCache.Forget(server.URL) })
+	return server
+}
+
+func TestLegacyInstance(t *testing.T) {
+	options := CreateDefaultSettings()
+	options.Url = "https://www.youtube.com/watch?v=dQw4w9WgXcQ"
+	options.Mode = Audio
+	options.AudioFormat = MP3
+
+	var body map[string]any
+	server := legacyCobalt(t, `{"status":"stream","url":"https://co.example.com/api/stream?t=abc"}`, &body)
+	media, err := New(WithInstance(server.URL)).Run(context.Background(), options)
+	if err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	if media.Status != "tunnel" || media.URL != "https://co.example.com/api/stream?t=abc" {
+		t.Fatalf("stream should become a tunnel, got %+v", media)
+	}
+	if media.Server.Cobalt.Version != "7.15" || media.Server.Git.Commit != "abc123" {
+		t.Fatalf("unexpected server info %+v", media.Server)
+	}
+	if body["isAudioOnly"] != true || body["aFormat"] != "mp3" || body["vQuality"] != "1080" || body["url"] != options.Url {
+		t.Fatalf("unexpected legacy request %v", body)
+	}
+	if body["filenamePattern"] != "basic" {
+		t.Fatalf("filenameStyle should be sent as filenamePattern, got %v", body)
+	}
+	for _, field := range []string{"downloadMode", "filenameStyle"} {
+		if _, ok := body[field]; ok {
+			t.Fatalf("v10 fields should not be sent, got %v", body)
+		}
+	}
+
+	server = legacyCobalt(t, `{"status":"error","text":"i couldn't process your request :("}`, &body)
+	_, err = New(WithInstance(server.URL)).Run(context.Background(), options)
+	var cobaltErr *CobaltError
+	if !errors.As(err, &cobaltErr) || cobaltErr.Code != "error.api.generic" || cobaltErr.HumanMessage != "i couldn't process your request :(" {
+		t.Fatalf("expected a generic error with the server message, got %v", err)
+	}
+
+	server = legacyCobalt(t, `{"status":"rate-limit","text":"you're making too many requests"}`, &body)
+	_, err = New(WithInstance(server.URL)).Run(context.Background(), options)
+	if !errors.As(err, &cobaltErr) || cobaltErr.Code != "error.api.rate_exceeded" {
+		t.Fatalf("expected a rate_exceeded error, got %v", err)
+	}
+}
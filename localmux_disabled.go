@@ -0,0 +1,5 @@
+//go:build !localmux
+
+package gobalt
+
+const localMuxAvailable = false
@@ -0,0 +1,195 @@
+package gobalt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type localProcessing string
+
+const (
+	LocalProcessingDisabled  localProcessing = "disabled"  //Cobalt processes the media itself, the default.
+	LocalProcessingPreferred localProcessing = "preferred" //Cobalt answers with status local-processing when the media needs processing (like merging video and audio).
+	LocalProcessingForced    localProcessing = "forced"    //Cobalt always answers with status local-processing.
+)
+
+// ErrLocalMuxUnavailable is returned by ProcessLocally() when gobalt was built without the "localmux" build tag.
+var ErrLocalMuxUnavailable = errors.New("gobalt was built without local processing, build with -tags localmux to enable it")
+
+// MediaPart is a part of the media of a local-processing response, see CobaltResponse.Parts().
+type MediaPart struct {
+	Kind string //What the part holds: "video", "audio" or "subtitles".
+	URL  string //Tunnel to download the part from.
+}
+
+// Parts() returns the parts of a local-processing response (see Settings.LocalProcessing), telling what each tunnel holds
+// from the processing type: a merge has a video and an audio part, an audio download only an audio part, everything else
+// a video part. Subtitles come last. Returns nil for other responses.
+func (c *CobaltResponse) Parts() []MediaPart {
+	kinds := []string{"video"}
+	switch c.Type {
+	case "merge":
+		kinds = []string{"video", "audio"}
+	case "audio":
+		kinds = []string{"audio"}
+	}
+	parts := make([]MediaPart, 0, len(c.Tunnel))
+	for i, url := range c.Tunnel {
+		kind := "video"
+		switch {
+		case i == len(c.Tunnel)-1 && c.SubtitlesURL() != "":
+			kind = "subtitles"
+		case i < len(kinds):
+			kind = kinds[i]
+		}
+		parts = append(parts, MediaPart{Kind: kind, URL: url})
+	}
+	if len(parts) == 0 {
+		return nil
+	}
+	return parts
+}
+
+// partFilename names a part of the media saved as filename, like "video.audio.mp4" for the audio part of "video.mp4".
+func partFilename(filename string, part MediaPart) string {
+	ext := filepath.Ext(filename)
+	if part.Kind == "subtitles" {
+		return strings.TrimSuffix(filename, ext) + ".subtitles.vtt"
+	}
+	return strings.TrimSuffix(filename, ext) + "." + part.Kind + ext
+}
+
+// outputFilename returns the name of the file cobalt would have made, cleaned to be used as a file name.
+func (c *CobaltResponse) outputFilename() string {
+	if c.Output != nil && cleanPathSegment(c.Output.Filename) != "" {
+		return cleanPathSegment(c.Output.Filename)
+	}
+	return "media"
+}
+
+// SaveParts(ctx, dir) downloads the parts of a local-processing response to dir, without processing them (see Parts()).
+// Each part is named after Output.Filename with its kind added, like "video.audio.mp4", and the results are in the order of Parts().
+func (c *CobaltResponse) SaveParts(ctx context.Context, dir string, opts ...DownloadOption) ([]*DownloadResult, error) {
+	parts := c.Parts()
+	if len(parts) == 0 {
+		return nil, fmt.Errorf("response with status %v has no parts to download", c.Status)
+	}
+	filename := c.outputFilename()
+	opts = append([]DownloadOption{withRequest(c.request)}, opts...)
+	config := newDownloadConfig(opts)
+
+	results := make([]*DownloadResult, 0, len(parts))
+	for _, part := range parts {
+		path := filepath.Join(dir, config.filenamePrefix+partFilename(filename, part))
+		result, err := c.cobalt().Download(ctx, part.URL, path, opts...)
+		if err != nil {
+			return results, fmt.Errorf("failed to download the %v part: %w", part.Kind, err)
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// ProcessLocally(ctx, dir) downloads the parts of a local-processing response to dir, and makes the file cobalt would have
+// made from them with ffmpeg (see FFmpegPath): the parts are merged, remuxed or converted following Type, Output and Audio.
+// The parts are removed once the file is made. It needs the "localmux" build tag, otherwise ErrLocalMuxUnavailable is returned
+// and SaveParts() can be used to process them some other way.
+func (c *CobaltResponse) ProcessLocally(ctx context.Context, dir string, opts ...DownloadOption) (*DownloadResult, error) {
+	if !localMuxAvailable {
+		return nil, ErrLocalMuxUnavailable
+	}
+	results, err := c.SaveParts(ctx, dir, opts...)
+	if err != nil {
+		return nil, err
+	}
+	paths := make([]string, len(results))
+	for i, result := range results {
+		paths[i] = result.Path
+	}
+	path := filepath.Join(dir, newDownloadConfig(opts).filenamePrefix+c.outputFilename())
+	if err := runFFmpeg(ctx, localMuxArgs(c, paths, path)...); err != nil {
+		return nil, err
+	}
+	for _, part := range paths {
+		os.Remove(part)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	return &DownloadResult{
+		Path:     path,
+		Size:     info.Size(),
+		URL:      results[0].URL,
+		Source:   results[0].Source,
+		Service:  results[0].Service,
+		Metadata: results[0].Metadata,
+	}, nil
+}
+
+// localMuxArgs returns the ffmpeg arguments making path from the parts of media, saved at paths (in the order of Parts()).
+func localMuxArgs(media *CobaltResponse, paths []string, path string) []string {
+	var args []string
+	for _, part := range paths {
+		args = append(args, "-i", part)
+	}
+	parts := media.Parts()
+	for i, part := range parts {
+		switch part.Kind {
+		case "video":
+			args = append(args, "-map", fmt.Sprintf("%v:v", i))
+			if media.Type != "merge" && media.Type != "mute" && media.Type != "gif" {
+				//Remuxed or proxied videos keep their audio.
+				args = append(args, "-map", fmt.Sprintf("%v:a?", i))
+			}
+		case "audio":
+			args = append(args, "-map", fmt.Sprintf("%v:a", i))
+		case "subtitles":
+			args = append(args, "-map", fmt.Sprintf("%v:s", i))
+		}
+	}
+
+	switch media.Type {
+	case "gif":
+		args = append(args, "-vf", "split[s0][s1];[s0]palettegen[p];[s1][p]paletteuse", "-loop", "0")
+	case "audio":
+		if media.Audio != nil && !media.Audio.Copy && audioEncoders[audioCodec(media.Audio.Format)] != nil {
+			args = append(args, audioEncoders[audioCodec(media.Audio.Format)]...)
+			if media.Audio.Bitrate != "" && media.Audio.Format != string(Wav) {
+				args = append(args, "-b:a", media.Audio.Bitrate+"k")
+			}
+		} else {
+			args = append(args, "-c:a", "copy")
+		}
+	default:
+		args = append(args, "-c:v", "copy", "-c:a", "copy")
+	}
+	if media.SubtitlesURL() != "" {
+		if strings.EqualFold(filepath.Ext(path), ".mp4") {
+			args = append(args, "-c:s", "mov_text")
+		} else {
+			args = append(args, "-c:s", "webvtt")
+		}
+	}
+	if media.Output != nil && media.Output.Metadata != nil {
+		metadata := media.Output.Metadata
+		for _, tag := range [][2]string{
+			{"title", metadata.Title},
+			{"artist", metadata.Artist},
+			{"album", metadata.Album},
+			{"album_artist", metadata.AlbumArtist},
+			{"track", metadata.Track},
+			{"date", metadata.Date},
+			{"copyright", metadata.Copyright},
+		} {
+			if tag[1] != "" {
+				args = append(args, "-metadata", tag[0]+"="+tag[1])
+			}
+		}
+	}
+	return append(args, path)
+}
@@ -0,0 +1,81 @@
+package gobalt
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestLocalProcessing(t *testing.T) {
+	var body map[string]any
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/":
+			w.Write([]byte(`{"cobalt":{"version":"11.0.2","services":["youtube"]}}`))
+		case r.Method == http.MethodPost:
+			json.NewDecoder(r.Body).Decode(&body)
+			w.Write([]byte(`{"status":"local-processing","type":"merge","service":"youtube","tunnel":["` + server.URL + `/video","` + server.URL + `/audio","` + server.URL + `/subs"],` +
+				`"output":{"type":"video/mp4","filename":"rick.mp4","subtitles":true,"metadata":{"title":"Never Gonna Give You Up"}}}`))
+		default:
+			w.Write([]byte(r.URL.Path))
+		}
+	}))
+	defer server.Close()
+	defer DefaultServerInfoCache.Forget(server.URL)
+
+	options := CreateDefaultSettings()
+	options.Url = "https://www.youtube.com/watch?v=dQw4w9WgXcQ"
+	options.LocalProcessing = LocalProcessingForced
+	media, err := New(WithInstance(server.URL)).Run(context.Background(), options)
+	if err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	if body["localProcessing"] != "forced" {
+		t.Fatalf("localProcessing should be sent, got %v", body)
+	}
+	want := []MediaPart{{"video", server.URL + "/video"}, {"audio", server.URL + "/audio"}, {"subtitles", server.URL + "/subs"}}
+	if got := media.Parts(); !slices.Equal(got, want) {
+		t.Fatalf("got parts %v, want %v", got, want)
+	}
+
+	dir := t.TempDir()
+	if _, err := media.SaveToContext(context.Background(), dir); err == nil {
+		t.Fatal("a local-processing response has no single file to save")
+	}
+	results, err := media.SaveParts(context.Background(), dir)
+	if err != nil {
+		t.Fatalf("saving the parts failed: %v", err)
+	}
+	for i, name := range []string{"rick.video.mp4", "rick.audio.mp4", "rick.subtitles.vtt"} {
+		content, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil || results[i].Path != filepath.Join(dir, name) || string(content) != "/"+[]string{"video", "audio", "subs"}[i] {
+			t.Fatalf("part %v: got %q (%v), result %+v", name, content, err, results[i])
+		}
+	}
+
+	args := localMuxArgs(media, []string{"v.mp4", "a.mp4", "s.vtt"}, "out.mp4")
+	wantArgs := []string{"-i", "v.mp4", "-i", "a.mp4", "-i", "s.vtt", "-map", "0:v", "-map", "1:a", "-map", "2:s",
+		"-c:v", "copy", "-c:a", "copy", "-c:s", "mov_text", "-metadata", "title=Never Gonna Give You Up", "out.mp4"}
+	if !slices.Equal(args, wantArgs) {
+		t.Fatalf("got ffmpeg arguments %q, want %q", args, wantArgs)
+	}
+	if !localMuxAvailable {
+		if _, err := media.ProcessLocally(context.Background(), dir); !errors.Is(err, ErrLocalMuxUnavailable) {
+			t.Fatalf("expected ErrLocalMuxUnavailable, got %v", err)
+		}
+	}
+
+	//Older instances don't know the field.
+	_, err = New(WithInstance(versionedCobalt(t, "10.9.2", &body).URL)).Run(context.Background(), options)
+	var unsupported *UnsupportedParameterError
+	if !errors.As(err, &unsupported) || unsupported.Field != "localProcessing" {
+		t.Fatalf("expected an UnsupportedParameterError, got %v", err)
+	}
+}
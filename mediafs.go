@@ -0,0 +1,259 @@
+package gobalt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Links used by MediaURL() to go back from a media ID to the media, per service.
+var mediaURLTemplates = map[string]string{
+	"youtube":     "https://www.youtube.com/watch?v=%s",
+	"twitter":     "https://x.com/i/status/%s",
+	"instagram":   "https://www.instagram.com/p/%s/",
+	"reddit":      "https://www.reddit.com/comments/%s",
+	"vimeo":       "https://vimeo.com/%s",
+	"bilibili":    "https://www.bilibili.com/video/%s",
+	"twitch":      "https://clips.twitch.tv/%s",
+	"streamable":  "https://streamable.com/%s",
+	"dailymotion": "https://www.dailymotion.com/video/%s",
+	"soundcloud":  "https://soundcloud.com/%s",
+	"pinterest":   "https://www.pinterest.com/pin/%s/",
+	"vk":          "https://vk.com/video%s",
+	"rutube":      "https://rutube.ru/video/%s/",
+}
+
+// MediaURL(service, id) returns a link to the media with id on service, the opposite of MediaID().
+// ok is false for services where the ID alone isn't enough to build a link (like tiktok, that needs the username).
+func MediaURL(service, id string) (link string, ok bool) {
+	template, ok := mediaURLTemplates[service]
+	if !ok || id == "" {
+		return "", false
+	}
+	return fmt.Sprintf(template, id), true
+}
+
+// MediaFS is a read-only fs.FS where every file is a media: Open("youtube/dQw4w9WgXcQ.mp4") sends the request to cobalt
+// and streams the tunnel, so code that reads an fs.FS (like http.FileServer(http.FS(...)) or archivers) can serve media
+// without any glue. Paths are "<service>/<id>.<ext>", see MediaID() and MediaURL().
+//
+// Nothing is sent to cobalt until the file is read or its size is needed. Extensions of audio formats (mp3, ogg, wav, opus)
+// download only the audio, in that format. Directories exist, but are always empty, since there's no way to list media.
+type MediaFS struct {
+	ctx      context.Context //Requests and downloads of every file are canceled when it's done.
+	client   *Cobalt
+	settings Settings
+}
+
+var _ fs.FS = (*MediaFS)(nil)
+
+// NewMediaFS creates a MediaFS that uses client (or the package-level settings if nil) and settings for every request.
+// settings.Url is ignored.
+func NewMediaFS(client *Cobalt, settings Settings) *MediaFS {
+	return NewMediaFSContext(context.Background(), client, settings)
+}
+
+// NewMediaFSContext(ctx, client, settings) is the same as NewMediaFS(), but the requests to cobalt and the downloads of
+// every file are canceled when ctx is done, since fs.FS has no way to pass one to Open().
+func NewMediaFSContext(ctx context.Context, client *Cobalt, settings Settings) *MediaFS {
+	if client == nil {
+		client = defaultClient()
+	}
+	return &MediaFS{ctx: ctx, client: client, settings: settings}
+}
+
+// Open opens the media at name, see MediaFS.
+func (m *MediaFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	service, rest, found := strings.Cut(name, "/")
+	if name == "." || (!found && mediaURLTemplates[service] != "") {
+		return &mediaDir{name: name}, nil
+	}
+	ext := path.Ext(rest)
+	link, ok := MediaURL(service, strings.TrimSuffix(rest, ext))
+	if !ok || ext == "" {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+
+	options := m.settings
+	options.Url = link
+	switch format := audioCodec(strings.TrimPrefix(ext, ".")); format {
+	case MP3, Ogg, Wav, Opus:
+		options.Mode = Audio
+		options.AudioFormat = format
+	}
+	return &mediaFile{fs: m, name: name, options: options, size: -1}, nil
+}
+
+// mediaFile streams a media from cobalt. It supports Seek with Range requests, so http.FileServer can serve it.
+type mediaFile struct {
+	fs      *MediaFS
+	name    string
+	options Settings
+
+	mu     sync.Mutex
+	url    string //Tunnel url, once the request was sent.
+	size   int64  //Size of the media, -1 until known.
+	offset int64
+	body   io.ReadCloser
+}
+
+// resolve sends the request to cobalt once, and gets the size of the media.
+func (f *mediaFile) resolve() error {
+	if f.url != "" {
+		return nil
+	}
+	media, err := f.fs.client.Run(f.fs.ctx, f.options)
+	if err != nil {
+		return err
+	}
+	if media.URL == "" {
+		return fmt.Errorf("cobalt answered %v, which has no single file", media.Status)
+	}
+	f.url = media.URL
+	if info, err := f.fs.client.probeMedia(f.fs.ctx, f.url); err == nil && info.Size > 0 {
+		f.size = int64(info.Size)
+	}
+	return nil
+}
+
+func (f *mediaFile) Stat() (fs.FileInfo, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.resolve(); err != nil {
+		return nil, &fs.PathError{Op: "stat", Path: f.name, Err: err}
+	}
+	return mediaFileInfo{name: path.Base(f.name), size: max(f.size, 0)}, nil
+}
+
+func (f *mediaFile) Read(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.body == nil {
+		if err := f.open(); err != nil {
+			return 0, &fs.PathError{Op: "read", Path: f.name, Err: err}
+		}
+	}
+	n, err := f.body.Read(p)
+	f.offset += int64(n)
+	return n, err
+}
+
+// open starts streaming the tunnel from the current offset.
+func (f *mediaFile) open() error {
+	if err := f.resolve(); err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(f.fs.ctx, http.MethodGet, f.url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Add("User-Agent", useragent)
+	if f.offset > 0 {
+		req.Header.Add("Range", fmt.Sprintf("bytes=%v-", f.offset))
+	}
+	res, err := f.fs.client.doTransfer(req)
+	if err != nil {
+		return err
+	}
+	switch {
+	case res.StatusCode == http.StatusPartialContent:
+	case res.StatusCode == http.StatusOK:
+		//The server ignored Range, skip the bytes before offset.
+		if _, err := io.CopyN(io.Discard, res.Body, f.offset); err != nil {
+			res.Body.Close()
+			return err
+		}
+	case res.StatusCode == http.StatusRequestedRangeNotSatisfiable:
+		res.Body.Close()
+		f.body = io.NopCloser(strings.NewReader(""))
+		return nil
+	default:
+		res.Body.Close()
+		return fmt.Errorf("download failed with %v", res.Status)
+	}
+	f.body = res.Body
+	return nil
+}
+
+func (f *mediaFile) Seek(offset int64, whence int) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch whence {
+	case io.SeekCurrent:
+		offset += f.offset
+	case io.SeekEnd:
+		if err := f.resolve(); err != nil {
+			return 0, &fs.PathError{Op: "seek", Path: f.name, Err: err}
+		}
+		if f.size < 0 {
+			return 0, &fs.PathError{Op: "seek", Path: f.name, Err: errors.New("size of the media is unknown")}
+		}
+		offset += f.size
+	}
+	if offset < 0 {
+		return 0, &fs.PathError{Op: "seek", Path: f.name, Err: fs.ErrInvalid}
+	}
+	if offset != f.offset && f.body != nil {
+		f.body.Close()
+		f.body = nil
+	}
+	f.offset = offset
+	return offset, nil
+}
+
+func (f *mediaFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.body != nil {
+		return f.body.Close()
+	}
+	return nil
+}
+
+type mediaFileInfo struct {
+	name  string
+	size  int64
+	isDir bool
+}
+
+func (i mediaFileInfo) Name() string { return i.name }
+func (i mediaFileInfo) Size() int64  { return i.size }
+func (i mediaFileInfo) Mode() fs.FileMode {
+	if i.isDir {
+		return fs.ModeDir | 0o555
+	}
+	return 0o444
+}
+func (i mediaFileInfo) ModTime() time.Time { return time.Time{} }
+func (i mediaFileInfo) IsDir() bool        { return i.isDir }
+func (i mediaFileInfo) Sys() any           { return nil }
+
+// mediaDir is the root or a service directory of a MediaFS, always empty.
+type mediaDir struct {
+	name string
+}
+
+func (d *mediaDir) Stat() (fs.FileInfo, error) {
+	return mediaFileInfo{name: path.Base(d.name), isDir: true}, nil
+}
+func (d *mediaDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.name, Err: errors.New("is a directory")}
+}
+func (d *mediaDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	//Like an empty os.File: asking for n > 0 entries ends the directory.
+	if n > 0 {
+		return nil, io.EOF
+	}
+	return nil, nil
+}
+func (d *mediaDir) Close() error { return nil }
@@ -0,0 +1,75 @@
+package gobalt
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMediaFS(t *testing.T) {
+	var requested map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/tunnel":
+			http.ServeContent(w, r, "video.mp4", time.Time{}, strings.NewReader("0123456789"))
+		case r.Method == http.MethodGet:
+			w.Write([]byte(`{"cobalt":{"version":"10.5.0","services":["youtube"]}}`))
+		default:
+			json.NewDecoder(r.Body).Decode(&requested)
+			w.Write([]byte(`{"status":"tunnel","url":"http://` + r.Host + `/tunnel","filename":"video.mp4"}`))
+		}
+	}))
+	defer server.Close()
+
+	media := NewMediaFS(New(WithInstance(server.URL)), CreateDefaultSettings())
+	file, err := media.Open("youtube/dQw4w9WgXcQ.mp3")
+	if err != nil {
+		t.Fatalf("open failed: %v", err)
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil || info.Size() != 10 || info.Name() != "dQw4w9WgXcQ.mp3" {
+		t.Fatalf("unexpected stat %v: %v", info, err)
+	}
+	if requested["url"] != "https://www.youtube.com/watch?v=dQw4w9WgXcQ" || requested["downloadMode"] != "audio" || requested["audioFormat"] != "mp3" {
+		t.Fatalf("unexpected request %v", requested)
+	}
+
+	file.(io.Seeker).Seek(6, io.SeekStart)
+	data, err := io.ReadAll(file)
+	if err != nil || string(data) != "6789" {
+		t.Fatalf("seek + read returned %q, %v", data, err)
+	}
+
+	if _, err := media.Open("nothing/123.mp4"); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("unknown services should not exist, got %v", err)
+	}
+
+	dir, err := media.Open("youtube")
+	if err != nil {
+		t.Fatalf("open of a directory failed: %v", err)
+	}
+	if entries, err := dir.(fs.ReadDirFile).ReadDir(10); len(entries) != 0 || err != io.EOF {
+		t.Fatalf("ReadDir(10) should return io.EOF, got %v, %v", entries, err)
+	}
+	if entries, err := fs.ReadDir(media, "youtube"); len(entries) != 0 || err != nil {
+		t.Fatalf("fs.ReadDir should succeed empty, got %v, %v", entries, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	file, err = NewMediaFSContext(ctx, New(WithInstance(server.URL)), CreateDefaultSettings()).Open("youtube/dQw4w9WgXcQ.mp4")
+	if err != nil {
+		t.Fatalf("open failed: %v", err)
+	}
+	if _, err := io.ReadAll(file); !errors.Is(err, context.Canceled) {
+		t.Fatalf("reading with a canceled context should fail, got %v", err)
+	}
+}
@@ -0,0 +1,40 @@
+package gobalt
+
+import (
+	"maps"
+	"strings"
+)
+
+// WithMetadata attaches metadata chosen by the caller (like a user id, a chat id or a correlation token) to the download.
+// gobalt never reads it: it's copied unchanged to DownloadResult.Metadata (so hooks get it), to the History entry,
+// and to the reports of batches and manager jobs, so finished downloads can be mapped back to whoever asked for them.
+// Calling it several times merges the maps, later values win.
+func WithMetadata(metadata map[string]string) DownloadOption {
+	return func(c *downloadConfig) {
+		if len(metadata) == 0 {
+			return
+		}
+		if c.metadata == nil {
+			c.metadata = make(map[string]string, len(metadata))
+		}
+		maps.Copy(c.metadata, metadata)
+	}
+}
+
+// metadataEnv returns the metadata as environment variables, like GOBALT_META_CHAT_ID for "chat-id".
+func metadataEnv(metadata map[string]string) []string {
+	var env []string
+	for key, value := range metadata {
+		name := strings.Map(func(r rune) rune {
+			switch {
+			case r >= 'a' && r <= 'z':
+				return r - 'a' + 'A'
+			case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+				return r
+			}
+			return '_'
+		}, key)
+		env = append(env, "GOBALT_META_"+name+"="+value)
+	}
+	return env
+}
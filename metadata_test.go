@@ -0,0 +1,38 @@
+package gobalt
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+func TestMetadataIsCarriedThrough(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("media"))
+	}))
+	defer server.Close()
+
+	history := NewMemoryHistory()
+	var hooked map[string]string
+	hook := func(ctx context.Context, result *DownloadResult) error {
+		hooked = result.Metadata
+		return nil
+	}
+	source := "https://youtu.be/dQw4w9WgXcQ"
+	result, err := Download(server.URL, filepath.Join(t.TempDir(), "a.mp4"), WithSource(source), WithHistory(history),
+		WithMetadata(map[string]string{"user": "42"}), WithMetadata(map[string]string{"chat": "7"}), WithPostDownloadHook(hook))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Metadata["user"] != "42" || result.Metadata["chat"] != "7" || hooked["chat"] != "7" {
+		t.Fatalf("unexpected metadata %v, hook got %v", result.Metadata, hooked)
+	}
+	if entry, _, _ := history.Lookup(MediaKey(source)); entry.Metadata["user"] != "42" {
+		t.Fatalf("history entry should keep the metadata, got %+v", entry)
+	}
+	if env := metadataEnv(map[string]string{"chat-id": "7"}); len(env) != 1 || env[0] != "GOBALT_META_CHAT_ID=7" {
+		t.Fatalf("unexpected environment %v", env)
+	}
+}
@@ -0,0 +1,127 @@
+package gobalt
+
+import (
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Metrics receives measurements from a client, to export them to a monitoring system. The gobaltprom package has a
+// ready-made implementation for Prometheus. Implementations must be safe for concurrent use. See WithMetrics().
+type Metrics interface {
+	RequestFinished(instance, code string, duration time.Duration) //A request to cobalt finished, code is "ok" or the error code (see MetricCode()).
+	BytesDownloaded(host string, n int64)                          //n bytes of a file were downloaded from host.
+	JobStarted()                                                   //A job runner (like downloader.Manager) started a job, see DoJob().
+	JobFinished(err error)                                         //A job finished, err is nil if it succeeded.
+	InstanceHealth(instance string, healthy bool)                  //The server information of instance was checked.
+	DownloadStarted(host string)                                   //A file transfer from host started.
+	DownloadFinished(host string)                                  //A file transfer from host ended, successfully or not.
+}
+
+// WithMetrics makes the client report what it does to m. It can be used several times (or with WithExpvar()),
+// every Metrics gets every measurement.
+func WithMetrics(m Metrics) Option {
+	return func(c *Cobalt) {
+		switch existing := c.metrics.(type) {
+		case nil, nopMetrics:
+			c.metrics = m
+		case multiMetrics:
+			c.metrics = append(existing[:len(existing):len(existing)], m)
+		default:
+			c.metrics = multiMetrics{existing, m}
+		}
+	}
+}
+
+// MetricCode(err) returns a short code for err, usable as a metric label: "ok" for nil, the cobalt error code
+// (like "error.api.link.invalid") when there's one, and "error.unknown" otherwise.
+func MetricCode(err error) string {
+	if err == nil {
+		return "ok"
+	}
+	if code := errorCode(err); code != "" {
+		return code
+	}
+	return "error.unknown"
+}
+
+// errorCode returns the cobalt error code err starts with, or "".
+func errorCode(err error) string {
+	code, _, _ := strings.Cut(err.Error(), ":")
+	if strings.HasPrefix(code, "error.") && !strings.ContainsAny(code, " \t") {
+		return code
+	}
+	return ""
+}
+
+// instanceLabel returns the normalized url of instance, so every metric about it uses the same label.
+func instanceLabel(instance string) string {
+	if normalized, err := normalizeInstanceURL(instance); err == nil {
+		return normalized
+	}
+	return instance
+}
+
+// hostOf returns the host of link, used to label download metrics.
+func hostOf(link string) string {
+	parsed, err := url.Parse(link)
+	if err != nil {
+		return ""
+	}
+	return parsed.Host
+}
+
+type nopMetrics struct{}
+
+func (nopMetrics) RequestFinished(string, string, time.Duration) {}
+func (nopMetrics) BytesDownloaded(string, int64)                 {}
+func (nopMetrics) JobStarted()                                   {}
+func (nopMetrics) JobFinished(error)                             {}
+func (nopMetrics) InstanceHealth(string, bool)                   {}
+func (nopMetrics) DownloadStarted(string)                        {}
+func (nopMetrics) DownloadFinished(string)                       {}
+
+// multiMetrics sends every measurement to several Metrics.
+type multiMetrics []Metrics
+
+func (m multiMetrics) RequestFinished(instance, code string, duration time.Duration) {
+	for _, metrics := range m {
+		metrics.RequestFinished(instance, code, duration)
+	}
+}
+
+func (m multiMetrics) BytesDownloaded(host string, n int64) {
+	for _, metrics := range m {
+		metrics.BytesDownloaded(host, n)
+	}
+}
+
+func (m multiMetrics) JobStarted() {
+	for _, metrics := range m {
+		metrics.JobStarted()
+	}
+}
+
+func (m multiMetrics) JobFinished(err error) {
+	for _, metrics := range m {
+		metrics.JobFinished(err)
+	}
+}
+
+func (m multiMetrics) InstanceHealth(instance string, healthy bool) {
+	for _, metrics := range m {
+		metrics.InstanceHealth(instance, healthy)
+	}
+}
+
+func (m multiMetrics) DownloadStarted(host string) {
+	for _, metrics := range m {
+		metrics.DownloadStarted(host)
+	}
+}
+
+func (m multiMetrics) DownloadFinished(host string) {
+	for _, metrics := range m {
+		metrics.DownloadFinished(host)
+	}
+}
@@ -0,0 +1,38 @@
+package gobalt
+
+import "net/http"
+
+// Handler sends an HTTP request and returns its response, like http.RoundTripper.
+type Handler func(req *http.Request) (*http.Response, error)
+
+// Middleware wraps the Handler of a client to add headers, tracing, request changes or response checks to every call
+// it makes: requests to cobalt, server information, trackers and downloads. It calls next to send the request.
+//
+//	client.Use(func(next gobalt.Handler) gobalt.Handler {
+//		return func(req *http.Request) (*http.Response, error) {
+//			req.Header.Set("X-Trace-Id", traceID(req.Context()))
+//			return next(req)
+//		}
+//	})
+type Middleware func(next Handler) Handler
+
+// WithMiddleware adds middleware to the client, see Use().
+func WithMiddleware(middleware ...Middleware) Option {
+	return func(c *Cobalt) {
+		c.middleware = append(c.middleware, middleware...)
+	}
+}
+
+// Use adds middleware to c. The first middleware added is the outermost: it sees the request first and the response last.
+// Don't call it while c is sending requests.
+func (c *Cobalt) Use(middleware ...Middleware) {
+	c.middleware = append(c.middleware, middleware...)
+}
+
+// handler wraps send with the middleware of c.
+func (c *Cobalt) handler(send Handler) Handler {
+	for i := len(c.middleware) - 1; i >= 0; i-- {
+		send = c.middleware[i](send)
+	}
+	return send
+}
@@ -0,0 +1,49 @@
+package gobalt
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMiddleware(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Test") != "outer,inner" {
+			t.Errorf("%v %v: unexpected header %q", r.Method, r.URL.Path, r.Header.Get("X-Test"))
+		}
+		if r.Method == http.MethodGet {
+			w.Write([]byte(`{"cobalt":{"version":"10.5.0","services":["youtube"]}}`))
+			return
+		}
+		w.Write([]byte(`{"status":"tunnel","url":"https://example.com/tunnel"}`))
+	}))
+	defer server.Close()
+
+	var seen []string
+	tag := func(name string) Middleware {
+		return func(next Handler) Handler {
+			return func(req *http.Request) (*http.Response, error) {
+				req.Header.Set("X-Test", strings.Trim(req.Header.Get("X-Test")+","+name, ","))
+				res, err := next(req)
+				if err == nil {
+					seen = append(seen, name+" "+req.Method)
+				}
+				return res, err
+			}
+		}
+	}
+	client := New(WithInstance(server.URL), WithMiddleware(tag("outer")))
+	client.Use(tag("inner"))
+
+	options := CreateDefaultSettings()
+	options.Url = "https://youtu.be/dQw4w9WgXcQ"
+	if _, err := client.Run(context.Background(), options); err != nil {
+		t.Fatal(err)
+	}
+	want := "inner GET,outer GET,inner POST,outer POST"
+	if strings.Join(seen, ",") != want {
+		t.Fatalf("expected %v, got %v", want, seen)
+	}
+}
@@ -0,0 +1,70 @@
+package gobalt
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ErrNoUsableInstance is returned by SelectNearest() when no instance is online and open to requests without an api key.
+var ErrNoUsableInstance = errors.New("no usable instance")
+
+// Selection is the instance chosen by SelectNearest(), and why.
+type Selection struct {
+	Instance Instance      //Chosen instance.
+	RTT      time.Duration //Latency measured when the instance was probed.
+	Reason   string        //Human-readable reason, like "lowest latency in DE (42ms)".
+}
+
+// SelectNearest(instances, country) picks the instance closest to the caller: among the online instances that don't need
+// an api key, the one with the lowest measured latency (see EnrichInstances()). If country is set (an ISO 3166-1 alpha-2 code,
+// like "DE") and some instances are known to be hosted there, only those are considered.
+func SelectNearest(instances []Instance, country string) (*Selection, error) {
+	usable := slices.DeleteFunc(slices.Clone(instances), func(instance Instance) bool {
+		return !instance.Online || instance.AuthRequired
+	})
+	if len(usable) == 0 {
+		return nil, ErrNoUsableInstance
+	}
+
+	where := "among all instances"
+	if country != "" {
+		local := slices.DeleteFunc(slices.Clone(usable), func(instance Instance) bool {
+			return !strings.EqualFold(instance.Tracker.Country, country)
+		})
+		if len(local) > 0 {
+			usable, where = local, "in "+strings.ToUpper(country)
+		} else {
+			where = "among all instances, none is known to be in " + strings.ToUpper(country)
+		}
+	}
+	nearest := slices.MinFunc(usable, func(a, b Instance) int {
+		return cmp.Compare(a.Latency, b.Latency)
+	})
+	return &Selection{
+		Instance: nearest,
+		RTT:      nearest.Latency,
+		Reason:   fmt.Sprintf("lowest latency %v (%v)", where, nearest.Latency.Round(time.Millisecond)),
+	}, nil
+}
+
+// RunNearest(ctx, instances, country, options) sends the request to the instance chosen by SelectNearest(), and tells which one
+// in CobaltResponse.Selection. instances are usually made by EnrichInstances(), so the latency is measured once for many requests.
+func (c *Cobalt) RunNearest(ctx context.Context, instances []Instance, country string, options Settings) (*CobaltResponse, error) {
+	selection, err := SelectNearest(instances, country)
+	if err != nil {
+		return nil, err
+	}
+	client := *c
+	client.api = selection.Instance.API
+	media, err := client.Run(ctx, options)
+	if err != nil {
+		return nil, err
+	}
+	media.Selection = selection
+	return media, nil
+}
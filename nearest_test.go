@@ -0,0 +1,35 @@
+package gobalt
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestSelectNearest(t *testing.T) {
+	server := fakeCobalt(t, `{"status":"tunnel","url":"https://example.com/tunnel","filename":"video.mp4"}`)
+	instances := []Instance{
+		{API: "https://far.example.com", Online: true, Latency: 300 * time.Millisecond, Tracker: InstanceInfo{Country: "BR"}},
+		{API: server.URL, Online: true, Latency: 80 * time.Millisecond, Tracker: InstanceInfo{Country: "DE"}},
+		{API: "https://fast.example.com", Online: true, Latency: 10 * time.Millisecond, AuthRequired: true, Tracker: InstanceInfo{Country: "DE"}},
+		{API: "https://down.example.com", Latency: time.Millisecond},
+	}
+
+	selection, err := SelectNearest(instances, "")
+	if err != nil || selection.Instance.API != server.URL {
+		t.Fatalf("expected the fastest usable instance, got %+v, %v", selection, err)
+	}
+	if selection, _ := SelectNearest(instances, "br"); selection.Instance.API != "https://far.example.com" {
+		t.Fatalf("the country hint should win over latency, got %+v", selection)
+	}
+
+	options := CreateDefaultSettings()
+	options.Url = "https://youtu.be/dQw4w9WgXcQ"
+	media, err := New().RunNearest(context.Background(), instances, "DE", options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if media.Selection == nil || media.Selection.RTT != 80*time.Millisecond {
+		t.Fatalf("the response should tell which instance was chosen, got %+v", media.Selection)
+	}
+}
@@ -0,0 +1,52 @@
+package gobalt
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// OnionTimeout is the minimum timeout of requests to .onion addresses, which are a lot slower than the clearnet.
+// Clients with a shorter (non-zero) timeout use this one for onion urls instead.
+var OnionTimeout = 2 * time.Minute
+
+// ErrOnionNeedsProxy is returned when connecting to an .onion address without a proxy: onion services can only be reached
+// through Tor, and resolving them with the system DNS would leak which service is used. See TransportOptions.Proxy.
+var ErrOnionNeedsProxy = errors.New("onion addresses can only be reached through a Tor SOCKS5 proxy, see TransportOptions.Proxy")
+
+// IsOnion(link) reports if link (an url or a host) points to a Tor onion service.
+func IsOnion(link string) bool {
+	host := link
+	if parsed, err := url.Parse(link); err == nil && parsed.Host != "" {
+		host = parsed.Hostname()
+	} else if h, _, err := net.SplitHostPort(link); err == nil {
+		host = h
+	}
+	return strings.HasSuffix(strings.ToLower(strings.TrimSuffix(host, ".")), ".onion")
+}
+
+// do sends req with the HTTP client of c through its middleware (see Use()), giving onion urls at least OnionTimeout.
+func (c *Cobalt) do(req *http.Request) (*http.Response, error) {
+	client := c.httpClient
+	if client.Timeout > 0 && client.Timeout < OnionTimeout && IsOnion(req.URL.Host) {
+		slow := *client
+		slow.Timeout = OnionTimeout
+		client = &slow
+	}
+	return c.send(client, req)
+}
+
+// refuseOnion wraps a dial function so it never resolves or dials onion addresses directly.
+// With a proxy, the transport only dials the proxy itself, so onion hosts never get here.
+func refuseOnion(dial func(ctx context.Context, network, address string) (net.Conn, error)) func(ctx context.Context, network, address string) (net.Conn, error) {
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		if IsOnion(address) {
+			return nil, &net.OpError{Op: "dial", Net: network, Err: ErrOnionNeedsProxy}
+		}
+		return dial(ctx, network, address)
+	}
+}
@@ -0,0 +1,86 @@
+package gobalt
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// fakeSOCKS5 is a SOCKS5 proxy (no auth, CONNECT only) sending every connection to target, and reporting the requested hosts.
+func fakeSOCKS5(t *testing.T, target string) (addr string, hosts chan string) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	hosts = make(chan string, 10)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				greeting := make([]byte, 2)
+				io.ReadFull(conn, greeting)
+				io.ReadFull(conn, make([]byte, greeting[1]))
+				conn.Write([]byte{5, 0})
+
+				header := make([]byte, 4) //Version, command, reserved, address type.
+				io.ReadFull(conn, header)
+				host := ""
+				if header[3] == 3 {
+					length := make([]byte, 1)
+					io.ReadFull(conn, length)
+					name := make([]byte, length[0])
+					io.ReadFull(conn, name)
+					host = string(name)
+				}
+				io.ReadFull(conn, make([]byte, 2)) //Port.
+				hosts <- host
+
+				upstream, err := net.Dial("tcp", target)
+				if err != nil {
+					return
+				}
+				defer upstream.Close()
+				reply := []byte{5, 0, 0, 1, 0, 0, 0, 0}
+				conn.Write(binary.BigEndian.AppendUint16(reply, 0))
+				go io.Copy(upstream, conn)
+				io.Copy(conn, upstream)
+			}()
+		}
+	}()
+	return listener.Addr().String(), hosts
+}
+
+func TestOnionThroughProxy(t *testing.T) {
+	server := fakeCobalt(t, `{"status":"tunnel","url":"https://example.com/tunnel","filename":"video.mp4"}`)
+	proxy, hosts := fakeSOCKS5(t, strings.TrimPrefix(server.URL, "http://"))
+	onion := "cobaltexampleonionaddressxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxx.onion"
+
+	client := New(WithInstance(onion), WithTransportOptions(TransportOptions{Proxy: &url.URL{Scheme: "socks5", Host: proxy}}))
+	info, err := client.ServerInfo(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Cobalt.Version != "10.5.0" {
+		t.Fatalf("unexpected server info %+v", info)
+	}
+	if host := <-hosts; host != onion {
+		t.Fatalf("the onion address should be resolved by the proxy, got %q", host)
+	}
+
+	direct := &http.Client{Transport: NewTransport(TransportOptions{})}
+	if _, err := direct.Get("http://" + onion + "/"); !errors.Is(err, ErrOnionNeedsProxy) {
+		t.Fatalf("expected ErrOnionNeedsProxy without a proxy, got %v", err)
+	}
+}
@@ -0,0 +1,167 @@
+// Package otelgobalt traces gobalt clients with OpenTelemetry.
+//
+//	client := otelgobalt.New(gobalt.New(gobalt.WithInstance("https://my.instance")))
+//	media, err := client.Run(ctx, settings) //Recorded as a "gobalt.Run" span.
+//
+// Client has spans for Run, ServerInfo, Download and SelectBestInstance, with the service, the instance url
+// and the cobalt error code as attributes. Every HTTP request the client sends (including the tunnel downloads
+// of CobaltResponse.SaveTo()) is also a child span, see HTTPMiddleware().
+package otelgobalt
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/lostdusty/gobalt/v2"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ScopeName is the instrumentation scope of the tracer.
+const ScopeName = "github.com/lostdusty/gobalt/v2/otelgobalt"
+
+// Attributes set on the spans.
+const (
+	AttrService   = attribute.Key("gobalt.service")    //Service of the media url, like "youtube".
+	AttrInstance  = attribute.Key("gobalt.instance")   //Url of the cobalt instance.
+	AttrErrorCode = attribute.Key("gobalt.error_code") //Cobalt error code, like "error.api.link.invalid".
+	AttrStatus    = attribute.Key("gobalt.status")     //Status of the cobalt response, like "tunnel" or "picker".
+	AttrBytes     = attribute.Key("gobalt.bytes")      //Bytes saved by a download.
+)
+
+// Option configures a Client.
+type Option func(*config)
+
+type config struct {
+	provider trace.TracerProvider
+}
+
+// WithTracerProvider sets the provider of the tracer. Default: otel.GetTracerProvider().
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(c *config) {
+		c.provider = provider
+	}
+}
+
+// Client is a gobalt.Cobalt recording spans, create one with New(). Methods it doesn't override aren't traced,
+// but the HTTP requests they send are.
+type Client struct {
+	*gobalt.Cobalt
+	tracer trace.Tracer
+}
+
+var _ gobalt.CobaltClient = (*Client)(nil)
+
+// New wraps client, adding HTTPMiddleware() to it. nil means a client with the package-level settings, see gobalt.New().
+func New(client *gobalt.Cobalt, opts ...Option) *Client {
+	config := config{provider: otel.GetTracerProvider()}
+	for _, opt := range opts {
+		opt(&config)
+	}
+	if client == nil {
+		client = gobalt.New()
+	}
+	tracer := config.provider.Tracer(ScopeName)
+	client.Use(httpMiddleware(tracer))
+	return &Client{Cobalt: client, tracer: tracer}
+}
+
+// Run sends the request like gobalt.Cobalt.Run(), in a "gobalt.Run" span.
+func (c *Client) Run(ctx context.Context, options gobalt.Settings) (*gobalt.CobaltResponse, error) {
+	ctx, span := c.tracer.Start(ctx, "gobalt.Run", trace.WithAttributes(AttrService.String(gobalt.DetectService(options.Url))))
+	defer span.End()
+	media, err := c.Cobalt.Run(ctx, options)
+	if err != nil {
+		recordError(span, err)
+		return nil, err
+	}
+	span.SetAttributes(AttrInstance.String(media.Instance), AttrStatus.String(media.Status))
+	return media, nil
+}
+
+// ServerInfo gets the information of the instance like gobalt.Cobalt.ServerInfo(), in a "gobalt.ServerInfo" span.
+func (c *Client) ServerInfo(ctx context.Context) (*gobalt.ServerInfo, error) {
+	ctx, span := c.tracer.Start(ctx, "gobalt.ServerInfo", trace.WithAttributes(AttrInstance.String(c.Instance())))
+	defer span.End()
+	info, err := c.Cobalt.ServerInfo(ctx)
+	recordError(span, err)
+	return info, err
+}
+
+// Download saves a file like gobalt.Cobalt.Download(), in a "gobalt.Download" span.
+func (c *Client) Download(ctx context.Context, url, path string, opts ...gobalt.DownloadOption) (*gobalt.DownloadResult, error) {
+	ctx, span := c.tracer.Start(ctx, "gobalt.Download")
+	defer span.End()
+	result, err := c.Cobalt.Download(ctx, url, path, opts...)
+	if err != nil {
+		recordError(span, err)
+		return nil, err
+	}
+	span.SetAttributes(AttrService.String(result.Service), AttrBytes.Int64(result.Size))
+	return result, nil
+}
+
+// SelectBestInstance chooses an instance like gobalt.Cobalt.SelectBestInstance(), in a "gobalt.SelectBestInstance" span.
+// The probes of the instances are child spans.
+func (c *Client) SelectBestInstance(ctx context.Context, criteria gobalt.InstanceCriteria) (*gobalt.Selection, error) {
+	ctx, span := c.tracer.Start(ctx, "gobalt.SelectBestInstance", trace.WithAttributes(AttrService.String(criteria.Service)))
+	defer span.End()
+	selection, err := c.Cobalt.SelectBestInstance(ctx, criteria)
+	if err != nil {
+		recordError(span, err)
+		return nil, err
+	}
+	span.SetAttributes(AttrInstance.String(selection.Instance.API), attribute.String("gobalt.reason", selection.Reason))
+	return selection, nil
+}
+
+// HTTPMiddleware returns a gobalt.Middleware recording every HTTP request of a client as a span named after
+// its method, like "HTTP GET", with the url without its query. The span ends when the response headers are received.
+func HTTPMiddleware(opts ...Option) gobalt.Middleware {
+	config := config{provider: otel.GetTracerProvider()}
+	for _, opt := range opts {
+		opt(&config)
+	}
+	return httpMiddleware(config.provider.Tracer(ScopeName))
+}
+
+func httpMiddleware(tracer trace.Tracer) gobalt.Middleware {
+	return func(next gobalt.Handler) gobalt.Handler {
+		return func(req *http.Request) (*http.Response, error) {
+			//Tunnel urls are signed in their query, so it's left out.
+			url := *req.URL
+			url.User, url.RawQuery = nil, ""
+			ctx, span := tracer.Start(req.Context(), "HTTP "+req.Method, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+				attribute.String("http.request.method", req.Method),
+				attribute.String("server.address", req.URL.Host),
+				attribute.String("url.full", url.String()),
+			))
+			defer span.End()
+			res, err := next(req.WithContext(ctx))
+			if err != nil {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+				return nil, err
+			}
+			span.SetAttributes(attribute.Int("http.response.status_code", res.StatusCode))
+			if res.StatusCode >= 500 {
+				span.SetStatus(codes.Error, res.Status)
+			}
+			return res, nil
+		}
+	}
+}
+
+// recordError records err on span, with its cobalt error code.
+func recordError(span trace.Span, err error) {
+	if err == nil {
+		return
+	}
+	if code := gobalt.MetricCode(err); code != "error.unknown" {
+		span.SetAttributes(AttrErrorCode.String(code))
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
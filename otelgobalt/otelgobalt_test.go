@@ -0,0 +1,53 @@
+package otelgobalt
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/lostdusty/gobalt/v2"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestClient(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			w.Write([]byte(`{"cobalt":{"version":"10.5.0","services":["youtube"]}}`))
+			return
+		}
+		w.Write([]byte(`{"status":"error","error":{"code":"error.api.content.video.private"}}`))
+	}))
+	defer api.Close()
+
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	client := New(gobalt.New(gobalt.WithInstance(api.URL)), WithTracerProvider(provider))
+	options := gobalt.CreateDefaultSettings()
+	options.Url = "https://youtu.be/dQw4w9WgXcQ"
+	if _, err := client.Run(context.Background(), options); err == nil {
+		t.Fatal("expected the error of the instance")
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 3 {
+		t.Fatalf("expected the server info and request spans inside gobalt.Run, got %v spans", len(spans))
+	}
+	run := spans[len(spans)-1]
+	if run.Name() != "gobalt.Run" {
+		t.Fatalf("the last span should be gobalt.Run, got %v", run.Name())
+	}
+	attributes := map[string]string{}
+	for _, kv := range run.Attributes() {
+		attributes[string(kv.Key)] = kv.Value.Emit()
+	}
+	if attributes["gobalt.service"] != "youtube" || attributes["gobalt.error_code"] != "error.api.content.video.private" {
+		t.Fatalf("unexpected attributes %v", attributes)
+	}
+	for _, span := range spans[:2] {
+		if span.Parent().SpanID() != run.SpanContext().SpanID() {
+			t.Errorf("%v should be a child of gobalt.Run", span.Name())
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package gobalt
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// fileOwner is the uid and gid set by WithOwner().
+type fileOwner struct {
+	uid, gid int
+}
+
+// WithFileMode sets the permissions of saved files, e.g. 0o644 for shared media directories or 0o600 for private ones.
+// The mode is set explicitly, so it doesn't depend on the umask of the process. By default files are created with 0o666 minus the umask.
+func WithFileMode(mode os.FileMode) DownloadOption {
+	return func(c *downloadConfig) {
+		c.fileMode = mode
+	}
+}
+
+// WithDirMode makes Download() create the missing parent directories of the file with mode (e.g. 0o755).
+// Like WithFileMode(), the umask is ignored. Without it, the parent directory must already exist.
+func WithDirMode(mode os.FileMode) DownloadOption {
+	return func(c *downloadConfig) {
+		c.dirMode = mode
+	}
+}
+
+// WithOwner sets the owner of saved files, and of directories created because of WithDirMode(). Use -1 to keep the uid or gid.
+// Changing the owner usually needs privileges, and is not supported on Windows.
+func WithOwner(uid, gid int) DownloadOption {
+	return func(c *downloadConfig) {
+		c.owner = &fileOwner{uid: uid, gid: gid}
+	}
+}
+
+// makeParentDirs creates the missing parents of path if WithDirMode() was used.
+func (c *downloadConfig) makeParentDirs(path string) error {
+	if c.dirMode == 0 {
+		return nil
+	}
+	var missing []string
+	for dir := filepath.Dir(path); ; dir = filepath.Dir(dir) {
+		if _, err := os.Stat(dir); err == nil {
+			break
+		}
+		missing = append(missing, dir)
+		if dir == filepath.Dir(dir) {
+			break
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	if err := os.MkdirAll(missing[0], c.dirMode); err != nil {
+		return err
+	}
+	for _, dir := range missing {
+		if err := c.setPermissions(dir, c.dirMode); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setFilePermissions applies WithFileMode() and WithOwner() to a saved file.
+func (c *downloadConfig) setFilePermissions(path string) error {
+	return c.setPermissions(path, c.fileMode)
+}
+
+func (c *downloadConfig) setPermissions(path string, mode os.FileMode) error {
+	if mode != 0 {
+		if err := os.Chmod(path, mode); err != nil {
+			return err
+		}
+	}
+	if c.owner != nil {
+		return os.Chown(path, c.owner.uid, c.owner.gid)
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+//go:build !windows
+
+package gobalt
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+func TestDownloadPermissions(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("not really a video"))
+	}))
+	defer server.Close()
+
+	old := syscall.Umask(0o077)
+	defer syscall.Umask(old)
+
+	root := t.TempDir()
+	path := filepath.Join(root, "youtube", "2024", "video.mp4")
+	if _, err := Download(server.URL, path, WithFileMode(0o644), WithDirMode(0o755)); err != nil {
+		t.Fatalf("download failed: %v", err)
+	}
+	for _, check := range []struct {
+		path string
+		mode os.FileMode
+	}{{path, 0o644}, {filepath.Join(root, "youtube"), 0o755}, {filepath.Join(root, "youtube", "2024"), 0o755}} {
+		info, err := os.Stat(check.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode().Perm() != check.mode {
+			t.Fatalf("%v has mode %v, want %v", check.path, info.Mode().Perm(), check.mode)
+		}
+	}
+}
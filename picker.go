@@ -0,0 +1,222 @@
+package gobalt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+type pickerType string
+
+const (
+	Photo pickerType = "photo" //A picture.
+	Video pickerType = "video" //A video.
+	Gif   pickerType = "gif"   //A short looping animation, usually sent as a video.
+)
+
+// pickerExtensions is the file extension used for picker items whose url doesn't have one, by item type.
+var pickerExtensions = map[pickerType]string{
+	Photo: ".jpg",
+	Video: ".mp4",
+	Gif:   ".gif",
+}
+
+// PickerItem is a media of a picker response (like a photo of a carousel), see CobaltResponse.Picker.
+type PickerItem struct {
+	Type  pickerType `json:"type"`  //Type of the media, either Photo, Video or Gif.
+	URL   string     `json:"url"`   //Url to download.
+	Thumb string     `json:"thumb"` //Media preview url, optional.
+
+	index  int             //Position of the item in the picker.
+	parent *CobaltResponse //Response the item comes from, <NIL> for items made by hand.
+}
+
+// bindPicker tells every picker item where it comes from, so they can be downloaded on their own.
+func (c *CobaltResponse) bindPicker() {
+	if c.Picker == nil {
+		return
+	}
+	for i := range *c.Picker {
+		(*c.Picker)[i].index, (*c.Picker)[i].parent = i, c
+	}
+}
+
+// Download(dir) saves the item to dir, named by its position and type like DownloadAllPicker() does ("02_photo.jpg").
+func (p PickerItem) Download(dir string, opts ...DownloadOption) (*DownloadResult, error) {
+	return p.DownloadContext(context.Background(), dir, opts...)
+}
+
+// DownloadContext(ctx, dir) is the same as Download(), but the download is canceled when ctx is done.
+func (p PickerItem) DownloadContext(ctx context.Context, dir string, opts ...DownloadOption) (*DownloadResult, error) {
+	client, opts := p.download(opts)
+	return client.Download(ctx, p.URL, filepath.Join(dir, pickerItemName(p.index, p.Type, p.URL)), opts...)
+}
+
+// DownloadThumb(dir) saves the thumbnail of the item to dir, named like its item ("02_video.thumb.jpg").
+// Returns an error if the item has no thumbnail.
+func (p PickerItem) DownloadThumb(dir string, opts ...DownloadOption) (*DownloadResult, error) {
+	return p.DownloadThumbContext(context.Background(), dir, opts...)
+}
+
+// DownloadThumbContext(ctx, dir) is the same as DownloadThumb(), but the download is canceled when ctx is done.
+func (p PickerItem) DownloadThumbContext(ctx context.Context, dir string, opts ...DownloadOption) (*DownloadResult, error) {
+	if p.Thumb == "" {
+		return nil, fmt.Errorf("picker item %v has no thumbnail", p.index+1)
+	}
+	client, opts := p.download(opts)
+	name := pickerThumbName(pickerItemName(p.index, p.Type, p.URL), p.Thumb)
+	return client.Download(ctx, p.Thumb, filepath.Join(dir, name), opts...)
+}
+
+// download returns the client to download the item with, and opts with the source of the picker.
+func (p PickerItem) download(opts []DownloadOption) (*Cobalt, []DownloadOption) {
+	if p.parent == nil {
+		return defaultClient(), opts
+	}
+	return p.parent.cobalt(), append([]DownloadOption{withRequest(p.parent.request)}, opts...)
+}
+
+// PickerOption changes how DownloadAllPicker() behaves.
+type PickerOption func(*pickerConfig)
+
+type pickerConfig struct {
+	concurrency int
+	thumbnails  bool
+	download    []DownloadOption
+}
+
+// WithPickerConcurrency sets how many items are downloaded at the same time. Default: 4.
+func WithPickerConcurrency(n int) PickerOption {
+	return func(c *pickerConfig) {
+		c.concurrency = n
+	}
+}
+
+// WithPickerThumbnails also saves the thumbnail of every item that has one, next to it, like "01_video.thumb.jpg".
+func WithPickerThumbnails() PickerOption {
+	return func(c *pickerConfig) {
+		c.thumbnails = true
+	}
+}
+
+// WithPickerDownload sets the options used to download every item, see Download().
+func WithPickerDownload(opts ...DownloadOption) PickerOption {
+	return func(c *pickerConfig) {
+		c.download = append(c.download, opts...)
+	}
+}
+
+// PickerResult is the result of one item of a picker, see DownloadAllPicker().
+type PickerResult struct {
+	Index     int             //Position of the item in CobaltResponse.Picker, starting at 0.
+	Type      pickerType      //Type of the item: Photo, Video or Gif.
+	URL       string          //Url the item was downloaded from.
+	Result    *DownloadResult //Saved file, <NIL> if the item failed.
+	Thumbnail string          //Path of the saved thumbnail, empty if there's none or WithPickerThumbnails() wasn't used.
+	Err       error           //Why the item failed, <NIL> if it succeeded. A thumbnail that failed doesn't fail the item.
+}
+
+// DownloadAllPicker(dir) downloads every item of a picker response (like a carousel of photos) to dir, at the same time,
+// naming them by position and type like "01_photo.jpg". It returns the result of every item in the original order,
+// and the errors of the failed items joined together.
+//
+// With WithHistory(), the picker counts as a single media: it's skipped as a whole, and recorded once every item is saved.
+func (c *CobaltResponse) DownloadAllPicker(dir string, opts ...PickerOption) ([]PickerResult, error) {
+	return c.DownloadAllPickerContext(context.Background(), dir, opts...)
+}
+
+// DownloadAllPickerContext(ctx, dir) is the same as DownloadAllPicker(), but the downloads are canceled when ctx is done.
+func (c *CobaltResponse) DownloadAllPickerContext(ctx context.Context, dir string, opts ...PickerOption) ([]PickerResult, error) {
+	if c.Status != "picker" || c.Picker == nil {
+		return nil, fmt.Errorf("response with status %v has no picker items", c.Status)
+	}
+	config := pickerConfig{concurrency: 4}
+	for _, opt := range opts {
+		opt(&config)
+	}
+	downloadOpts := append([]DownloadOption{withRequest(c.request)}, config.download...)
+	download := newDownloadConfig(downloadOpts)
+	if err := download.checkHistory(); err != nil {
+		return nil, err
+	}
+	//Items share the link of the post, so the history is only checked and recorded once for all of them.
+	downloadOpts = append(downloadOpts, func(c *downloadConfig) { c.history = nil })
+
+	client := c.cobalt()
+	items := *c.Picker
+	results := make([]PickerResult, len(items))
+	limit := make(chan struct{}, max(config.concurrency, 1))
+	var wg sync.WaitGroup
+	for i, item := range items {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result := &results[i]
+			result.Index, result.Type, result.URL = i, item.Type, item.URL
+			select {
+			case limit <- struct{}{}:
+			case <-ctx.Done():
+				result.Err = ctx.Err()
+				return
+			}
+			defer func() { <-limit }()
+
+			name := pickerItemName(i, item.Type, item.URL)
+			result.Result, result.Err = client.Download(ctx, item.URL, filepath.Join(dir, name), downloadOpts...)
+			if result.Err != nil || !config.thumbnails || item.Thumb == "" {
+				return
+			}
+			saved, err := client.Download(ctx, item.Thumb, filepath.Join(dir, pickerThumbName(name, item.Thumb)), WithSource(c.request.Url))
+			if err != nil {
+				client.logger.Warn("failed to save a picker thumbnail", "url", item.Thumb, "error", err)
+				return
+			}
+			result.Thumbnail = saved.Path
+		}()
+	}
+	wg.Wait()
+
+	var errs []error
+	var total int64
+	for _, result := range results {
+		if result.Err != nil {
+			errs = append(errs, fmt.Errorf("item %v of %v: %w", result.Index+1, len(results), result.Err))
+		} else {
+			total += result.Result.Size
+		}
+	}
+	if len(errs) == 0 && download.history != nil {
+		errs = append(errs, download.history.Add(HistoryEntry{Key: historyKey(c.request.Url, c.request.Mode), Source: c.request.Url, Path: dir, Size: total, Time: time.Now(), Metadata: download.metadata}))
+	}
+	return results, errors.Join(errs...)
+}
+
+// pickerItemName names the item number index of a picker by its type, like "02_photo.jpg".
+func pickerItemName(index int, itemType pickerType, itemURL string) string {
+	kind := cleanPathSegment(string(itemType))
+	if kind == "" {
+		kind = "media"
+	}
+	return fmt.Sprintf("%02d_%v%v", index+1, kind, pickerExt(itemType, itemURL))
+}
+
+// pickerThumbName names the thumbnail of the item saved as name, like "02_video.thumb.jpg".
+func pickerThumbName(name, thumbURL string) string {
+	return strings.TrimSuffix(name, filepath.Ext(name)) + ".thumb" + pickerExt(Photo, thumbURL)
+}
+
+// pickerExt returns the extension of a picker item, from its url or else its type.
+func pickerExt(itemType pickerType, itemURL string) string {
+	if parsed, err := url.Parse(itemURL); err == nil {
+		if urlExt := path.Ext(parsed.Path); len(urlExt) > 1 && len(urlExt) <= 5 {
+			return "." + cleanPathSegment(strings.ToLower(urlExt[1:]))
+		}
+	}
+	return pickerExtensions[itemType]
+}
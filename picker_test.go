@@ -0,0 +1,93 @@
+package gobalt
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDownloadAllPicker(t *testing.T) {
+	var api *httptest.Server
+	api = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/missing":
+			http.NotFound(w, r)
+		case r.URL.Path != "/":
+			w.Write([]byte("file " + r.URL.Path))
+		case r.Method == http.MethodGet:
+			w.Write([]byte(`{"cobalt":{"version":"10.5.0","services":["instagram"]}}`))
+		default:
+			w.Write([]byte(`{"status":"picker","picker":[
+				{"type":"photo","url":"` + api.URL + `/a.webp"},
+				{"type":"video","url":"` + api.URL + `/stream","thumb":"` + api.URL + `/thumb.png"},
+				{"type":"gif","url":"` + api.URL + `/missing"}
+			]}`))
+		}
+	}))
+	defer api.Close()
+
+	options := CreateDefaultSettings()
+	options.Url = "https://www.instagram.com/p/C1a2B3c/"
+	media, err := New(WithInstance(api.URL)).Run(context.Background(), options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	results, err := media.DownloadAllPicker(dir, WithPickerThumbnails(), WithPickerConcurrency(2))
+	if err == nil || len(results) != 3 || results[2].Err == nil {
+		t.Fatalf("the missing gif should fail, got %v", err)
+	}
+	if results[0].Result.Path != filepath.Join(dir, "01_photo.webp") || results[1].Result.Path != filepath.Join(dir, "02_video.mp4") {
+		t.Fatalf("unexpected paths %v and %v", results[0].Result.Path, results[1].Result.Path)
+	}
+	if data, _ := os.ReadFile(results[1].Thumbnail); results[1].Thumbnail != filepath.Join(dir, "02_video.thumb.png") || string(data) != "file /thumb.png" {
+		t.Fatalf("unexpected thumbnail %v: %q", results[1].Thumbnail, data)
+	}
+	if results[0].Thumbnail != "" {
+		t.Fatalf("items without a thumbnail shouldn't have one, got %v", results[0].Thumbnail)
+	}
+}
+
+func TestPickerItem(t *testing.T) {
+	var api *httptest.Server
+	api = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path != "/":
+			w.Write([]byte("file " + r.URL.Path))
+		case r.Method == http.MethodGet:
+			w.Write([]byte(`{"cobalt":{"version":"10.5.0","services":["instagram"]}}`))
+		default:
+			w.Write([]byte(`{"status":"picker","picker":[
+				{"type":"photo","url":"` + api.URL + `/a.jpg"},
+				{"type":"video","url":"` + api.URL + `/stream","thumb":"` + api.URL + `/thumb.png"}
+			]}`))
+		}
+	}))
+	defer api.Close()
+
+	options := CreateDefaultSettings()
+	options.Url = "https://www.instagram.com/p/C1a2B3c/"
+	media, err := New(WithInstance(api.URL)).Run(context.Background(), options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	video := (*media.Picker)[1]
+	if video.Type != Video {
+		t.Fatalf("expected a video, got %v", video.Type)
+	}
+	dir := t.TempDir()
+	result, err := video.Download(dir)
+	if err != nil || result.Path != filepath.Join(dir, "02_video.mp4") || result.Source != options.Url {
+		t.Fatalf("unexpected result %+v, %v", result, err)
+	}
+	thumb, err := video.DownloadThumb(dir)
+	if err != nil || thumb.Path != filepath.Join(dir, "02_video.thumb.png") {
+		t.Fatalf("unexpected thumbnail %+v, %v", thumb, err)
+	}
+	if _, err := (*media.Picker)[0].DownloadThumb(dir); err == nil {
+		t.Fatal("items without a thumbnail should fail")
+	}
+}
@@ -0,0 +1,62 @@
+package gobalt
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// PlaylistReport is the result of DownloadPlaylist().
+type PlaylistReport struct {
+	Title string //Title of the playlist, empty if it couldn't be found.
+	*BatchReport
+}
+
+// Summary returns a short description of the report, like "Mix: 9 of 10 videos saved, 1 failed" followed by a line per failed video.
+func (r *PlaylistReport) Summary() string {
+	var b strings.Builder
+	if r.Title != "" {
+		b.WriteString(r.Title + ": ")
+	}
+	fmt.Fprintf(&b, "%v of %v videos saved, %v failed", r.Succeeded, len(r.Entries), r.Failed)
+	for _, entry := range r.Entries {
+		if entry.Err != nil {
+			fmt.Fprintf(&b, "\n%02d %v: %v", entry.Index+1, entry.Url, entry.Err)
+		}
+	}
+	return b.String()
+}
+
+// DownloadPlaylist(ctx, playlistURL, dir, settings) gets the videos of an Youtube playlist (see GetYoutubePlaylist()),
+// sends each one to cobalt with settings and saves them to dir, 4 at a time, prefixed with their position like "01 - Title.mp4".
+//
+// Failed videos don't stop the others, the report has the result of each one; an error is only returned if the playlist
+// couldn't be resolved.
+func DownloadPlaylist(ctx context.Context, playlistURL, dir string, settings Settings, opts ...DownloadOption) (*PlaylistReport, error) {
+	return defaultClient().DownloadPlaylist(ctx, playlistURL, dir, settings, opts...)
+}
+
+// DownloadPlaylist(ctx, playlistURL, dir, settings) is the same as DownloadPlaylist(), using c.
+func (c *Cobalt) DownloadPlaylist(ctx context.Context, playlistURL, dir string, settings Settings, opts ...DownloadOption) (*PlaylistReport, error) {
+	urls, err := c.YoutubePlaylist(ctx, playlistURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the playlist: %w", err)
+	}
+	report := &PlaylistReport{}
+	if report.Title, err = c.YoutubePlaylistTitle(ctx, playlistURL); err != nil {
+		c.logger.Debug("failed to get the playlist title", "playlist", playlistURL, "error", err)
+	}
+
+	items := make([]Settings, len(urls))
+	for i, url := range urls {
+		items[i] = settings
+		items[i].Url = url
+	}
+	report.BatchReport = c.DownloadBatch(ctx, items, BatchOptions{
+		Dir:      dir,
+		Numbered: true,
+		Download: opts,
+	})
+	c.logger.Info("playlist downloaded", "playlist", playlistURL, "succeeded", report.Succeeded, "failed", report.Failed)
+	return report, nil
+}
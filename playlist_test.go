@@ -0,0 +1,61 @@
+package gobalt
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDownloadPlaylist(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/api/getvideos":
+			w.Write([]byte(`["https://www.youtube.com/watch?v=aaaaaaaaaaa","https://www.youtube.com/watch?v=bbbbbbbbbbb","https://www.youtube.com/watch?v=ccccccccccc"]`))
+		case r.URL.Path == "/oembed":
+			w.Write([]byte(`{"title":"Mix"}`))
+		case r.URL.Path != "/":
+			w.Write([]byte("video " + r.URL.Path))
+		case r.Method == http.MethodGet:
+			w.Write([]byte(`{"cobalt":{"version":"10.5.0","services":["youtube"]}}`))
+		default:
+			var request struct{ Url string }
+			json.NewDecoder(r.Body).Decode(&request)
+			id := request.Url[len(request.Url)-11:]
+			if id == "bbbbbbbbbbb" {
+				w.Write([]byte(`{"status":"error","error":{"code":"error.api.content.video.private"}}`))
+				return
+			}
+			w.Write([]byte(`{"status":"tunnel","url":"` + server.URL + `/` + id + `","filename":"Video ` + id[:1] + `.mp4"}`))
+		}
+	}))
+	defer server.Close()
+	//Send the requests to the playlist service and youtube to the test server.
+	target, _ := url.Parse(server.URL)
+	httpClient := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		req.URL.Scheme, req.URL.Host = target.Scheme, target.Host
+		return http.DefaultTransport.RoundTrip(req)
+	})}
+
+	dir := t.TempDir()
+	settings := CreateDefaultSettings()
+	report, err := New(WithInstance(server.URL), WithHTTPClient(httpClient)).DownloadPlaylist(context.Background(), "https://www.youtube.com/playlist?list=PL1234", dir, settings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Title != "Mix" || report.Succeeded != 2 || report.Failed != 1 {
+		t.Fatalf("unexpected report %+v", report)
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "03 - Video c.mp4")); err != nil || string(data) != "video /ccccccccccc" {
+		t.Fatalf("the third video was not saved with its position: %v", err)
+	}
+	if summary := report.Summary(); !strings.HasPrefix(summary, "Mix: 2 of 3 videos saved, 1 failed\n02 ") {
+		t.Fatalf("unexpected summary %q", summary)
+	}
+}
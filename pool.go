@@ -0,0 +1,140 @@
+package gobalt
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// BalanceStrategy is how a Pool chooses the instance for each request.
+type BalanceStrategy int
+
+const (
+	WeightedRoundRobin BalanceStrategy = iota //Spread requests following the weight of each instance, e.g. weight 2 gets twice the requests of weight 1.
+	LeastOutstanding                          //Send the request to the instance with the fewest requests in progress (relative to its weight).
+)
+
+// PoolInstance is a cobalt instance that is part of a Pool.
+type PoolInstance struct {
+	API    string //Url of the cobalt api.
+	Weight int    //How much traffic this instance should get compared to the others. Values lower than 1 are treated as 1.
+}
+
+// Pool spreads requests between several cobalt instances (e.g. your own private backends), as an alternative to failing over one after another.
+//
+// Instances that fail with network or capacity errors get their weight lowered, so they receive less requests,
+// and slowly recover their configured weight after successful requests. It's safe for concurrent use.
+type Pool struct {
+	strategy BalanceStrategy
+
+	mu      sync.Mutex
+	members []*poolMember
+}
+
+type poolMember struct {
+	client *Cobalt //Client of the pool, sending to api.
+
+	api         string
+	weight      int //Configured weight.
+	effective   int //Weight after health adjustments, between 1 and weight.
+	current     int //Used by smooth weighted round-robin.
+	outstanding int //Requests in progress.
+}
+
+// NewPool creates a Pool with the given strategy and instances. Requests are sent with the settings of client
+// (HTTP client, user agent, api key, retry policy...), nil means gobalt.New(). Its failover isn't used, the pool replaces it.
+func NewPool(client *Cobalt, strategy BalanceStrategy, instances ...PoolInstance) *Pool {
+	if client == nil {
+		client = New()
+	}
+	pool := &Pool{strategy: strategy}
+	for _, instance := range instances {
+		weight := max(instance.Weight, 1)
+		member := *client
+		member.api, member.failover = instance.API, nil
+		pool.members = append(pool.members, &poolMember{
+			api:       instance.API,
+			client:    &member,
+			weight:    weight,
+			effective: weight,
+		})
+	}
+	return pool
+}
+
+// Run(ctx, gobalt.Settings) sends the request to the instance chosen by the pool strategy, see Cobalt.Run().
+// RequestOptions attached to ctx apply, except their Instance: the pool chooses it.
+func (p *Pool) Run(ctx context.Context, options Settings) (*CobaltResponse, error) {
+	member, err := p.acquire()
+	if err != nil {
+		return nil, err
+	}
+	if opts, _ := RequestOptionsFromContext(ctx); opts.Instance != "" {
+		ctx = ContextWithRequestOptions(ctx, RequestOptions{Instance: member.api})
+	}
+	media, err := member.client.Run(ctx, options)
+	p.release(member, err)
+	return media, err
+}
+
+// Weights returns the current (health adjusted) weight of each instance in the pool, by api url.
+func (p *Pool) Weights() map[string]int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	weights := make(map[string]int, len(p.members))
+	for _, member := range p.members {
+		weights[member.api] = member.effective
+	}
+	return weights
+}
+
+// acquire chooses the next instance and marks a request as in progress on it.
+func (p *Pool) acquire() (*poolMember, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.members) == 0 {
+		return nil, errors.New("the pool has no instances")
+	}
+
+	var chosen *poolMember
+	switch p.strategy {
+	case LeastOutstanding:
+		for _, member := range p.members {
+			//Compare outstanding/effective without dividing: a/b < c/d is the same as a*d < c*b.
+			if chosen == nil || member.outstanding*chosen.effective < chosen.outstanding*member.effective {
+				chosen = member
+			}
+		}
+	default:
+		//Smooth weighted round-robin, the same algorithm nginx uses. It avoids sending bursts to the heaviest instance.
+		total := 0
+		for _, member := range p.members {
+			member.current += member.effective
+			total += member.effective
+			if chosen == nil || member.current > chosen.current {
+				chosen = member
+			}
+		}
+		chosen.current -= total
+	}
+	chosen.outstanding++
+	return chosen, nil
+}
+
+// release marks the request as finished and adjusts the instance weight depending on the result.
+func (p *Pool) release(member *poolMember, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	member.outstanding--
+	switch {
+	case err == nil:
+		member.effective = min(member.effective+1, member.weight)
+	case isInstanceFailure(err):
+		member.effective = max(member.effective/2, 1)
+	}
+}
+
+// isInstanceFailure reports if an error means the instance itself is unhealthy, and not that the media couldn't be downloaded.
+func isInstanceFailure(err error) bool {
+	return errors.Is(err, ErrNetwork) || errors.Is(err, ErrRateLimited) || errors.Is(err, ErrServerError)
+}
@@ -0,0 +1,108 @@
+package gobalt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+// countingCobalt is like fakeCobalt, but counts the POST requests it received.
+func countingCobalt(t *testing.T, response string, posts *atomic.Int32) *httptest.Server {
+	t.Helper()
+	fake := fakeCobalt(t, response)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			posts.Add(1)
+		}
+		fake.Config.Handler.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestPoolWeightedRoundRobin(t *testing.T) {
+	var heavy, light atomic.Int32
+	tunnel := `{"status":"tunnel","url":"https://example.com/tunnel","filename":"video.mp4"}`
+	pool := NewPool(nil, WeightedRoundRobin,
+		PoolInstance{API: countingCobalt(t, tunnel, &heavy).URL, Weight: 2},
+		PoolInstance{API: countingCobalt(t, tunnel, &light).URL, Weight: 1},
+	)
+
+	options := CreateDefaultSettings()
+	options.Url = "https://youtu.be/dQw4w9WgXcQ"
+	for range 6 {
+		if _, err := pool.Run(context.Background(), options); err != nil {
+			t.Fatalf("pool run failed: %v", err)
+		}
+	}
+	if heavy.Load() != 4 || light.Load() != 2 {
+		t.Fatalf("expected a 4/2 split, got %v/%v", heavy.Load(), light.Load())
+	}
+}
+
+func TestPoolLowersWeightOnFailure(t *testing.T) {
+	var posts atomic.Int32
+	failing := countingCobalt(t, `{"status":"error","error":{"code":"error.api.capacity"}}`, &posts)
+	pool := NewPool(nil, LeastOutstanding, PoolInstance{API: failing.URL, Weight: 8})
+
+	options := CreateDefaultSettings()
+	options.Url = "https://youtu.be/dQw4w9WgXcQ"
+	if _, err := pool.Run(context.Background(), options); err == nil {
+		t.Fatal("expected the request to fail")
+	}
+	if weight := pool.Weights()[failing.URL]; weight != 4 {
+		t.Fatalf("expected weight to be halved to 4, got %v", weight)
+	}
+}
+
+func TestPoolUsesClientSettings(t *testing.T) {
+	var posts atomic.Int32
+	var authorization atomic.Value
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			w.Write([]byte(`{"cobalt":{"version":"10.5.0","services":["youtube"]}}`))
+			return
+		}
+		authorization.Store(r.Header.Get("Authorization"))
+		if posts.Add(1) == 1 {
+			w.Write([]byte(`{"status":"error","error":{"code":"error.api.capacity"}}`))
+			return
+		}
+		w.Write([]byte(`{"status":"tunnel","url":"https://example.com/tunnel","filename":"video.mp4"}`))
+	}))
+	defer server.Close()
+	t.Cleanup(func() { DefaultServerInfoCache.Forget(server.URL) })
+
+	client := New(WithAPIKey("pool-key"), WithRetry(RetryPolicy{MaxAttempts: 2}), WithClock(&fakeClock{}))
+	pool := NewPool(client, WeightedRoundRobin, PoolInstance{API: server.URL})
+	options := CreateDefaultSettings()
+	options.Url = "https://youtu.be/dQw4w9WgXcQ"
+	//The pool chooses the instance, even if the context names another one.
+	ctx := ContextWithRequestOptions(context.Background(), RequestOptions{Instance: "http://127.0.0.1:1"})
+	if _, err := pool.Run(ctx, options); err != nil {
+		t.Fatalf("the request should be retried following the policy of the client, got %v", err)
+	}
+	if posts.Load() != 2 || authorization.Load() != "Api-Key pool-key" {
+		t.Fatalf("expected 2 requests with the api key of the client, got %v with %q", posts.Load(), authorization.Load())
+	}
+}
+
+func TestIsInstanceFailure(t *testing.T) {
+	failures := map[error]bool{
+		&CobaltError{Code: "error.net.failed"}:                                     true,
+		fmt.Errorf("request failed: %w", &CobaltError{Code: "error.api.capacity"}): true,
+		&CobaltError{Code: "error.api.fetch.rate"}:                                 true,
+		&InvalidResponseError{}:                                                    true,
+		&CobaltError{Code: "error.api.link.invalid"}:                               false,
+		errors.New("error.api.capacity"):                                           false,
+	}
+	for err, want := range failures {
+		if got := isInstanceFailure(err); got != want {
+			t.Errorf("isInstanceFailure(%v) = %v, expected %v", err, got, want)
+		}
+	}
+}
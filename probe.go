@@ -0,0 +1,118 @@
+package gobalt
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// probeMedia asks the server for the size, type and name of the media at url, without downloading it.
+// It sends a HEAD request first, and if the server doesn't answer it properly (not every tunnel supports HEAD),
+// a GET for the first byte of the file only.
+func (c *Cobalt) probeMedia(ctx context.Context, url string) (*MediaInfo, error) {
+	head, err := http.NewRequestWithContext(ctx, http.MethodHead, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	head.Header.Add("User-Agent", useragent)
+	res, err := c.do(head)
+	if err == nil {
+		res.Body.Close()
+		if res.StatusCode == http.StatusOK && res.ContentLength > 0 {
+			return mediaInfoFromResponse(res, res.ContentLength), nil
+		}
+	}
+
+	ranged, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	ranged.Header.Add("User-Agent", useragent)
+	ranged.Header.Add("Range", "bytes=0-0")
+	res, err = c.do(ranged)
+	if err != nil {
+		return nil, err
+	}
+	//Closing without reading means the body (the whole file, if the server ignored Range) is never transferred.
+	res.Body.Close()
+
+	switch res.StatusCode {
+	case http.StatusPartialContent:
+		return mediaInfoFromResponse(res, sizeFromContentRange(res.Header.Get("Content-Range"))), nil
+	case http.StatusOK:
+		return mediaInfoFromResponse(res, max(res.ContentLength, 0)), nil
+	default:
+		return nil, fmt.Errorf("request failed with %v", res.Status)
+	}
+}
+
+// mediaInfoFromResponse builds a MediaInfo from the response headers.
+func mediaInfoFromResponse(res *http.Response, size int64) *MediaInfo {
+	_, params, err := mime.ParseMediaType(res.Header.Get("Content-Disposition"))
+	filename := params["filename"]
+	if err != nil || filename == "" {
+		filename = path.Base(res.Request.URL.Path)
+	}
+	return &MediaInfo{
+		Size: uint(size),
+		Name: filename,
+		Type: res.Header.Get("Content-Type"),
+	}
+}
+
+// sizeFromContentRange gets the total size from a header like "bytes 0-0/12345", returns 0 if it's unknown.
+func sizeFromContentRange(header string) int64 {
+	_, total, found := strings.Cut(header, "/")
+	if !found {
+		return 0
+	}
+	size, err := strconv.ParseInt(total, 10, 64)
+	if err != nil {
+		return 0
+	}
+	return size
+}
+
+// PreCheck decides if a file should be downloaded, using the size, type and name the server reported for it.
+// Return true and a reason to skip the download.
+type PreCheck func(info MediaInfo) (skip bool, reason string)
+
+// WithPreCheck asks the server for the size and type of the file before downloading it (see PreCheck),
+// so size-limited consumers (like chat bots) can give up without downloading anything.
+// If the download is skipped, an error wrapping ErrSkipped is returned.
+func WithPreCheck(check PreCheck) DownloadOption {
+	return func(c *downloadConfig) {
+		c.preChecks = append(c.preChecks, check)
+	}
+}
+
+// MaxSize is a PreCheck that skips files bigger than limit bytes. Files with an unknown size are downloaded.
+func MaxSize(limit int64) PreCheck {
+	return func(info MediaInfo) (bool, string) {
+		if limit > 0 && int64(info.Size) > limit {
+			return true, fmt.Sprintf("file has %v bytes, the limit is %v bytes", info.Size, limit)
+		}
+		return false, ""
+	}
+}
+
+// runPreChecks probes url and runs every PreCheck, returning an error wrapping ErrSkipped if one of them skipped it.
+func (c *downloadConfig) runPreChecks(ctx context.Context, client *Cobalt, url string) error {
+	if len(c.preChecks) == 0 {
+		return nil
+	}
+	info, err := client.probeMedia(ctx, url)
+	if err != nil {
+		return fmt.Errorf("failed to check the file before downloading: %w", err)
+	}
+	for _, check := range c.preChecks {
+		if skip, reason := check(*info); skip {
+			return fmt.Errorf("%w: %v", ErrSkipped, reason)
+		}
+	}
+	return nil
+}
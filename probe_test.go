@@ -0,0 +1,48 @@
+package gobalt
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPreCheckWithRangedGet(t *testing.T) {
+	fullDownloads := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed) //Like a tunnel without HEAD support.
+			return
+		}
+		if r.Header.Get("Range") == "" {
+			fullDownloads++
+		}
+		w.Header().Set("Content-Type", "video/mp4")
+		http.ServeContent(w, r, "video.mp4", time.Time{}, strings.NewReader(strings.Repeat("a", 100)))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	_, err := Download(server.URL, filepath.Join(dir, "video.mp4"), WithPreCheck(MaxSize(50)))
+	if !errors.Is(err, ErrSkipped) {
+		t.Fatalf("expected ErrSkipped, got %v", err)
+	}
+	if fullDownloads != 0 {
+		t.Fatalf("the file should not be downloaded, got %v full downloads", fullDownloads)
+	}
+
+	var seen MediaInfo
+	result, err := Download(server.URL, filepath.Join(dir, "video.mp4"), WithPreCheck(func(info MediaInfo) (bool, string) {
+		seen = info
+		return false, ""
+	}))
+	if err != nil || result.Size != 100 {
+		t.Fatalf("expected the file to be downloaded, got %+v (err: %v)", result, err)
+	}
+	if seen.Size != 100 || seen.Type != "video/mp4" {
+		t.Fatalf("got unexpected media info %+v", seen)
+	}
+}
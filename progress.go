@@ -0,0 +1,69 @@
+package gobalt
+
+import "io"
+
+// ProgressFunc is called while a file is downloaded, with how many bytes were written so far and the size of the file.
+// total is 0 when the server didn't send the size. It's called from the goroutine doing the download, so it should return quickly.
+type ProgressFunc func(done, total int64)
+
+// WithProgress calls fn as the download goes, so a progress bar can be shown. See ProgressFunc.
+func WithProgress(fn ProgressFunc) DownloadOption {
+	return func(c *downloadConfig) {
+		c.progress = append(c.progress, fn)
+	}
+}
+
+// progressWriter reports every write to the progress functions.
+type progressWriter struct {
+	w     io.Writer
+	fns   []ProgressFunc
+	done  int64
+	total int64
+}
+
+// trackProgress wraps w to report the progress of a download of total bytes, done of which were already written
+// (e.g. by a previous attempt), if there are progress functions.
+func (c *downloadConfig) trackProgress(w io.Writer, done, total int64) io.Writer {
+	if len(c.progress) == 0 {
+		return w
+	}
+	return &progressWriter{w: w, fns: c.progress, done: done, total: max(total, 0)}
+}
+
+func (p *progressWriter) Write(b []byte) (int, error) {
+	n, err := p.w.Write(b)
+	p.done += int64(n)
+	for _, fn := range p.fns {
+		fn(p.done, p.total)
+	}
+	return n, err
+}
+
+// DownloadWithProgress(url, path, fn) is the same as Download() with WithProgress(fn).
+func DownloadWithProgress(url, path string, fn ProgressFunc, opts ...DownloadOption) (*DownloadResult, error) {
+	return Download(url, path, append(opts[:len(opts):len(opts)], WithProgress(fn))...)
+}
+
+// ProgressReader reports what is read from an io.Reader to a ProgressFunc, for the transfers gobalt doesn't do itself,
+// like uploading a downloaded file somewhere else. Create one with NewProgressReader().
+type ProgressReader struct {
+	r     io.Reader
+	fn    ProgressFunc
+	done  int64
+	total int64
+}
+
+// NewProgressReader(r, total, fn) wraps r, calling fn after every read with how many bytes were read so far and total
+// (0 if unknown, see ProgressFunc).
+func NewProgressReader(r io.Reader, total int64, fn ProgressFunc) *ProgressReader {
+	return &ProgressReader{r: r, fn: fn, total: max(total, 0)}
+}
+
+func (p *ProgressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	if n > 0 {
+		p.done += int64(n)
+		p.fn(p.done, p.total)
+	}
+	return n, err
+}
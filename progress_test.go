@@ -0,0 +1,18 @@
+package gobalt
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestProgressReader(t *testing.T) {
+	var done, total int64
+	reader := NewProgressReader(io.LimitReader(strings.NewReader("0123456789"), 10), 10, func(d, t int64) { done, total = d, t })
+	if data, err := io.ReadAll(reader); err != nil || len(data) != 10 {
+		t.Fatalf("unexpected read %q, %v", data, err)
+	}
+	if done != 10 || total != 10 {
+		t.Fatalf("progress should end at 10/10, got %v/%v", done, total)
+	}
+}
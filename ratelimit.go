@@ -0,0 +1,99 @@
+package gobalt
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// RateLimiter keeps a client under the rate limits of cobalt instances, which limit requests per IP and may ban bots hammering them.
+// Each instance has its own token bucket, refilled at RPS requests per second and holding up to Burst requests.
+// When an instance answers error.api.rate_exceeded with Context.Limit, its bucket is paused for that many seconds and its rate halved,
+// then doubled back (up to RPS) after each quiet window. Share one between clients with WithRateLimiter(). It's safe for concurrent use.
+type RateLimiter struct {
+	RPS   float64 //Requests per second allowed to each instance.
+	Burst int     //Requests that may be sent at once after being idle, at least 1.
+	Clock Clock   //Clock used to refill the buckets, nil is SystemClock.
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+}
+
+// bucket is the token bucket of an instance.
+type bucket struct {
+	tokens float64
+	rate   float64   //Current rate, lower than RPS after a rate_exceeded answer.
+	last   time.Time //Last refill.
+	until  time.Time //No request is sent before, set by Tighten().
+	relax  time.Time //The rate may go back up after.
+	window time.Duration
+}
+
+// NewRateLimiter creates a RateLimiter allowing rps requests per second to each instance, with bursts of burst requests.
+func NewRateLimiter(rps float64, burst int) *RateLimiter {
+	return &RateLimiter{RPS: rps, Burst: burst}
+}
+
+// WithRateLimiter makes the client wait for limiter before each request to an instance, retries included. See RateLimiter.
+func WithRateLimiter(limiter *RateLimiter) Option {
+	return func(c *Cobalt) {
+		c.rateLimiter = limiter
+	}
+}
+
+// bucket returns the bucket of instance, refilled up to now. l.mu must be held.
+func (l *RateLimiter) bucket(instance string, now time.Time) *bucket {
+	if l.buckets == nil {
+		l.buckets = make(map[string]*bucket)
+	}
+	b, ok := l.buckets[instance]
+	if !ok {
+		b = &bucket{tokens: float64(max(l.Burst, 1)), rate: l.RPS, last: now}
+		l.buckets[instance] = b
+	}
+	if b.rate < l.RPS && b.window > 0 && !now.Before(b.relax) {
+		b.rate, b.relax = min(b.rate*2, l.RPS), now.Add(b.window)
+	}
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens = min(b.tokens+elapsed.Seconds()*b.rate, float64(max(l.Burst, 1)))
+		b.last = now
+	}
+	return b
+}
+
+// Wait blocks until a request may be sent to instance, or until ctx is done.
+func (l *RateLimiter) Wait(ctx context.Context, instance string) error {
+	clock := orSystemClock(l.Clock)
+	for {
+		l.mu.Lock()
+		now := clock.Now()
+		b := l.bucket(instance, now)
+		var wait time.Duration
+		switch {
+		case now.Before(b.until):
+			wait = b.until.Sub(now)
+		case l.RPS <= 0 || b.tokens >= 1:
+			//No rate means no limit, besides the pauses of Tighten().
+			b.tokens--
+			l.mu.Unlock()
+			return nil
+		default:
+			wait = time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+		}
+		l.mu.Unlock()
+		if err := clock.Sleep(ctx, wait); err != nil {
+			return err
+		}
+	}
+}
+
+// Tighten pauses requests to instance for window and halves its rate, as done when it answers error.api.rate_exceeded.
+func (l *RateLimiter) Tighten(instance string, window time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := orSystemClock(l.Clock).Now()
+	b := l.bucket(instance, now)
+	b.tokens, b.rate = 0, b.rate/2
+	b.until, b.window = now.Add(window), window
+	b.relax = b.until.Add(window)
+}
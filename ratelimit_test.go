@@ -0,0 +1,60 @@
+package gobalt
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterWait(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	limiter := &RateLimiter{RPS: 2, Burst: 2, Clock: clock}
+	for range 4 {
+		if err := limiter.Wait(context.Background(), "a"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	//The burst goes through, then one request every half second.
+	if !slices.Equal(clock.slept, []time.Duration{500 * time.Millisecond, 500 * time.Millisecond}) {
+		t.Fatalf("unexpected waits %v", clock.slept)
+	}
+	if err := limiter.Wait(context.Background(), "b"); err != nil || len(clock.slept) != 2 {
+		t.Fatalf("instances should have their own bucket, waits %v, %v", clock.slept, err)
+	}
+}
+
+func TestRateLimiterTighten(t *testing.T) {
+	limited := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet:
+			w.Write([]byte(`{"cobalt":{"version":"10.5.0","services":["youtube"]}}`))
+		case limited:
+			limited = false
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte(`{"status":"error","error":{"code":"error.api.rate_exceeded","context":{"limit":30}}}`))
+		default:
+			w.Write([]byte(`{"status":"tunnel","url":"https://example.com/a.mp4","filename":"a.mp4"}`))
+		}
+	}))
+	defer server.Close()
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	limiter := &RateLimiter{RPS: 10, Burst: 10, Clock: clock}
+	client := New(WithInstance(server.URL), WithRateLimiter(limiter), WithClock(clock))
+	options := CreateDefaultSettings()
+	options.Url = "https://youtu.be/dQw4w9WgXcQ"
+
+	if _, err := client.Run(context.Background(), options); !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("expected ErrRateLimited, got %v", err)
+	}
+	if _, err := client.Run(context.Background(), options); err != nil {
+		t.Fatal(err)
+	}
+	if len(clock.slept) == 0 || clock.slept[0] != 30*time.Second {
+		t.Fatalf("the instance should be paused for the limit it sent, waits %v", clock.slept)
+	}
+}
@@ -0,0 +1,122 @@
+package gobalt
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/mcuadros/go-version"
+)
+
+// InstanceTrackers are the urls of the instance lists used when InstanceSources.Trackers is empty.
+var InstanceTrackers = []string{"https://instances.cobalt.best/api/instances.json"}
+
+// InstanceSources tells GetInstances() where to find cobalt instances. Private fleets can run their own tracker
+// (serving the same json as instances.cobalt.best) or be listed in Static, and still be used together with the public tracker.
+type InstanceSources struct {
+	Trackers []string       //Urls of instance lists. Default: InstanceTrackers.
+	Static   []InstanceInfo //Instances always included, e.g. your own. They win over tracker entries for the same api.
+}
+
+// GetInstances(ctx, sources) fetches every tracker of sources and merges them with the static instances, keeping one entry
+// per api host. Tracker entries older than cobalt 10 are left out. A tracker that fails is skipped (and logged),
+// an error is only returned if every tracker failed and there are no static instances.
+// Lists are kept for a while by DefaultInstanceListCache, see WithInstanceListCache().
+func GetInstances(ctx context.Context, sources InstanceSources) (CobaltInstance, error) {
+	return defaultClient().GetInstances(ctx, sources)
+}
+
+// GetInstances(ctx, sources) is the same as GetInstances(), using c.
+func (c *Cobalt) GetInstances(ctx context.Context, sources InstanceSources) (CobaltInstance, error) {
+	trackers := sources.Trackers
+	if len(trackers) == 0 {
+		trackers = InstanceTrackers
+	}
+
+	merged := make(CobaltInstance, 0)
+	index := make(map[string]int) //Position in merged, by api host.
+	add := func(instance InstanceInfo, replace bool) {
+		key := instanceKey(instance.API)
+		if i, found := index[key]; found {
+			if replace || betterInstance(instance, merged[i]) {
+				merged[i] = instance
+			}
+			return
+		}
+		index[key] = len(merged)
+		merged = append(merged, instance)
+	}
+
+	var errs []error
+	for _, tracker := range trackers {
+		instances, err := c.trackerList(ctx, tracker)
+		if err != nil {
+			c.logger.Warn("failed to fetch instance tracker", "tracker", tracker, "error", err)
+			errs = append(errs, fmt.Errorf("%v: %w", tracker, err))
+			continue
+		}
+		for _, instance := range instances {
+			if version.Compare(instance.Version, "10.0.0", ">=") {
+				add(instance, false)
+			}
+		}
+	}
+	for _, instance := range sources.Static {
+		add(instance, true)
+	}
+	if len(errs) == len(trackers) && len(sources.Static) == 0 {
+		return nil, errors.Join(errs...)
+	}
+	return merged, nil
+}
+
+// trackerList returns the instance list at url, from the cache of c if it has one (see WithInstanceListCache()).
+func (c *Cobalt) trackerList(ctx context.Context, url string) (CobaltInstance, error) {
+	if c.instanceCache == nil {
+		return c.fetchTracker(ctx, url)
+	}
+	return c.instanceCache.get(ctx, c, url)
+}
+
+// fetchTracker downloads the instance list at url.
+func (c *Cobalt) fetchTracker(ctx context.Context, url string) (CobaltInstance, error) {
+	res, err := c.genericHttpRequest(ctx, url, http.MethodGet, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("tracker answered %v", res.Status)
+	}
+
+	jsonbody, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	var instances CobaltInstance
+	if err := json.Unmarshal(jsonbody, &instances); err != nil {
+		return nil, err
+	}
+	return instances, nil
+}
+
+// instanceKey identifies an instance by its api host (and path), whatever the scheme, case or trailing slash used.
+func instanceKey(api string) string {
+	parsed, err := parseHTTPURL(api)
+	if err != nil {
+		return strings.ToLower(strings.TrimSpace(api))
+	}
+	return strings.ToLower(parsed.Host) + strings.TrimSuffix(parsed.Path, "/")
+}
+
+// betterInstance reports if a should replace b when two trackers list the same instance.
+func betterInstance(a, b InstanceInfo) bool {
+	if a.Trust != b.Trust {
+		return a.Trust > b.Trust
+	}
+	return a.Score > b.Score
+}
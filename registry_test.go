@@ -0,0 +1,44 @@
+package gobalt
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGetInstancesMerge(t *testing.T) {
+	public := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[
+			{"api":"cobalt.example.com","version":"10.5.0","score":80,"trust":1},
+			{"api":"old.example.com","version":"7.15.0","score":100,"trust":1},
+			{"api":"private.example.com","version":"10.1.0","score":10,"trust":0}
+		]`))
+	}))
+	defer public.Close()
+	fleet := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"api":"https://Cobalt.example.com/","version":"10.5.0","score":95,"trust":1}]`))
+	}))
+	defer fleet.Close()
+
+	instances, err := New().GetInstances(context.Background(), InstanceSources{
+		Trackers: []string{public.URL, fleet.URL, "http://127.0.0.1:1/unreachable"},
+		Static:   []InstanceInfo{{API: "private.example.com", Name: "mine"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	byKey := make(map[string]InstanceInfo)
+	for _, instance := range instances {
+		byKey[instanceKey(instance.API)] = instance
+	}
+	if len(instances) != 2 || len(byKey) != 2 {
+		t.Fatalf("expected 2 deduplicated instances, got %+v", instances)
+	}
+	if byKey["cobalt.example.com"].Score != 95 {
+		t.Fatalf("the best tracker entry should win, got %+v", byKey["cobalt.example.com"])
+	}
+	if byKey["private.example.com"].Name != "mine" {
+		t.Fatalf("static instances should win over trackers, got %+v", byKey["private.example.com"])
+	}
+}
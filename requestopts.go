@@ -0,0 +1,68 @@
+package gobalt
+
+import (
+	"context"
+	"time"
+)
+
+// RequestOptions change how a single request is sent, overriding the settings of the client. They're attached to a context
+// with ContextWithRequestOptions(), so middleware (like an HTTP handler deciding which instance a user may use) can change
+// the behavior of (*Cobalt).Run() without changing every signature in between. Empty fields keep the client settings.
+type RequestOptions struct {
+	Instance string        //Url of the cobalt api to use instead of the client one.
+	APIKey   string        //Api key to send instead of the client one.
+	Timeout  time.Duration //Maximum time for the request, including retries.
+	NoRetry  bool          //Send the request only once, whatever the RetryPolicy of the client.
+	OnRetry  RetryFunc     //Called before the request is sent again, see WithRetry().
+}
+
+type requestOptionsKey struct{}
+
+// ContextWithRequestOptions returns a copy of ctx carrying opts. Fields set in opts override the ones already in ctx.
+func ContextWithRequestOptions(ctx context.Context, opts RequestOptions) context.Context {
+	merged, _ := RequestOptionsFromContext(ctx)
+	if opts.Instance != "" {
+		merged.Instance = opts.Instance
+	}
+	if opts.APIKey != "" {
+		merged.APIKey = opts.APIKey
+	}
+	if opts.Timeout > 0 {
+		merged.Timeout = opts.Timeout
+	}
+	merged.NoRetry = merged.NoRetry || opts.NoRetry
+	if opts.OnRetry != nil {
+		merged.OnRetry = opts.OnRetry
+	}
+	return context.WithValue(ctx, requestOptionsKey{}, merged)
+}
+
+// RequestOptionsFromContext returns the RequestOptions attached to ctx, ok is false if there are none.
+func RequestOptionsFromContext(ctx context.Context) (opts RequestOptions, ok bool) {
+	opts, ok = ctx.Value(requestOptionsKey{}).(RequestOptions)
+	return opts, ok
+}
+
+// forRequest returns the client to use for a request with ctx, applying its RequestOptions.
+// cancel must be called once the request is done.
+func (c *Cobalt) forRequest(ctx context.Context) (client *Cobalt, requestCtx context.Context, cancel context.CancelFunc) {
+	opts, ok := RequestOptionsFromContext(ctx)
+	if !ok {
+		return c, ctx, func() {}
+	}
+	copied := *c
+	if opts.Instance != "" {
+		copied.api = opts.Instance
+	}
+	if opts.APIKey != "" {
+		copied.apiKey = opts.APIKey
+	}
+	if opts.NoRetry {
+		copied.retry = RetryPolicy{}
+	}
+	if opts.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		return &copied, ctx, cancel
+	}
+	return &copied, ctx, func() {}
+}
@@ -0,0 +1,75 @@
+package gobalt
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequestOptionsFromContext(t *testing.T) {
+	var body map[string]any
+	override := versionedCobalt(t, "10.5.0", &body)
+	options := CreateDefaultSettings()
+	options.Url = "https://youtu.be/dQw4w9WgXcQ"
+
+	//The client instance doesn't exist, the request must go to the instance of the context.
+	client := New(WithInstance("http://127.0.0.1:1"))
+	ctx := ContextWithRequestOptions(context.Background(), RequestOptions{Instance: override.URL})
+	ctx = ContextWithRequestOptions(ctx, RequestOptions{NoRetry: true})
+	if _, err := client.Run(ctx, options); err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	if body["url"] != options.Url {
+		t.Fatalf("request was not sent to the instance of the context, got %v", body)
+	}
+
+	opts, ok := RequestOptionsFromContext(ctx)
+	if !ok || opts.Instance != override.URL || !opts.NoRetry {
+		t.Fatalf("options were not merged, got %+v", opts)
+	}
+	if client.Instance() != "http://127.0.0.1:1" {
+		t.Fatal("the client should not be changed")
+	}
+}
+
+func TestRunContextRequestOptions(t *testing.T) {
+	var body map[string]any
+	override := versionedCobalt(t, "10.5.0", &body)
+	options := CreateDefaultSettings()
+	options.Url = "https://youtu.be/dQw4w9WgXcQ"
+
+	//Multi-tenant use: the global instance stays untouched, each request says where to go.
+	ctx := ContextWithRequestOptions(context.Background(), RequestOptions{Instance: override.URL, APIKey: "tenant-key"})
+	if _, err := RunContext(ctx, options); err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	if body["url"] != options.Url {
+		t.Fatalf("request was not sent to the instance of the context, got %v", body)
+	}
+}
+
+func TestRunOnRequestOptions(t *testing.T) {
+	var authorization string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			w.Write([]byte(`{"cobalt":{"version":"10.5.0","services":["youtube"]}}`))
+			return
+		}
+		authorization = r.Header.Get("Authorization")
+		w.Write([]byte(`{"status":"tunnel","url":"https://example.com/tunnel","filename":"video.mp4"}`))
+	}))
+	defer server.Close()
+	t.Cleanup(func() { DefaultServerInfoCache.Forget(server.URL) })
+	options := CreateDefaultSettings()
+	options.Url = "https://youtu.be/dQw4w9WgXcQ"
+
+	//The instance given to RunOn() wins over the one of the context, the api key of the context is used.
+	ctx := ContextWithRequestOptions(context.Background(), RequestOptions{Instance: "http://127.0.0.1:1", APIKey: "tenant-key"})
+	if _, err := RunOn(ctx, server.URL, options); err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	if authorization != "Api-Key tenant-key" {
+		t.Fatalf("the api key of the context should be sent, got %q", authorization)
+	}
+}
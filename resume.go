@@ -0,0 +1,203 @@
+package gobalt
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+)
+
+// ResumeStateVersion is the version of the ResumeState format written by this version of gobalt.
+const ResumeStateVersion = 1
+
+// ErrUnsupportedResumeState is returned by UnmarshalResumeState() for states written by a newer gobalt, or that are not resume states at all.
+var ErrUnsupportedResumeState = errors.New("unsupported resume state")
+
+// ResumeState describes a download in progress. While Download() runs, the data is written to PartPath and this state is saved
+// in a ResumeStore (by default next to it, at ResumeStatePath(Path)), so other tools (and later versions of gobalt) can tell
+// what the partial file is.
+//
+// The format is stable: fields are only added, and when the meaning of a field changes Version is increased and
+// UnmarshalResumeState() migrates older states.
+type ResumeState struct {
+	Version      int       `json:"version"`                //Format version, see ResumeStateVersion.
+	URL          string    `json:"url"`                    //Url being downloaded, usually a cobalt tunnel.
+	Source       string    `json:"source,omitempty"`       //Original media url, if known. See WithSource().
+	Path         string    `json:"path"`                   //Where the file is saved once complete.
+	PartPath     string    `json:"partPath"`               //Where the data is written while downloading.
+	Size         int64     `json:"size"`                   //Bytes written to PartPath so far.
+	TotalSize    int64     `json:"totalSize,omitempty"`    //Size of the complete file, 0 if the server didn't tell.
+	ETag         string    `json:"etag,omitempty"`         //ETag sent by the server, used to check the file didn't change.
+	LastModified string    `json:"lastModified,omitempty"` //Last-Modified sent by the server.
+	Segmented    bool      `json:"segmented,omitempty"`    //PartPath is being written in segments (see WithSegments()), its length tells nothing about what was written.
+	UpdatedAt    time.Time `json:"updatedAt"`              //When the state was last saved.
+
+	store ResumeStore //Where the state is saved, nil means FileResumeStore.
+}
+
+// ResumeStore keeps the resume states of downloads, so an interrupted or paused download can be continued.
+// The default, FileResumeStore, saves them next to the partial files. See WithResumeStore().
+type ResumeStore interface {
+	LoadResumeState(path string) (*ResumeState, error) //LoadResumeState returns the state of the download to path, or an error wrapping os.ErrNotExist.
+	SaveResumeState(state *ResumeState) error          //SaveResumeState saves state, replacing any state with the same Path.
+	DeleteResumeState(path string) error               //DeleteResumeState removes the state of the download to path, if there's one.
+}
+
+// FileResumeStore is the default ResumeStore, it saves each state as json at ResumeStatePath(path).
+type FileResumeStore struct{}
+
+// WithResumeStore makes Download() keep its resume states in store instead of json files next to the partial files,
+// e.g. to keep them in a database (see the sqlitestore package). The partial files stay next to the destination.
+func WithResumeStore(store ResumeStore) DownloadOption {
+	return func(c *downloadConfig) {
+		c.resumeStore = store
+	}
+}
+
+// ResumeStatePath(path) returns where the resume state of a download to path is saved.
+func ResumeStatePath(path string) string {
+	return path + ".part.json"
+}
+
+// partPath returns where the data of a download to path is written while downloading.
+func partPath(path string) string {
+	return path + ".part"
+}
+
+// newResumeState creates the state of a download of url to path, with the validators sent by the server in res.
+func newResumeState(url, source, path string, res *http.Response) *ResumeState {
+	state := &ResumeState{
+		Version:      ResumeStateVersion,
+		URL:          url,
+		Source:       source,
+		Path:         path,
+		PartPath:     partPath(path),
+		ETag:         res.Header.Get("ETag"),
+		LastModified: res.Header.Get("Last-Modified"),
+	}
+	if size, err := strconv.ParseInt(res.Header.Get("Content-Length"), 10, 64); err == nil && size > 0 {
+		state.TotalSize = size
+	}
+	return state
+}
+
+// Marshal encodes the state as json, setting Version if it's empty.
+func (s *ResumeState) Marshal() ([]byte, error) {
+	encoded := *s
+	if encoded.Version == 0 {
+		encoded.Version = ResumeStateVersion
+	}
+	return json.MarshalIndent(encoded, "", "  ")
+}
+
+// UnmarshalResumeState(data) decodes a state made by Marshal(), migrating it from older versions of the format if needed.
+func UnmarshalResumeState(data []byte) (*ResumeState, error) {
+	var state ResumeState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupportedResumeState, err)
+	}
+	switch {
+	case state.Version < 1:
+		return nil, fmt.Errorf("%w: missing version", ErrUnsupportedResumeState)
+	case state.Version > ResumeStateVersion:
+		return nil, fmt.Errorf("%w: version %v is newer than %v", ErrUnsupportedResumeState, state.Version, ResumeStateVersion)
+	}
+	//Migrations from older versions go here, once there are any.
+	return &state, nil
+}
+
+// ReadResumeState(path) reads the resume state of a download to path saved by FileResumeStore, see ResumeStatePath().
+func ReadResumeState(path string) (*ResumeState, error) {
+	return FileResumeStore{}.LoadResumeState(path)
+}
+
+func (FileResumeStore) LoadResumeState(path string) (*ResumeState, error) {
+	data, err := os.ReadFile(ResumeStatePath(path))
+	if err != nil {
+		return nil, err
+	}
+	return UnmarshalResumeState(data)
+}
+
+// SaveResumeState writes state to ResumeStatePath(state.Path), replacing it atomically.
+func (FileResumeStore) SaveResumeState(state *ResumeState) error {
+	data, err := state.Marshal()
+	if err != nil {
+		return err
+	}
+	tmp := ResumeStatePath(state.Path) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, ResumeStatePath(state.Path))
+}
+
+func (FileResumeStore) DeleteResumeState(path string) error {
+	err := os.Remove(ResumeStatePath(path))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// Save sets UpdatedAt and saves the state in the ResumeStore of its download (FileResumeStore if it has none).
+func (s *ResumeState) Save() error {
+	s.UpdatedAt = time.Now().UTC()
+	return s.resumeStore().SaveResumeState(s)
+}
+
+// resumeStore returns where the state is saved.
+func (s *ResumeState) resumeStore() ResumeStore {
+	if s.store == nil {
+		return FileResumeStore{}
+	}
+	return s.store
+}
+
+// resumePart opens the partial file of a download of url to path. If a resume state of the same media is found in store, the file
+// is opened to be continued from its end: it's the same media if the url didn't change, or if the source is the same
+// and the state has a validator (ETag or Last-Modified) the server can check with If-Range. Otherwise a new file is created.
+func resumePart(store ResumeStore, url, source, path string) (*ResumeState, *os.File, error) {
+	fresh := &ResumeState{Version: ResumeStateVersion, URL: url, Source: source, Path: path, PartPath: partPath(path), store: store}
+	state, err := fresh.resumeStore().LoadResumeState(path)
+	if err == nil && state.PartPath == fresh.PartPath && state.sameMedia(url, source) {
+		state.store = store
+		file, err := os.OpenFile(state.PartPath, os.O_WRONLY, 0)
+		if err == nil && state.Segmented {
+			//The segments may have holes, start again.
+			state.Segmented = false
+			err = file.Truncate(0)
+		}
+		if err == nil {
+			size, err := file.Seek(0, io.SeekEnd)
+			if err == nil {
+				state.URL, state.Size = url, size
+				return state, file, nil
+			}
+			file.Close()
+		}
+	}
+	file, err := os.Create(fresh.PartPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	return fresh, file, nil
+}
+
+// sameMedia reports if the state can be used to continue a download of url, with the given source.
+func (s *ResumeState) sameMedia(url, source string) bool {
+	if s.URL == url {
+		return true
+	}
+	return source != "" && s.Source == source && (s.ETag != "" || s.LastModified != "")
+}
+
+// Remove deletes the partial file and the state, like Download() does when it fails without being paused.
+func (s *ResumeState) Remove() {
+	os.Remove(s.PartPath)
+	s.resumeStore().DeleteResumeState(s.Path)
+}
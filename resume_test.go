@@ -0,0 +1,171 @@
+package gobalt
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestResumeStateRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "video.mp4")
+	state := &ResumeState{URL: "https://example.com/tunnel", Path: path, PartPath: partPath(path), Size: 10, TotalSize: 100, ETag: `"abc"`}
+	if err := state.Save(); err != nil {
+		t.Fatalf("save failed: %v", err)
+	}
+	read, err := ReadResumeState(path)
+	if err != nil {
+		t.Fatalf("read failed: %v", err)
+	}
+	if read.Version != ResumeStateVersion || read.Size != 10 || read.TotalSize != 100 || read.ETag != `"abc"` || read.PartPath != path+".part" {
+		t.Fatalf("state changed after a round trip: %+v", read)
+	}
+
+	if _, err := UnmarshalResumeState([]byte(`{"version":99,"url":"x"}`)); !errors.Is(err, ErrUnsupportedResumeState) {
+		t.Fatalf("newer versions should be rejected, got %v", err)
+	}
+	if _, err := UnmarshalResumeState([]byte(`{"url":"x"}`)); !errors.Is(err, ErrUnsupportedResumeState) {
+		t.Fatalf("states without a version should be rejected, got %v", err)
+	}
+}
+
+func TestDownloadCleansResumeState(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "video.mp4")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("not really a video"))
+	}))
+	defer server.Close()
+	if _, err := Download(server.URL, path); err != nil {
+		t.Fatalf("download failed: %v", err)
+	}
+	for _, leftover := range []string{partPath(path), ResumeStatePath(path)} {
+		if _, err := os.Stat(leftover); !os.IsNotExist(err) {
+			t.Fatalf("%v should be removed after the download", leftover)
+		}
+	}
+}
+
+func TestDownloadCleansRenamedResumeState(t *testing.T) {
+	dir := t.TempDir()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Disposition", `attachment; filename="renamed.mp4"`)
+		w.Write([]byte("not really a video"))
+	}))
+	defer server.Close()
+	result, err := Download(server.URL+"/tunnel", dir+string(filepath.Separator))
+	if err != nil {
+		t.Fatalf("download failed: %v", err)
+	}
+	if result.Path != filepath.Join(dir, "renamed.mp4") {
+		t.Fatalf("the file should be named from Content-Disposition, got %v", result.Path)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Fatalf("only the file should be left, got %v", entries)
+	}
+}
+
+func TestDownloadResumesPartialFile(t *testing.T) {
+	source := "https://youtu.be/dQw4w9WgXcQ"
+	for _, honorRange := range []bool{true, false} {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if honorRange && r.Header.Get("Range") == "bytes=4-" && r.Header.Get("If-Range") == `"v1"` {
+				w.Header().Set("Content-Range", "bytes 4-7/8")
+				w.WriteHeader(http.StatusPartialContent)
+				w.Write([]byte("5678"))
+				return
+			}
+			w.Header().Set("ETag", `"v1"`)
+			w.Write([]byte("12345678"))
+		}))
+
+		//A download of the same media with another tunnel, stopped after 4 bytes.
+		path := filepath.Join(t.TempDir(), "video.mp4")
+		state := &ResumeState{URL: "https://old.example.com/tunnel", Source: source, Path: path, PartPath: partPath(path), Size: 4, ETag: `"v1"`}
+		os.WriteFile(state.PartPath, []byte("1234"), 0o644)
+		state.Save()
+
+		result, err := Download(server.URL+"/tunnel", path, WithSource(source))
+		server.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if data, _ := os.ReadFile(path); string(data) != "12345678" || result.Size != 8 {
+			t.Fatalf("range supported: %v, unexpected file %q (%v bytes)", honorRange, data, result.Size)
+		}
+	}
+}
+
+// memoryResumeStore is a ResumeStore for tests.
+type memoryResumeStore struct {
+	mu     sync.Mutex
+	states map[string][]byte
+}
+
+func (s *memoryResumeStore) LoadResumeState(path string) (*ResumeState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, ok := s.states[path]
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+	return UnmarshalResumeState(data)
+}
+
+func (s *memoryResumeStore) SaveResumeState(state *ResumeState) error {
+	data, err := state.Marshal()
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.states[state.Path] = data
+	return nil
+}
+
+func (s *memoryResumeStore) DeleteResumeState(path string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.states, path)
+	return nil
+}
+
+func TestDownloadWithResumeStore(t *testing.T) {
+	resumed := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Range") == "bytes=4-" {
+			resumed = true
+			w.Header().Set("Content-Range", "bytes 4-7/8")
+			w.WriteHeader(http.StatusPartialContent)
+			w.Write([]byte("5678"))
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte("12345678"))
+	}))
+	defer server.Close()
+
+	store := &memoryResumeStore{states: make(map[string][]byte)}
+	path := filepath.Join(t.TempDir(), "video.mp4")
+	state := &ResumeState{URL: server.URL, Path: path, PartPath: partPath(path), Size: 4, ETag: `"v1"`, store: store}
+	os.WriteFile(state.PartPath, []byte("1234"), 0o644)
+	if err := state.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(ResumeStatePath(path)); !os.IsNotExist(err) {
+		t.Fatal("the state should only be saved in the store")
+	}
+
+	if _, err := Download(server.URL, path, WithResumeStore(store)); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(path); !resumed || string(data) != "12345678" {
+		t.Fatalf("the download should continue from the state in the store, got %q", data)
+	}
+	if len(store.states) != 0 {
+		t.Fatalf("the state should be removed from the store, got %v", store.states)
+	}
+}
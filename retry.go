@@ -0,0 +1,124 @@
+package gobalt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"time"
+)
+
+// RetryPolicy tells a client how to retry requests that failed for a transient reason, like a rate limit,
+// an overloaded instance, a 5xx response or a network error (timeouts included). The zero value never retries.
+//
+// When an instance rate limits the client and tells for how long (Error.Context.Limit, in seconds), the next retry
+// waits at least that long, even beyond MaxBackoff.
+type RetryPolicy struct {
+	MaxAttempts    int           //How many times a request is sent at most, including the first one. 0 or 1 means no retries.
+	InitialBackoff time.Duration //Wait before the first retry, doubled after each one. Default: 1 second.
+	MaxBackoff     time.Duration //Longest wait between retries. Default: 30 seconds.
+	Jitter         float64       //Share of each wait that is random, from 0 to 1, so clients don't all retry at once. 0.2 waits 80% to 100% of the backoff.
+
+	//Budget is the total time a request can take, including every attempt and the waits between them. 0 means there's no limit.
+	//Interactive bots can use a short budget so users never wait forever, while archivers can be generous.
+	Budget time.Duration
+}
+
+// ErrRetryBudgetExhausted is returned (wrapping the last error) when RetryPolicy.Budget ran out before a request succeeded.
+var ErrRetryBudgetExhausted = errors.New("retry budget exhausted")
+
+// WithRetry sets the policy used to retry failed requests, see RetryPolicy.
+func WithRetry(policy RetryPolicy) Option {
+	return func(c *Cobalt) {
+		c.retry = policy
+	}
+}
+
+// backoff returns how long to wait before the retry number attempt (starting at 1).
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	wait, limit := p.InitialBackoff, p.MaxBackoff
+	if wait <= 0 {
+		wait = time.Second
+	}
+	if limit <= 0 {
+		limit = 30 * time.Second
+	}
+	for i := 1; i < attempt && wait < limit; i++ {
+		wait *= 2
+	}
+	wait = min(wait, limit)
+	if jitter := min(p.Jitter, 1); jitter > 0 {
+		wait -= time.Duration(rand.Float64() * jitter * float64(wait))
+	}
+	return wait
+}
+
+// rateLimitWait returns how long the instance asked to wait before sending requests again, 0 if it didn't.
+func rateLimitWait(err error) time.Duration {
+	var cobaltErr *CobaltError
+	if errors.As(err, &cobaltErr) && cobaltErr.Code == "error.api.rate_exceeded" && cobaltErr.Context.Limit > 0 {
+		return time.Duration(cobaltErr.Context.Limit) * time.Second
+	}
+	return 0
+}
+
+// retry calls do until it succeeds, fails with an error that isn't worth retrying, or the policy gives up.
+// The budget is checked with clock, but also enforced with a real deadline on ctx. onRetry, if set, is called before each wait.
+func (p RetryPolicy) retry(ctx context.Context, clock Clock, onRetry func(attempt int, wait time.Duration, err error), do func(ctx context.Context) error) error {
+	clock = orSystemClock(clock)
+	start, parent := clock.Now(), ctx
+	if p.Budget > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.Budget)
+		defer cancel()
+	}
+	exhausted := func(attempts int, err error) error {
+		return fmt.Errorf("%w after %v attempts: %w", ErrRetryBudgetExhausted, attempts, err)
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := do(ctx)
+		//The budget ran out if ctx is done, but not because of the context of the caller.
+		if err != nil && ctx.Err() != nil && parent.Err() == nil {
+			return exhausted(attempt, err)
+		}
+		if err == nil || attempt >= max(p.MaxAttempts, 1) || !isRetryable(err) {
+			return err
+		}
+		wait := max(p.backoff(attempt), rateLimitWait(err))
+		if p.Budget > 0 && clock.Now().Sub(start)+wait >= p.Budget {
+			return exhausted(attempt, err)
+		}
+		if onRetry != nil {
+			onRetry(attempt, wait, err)
+		}
+		if clock.Sleep(parent, wait) != nil {
+			return err
+		}
+	}
+}
+
+// isRetryable reports if a request that failed with err may work if sent again.
+// Network errors are retried even when they are timeouts (like http.Client.Timeout), but not when they were canceled.
+func isRetryable(err error) bool {
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	if cobaltErr := (*CobaltError)(nil); errors.As(err, &cobaltErr) && cobaltErr.Is(ErrNetwork) {
+		return true
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var invalid *InvalidResponseError
+	if errors.As(err, &invalid) {
+		return invalid.StatusCode >= 500 || invalid.StatusCode == 429 || strings.Contains(invalid.Cause, "cut in the middle")
+	}
+	code, _, _ := strings.Cut(err.Error(), ":")
+	switch code {
+	case "error.net.failed", "error.net.generic", "error.api.capacity", "error.api.rate_exceeded", "error.api.fetch.rate":
+		return true
+	}
+	return false
+}
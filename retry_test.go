@@ -0,0 +1,101 @@
+package gobalt
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// flakyCobalt is a fake instance that answers error.api.capacity to the first failures requests.
+func flakyCobalt(t *testing.T, failures int32) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			w.Write([]byte(`{"cobalt":{"version":"10.5.0","services":["youtube"]}}`))
+			return
+		}
+		if requests.Add(1) <= failures {
+			w.Write([]byte(`{"status":"error","error":{"code":"error.api.capacity"}}`))
+			return
+		}
+		w.Write([]byte(`{"status":"tunnel","url":"https://example.com/tunnel"}`))
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func TestRetry(t *testing.T) {
+	server, requests := flakyCobalt(t, 2)
+	options := CreateDefaultSettings()
+	options.Url = "https://youtu.be/dQw4w9WgXcQ"
+
+	client := New(WithInstance(server.URL), WithRetry(RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond}))
+	if _, err := client.Run(context.Background(), options); err != nil {
+		t.Fatalf("run should succeed on the third attempt: %v", err)
+	}
+	if requests.Load() != 3 {
+		t.Fatalf("expected 3 requests, got %v", requests.Load())
+	}
+}
+
+func TestRetryBudget(t *testing.T) {
+	server, requests := flakyCobalt(t, 1000)
+	options := CreateDefaultSettings()
+	options.Url = "https://youtu.be/dQw4w9WgXcQ"
+
+	client := New(WithInstance(server.URL), WithRetry(RetryPolicy{MaxAttempts: 100, InitialBackoff: 20 * time.Millisecond, Budget: 100 * time.Millisecond}))
+	start := time.Now()
+	_, err := client.Run(context.Background(), options)
+	if !errors.Is(err, ErrRetryBudgetExhausted) {
+		t.Fatalf("expected ErrRetryBudgetExhausted, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Fatalf("the budget was not respected, took %v", elapsed)
+	}
+	if requests.Load() < 2 || requests.Load() >= 100 {
+		t.Fatalf("unexpected number of attempts %v", requests.Load())
+	}
+}
+
+func TestRetryRateLimit(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			w.Write([]byte(`{"cobalt":{"version":"10.5.0","services":["youtube"]}}`))
+			return
+		}
+		if requests.Add(1) == 1 {
+			w.Write([]byte(`{"status":"error","error":{"code":"error.api.rate_exceeded","context":{"limit":60}}}`))
+			return
+		}
+		w.Write([]byte(`{"status":"tunnel","url":"https://example.com/tunnel"}`))
+	}))
+	defer server.Close()
+	options := CreateDefaultSettings()
+	options.Url = "https://youtu.be/dQw4w9WgXcQ"
+
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	policy := RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Second, MaxBackoff: 5 * time.Second, Jitter: 0.5}
+	if _, err := New(WithInstance(server.URL), WithRetry(policy), WithClock(clock)).Run(context.Background(), options); err != nil {
+		t.Fatalf("run should succeed on the second attempt: %v", err)
+	}
+	if len(clock.slept) != 1 || clock.slept[0] != time.Minute {
+		t.Fatalf("expected to wait the 60 seconds asked by the instance, waited %v", clock.slept)
+	}
+}
+
+func TestRetryTimeout(t *testing.T) {
+	timeout := newCobaltError("", "error.net.failed", "", &url.Error{Op: "Get", URL: "http://x", Err: context.DeadlineExceeded})
+	if !isRetryable(timeout) {
+		t.Errorf("timeouts of the http client should be retried")
+	}
+	if isRetryable(newCobaltError("", "error.net.failed", "", context.Canceled)) || isRetryable(context.DeadlineExceeded) {
+		t.Errorf("canceled requests and deadlines of the caller should not be retried")
+	}
+}
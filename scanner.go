@@ -0,0 +1,79 @@
+package gobalt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// ErrRejectedByScanner is returned (wrapped with the reason) by Download() when a Scanner rejected the file.
+var ErrRejectedByScanner = errors.New("file rejected by scanner")
+
+// Scanner checks a downloaded file before it's moved to its final path, e.g. with an antivirus (like clamd) or by sniffing its type.
+// path is the temporary file, which must not be modified. Return an error to reject the file: it's deleted,
+// and Download() fails with an error wrapping ErrRejectedByScanner.
+type Scanner interface {
+	Scan(ctx context.Context, path string) error
+}
+
+// ScannerFunc lets a function be used as a Scanner.
+type ScannerFunc func(ctx context.Context, path string) error
+
+func (f ScannerFunc) Scan(ctx context.Context, path string) error {
+	return f(ctx, path)
+}
+
+// WithScanner adds a Scanner that checks the file before the download is finished. Scanners run in the order they were added.
+func WithScanner(scanner Scanner) DownloadOption {
+	return func(c *downloadConfig) {
+		c.scanners = append(c.scanners, scanner)
+	}
+}
+
+// AllowContentTypes(types...) is a Scanner that sniffs the content of the file (see http.DetectContentType) and rejects it
+// unless its type is one of types. A type can be a prefix ending in "/", like "video/" or "image/". Ogg files (like
+// cobalt's ogg and opus audio) are sniffed as "application/ogg", and match "audio/ogg" and "audio/" too.
+//
+// Example: WithScanner(AllowContentTypes("video/", "audio/"))
+func AllowContentTypes(types ...string) Scanner {
+	return ScannerFunc(func(ctx context.Context, path string) error {
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		head := make([]byte, 512)
+		n, err := io.ReadFull(file, head)
+		if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+			return err
+		}
+		detected, _, _ := mime.ParseMediaType(http.DetectContentType(head[:n]))
+		candidates := []string{detected}
+		if detected == "application/ogg" {
+			candidates = append(candidates, "audio/ogg")
+		}
+		for _, allowed := range types {
+			for _, candidate := range candidates {
+				if candidate == allowed || (strings.HasSuffix(allowed, "/") && strings.HasPrefix(candidate, allowed)) {
+					return nil
+				}
+			}
+		}
+		return fmt.Errorf("content type %v is not allowed", detected)
+	})
+}
+
+// runScanners runs every Scanner on the file at path, stopping at the first rejection.
+func (c *downloadConfig) runScanners(ctx context.Context, path string) error {
+	for _, scanner := range c.scanners {
+		if err := scanner.Scan(ctx, path); err != nil {
+			return fmt.Errorf("%w: %w", ErrRejectedByScanner, err)
+		}
+	}
+	return nil
+}
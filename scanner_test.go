@@ -0,0 +1,50 @@
+package gobalt
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestScannerRejects(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("<html><body>not a video</body></html>"))
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "video.mp4")
+	_, err := Download(server.URL, path, WithScanner(AllowContentTypes("video/", "audio/")))
+	if !errors.Is(err, ErrRejectedByScanner) {
+		t.Fatalf("expected ErrRejectedByScanner, got %v", err)
+	}
+	for _, leftover := range []string{path, partPath(path), ResumeStatePath(path)} {
+		if _, err := os.Stat(leftover); !os.IsNotExist(err) {
+			t.Fatalf("%v should have been removed", leftover)
+		}
+	}
+
+	if _, err := Download(server.URL, path, WithScanner(AllowContentTypes("text/html"))); err != nil {
+		t.Fatalf("allowed content should be saved, got %v", err)
+	}
+}
+
+func TestScannerAllowsOgg(t *testing.T) {
+	dir := t.TempDir()
+	ogg := filepath.Join(dir, "audio.opus")
+	os.WriteFile(ogg, append([]byte("OggS\x00\x02"), make([]byte, 40)...), 0o644)
+
+	ctx := context.Background()
+	if err := AllowContentTypes("video/", "audio/").Scan(ctx, ogg); err != nil {
+		t.Fatalf("ogg audio should match audio/, got %v", err)
+	}
+	if err := AllowContentTypes("application/ogg").Scan(ctx, ogg); err != nil {
+		t.Fatalf("ogg audio should match its sniffed type, got %v", err)
+	}
+	if err := AllowContentTypes("video/").Scan(ctx, ogg); err == nil {
+		t.Fatal("ogg audio should not match video/")
+	}
+}
@@ -0,0 +1,146 @@
+package gobalt
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"sync"
+)
+
+// WithSegments makes Download() fetch files of at least minSize bytes with n connections at once, each one
+// downloading its own range of the file. It speeds up big downloads (like 4K videos) from servers limiting the speed
+// of each connection. It only works with servers supporting Range requests, usually redirect urls: cobalt tunnels stream
+// the file as it's made and are downloaded with a single connection, like any other file when n is less than 2.
+func WithSegments(n int, minSize int64) DownloadOption {
+	return func(c *downloadConfig) {
+		c.segments, c.minSegmentedSize = n, minSize
+	}
+}
+
+// fetchSegmented downloads state.URL to file in segments when possible, and with c.fetch() otherwise or if a segment failed.
+func (c *Cobalt) fetchSegmented(ctx context.Context, config *downloadConfig, state *ResumeState, file *os.File) error {
+	if config.segments < 2 || state.Size > 0 {
+		return c.fetch(ctx, config, state, file)
+	}
+	//Ask for the first byte only, to know the size of the file and if the server supports Range.
+	res, err := c.requestRange(ctx, state.URL, config, 0, 0, "")
+	if err != nil {
+		return c.fetch(ctx, config, state, file)
+	}
+	res.Body.Close()
+	total := sizeFromContentRange(res.Header.Get("Content-Range"))
+	if res.StatusCode != http.StatusPartialContent || total <= 0 || total < config.minSegmentedSize {
+		return c.fetch(ctx, config, state, file)
+	}
+	if config.nameFromResponse {
+		if _, params, err := mime.ParseMediaType(res.Header.Get("Content-Disposition")); err == nil {
+			config.responseName = params["filename"]
+		}
+	}
+	fresh := newResumeState(state.URL, state.Source, state.Path, res)
+	state.ETag, state.LastModified, state.TotalSize = fresh.ETag, fresh.LastModified, total
+	validator := state.ETag
+	if validator == "" {
+		validator = state.LastModified
+	}
+	//The file gets its full length before the segments are written, so the state must not let it be continued from its end.
+	state.Segmented = true
+	if err := state.Save(); err != nil {
+		return err
+	}
+	if err := file.Truncate(total); err != nil {
+		return err
+	}
+
+	n := min(int64(config.segments), total)
+	size := (total + n - 1) / n
+	segmentCtx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+	)
+	progress := &sharedProgress{fns: config.progress, total: total}
+	for start := int64(0); start < total; start += size {
+		end := min(start+size, total) - 1
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := c.fetchSegment(segmentCtx, config, state.URL, start, end, validator, file, progress); err != nil {
+				once.Do(func() {
+					firstErr = err
+					cancel(err)
+				})
+			}
+		}()
+	}
+	wg.Wait()
+	state.Segmented = false
+	if firstErr == nil {
+		state.Size = total
+		return nil
+	}
+	//Nothing tells which bytes of the file were written: empty it, so a paused download starts again when resumed.
+	if err := file.Truncate(0); err != nil {
+		return err
+	}
+	if ctx.Err() != nil {
+		return firstErr
+	}
+	//Some servers limit the connections, or fail some ranges: download the file again with a single connection.
+	c.logger.Debug("segmented download failed, retrying with one connection", "url", state.URL, "error", firstErr)
+	state.ETag, state.LastModified, state.TotalSize = "", "", 0
+	return c.fetch(ctx, config, state, file)
+}
+
+// fetchSegment downloads the bytes from start to end (included) of url, and writes them at the same place in file.
+func (c *Cobalt) fetchSegment(ctx context.Context, config *downloadConfig, url string, start, end int64, validator string, file *os.File, progress *sharedProgress) error {
+	res, err := c.requestRange(ctx, url, config, start, end, validator)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusPartialContent {
+		return fmt.Errorf("asked for bytes %v-%v, the server sent the whole file", start, end)
+	}
+
+	w, leave := config.limitBandwidth(ctx, io.NewOffsetWriter(file, start))
+	defer leave()
+	length := end - start + 1
+	written, err := io.Copy(&segmentWriter{w: w, progress: progress}, io.LimitReader(res.Body, length))
+	c.metrics.BytesDownloaded(hostOf(url), written)
+	if err == nil && written < length {
+		err = io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// sharedProgress reports the progress of the segments of a download to its ProgressFuncs, as a whole.
+type sharedProgress struct {
+	mu    sync.Mutex
+	fns   []ProgressFunc
+	done  int64
+	total int64
+}
+
+// segmentWriter writes a segment, and reports what it wrote to progress.
+type segmentWriter struct {
+	w        io.Writer
+	progress *sharedProgress
+}
+
+func (s *segmentWriter) Write(b []byte) (int, error) {
+	n, err := s.w.Write(b)
+	p := s.progress
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.done += int64(n)
+	for _, fn := range p.fns {
+		fn(p.done, p.total)
+	}
+	return n, err
+}
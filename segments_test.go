@@ -0,0 +1,104 @@
+package gobalt
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSegmentedDownload(t *testing.T) {
+	media := bytes.Repeat([]byte("0123456789"), 1000)
+	var ranges atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/tunnel" {
+			//Tunnels stream the file, and don't support Range.
+			w.Write(media)
+			return
+		}
+		if r.Header.Get("Range") != "" {
+			ranges.Add(1)
+		}
+		http.ServeContent(w, r, "video.mp4", time.Time{}, bytes.NewReader(media))
+	}))
+	defer server.Close()
+	client := New()
+	var done int64
+	progress := WithProgress(func(written, total int64) { done = written })
+
+	for _, path := range []string{"/redirect", "/tunnel"} {
+		file := filepath.Join(t.TempDir(), "video.mp4")
+		result, err := client.Download(context.Background(), server.URL+path, file, WithSegments(4, 1000), progress)
+		if err != nil {
+			t.Fatalf("%v: %v", path, err)
+		}
+		if data, _ := os.ReadFile(file); result.Size != int64(len(media)) || !bytes.Equal(data, media) {
+			t.Fatalf("%v: the file was not reassembled, got %v bytes", path, len(data))
+		}
+		if done != int64(len(media)) {
+			t.Fatalf("%v: progress should reach the size of the file, got %v", path, done)
+		}
+	}
+	//The first byte to probe the server, then the 4 segments.
+	if n := ranges.Load(); n != 5 {
+		t.Fatalf("expected 5 range requests, got %v", n)
+	}
+}
+
+func TestSegmentedDownloadPauseResume(t *testing.T) {
+	media := bytes.Repeat([]byte("0123456789"), 1000)
+	var block atomic.Bool
+	block.Store(true)
+	started := make(chan struct{}, 8)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if block.Load() && r.Header.Get("Range") != "bytes=0-0" {
+			//Hold the segments until the download is paused.
+			started <- struct{}{}
+			<-r.Context().Done()
+			return
+		}
+		http.ServeContent(w, r, "video.mp4", time.Time{}, bytes.NewReader(media))
+	}))
+	defer server.Close()
+	client := New()
+	path := filepath.Join(t.TempDir(), "video.mp4")
+
+	ctx, pause := context.WithCancelCause(context.Background())
+	go func() {
+		<-started
+		pause(ErrPaused)
+	}()
+	if _, err := client.Download(ctx, server.URL, path, WithSegments(4, 1000)); !errors.Is(err, ErrPaused) {
+		t.Fatalf("expected the download to be paused, got %v", err)
+	}
+	state, err := ReadResumeState(path)
+	if err != nil || state.Size != 0 || state.Segmented {
+		t.Fatalf("a paused segmented download should be saved as not started, got %+v, %v", state, err)
+	}
+
+	block.Store(false)
+	result, err := client.Download(context.Background(), server.URL, path, WithSegments(4, 1000))
+	if err != nil {
+		t.Fatalf("resume failed: %v", err)
+	}
+	if data, _ := os.ReadFile(path); result.Size != int64(len(media)) || !bytes.Equal(data, media) {
+		t.Fatalf("the resumed file is not the media, got %v bytes", len(data))
+	}
+
+	//A state left while the segments were written (like after a crash) must not trust the length of the part.
+	zeros := make([]byte, len(media))
+	os.WriteFile(partPath(path), zeros, 0o644)
+	(&ResumeState{URL: server.URL, Path: path, PartPath: partPath(path), TotalSize: int64(len(media)), Segmented: true}).Save()
+	if _, err := client.Download(context.Background(), server.URL, path); err != nil {
+		t.Fatalf("download after a crash failed: %v", err)
+	}
+	if data, _ := os.ReadFile(path); !bytes.Equal(data, media) {
+		t.Fatal("the zero-filled part was taken as complete")
+	}
+}
@@ -0,0 +1,165 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sort"
+
+	"github.com/lostdusty/gobalt/v2"
+	"github.com/lostdusty/gobalt/v2/downloader"
+)
+
+// Job is the answer of the /jobs endpoints.
+type Job struct {
+	ID      string              `json:"id"`              //Id of the job, used in /jobs/{id}.
+	Url     string              `json:"url"`             //Media link of the job.
+	State   downloader.JobState `json:"state"`           //queued, running, paused, done, failed or canceled.
+	Written int64               `json:"written"`         //Bytes saved so far.
+	Total   int64               `json:"total,omitempty"` //Size of the file, if known.
+	Path    string              `json:"path,omitempty"`  //Where the file was saved, once done.
+	Code    string              `json:"code,omitempty"`  //Cobalt error code, if the job failed with one.
+	Error   string              `json:"error,omitempty"` //Why the job failed, was paused or canceled.
+}
+
+// newJob describes handle.
+func newJob(handle *downloader.JobHandle) Job {
+	status := handle.Status()
+	job := Job{ID: handle.ID, Url: handle.Job.Settings.Url, State: status.State, Written: status.Written, Total: status.Total}
+	if status.State == downloader.JobDone {
+		if result, _ := handle.Result(); result != nil {
+			job.Path = result.Path
+		}
+	}
+	if status.Err != nil {
+		job.Code, job.Error = cobaltCode(status.Err), status.Err.Error()
+	}
+	return job
+}
+
+func (h *Handler) submitJob(w http.ResponseWriter, r *http.Request) {
+	if h.Manager == nil {
+		writeError(w, http.StatusNotImplemented, "", "jobs need a download directory")
+		return
+	}
+	options, ok := decodeSettings(w, r)
+	if !ok {
+		return
+	}
+	handle, err := h.Manager.Submit(downloader.Job{Settings: options, Dir: h.Dir})
+	if err != nil {
+		writeError(w, http.StatusServiceUnavailable, "", err.Error())
+		return
+	}
+	h.track(handle)
+	writeJSON(w, http.StatusAccepted, newJob(handle))
+}
+
+func (h *Handler) listJobs(w http.ResponseWriter, r *http.Request) {
+	h.mu.Lock()
+	jobs := make([]Job, 0, len(h.jobs))
+	for _, handle := range h.jobs {
+		jobs = append(jobs, newJob(handle))
+	}
+	h.mu.Unlock()
+	sort.Slice(jobs, func(i, j int) bool { return jobBefore(jobs[i].ID, jobs[j].ID) })
+	writeJSON(w, http.StatusOK, jobs)
+}
+
+func (h *Handler) getJob(w http.ResponseWriter, r *http.Request) {
+	if handle, ok := h.job(w, r); ok {
+		writeJSON(w, http.StatusOK, newJob(handle))
+	}
+}
+
+// cancelJob answers DELETE /jobs/{id}, once the job stopped.
+func (h *Handler) cancelJob(w http.ResponseWriter, r *http.Request) {
+	if handle, ok := h.job(w, r); ok {
+		handle.Cancel()
+		<-handle.Done()
+		writeJSON(w, http.StatusOK, newJob(handle))
+	}
+}
+
+// pauseJob answers POST /jobs/{id}/pause, once the job stopped.
+func (h *Handler) pauseJob(w http.ResponseWriter, r *http.Request) {
+	if handle, ok := h.job(w, r); ok {
+		handle.Pause()
+		<-handle.Done()
+		writeJSON(w, http.StatusOK, newJob(handle))
+	}
+}
+
+func (h *Handler) resumeJob(w http.ResponseWriter, r *http.Request) {
+	handle, ok := h.job(w, r)
+	if !ok {
+		return
+	}
+	resumed, err := h.Manager.Resume(handle.ID)
+	if err != nil {
+		writeError(w, http.StatusConflict, "", err.Error())
+		return
+	}
+	h.track(resumed)
+	writeJSON(w, http.StatusAccepted, newJob(resumed))
+}
+
+// track remembers handle, so its status can be asked once it's finished too. Resumed jobs replace their old handle.
+// The oldest finished jobs are forgotten when there are more than KeepJobs.
+func (h *Handler) track(handle *downloader.JobHandle) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.jobs[handle.ID] = handle
+
+	keep := h.KeepJobs
+	if keep <= 0 {
+		keep = 100
+	}
+	var finished []string
+	for id, job := range h.jobs {
+		switch job.Status().State {
+		case downloader.JobDone, downloader.JobFailed, downloader.JobCanceled:
+			finished = append(finished, id)
+		}
+	}
+	if len(finished) <= keep {
+		return
+	}
+	sort.Slice(finished, func(i, j int) bool { return jobBefore(finished[i], finished[j]) })
+	for _, id := range finished[:len(finished)-keep] {
+		delete(h.jobs, id)
+	}
+}
+
+// jobBefore reports if the job with id a was submitted before b, comparing "job-9" and "job-10" by number.
+func jobBefore(a, b string) bool {
+	return len(a) < len(b) || len(a) == len(b) && a < b
+}
+
+// job returns the job named in the path of r, or answers 404.
+func (h *Handler) job(w http.ResponseWriter, r *http.Request) (*downloader.JobHandle, bool) {
+	h.mu.Lock()
+	handle, ok := h.jobs[r.PathValue("id")]
+	h.mu.Unlock()
+	if !ok {
+		writeError(w, http.StatusNotFound, "", "no job "+r.PathValue("id"))
+	}
+	return handle, ok
+}
+
+// Shutdown stops accepting jobs and waits for the running ones, pausing them if ctx is done first. See downloader.Manager.Shutdown().
+func (h *Handler) Shutdown(ctx context.Context) error {
+	if h.Manager == nil {
+		return nil
+	}
+	return h.Manager.Shutdown(ctx)
+}
+
+// cobaltCode returns the code of the gobalt.CobaltError in err, or "" if there's none.
+func cobaltCode(err error) string {
+	var cobaltErr *gobalt.CobaltError
+	if errors.As(err, &cobaltErr) {
+		return cobaltErr.Code
+	}
+	return ""
+}
@@ -0,0 +1,127 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lostdusty/gobalt/v2"
+	"github.com/lostdusty/gobalt/v2/downloader"
+)
+
+func TestJobs(t *testing.T) {
+	cobalt := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/file":
+			w.Write([]byte("media"))
+		case r.Method == http.MethodGet:
+			w.Write([]byte(`{"cobalt":{"version":"10.5.0","services":["youtube"]}}`))
+		default:
+			w.Write([]byte(`{"status":"tunnel","url":"http://` + r.Host + `/file","filename":"video.mp4"}`))
+		}
+	}))
+	defer cobalt.Close()
+
+	dir := t.TempDir()
+	handler := New(gobalt.New(gobalt.WithInstance(cobalt.URL)), dir)
+	defer handler.Shutdown(context.Background())
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	res, err := http.Post(server.URL+"/jobs", "application/json", strings.NewReader(`{"url":"https://youtu.be/dQw4w9WgXcQ"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var job Job
+	json.NewDecoder(res.Body).Decode(&job)
+	res.Body.Close()
+	if res.StatusCode != http.StatusAccepted || job.ID == "" {
+		t.Fatalf("unexpected answer %v: %+v", res.Status, job)
+	}
+
+	for deadline := time.Now().Add(5 * time.Second); job.State != downloader.JobDone; {
+		if time.Now().After(deadline) || job.State == downloader.JobFailed {
+			t.Fatalf("the job didn't finish: %+v", job)
+		}
+		time.Sleep(10 * time.Millisecond)
+		res, err := http.Get(server.URL + "/jobs/" + job.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		json.NewDecoder(res.Body).Decode(&job)
+		res.Body.Close()
+	}
+	if job.Path != filepath.Join(dir, "video.mp4") || job.Written != 5 {
+		t.Fatalf("unexpected job %+v", job)
+	}
+	if data, _ := os.ReadFile(job.Path); string(data) != "media" {
+		t.Fatalf("file was not saved, got %q", data)
+	}
+
+	res, err = http.Get(server.URL + "/jobs/job-42")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusNotFound {
+		t.Fatalf("unknown jobs should answer 404, got %v", res.Status)
+	}
+}
+
+func TestJobsForgotten(t *testing.T) {
+	cobalt := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			w.Write([]byte(`{"cobalt":{"version":"10.5.0","services":["youtube"]}}`))
+			return
+		}
+		w.Write([]byte(`{"status":"error","error":{"code":"error.api.content.video.unavailable"}}`))
+	}))
+	defer cobalt.Close()
+
+	handler := New(gobalt.New(gobalt.WithInstance(cobalt.URL)), t.TempDir())
+	handler.KeepJobs = 1
+	defer handler.Shutdown(context.Background())
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	get := func(id string) (Job, int) {
+		res, err := http.Get(server.URL + "/jobs/" + id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+		var job Job
+		json.NewDecoder(res.Body).Decode(&job)
+		return job, res.StatusCode
+	}
+	var job Job
+	for range 3 {
+		res, err := http.Post(server.URL+"/jobs", "application/json", strings.NewReader(`{"url":"https://youtu.be/dQw4w9WgXcQ"}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		json.NewDecoder(res.Body).Decode(&job)
+		res.Body.Close()
+		for deadline := time.Now().Add(5 * time.Second); job.State != downloader.JobFailed; job, _ = get(job.ID) {
+			if time.Now().After(deadline) {
+				t.Fatalf("the job didn't fail: %+v", job)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	if job.Code != "error.api.content.video.unavailable" {
+		t.Fatalf("expected the cobalt error code, got %+v", job)
+	}
+	if _, status := get("job-1"); status != http.StatusNotFound {
+		t.Fatalf("the oldest finished job should be forgotten, got %v", status)
+	}
+	if _, status := get(job.ID); status != http.StatusOK {
+		t.Fatalf("the last job should be kept, got %v", status)
+	}
+}
@@ -0,0 +1,200 @@
+// Package server exposes gobalt as a small REST service, so applications not written in Go can use it over HTTP.
+//
+// Endpoints:
+//
+//	POST /download    Body: gobalt.Settings as json. Sends the request to cobalt and, if Dir is set, saves the file there.
+//	GET  /instances   Lists public cobalt instances, see gobalt.Cobalt.Instances().
+//	GET  /status      Information about the instance used, see gobalt.CobaltServerInfo().
+//	GET  /healthz     Health of the service, for probes: 200 if the instance is reachable, 503 if it's not. See Health.
+//
+// With a download directory, downloads can also run in the background as jobs (see downloader.Manager), answered as Job:
+//
+//	POST   /jobs              Body: gobalt.Settings as json. Queues the download, answers 202 with the new job.
+//	GET    /jobs              Lists the jobs.
+//	GET    /jobs/{id}         Status of a job, including finished ones.
+//	DELETE /jobs/{id}         Cancels a job, removing its partial file.
+//	POST   /jobs/{id}/pause   Pauses a job, keeping its partial file.
+//	POST   /jobs/{id}/resume  Continues a paused job.
+//
+// Errors are answered as {"error":{"code":"error.api...","message":"..."}}.
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/lostdusty/gobalt/v2"
+	"github.com/lostdusty/gobalt/v2/downloader"
+)
+
+// Handler is an http.Handler serving the gobalt REST api. Create one with New(), and mount it anywhere
+// (e.g. with http.StripPrefix) in your own server.
+type Handler struct {
+	Client *gobalt.Cobalt //Client used to talk to cobalt.
+	Dir    string         //Directory downloads are saved to. If empty, POST /download only returns the cobalt response.
+	//Manager runs the jobs, nil if Dir is empty. Call Shutdown() to stop it.
+	Manager *downloader.Manager
+	//KeepJobs is how many finished (done, failed or canceled) jobs are remembered for /jobs, the oldest are forgotten
+	//first. Default: 100.
+	KeepJobs int
+
+	mux       *http.ServeMux
+	downloads atomic.Int64 //Downloads in progress, reported by /healthz.
+
+	mu   sync.Mutex
+	jobs map[string]*downloader.JobHandle //Jobs submitted, by id, see KeepJobs.
+}
+
+// New creates a Handler that uses client, saving files in dir (see Handler.Dir). If client is nil, gobalt.New() is used.
+func New(client *gobalt.Cobalt, dir string) *Handler {
+	if client == nil {
+		client = gobalt.New()
+	}
+	h := &Handler{Client: client, Dir: dir, mux: http.NewServeMux(), jobs: make(map[string]*downloader.JobHandle)}
+	if dir != "" {
+		h.Manager = downloader.NewManager(client, downloader.ManagerOptions{})
+	}
+	h.mux.HandleFunc("POST /download", h.download)
+	h.mux.HandleFunc("GET /instances", h.instances)
+	h.mux.HandleFunc("GET /status", h.status)
+	h.mux.HandleFunc("GET /healthz", h.Healthz)
+	h.mux.HandleFunc("POST /jobs", h.submitJob)
+	h.mux.HandleFunc("GET /jobs", h.listJobs)
+	h.mux.HandleFunc("GET /jobs/{id}", h.getJob)
+	h.mux.HandleFunc("DELETE /jobs/{id}", h.cancelJob)
+	h.mux.HandleFunc("POST /jobs/{id}/pause", h.pauseJob)
+	h.mux.HandleFunc("POST /jobs/{id}/resume", h.resumeJob)
+	return h
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+// DownloadResult is the answer of POST /download.
+type DownloadResult struct {
+	Response *gobalt.CobaltResponse `json:"response"`       //What cobalt answered.
+	Path     string                 `json:"path,omitempty"` //Where the file was saved, empty if Handler.Dir is not set or the response is a picker.
+	Size     int64                  `json:"size,omitempty"` //Size of the saved file in bytes.
+}
+
+// decodeSettings reads the settings sent in the body of r, or answers 400.
+func decodeSettings(w http.ResponseWriter, r *http.Request) (gobalt.Settings, bool) {
+	options := gobalt.CreateDefaultSettings()
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&options); err != nil {
+		writeError(w, http.StatusBadRequest, "", "invalid request body: "+err.Error())
+		return options, false
+	}
+	if options.Url == "" {
+		writeError(w, http.StatusBadRequest, "error.api.link.missing", "no url was provided to download")
+		return options, false
+	}
+	return options, true
+}
+
+func (h *Handler) download(w http.ResponseWriter, r *http.Request) {
+	options, ok := decodeSettings(w, r)
+	if !ok {
+		return
+	}
+	h.downloads.Add(1)
+	defer h.downloads.Add(-1)
+	media, err := h.Client.Run(r.Context(), options)
+	if err != nil {
+		writeCobaltError(w, h.Client, err)
+		return
+	}
+
+	result := DownloadResult{Response: media}
+	if h.Dir != "" && media.URL != "" {
+		saved, err := media.SaveToContext(r.Context(), h.Dir)
+		if err != nil {
+			writeError(w, http.StatusBadGateway, "", err.Error())
+			return
+		}
+		result.Path, result.Size = saved.Path, saved.Size
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (h *Handler) instances(w http.ResponseWriter, r *http.Request) {
+	instances, err := h.Client.Instances(r.Context())
+	if err != nil {
+		writeError(w, http.StatusBadGateway, "", err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, instances)
+}
+
+func (h *Handler) status(w http.ResponseWriter, r *http.Request) {
+	info, err := h.Client.ServerInfo(r.Context())
+	if err != nil {
+		writeCobaltError(w, h.Client, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, info)
+}
+
+// Health is the answer of GET /healthz.
+type Health struct {
+	Status    string `json:"status"`            //"ok", or "unavailable" if the instance can't be reached.
+	Instance  string `json:"instance"`          //Url of the instance used.
+	Version   string `json:"version,omitempty"` //Version of cobalt running on the instance, if it's reachable.
+	Error     string `json:"error,omitempty"`   //Why the instance can't be reached.
+	Downloads int64  `json:"downloads"`         //Requests to POST /download in progress.
+}
+
+// Healthz answers GET /healthz. It's exported so it can also be mounted at the root of a server, where probes
+// (systemd, Kubernetes) usually look for it. The instance is checked with Client.ServerInfo(), so its cache applies.
+func (h *Handler) Healthz(w http.ResponseWriter, r *http.Request) {
+	health := Health{Status: "ok", Instance: h.Client.Instance(), Downloads: h.downloads.Load()}
+	info, err := h.Client.ServerInfo(r.Context())
+	if err != nil {
+		health.Status, health.Error = "unavailable", err.Error()
+		writeJSON(w, http.StatusServiceUnavailable, health)
+		return
+	}
+	health.Version = info.Cobalt.Version
+	writeJSON(w, http.StatusOK, health)
+}
+
+// writeCobaltError answers with the cobalt error code of err, if it has one.
+func writeCobaltError(w http.ResponseWriter, client *gobalt.Cobalt, err error) {
+	var unsupported *gobalt.UnsupportedParameterError
+	if errors.As(err, &unsupported) {
+		writeError(w, http.StatusBadRequest, "", err.Error())
+		return
+	}
+	code := cobaltCode(err)
+	if code == "" {
+		writeError(w, http.StatusBadGateway, "", err.Error())
+		return
+	}
+	status := http.StatusBadGateway
+	if strings.HasPrefix(code, "error.api.link") {
+		status = http.StatusBadRequest
+	}
+	writeError(w, status, code, client.ResolveError(err))
+}
+
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	var body struct {
+		Error struct {
+			Code    string `json:"code,omitempty"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	body.Error.Code = code
+	body.Error.Message = message
+	writeJSON(w, status, body)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
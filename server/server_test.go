@@ -0,0 +1,87 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lostdusty/gobalt/v2"
+)
+
+func TestDownloadEndpoint(t *testing.T) {
+	cobalt := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/file":
+			w.Write([]byte("media"))
+		case r.Method == http.MethodGet:
+			w.Write([]byte(`{"cobalt":{"version":"10.5.0","services":["youtube"]}}`))
+		default:
+			w.Write([]byte(`{"status":"tunnel","url":"http://` + r.Host + `/file","filename":"video.mp4"}`))
+		}
+	}))
+	defer cobalt.Close()
+
+	dir := t.TempDir()
+	server := httptest.NewServer(New(gobalt.New(gobalt.WithInstance(cobalt.URL)), dir))
+	defer server.Close()
+
+	res, err := http.Post(server.URL+"/download", "application/json", strings.NewReader(`{"url":"https://youtu.be/dQw4w9WgXcQ"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	var result DownloadResult
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil || res.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected answer %v: %v", res.Status, err)
+	}
+	if result.Path != filepath.Join(dir, "video.mp4") || result.Response.Status != "tunnel" {
+		t.Fatalf("unexpected result %+v", result)
+	}
+	if data, _ := os.ReadFile(result.Path); string(data) != "media" {
+		t.Fatalf("file was not saved, got %q", data)
+	}
+
+	res, err = http.Post(server.URL+"/download", "application/json", strings.NewReader(`{"url":""}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusBadRequest {
+		t.Fatalf("an empty url should be a bad request, got %v", res.Status)
+	}
+}
+
+func TestHealthz(t *testing.T) {
+	cobalt := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"cobalt":{"version":"10.5.0","services":["youtube"]}}`))
+	}))
+	defer cobalt.Close()
+	offline := httptest.NewServer(http.NotFoundHandler())
+	offline.Close()
+
+	check := func(instance string, want int) Health {
+		t.Helper()
+		server := httptest.NewServer(New(gobalt.New(gobalt.WithInstance(instance)), ""))
+		defer server.Close()
+		res, err := http.Get(server.URL + "/healthz")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+		var health Health
+		if err := json.NewDecoder(res.Body).Decode(&health); err != nil || res.StatusCode != want {
+			t.Fatalf("expected %v, got %v: %v", want, res.Status, err)
+		}
+		return health
+	}
+	if health := check(cobalt.URL, http.StatusOK); health.Status != "ok" || health.Version != "10.5.0" {
+		t.Fatalf("unexpected health %+v", health)
+	}
+	if health := check(offline.URL, http.StatusServiceUnavailable); health.Status != "unavailable" || health.Error == "" {
+		t.Fatalf("unexpected health %+v", health)
+	}
+}
@@ -0,0 +1,164 @@
+package gobalt
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ServerInfoCache keeps the result of CobaltServerInfo() for each instance, so Run() doesn't need to ask for it before every request.
+//
+// When a cached value is older than TTL it's stale: Get() still returns it right away, and refreshes it in the background,
+// so the first request after a quiet period doesn't pay for an extra round trip. It's safe for concurrent use.
+type ServerInfoCache struct {
+	TTL            time.Duration               //How long a value is fresh. Default: 5 minutes.
+	MaxStale       time.Duration               //How long after TTL a stale value can still be returned. 0 means there's no limit.
+	OnRefreshError func(api string, err error) //Called when a background refresh fails. The stale value is kept.
+	Clock          Clock                       //Clock used to expire values. Default: SystemClock.
+
+	mu       sync.Mutex
+	entries  map[string]*serverInfoEntry
+	inflight map[string]*serverInfoCall
+}
+
+// serverInfoCall is a fetch in progress, shared by every caller asking for the same instance at the same time.
+type serverInfoCall struct {
+	done chan struct{}
+	info *ServerInfo
+	err  error
+}
+
+type serverInfoEntry struct {
+	info       *ServerInfo
+	fetched    time.Time
+	refreshing bool
+}
+
+// DefaultServerInfoCache is the cache used by Run() and RunOn() to check if the instance is online.
+var DefaultServerInfoCache = &ServerInfoCache{TTL: 5 * time.Minute}
+
+// Get(ctx, api) returns the server information of api, from the cache if possible.
+func (c *ServerInfoCache) Get(ctx context.Context, api string) (*ServerInfo, error) {
+	return c.get(ctx, defaultClient(), api)
+}
+
+// get is like Get(), but uses client to fetch the server information when needed.
+func (c *ServerInfoCache) get(ctx context.Context, client *Cobalt, api string) (*ServerInfo, error) {
+	c.mu.Lock()
+	if c.entries == nil {
+		c.entries = make(map[string]*serverInfoEntry)
+	}
+	entry, ok := c.entries[api]
+	if ok {
+		age := orSystemClock(c.Clock).Now().Sub(entry.fetched)
+		switch {
+		case age < c.ttl():
+			c.mu.Unlock()
+			return entry.info, nil
+		case c.MaxStale == 0 || age < c.ttl()+c.MaxStale:
+			if !entry.refreshing {
+				entry.refreshing = true
+				go c.refresh(client, api)
+			}
+			c.mu.Unlock()
+			return entry.info, nil
+		}
+	}
+	//Only one request per instance at a time: concurrent callers (like many goroutines calling Run() at startup) wait for it.
+	call, ok := c.inflight[api]
+	if !ok {
+		if c.inflight == nil {
+			c.inflight = make(map[string]*serverInfoCall)
+		}
+		call = &serverInfoCall{done: make(chan struct{})}
+		c.inflight[api] = call
+		//The fetch is shared, so it must not fail because the first caller gave up.
+		go c.fetch(context.WithoutCancel(ctx), client, api, call)
+	}
+	c.mu.Unlock()
+
+	select {
+	case <-call.done:
+		return call.info, call.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// fetch runs a shared server information request, stores its result and wakes up the callers waiting for it.
+func (c *ServerInfoCache) fetch(ctx context.Context, client *Cobalt, api string, call *serverInfoCall) {
+	call.info, call.err = client.fetchServerInfo(ctx, api)
+	if call.err == nil {
+		c.store(api, call.info)
+	}
+	c.mu.Lock()
+	delete(c.inflight, api)
+	c.mu.Unlock()
+	close(call.done)
+}
+
+// Forget removes api from the cache, the next Get() will ask the server again.
+func (c *ServerInfoCache) Forget(api string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, api)
+}
+
+// refresh fetches the server information again in the background.
+func (c *ServerInfoCache) refresh(client *Cobalt, api string) {
+	info, err := client.fetchServerInfo(context.Background(), api)
+	if err != nil {
+		c.mu.Lock()
+		if entry, ok := c.entries[api]; ok {
+			entry.refreshing = false
+		}
+		c.mu.Unlock()
+		if c.OnRefreshError != nil {
+			c.OnRefreshError(api, err)
+		}
+		return
+	}
+	c.store(api, info)
+}
+
+func (c *ServerInfoCache) store(api string, info *ServerInfo) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[api] = &serverInfoEntry{info: info, fetched: orSystemClock(c.Clock).Now()}
+}
+
+func (c *ServerInfoCache) ttl() time.Duration {
+	if c.TTL <= 0 {
+		return 5 * time.Minute
+	}
+	return c.TTL
+}
+
+// WithHealthCheck(false) makes Run() send requests without getting the server information of the instance first
+// (see ServerInfoCache), for high-throughput users who know the instance is up. The version of the instance is then
+// unknown, so every request field is sent as is, and WithServiceCheck() and CobaltResponse.Server have nothing to use.
+// Default: true.
+func WithHealthCheck(enabled bool) Option {
+	return func(c *Cobalt) {
+		c.noHealthCheck = !enabled
+	}
+}
+
+// ServerInfo(ctx) returns the server information of the instance that answered, to log which instance and version served
+// the request. It's CobaltResponse.Server when Run() had it, else it's fetched through the ServerInfoCache of the client.
+func (c *CobaltResponse) ServerInfo(ctx context.Context) (*ServerInfo, error) {
+	if c.Server.Cobalt.Version != "" {
+		return &c.Server, nil
+	}
+	if c.Instance == "" {
+		return nil, errors.New("response has no instance, it was not made by Run()")
+	}
+	client := c.cobalt()
+	info, err := client.serverInfoCache.get(ctx, client, c.Instance)
+	if err != nil {
+		return nil, err
+	}
+	c.Server = *info
+	return info, nil
+}
@@ -0,0 +1,131 @@
+package gobalt
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestServerInfoCacheStaleWhileRevalidate(t *testing.T) {
+	var gets atomic.Int32
+	fake := fakeCobalt(t, "")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gets.Add(1)
+		fake.Config.Handler.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	refreshErrors := make(chan error, 1)
+	cache := &ServerInfoCache{TTL: time.Nanosecond, OnRefreshError: func(api string, err error) { refreshErrors <- err }}
+	first, err := cache.Get(context.Background(), server.URL)
+	if err != nil {
+		t.Fatalf("first Get failed: %v", err)
+	}
+
+	//The value is already stale, so it's returned right away and refreshed in the background.
+	stale, err := cache.Get(context.Background(), server.URL)
+	if err != nil || stale != first {
+		t.Fatalf("expected the cached value, got %v (err: %v)", stale, err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for gets.Load() < 2 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if gets.Load() != 2 {
+		t.Fatalf("expected a background refresh, got %v requests", gets.Load())
+	}
+
+	//Once the server is gone, the stale value is still served, and the refresh error is reported.
+	server.Close()
+	time.Sleep(10 * time.Millisecond) //Let the previous refresh finish storing its value.
+	if _, err := cache.Get(context.Background(), server.URL); err != nil {
+		t.Fatalf("expected the stale value while the server is down, got %v", err)
+	}
+	select {
+	case err := <-refreshErrors:
+		if err == nil {
+			t.Fatal("expected a refresh error")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("OnRefreshError was not called")
+	}
+}
+
+func TestServerInfoCacheConcurrent(t *testing.T) {
+	var gets atomic.Int32
+	fake := fakeCobalt(t, "")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gets.Add(1)
+		time.Sleep(50 * time.Millisecond)
+		fake.Config.Handler.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	cache := &ServerInfoCache{TTL: time.Minute}
+	client := New()
+	var wg sync.WaitGroup
+	for range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := cache.get(context.Background(), client, server.URL); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if gets.Load() != 1 {
+		t.Fatalf("expected a single server info request, got %v", gets.Load())
+	}
+}
+
+func TestHealthCheck(t *testing.T) {
+	var infos atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			infos.Add(1)
+			w.Write([]byte(`{"cobalt":{"version":"10.5.0","services":["youtube"]}}`))
+			return
+		}
+		w.Write([]byte(`{"status":"tunnel","url":"https://example.com/tunnel"}`))
+	}))
+	defer server.Close()
+	options := CreateDefaultSettings()
+	options.Url = "https://youtu.be/dQw4w9WgXcQ"
+
+	media, err := New(WithInstance(server.URL), WithHealthCheck(false)).Run(context.Background(), options)
+	if err != nil || infos.Load() != 0 || media.Server.Cobalt.Version != "" {
+		t.Fatalf("the server information should not be fetched, got %v requests: %v", infos.Load(), err)
+	}
+
+	client := New(WithInstance(server.URL))
+	for range 2 {
+		if media, err = client.Run(context.Background(), options); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if infos.Load() != 1 || media.Server.Cobalt.Version != "10.5.0" {
+		t.Fatalf("expected the cached server information in the response, got %+v after %v requests", media.Server, infos.Load())
+	}
+}
+
+func TestResponseServerInfo(t *testing.T) {
+	server := fakeCobalt(t, `{"status":"tunnel","url":"https://example.com/tunnel"}`)
+	options := CreateDefaultSettings()
+	options.Url = "https://youtu.be/dQw4w9WgXcQ"
+	media, err := New(WithInstance(server.URL), WithHealthCheck(false)).Run(context.Background(), options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if media.Instance != server.URL+"/" && media.Instance != server.URL {
+		t.Fatalf("unexpected instance %v", media.Instance)
+	}
+	info, err := media.ServerInfo(context.Background())
+	if err != nil || info.Cobalt.Version != "10.5.0" || media.Server.Cobalt.Version != "10.5.0" {
+		t.Fatalf("expected the server information to be fetched, got %+v: %v", info, err)
+	}
+}
@@ -0,0 +1,37 @@
+package gobalt
+
+import (
+	"context"
+	"slices"
+)
+
+// SupportsURL(ctx, link) reports if the instance of the default client has the service of link enabled, see Cobalt.SupportsURL().
+func SupportsURL(ctx context.Context, link string) (bool, error) {
+	return defaultClient().SupportsURL(ctx, link)
+}
+
+// SupportsURL(ctx, link) reports if the instance of c has the service of link enabled, using its server information
+// (see ServerInfoCache). When the service can't be told (DetectService() doesn't know the link, or the instance
+// doesn't list its services), it reports true, and leaves the decision to the instance.
+func (c *Cobalt) SupportsURL(ctx context.Context, link string) (bool, error) {
+	c, ctx, cancel := c.forRequest(ctx)
+	defer cancel()
+	info, err := c.serverInfoCache.get(ctx, c, c.api)
+	if err != nil {
+		return false, err
+	}
+	return supportsService(info, DetectService(link)), nil
+}
+
+// WithServiceCheck makes Run() check that the instance has the service of the link enabled before sending the request,
+// and fail right away with error.api.service.unsupported (see ErrUnsupportedService) if it doesn't. See SupportsURL().
+func WithServiceCheck(enabled bool) Option {
+	return func(c *Cobalt) {
+		c.serviceCheck = enabled
+	}
+}
+
+// supportsService reports if the instance with info has service enabled, true when it can't be told.
+func supportsService(info *ServerInfo, service string) bool {
+	return service == "" || len(info.Cobalt.Services) == 0 || slices.Contains(info.Cobalt.Services, service)
+}
@@ -0,0 +1,45 @@
+package gobalt
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestSupportsURL(t *testing.T) {
+	var posts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			w.Write([]byte(`{"cobalt":{"version":"10.5.0","services":["youtube"]}}`))
+			return
+		}
+		posts.Add(1)
+		w.Write([]byte(`{"status":"error","error":{"code":"error.api.service.disabled"}}`))
+	}))
+	defer server.Close()
+	client := New(WithInstance(server.URL), WithServiceCheck(true))
+
+	for link, want := range map[string]bool{
+		"https://youtu.be/dQw4w9WgXcQ":                     true,
+		"https://www.tiktok.com/@user/video/1234567890123": false,
+		"https://example.com/unknown":                      true,
+	} {
+		if got, err := client.SupportsURL(context.Background(), link); err != nil || got != want {
+			t.Errorf("SupportsURL(%v) = %v, %v; want %v", link, got, err, want)
+		}
+	}
+
+	options := CreateDefaultSettings()
+	options.Url = "https://www.tiktok.com/@user/video/1234567890123"
+	_, err := client.Run(context.Background(), options)
+	var cobaltErr *CobaltError
+	if !errors.Is(err, ErrUnsupportedService) || !errors.As(err, &cobaltErr) || cobaltErr.Context.Service != "tiktok" {
+		t.Fatalf("expected a local ErrUnsupportedService for tiktok, got %v", err)
+	}
+	if posts.Load() != 0 {
+		t.Fatalf("the request should not be sent to the instance")
+	}
+}
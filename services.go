@@ -0,0 +1,140 @@
+package gobalt
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// serviceHosts maps known hostnames (without "www.") to the service name cobalt uses for them.
+var serviceHosts = map[string]string{
+	"bilibili.com":      "bilibili",
+	"b23.tv":            "bilibili",
+	"bilibili.tv":       "bilibili",
+	"bsky.app":          "bsky",
+	"dailymotion.com":   "dailymotion",
+	"dai.ly":            "dailymotion",
+	"facebook.com":      "facebook",
+	"fb.watch":          "facebook",
+	"instagram.com":     "instagram",
+	"ddinstagram.com":   "instagram",
+	"loom.com":          "loom",
+	"ok.ru":             "ok",
+	"pinterest.com":     "pinterest",
+	"pin.it":            "pinterest",
+	"reddit.com":        "reddit",
+	"redd.it":           "reddit",
+	"rutube.ru":         "rutube",
+	"snapchat.com":      "snapchat",
+	"soundcloud.com":    "soundcloud",
+	"on.soundcloud.com": "soundcloud",
+	"streamable.com":    "streamable",
+	"tiktok.com":        "tiktok",
+	"vm.tiktok.com":     "tiktok",
+	"vt.tiktok.com":     "tiktok",
+	"tumblr.com":        "tumblr",
+	"clips.twitch.tv":   "twitch",
+	"twitch.tv":         "twitch",
+	"twitter.com":       "twitter",
+	"x.com":             "twitter",
+	"vxtwitter.com":     "twitter",
+	"fixvx.com":         "twitter",
+	"vimeo.com":         "vimeo",
+	"player.vimeo.com":  "vimeo",
+	"vine.co":           "vine",
+	"vk.com":            "vk",
+	"vkvideo.ru":        "vk",
+	"youtube.com":       "youtube",
+	"m.youtube.com":     "youtube",
+	"music.youtube.com": "youtube",
+	"youtu.be":          "youtube",
+	"xiaohongshu.com":   "xiaohongshu",
+	"xhslink.com":       "xiaohongshu",
+}
+
+// DetectService(url) returns the name of the service (as cobalt calls it, e.g. "youtube" or "twitter") a link belongs to.
+// Returns an empty string if the link can't be parsed or the service is unknown.
+func DetectService(link string) string {
+	parsed, err := url.Parse(strings.TrimSpace(link))
+	if err != nil {
+		return ""
+	}
+	host := strings.TrimPrefix(strings.ToLower(parsed.Hostname()), "www.")
+	for host != "" {
+		if service, ok := serviceHosts[host]; ok {
+			return service
+		}
+		//Try the parent domain, so subdomains like "old.reddit.com" or "pt.tumblr.com" still match.
+		_, parent, found := strings.Cut(host, ".")
+		if !found || !strings.Contains(parent, ".") {
+			break
+		}
+		host = parent
+	}
+	return ""
+}
+
+// Patterns used by MediaID() to find the media ID in a link path, per service.
+var mediaIDPatterns = map[string][]*regexp.Regexp{
+	"youtube": {
+		regexp.MustCompile(`^/(?:shorts|embed|live|v)/([a-zA-Z0-9_-]{11})`),
+		regexp.MustCompile(`^/([a-zA-Z0-9_-]{11})$`), //youtu.be/<id>
+	},
+	"tiktok":      {regexp.MustCompile(`/(?:video|photo)/(\d+)`)},
+	"twitter":     {regexp.MustCompile(`/status(?:es)?/(\d+)`)},
+	"instagram":   {regexp.MustCompile(`/(?:p|reels?|tv)/([a-zA-Z0-9_-]+)`)},
+	"reddit":      {regexp.MustCompile(`/comments/([a-z0-9]+)`)},
+	"vimeo":       {regexp.MustCompile(`/(?:video/)?(\d+)`)},
+	"bilibili":    {regexp.MustCompile(`/video/((?:BV|av)[a-zA-Z0-9]+)`)},
+	"twitch":      {regexp.MustCompile(`^/(?:[^/]+/clip/)?([a-zA-Z0-9_-]+)$`)},
+	"streamable":  {regexp.MustCompile(`^/(?:e/)?([a-z0-9]+)$`)},
+	"dailymotion": {regexp.MustCompile(`/video/([a-z0-9]+)`)},
+	"soundcloud":  {regexp.MustCompile(`^/([^/]+/[^/]+)$`)},
+	"pinterest":   {regexp.MustCompile(`/pin/(\d+)`)},
+	"vk":          {regexp.MustCompile(`(?:video|clip)(-?\d+_\d+)`)},
+	"rutube":      {regexp.MustCompile(`/video/([a-f0-9]{32})`)},
+}
+
+// MediaID(url) returns the service and the ID of the media a link points to, so different forms of the same link
+// (like youtu.be/ID, youtube.com/watch?v=ID and music.youtube.com/watch?v=ID) can be recognized as the same media.
+//
+// ok is false if the service is unknown or the ID couldn't be found in the link.
+func MediaID(link string) (service, id string, ok bool) {
+	service = DetectService(link)
+	if service == "" {
+		return "", "", false
+	}
+	parsed, err := url.Parse(strings.TrimSpace(link))
+	if err != nil {
+		return "", "", false
+	}
+
+	if service == "youtube" {
+		if v := parsed.Query().Get("v"); v != "" {
+			return service, v, true
+		}
+	}
+
+	path := strings.TrimSuffix(parsed.EscapedPath(), "/")
+	for _, pattern := range mediaIDPatterns[service] {
+		if match := pattern.FindStringSubmatch(path); match != nil {
+			return service, match[1], true
+		}
+	}
+	return "", "", false
+}
+
+// MediaKey(url) returns a key that identifies the media of a link, like "youtube:dQw4w9WgXcQ".
+// Links where MediaID() can't find an ID use the link itself (without fragment and with a lowercase host) as key.
+func MediaKey(link string) string {
+	if service, id, ok := MediaID(link); ok {
+		return service + ":" + id
+	}
+	parsed, err := url.Parse(strings.TrimSpace(link))
+	if err != nil {
+		return "url:" + link
+	}
+	parsed.Fragment = ""
+	parsed.Host = strings.ToLower(parsed.Host)
+	return "url:" + parsed.String()
+}
@@ -0,0 +1,39 @@
+package gobalt
+
+import "testing"
+
+func TestDetectService(t *testing.T) {
+	links := map[string]string{
+		"https://www.youtube.com/watch?v=dQw4w9WgXcQ": "youtube",
+		"https://youtu.be/dQw4w9WgXcQ":                "youtube",
+		"https://x.com/user/status/1":                 "twitter",
+		"https://old.reddit.com/r/golang/":            "reddit",
+		"https://example.com/video.mp4":               "",
+		"not a link":                                  "",
+	}
+	for link, want := range links {
+		if got := DetectService(link); got != want {
+			t.Errorf("DetectService(%v) = %q, expected %q", link, got, want)
+		}
+	}
+}
+
+func TestMediaKey(t *testing.T) {
+	sameVideo := []string{
+		"https://www.youtube.com/watch?v=dQw4w9WgXcQ&t=42",
+		"https://youtu.be/dQw4w9WgXcQ?si=share",
+		"https://music.youtube.com/watch?v=dQw4w9WgXcQ",
+		"https://www.youtube.com/shorts/dQw4w9WgXcQ",
+	}
+	for _, link := range sameVideo {
+		if got := MediaKey(link); got != "youtube:dQw4w9WgXcQ" {
+			t.Errorf("MediaKey(%v) = %v, expected youtube:dQw4w9WgXcQ", link, got)
+		}
+	}
+	if got := MediaKey("https://twitter.com/user/status/123"); got != MediaKey("https://x.com/other/status/123/video/1") {
+		t.Errorf("expected both twitter links to have the same key, got %v", got)
+	}
+	if got := MediaKey("https://Example.com/a.mp4#t=1"); got != "url:https://example.com/a.mp4" {
+		t.Errorf("got unexpected key for unknown link: %v", got)
+	}
+}
@@ -0,0 +1,47 @@
+package gobalt
+
+// AnyService is the service name of WithServiceSettings() that matches every link, even from unknown services.
+const AnyService = "*"
+
+// ServiceSettings changes the Settings of a request before it's sent, see WithServiceSettings().
+type ServiceSettings func(options *Settings)
+
+// WithServiceSettings(service, fn) applies fn to every request for a link of service (as named by DetectService(),
+// like "tiktok"), on top of the Settings given to Run(). Settings of AnyService are applied first, then the ones of
+// the service of the link, in the order they were added.
+func WithServiceSettings(service string, fn ServiceSettings) Option {
+	return func(c *Cobalt) {
+		if c.serviceSettings == nil {
+			c.serviceSettings = make(map[string][]ServiceSettings)
+		}
+		c.serviceSettings[service] = append(c.serviceSettings[service], fn)
+	}
+}
+
+// WithProxyFor(services...) tunnels the files of the given services through the instance (Settings.Proxy), and lets
+// every other service be downloaded directly, whatever Settings.Proxy was. Proxying everything wastes the bandwidth
+// of the instance, so only services that need it (e.g. WithProxyFor("instagram", "tiktok")) should be.
+func WithProxyFor(services ...string) Option {
+	return func(c *Cobalt) {
+		WithServiceSettings(AnyService, func(options *Settings) { options.Proxy = false })(c)
+		for _, service := range services {
+			WithServiceSettings(service, func(options *Settings) { options.Proxy = true })(c)
+		}
+	}
+}
+
+// applyServiceSettings returns options changed by the ServiceSettings of its service.
+func (c *Cobalt) applyServiceSettings(options Settings) Settings {
+	if len(c.serviceSettings) == 0 {
+		return options
+	}
+	for _, fn := range c.serviceSettings[AnyService] {
+		fn(&options)
+	}
+	if service := DetectService(options.Url); service != "" {
+		for _, fn := range c.serviceSettings[service] {
+			fn(&options)
+		}
+	}
+	return options
+}
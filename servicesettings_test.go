@@ -0,0 +1,39 @@
+package gobalt
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWithProxyFor(t *testing.T) {
+	proxied := make(map[string]bool)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			w.Write([]byte(`{"cobalt":{"version":"10.5.0","services":["youtube","tiktok"]}}`))
+			return
+		}
+		var body struct {
+			Url   string `json:"url"`
+			Proxy bool   `json:"alwaysProxy"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		proxied[DetectService(body.Url)] = body.Proxy
+		w.Write([]byte(`{"status":"tunnel","url":"http://` + r.Host + `/file","filename":"video.mp4"}`))
+	}))
+	defer server.Close()
+
+	client := New(WithInstance(server.URL), WithProxyFor("tiktok"))
+	for _, link := range []string{"https://youtu.be/dQw4w9WgXcQ", "https://www.tiktok.com/@user/video/7000000000000000000"} {
+		options := CreateDefaultSettings()
+		options.Url, options.Proxy = link, true
+		if _, err := client.Run(context.Background(), options); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if proxied["youtube"] || !proxied["tiktok"] {
+		t.Fatalf("only tiktok should be proxied, got %v", proxied)
+	}
+}
@@ -0,0 +1,196 @@
+package gobalt
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Sessions gets, caches and refreshes the short-lived JWTs that cobalt instances with Turnstile give out at their
+// /session endpoint. Requests to those instances are sent with "Authorization: Bearer <token>" instead of the api key.
+//
+// An instance needs a session when its server information has a Turnstile sitekey, or once it refused a request
+// with error.api.auth.jwt.missing. Instances with Turnstile only give sessions to clients that passed the challenge,
+// see WithTurnstile(). It's safe for concurrent use.
+type Sessions struct {
+	Clock Clock //Clock used to expire tokens. Default: SystemClock.
+
+	mu       sync.Mutex
+	tokens   map[string]sessionToken //By instanceKey().
+	required map[string]bool         //Instances that refused a request without a session.
+	inflight map[string]*sessionCall //Sessions being requested, by instanceKey().
+}
+
+// sessionCall is a session request shared by the requests to an instance waiting for a token.
+type sessionCall struct {
+	done  chan struct{}
+	token string
+	err   error
+}
+
+type sessionToken struct {
+	token   string
+	expires time.Time
+}
+
+// DefaultSessions is the Sessions used by clients that weren't given one with WithSessions().
+var DefaultSessions = &Sessions{}
+
+// WithSessions sets the Sessions used to authenticate to instances that need a JWT. nil disables sessions,
+// the api key is always sent.
+func WithSessions(sessions *Sessions) Option {
+	return func(c *Cobalt) {
+		c.sessions = sessions
+	}
+}
+
+// Invalidate forgets the token of the instance at api, a new one is requested by the next request.
+func (s *Sessions) Invalidate(api string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.tokens, instanceKey(api))
+}
+
+// needed reports if requests to api must be sent with a session.
+func (s *Sessions) needed(api string, info *ServerInfo) bool {
+	if info != nil && info.Cobalt.TurnstileSitekey != "" {
+		return true
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.required[instanceKey(api)]
+}
+
+// refused records that api refused a request because of a missing or invalid session, and forgets its token.
+func (s *Sessions) refused(api string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.required == nil {
+		s.required = make(map[string]bool)
+	}
+	s.required[instanceKey(api)] = true
+	delete(s.tokens, instanceKey(api))
+}
+
+// token returns a valid token for api, asking the instance for a new one with client if needed.
+// Concurrent requests to the same instance share the new token, and the lock isn't kept while asking, so a slow
+// challenge on one instance doesn't hold requests to the others.
+func (s *Sessions) token(ctx context.Context, client *Cobalt, api string) (string, error) {
+	key := instanceKey(api)
+	s.mu.Lock()
+	if cached, ok := s.tokens[key]; ok && orSystemClock(s.Clock).Now().Before(cached.expires) {
+		s.mu.Unlock()
+		return cached.token, nil
+	}
+	call, ok := s.inflight[key]
+	if !ok {
+		if s.inflight == nil {
+			s.inflight = make(map[string]*sessionCall)
+		}
+		call = &sessionCall{done: make(chan struct{})}
+		s.inflight[key] = call
+		//The request is shared, so it must not fail because the first caller gave up.
+		go s.fetch(context.WithoutCancel(ctx), client, api, call)
+	}
+	s.mu.Unlock()
+
+	select {
+	case <-call.done:
+		return call.token, call.err
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// fetch runs a shared session request, stores the token and wakes up the callers waiting for it.
+func (s *Sessions) fetch(ctx context.Context, client *Cobalt, api string, call *sessionCall) {
+	token, lifetime, err := client.newSession(ctx, api)
+	key := instanceKey(api)
+	s.mu.Lock()
+	if err == nil {
+		if s.tokens == nil {
+			s.tokens = make(map[string]sessionToken)
+		}
+		//Refresh a bit before the instance expires it, so a token never runs out in the middle of a request.
+		s.tokens[key] = sessionToken{token: token, expires: orSystemClock(s.Clock).Now().Add(lifetime * 9 / 10)}
+	}
+	delete(s.inflight, key)
+	s.mu.Unlock()
+	call.token, call.err = token, err
+	close(call.done)
+}
+
+// newSession asks the instance at api for a session token, and returns it with its lifetime.
+func (c *Cobalt) newSession(ctx context.Context, api string) (string, time.Duration, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(api, "/")+"/session", nil)
+	if err != nil {
+		return "", 0, err
+	}
+	req.Header.Add("User-Agent", useragent)
+	req.Header.Add("Accept", "application/json")
+	turnstile, err := c.turnstileToken(ctx, api)
+	if err != nil {
+		return "", 0, err
+	}
+	if turnstile != "" {
+		req.Header.Add("cf-turnstile-response", turnstile)
+	}
+	res, err := c.do(req)
+	if err != nil {
+		return "", 0, newCobaltError(c.language, "error.net.failed", api, err)
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(res.Body, 64<<10))
+	if err != nil {
+		return "", 0, newCobaltError(c.language, "error.net.failed", api, err)
+	}
+
+	var session struct {
+		Token string `json:"token"`
+		Exp   int    `json:"exp"` //Lifetime of the token, in seconds.
+		Error *Error `json:"error"`
+	}
+	if err := json.Unmarshal(body, &session); err != nil {
+		return "", 0, diagnoseResponse(api, res, body, err)
+	}
+	if session.Error != nil && session.Error.Code != "" {
+		cobaltErr := newCobaltError(c.language, session.Error.Code, api, nil)
+		cobaltErr.Context, cobaltErr.RequestID = session.Error.Context, requestIDFrom(res)
+		return "", 0, cobaltErr
+	}
+	if session.Token == "" || session.Exp <= 0 {
+		return "", 0, diagnoseResponse(api, res, body, errors.New("session response without a token"))
+	}
+	return session.Token, time.Duration(session.Exp) * time.Second, nil
+}
+
+// authorization returns the Authorization header of a request to instance: a session token if it needs one, else the api key.
+func (c *Cobalt) authorization(ctx context.Context, instance string, info *ServerInfo) (string, error) {
+	if c.sessions == nil || !c.sessions.needed(instance, info) {
+		return "Api-Key " + c.apiKey, nil
+	}
+	token, err := c.sessions.token(ctx, c, instance)
+	if err != nil {
+		return "", fmt.Errorf("failed to get a session: %w", err)
+	}
+	return "Bearer " + token, nil
+}
+
+// sessionRetryKey marks the context of a request sent again with a new session, so it's only done once.
+type sessionRetryKey struct{}
+
+// retryWithSession reports if a request refused with err should be sent again with a new session, and records the refusal.
+func (c *Cobalt) retryWithSession(ctx context.Context, instance string, err *CobaltError) bool {
+	refused := strings.HasPrefix(err.Code, "error.api.auth.jwt.") || strings.HasPrefix(err.Code, "error.api.auth.turnstile.")
+	if c.sessions == nil || ctx.Value(sessionRetryKey{}) != nil || !refused {
+		return false
+	}
+	c.sessions.refused(instance)
+	return true
+}
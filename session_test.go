@@ -0,0 +1,101 @@
+package gobalt
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// sessionCobalt is a fake instance that only accepts requests with a session token, announcing Turnstile if sitekey is set.
+func sessionCobalt(t *testing.T, sitekey bool) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var sessions atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/session":
+			sessions.Add(1)
+			w.Write([]byte(`{"token":"jwt-1","exp":100}`))
+		case r.Method == http.MethodGet && sitekey:
+			w.Write([]byte(`{"cobalt":{"version":"10.5.0","services":["youtube"],"turnstileSitekey":"0x4AAA"}}`))
+		case r.Method == http.MethodGet:
+			w.Write([]byte(`{"cobalt":{"version":"10.5.0","services":["youtube"]}}`))
+		case r.Header.Get("Authorization") != "Bearer jwt-1":
+			w.Write([]byte(`{"status":"error","error":{"code":"error.api.auth.jwt.missing"}}`))
+		default:
+			w.Write([]byte(`{"status":"tunnel","url":"https://example.com/tunnel"}`))
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, &sessions
+}
+
+func TestSessions(t *testing.T) {
+	options := CreateDefaultSettings()
+	options.Url = "https://youtu.be/dQw4w9WgXcQ"
+
+	for _, sitekey := range []bool{true, false} {
+		server, sessions := sessionCobalt(t, sitekey)
+		clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+		client := New(WithInstance(server.URL), WithSessions(&Sessions{Clock: clock}))
+		for range 2 {
+			if _, err := client.Run(context.Background(), options); err != nil {
+				t.Fatalf("sitekey %v: run failed: %v", sitekey, err)
+			}
+		}
+		if sessions.Load() != 1 {
+			t.Fatalf("sitekey %v: the token should be reused, got %v sessions", sitekey, sessions.Load())
+		}
+		//The token expires after 100 seconds, it's refreshed a bit before.
+		clock.now = clock.now.Add(95 * time.Second)
+		if _, err := client.Run(context.Background(), options); err != nil || sessions.Load() != 2 {
+			t.Fatalf("sitekey %v: expected a new session, got %v sessions: %v", sitekey, sessions.Load(), err)
+		}
+	}
+}
+
+func TestSessionsDisabled(t *testing.T) {
+	server, _ := sessionCobalt(t, false)
+	options := CreateDefaultSettings()
+	options.Url = "https://youtu.be/dQw4w9WgXcQ"
+	_, err := New(WithInstance(server.URL), WithSessions(nil)).Run(context.Background(), options)
+	if err == nil || err.Error() != "error.api.auth.jwt.missing" {
+		t.Fatalf("expected error.api.auth.jwt.missing, got %v", err)
+	}
+}
+
+func TestSessionsDontBlockOtherInstances(t *testing.T) {
+	release := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/session" {
+			<-release //A challenge that takes a while.
+		}
+		w.Write([]byte(`{"token":"jwt-1","exp":100}`))
+	}))
+	defer slow.Close()
+	defer close(release)
+	fast, sessions := sessionCobalt(t, true)
+
+	shared := &Sessions{}
+	client := New(WithSessions(shared))
+	go shared.token(context.Background(), client, slow.URL)
+	time.Sleep(50 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if token, err := shared.token(ctx, client, fast.URL); err != nil || token != "jwt-1" || sessions.Load() != 1 {
+		t.Fatalf("the session of another instance should not wait, got %q, %v", token, err)
+	}
+	if shared.needed(fast.URL, nil) {
+		t.Fatal("fast doesn't need a session without its server info")
+	}
+
+	//Callers waiting for the slow session can give up.
+	waitCtx, cancelWait := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancelWait()
+	if _, err := shared.token(waitCtx, client, slow.URL); err != context.DeadlineExceeded {
+		t.Fatalf("expected the caller to give up, got %v", err)
+	}
+}
@@ -0,0 +1,119 @@
+package gobalt
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// SettingsVersion is the version of the format written by MarshalSettings().
+//
+// The json mapping of Settings is stable: it's what is sent to cobalt (before the compatibility shims adapt it to the
+// version of the instance, see UnsupportedParameterError), and the fields only change together with this version.
+// Older versions are migrated by UnmarshalSettings(), so persisted profiles and queues keep meaning the same thing.
+const SettingsVersion = 1
+
+// ErrUnsupportedSettings is returned by UnmarshalSettings() for settings written by a newer gobalt, or that are not settings at all.
+var ErrUnsupportedSettings = errors.New("unsupported settings")
+
+// persistedSettings is the format written by MarshalSettings().
+type persistedSettings struct {
+	Version  int             `json:"version"`
+	Settings json.RawMessage `json:"settings"`
+}
+
+// MarshalSettings(options) encodes options to be saved, as {"version": SettingsVersion, "settings": {...}}.
+func MarshalSettings(options Settings) ([]byte, error) {
+	encoded, err := json.Marshal(options)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(persistedSettings{Version: SettingsVersion, Settings: encoded})
+}
+
+// UnmarshalSettings(data) decodes settings made by MarshalSettings(), migrating them from older versions of the format
+// if needed. A bare Settings object (like json.Marshal(Settings) makes) is read as the current version.
+// Fields missing from data keep their value from CreateDefaultSettings().
+func UnmarshalSettings(data []byte) (Settings, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return Settings{}, fmt.Errorf("%w: %v", ErrUnsupportedSettings, err)
+	}
+	persisted := persistedSettings{Settings: data}
+	if _, ok := fields["settings"]; ok {
+		if err := json.Unmarshal(data, &persisted); err != nil {
+			return Settings{}, fmt.Errorf("%w: %v", ErrUnsupportedSettings, err)
+		}
+		switch {
+		case persisted.Version < 1:
+			return Settings{}, fmt.Errorf("%w: missing version", ErrUnsupportedSettings)
+		case persisted.Version > SettingsVersion:
+			return Settings{}, fmt.Errorf("%w: version %v is newer than %v", ErrUnsupportedSettings, persisted.Version, SettingsVersion)
+		}
+	}
+	//Migrations from older versions go here, once there are any.
+	options := CreateDefaultSettings()
+	if err := json.Unmarshal(persisted.Settings, &options); err != nil {
+		return Settings{}, fmt.Errorf("%w: %v", ErrUnsupportedSettings, err)
+	}
+	return options, nil
+}
+
+// parseEnum returns the value of values text names, ignoring case. "" is always valid, it means the default of cobalt.
+func parseEnum[T ~string](kind string, text []byte, values ...T) (T, error) {
+	if len(text) == 0 {
+		return "", nil
+	}
+	for _, value := range values {
+		if strings.EqualFold(string(text), string(value)) {
+			return value, nil
+		}
+	}
+	return "", fmt.Errorf("unknown %v %q", kind, text)
+}
+
+func (m downloadMode) MarshalText() ([]byte, error) {
+	return []byte(m), nil
+}
+
+func (m *downloadMode) UnmarshalText(text []byte) (err error) {
+	*m, err = parseEnum("download mode", text, Auto, Audio, Mute)
+	return err
+}
+
+func (c videoCodecs) MarshalText() ([]byte, error) {
+	return []byte(c), nil
+}
+
+func (c *videoCodecs) UnmarshalText(text []byte) (err error) {
+	*c, err = parseEnum("video codec", text, H264, AV1, VP9)
+	return err
+}
+
+func (c audioCodec) MarshalText() ([]byte, error) {
+	return []byte(c), nil
+}
+
+func (c *audioCodec) UnmarshalText(text []byte) (err error) {
+	*c, err = parseEnum("audio format", text, Best, MP3, Opus, Ogg, Wav)
+	return err
+}
+
+func (l localProcessing) MarshalText() ([]byte, error) {
+	return []byte(l), nil
+}
+
+func (l *localProcessing) UnmarshalText(text []byte) (err error) {
+	*l, err = parseEnum("local processing", text, LocalProcessingDisabled, LocalProcessingPreferred, LocalProcessingForced)
+	return err
+}
+
+func (p pattern) MarshalText() ([]byte, error) {
+	return []byte(p), nil
+}
+
+func (p *pattern) UnmarshalText(text []byte) (err error) {
+	*p, err = parseEnum("filename style", text, Classic, Basic, Pretty, Nerdy)
+	return err
+}
@@ -0,0 +1,66 @@
+package gobalt
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"testing"
+)
+
+// TestSettingsWireFormat fails if the json sent to cobalt changes. If the change is wanted, increase SettingsVersion,
+// add a migration to UnmarshalSettings() and a new golden file.
+func TestSettingsWireFormat(t *testing.T) {
+	want, err := os.ReadFile("testdata/settings_v1.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	options := CreateDefaultSettings()
+	options.Url = "https://youtu.be/dQw4w9WgXcQ"
+	got, err := MarshalSettings(options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var indented bytes.Buffer
+	json.Indent(&indented, got, "", "  ")
+	if indented.String() != string(bytes.TrimSpace(want)) {
+		t.Fatalf("the wire format changed:\n%s\nwant:\n%s", indented.Bytes(), want)
+	}
+
+	decoded, err := UnmarshalSettings(want)
+	if err != nil || decoded != options {
+		t.Fatalf("unexpected settings %+v, %v", decoded, err)
+	}
+}
+
+func TestUnmarshalSettingsVersions(t *testing.T) {
+	bare, err := UnmarshalSettings([]byte(`{"url":"https://youtu.be/dQw4w9WgXcQ","downloadMode":"Audio"}`))
+	if err != nil || bare.Mode != Audio || bare.VideoQuality != 1080 {
+		t.Fatalf("bare settings should be read with defaults, got %+v, %v", bare, err)
+	}
+	if _, err := UnmarshalSettings([]byte(`{"version":2,"settings":{}}`)); !errors.Is(err, ErrUnsupportedSettings) {
+		t.Fatalf("newer versions should be refused, got %v", err)
+	}
+	if _, err := UnmarshalSettings([]byte(`{"audioFormat":"flac"}`)); !errors.Is(err, ErrUnsupportedSettings) {
+		t.Fatalf("unknown audio formats should be refused, got %v", err)
+	}
+}
+
+func FuzzSettingsRoundTrip(f *testing.F) {
+	f.Add([]byte(`{"url":"https://youtu.be/dQw4w9WgXcQ","videoQuality":"720","audioFormat":"mp3"}`))
+	f.Add([]byte(`{"version":1,"settings":{"downloadMode":"mute","filenameStyle":"nerdy"}}`))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		options, err := UnmarshalSettings(data)
+		if err != nil {
+			return
+		}
+		encoded, err := MarshalSettings(options)
+		if err != nil {
+			t.Fatal(err)
+		}
+		again, err := UnmarshalSettings(encoded)
+		if err != nil || again != options {
+			t.Fatalf("round trip changed %+v into %+v (%v)", options, again, err)
+		}
+	})
+}
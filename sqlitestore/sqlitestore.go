@@ -0,0 +1,234 @@
+// Package sqlitestore keeps the state of gobalt in a SQLite database: the download history (gobalt.History),
+// the queue of a download manager (downloader.JobStore) and the resume states of downloads (gobalt.ResumeStore).
+//
+// It uses the pure-Go driver modernc.org/sqlite, so it works without cgo or a SQLite library on the system:
+//
+//	store, err := sqlitestore.Open("gobalt.db")
+//	manager := downloader.NewManager(client, downloader.ManagerOptions{History: store, Store: store,
+//		Options: []gobalt.DownloadOption{gobalt.WithResumeStore(store)}})
+//
+// The package is its own module, so programs that don't use it don't depend on the driver.
+// The schema is created and migrated by Open() and New(), its version is kept in the schema_migrations table.
+package sqlitestore
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/lostdusty/gobalt/v2"
+	"github.com/lostdusty/gobalt/v2/downloader"
+	_ "modernc.org/sqlite"
+)
+
+// DriverName is the database/sql driver used by Open(). modernc.org/sqlite registers itself as "sqlite".
+var DriverName = "sqlite"
+
+// migrations are applied in order, migrations[i] brings the schema to version i+1. Only add new ones at the end.
+var migrations = []string{
+	`CREATE TABLE history (
+		key TEXT PRIMARY KEY,
+		source TEXT NOT NULL,
+		path TEXT NOT NULL,
+		size INTEGER NOT NULL,
+		time INTEGER NOT NULL
+	);
+	CREATE TABLE jobs (
+		seq INTEGER PRIMARY KEY AUTOINCREMENT,
+		id TEXT NOT NULL UNIQUE,
+		job TEXT NOT NULL
+	);
+	CREATE TABLE resume_states (
+		path TEXT PRIMARY KEY,
+		state TEXT NOT NULL
+	);`,
+	`ALTER TABLE history ADD COLUMN metadata TEXT`,
+	//Download() keeps resume states next to the partial files, a copy in the database was never read.
+	`DROP TABLE resume_states`,
+	//Download() can keep its resume states in a ResumeStore now, see gobalt.WithResumeStore().
+	`CREATE TABLE resume_states (
+		path TEXT PRIMARY KEY,
+		state TEXT NOT NULL
+	)`,
+}
+
+// Store is a gobalt.History, downloader.JobStore and gobalt.ResumeStore saved in a SQLite database. It's safe for concurrent use.
+type Store struct {
+	db *sql.DB
+}
+
+var (
+	_ gobalt.History      = (*Store)(nil)
+	_ downloader.JobStore = (*Store)(nil)
+	_ gobalt.ResumeStore  = (*Store)(nil)
+)
+
+// Open(path) opens (or creates) the database at path with DriverName, and migrates its schema.
+func Open(path string) (*Store, error) {
+	db, err := sql.Open(DriverName, path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %v: %w", path, err)
+	}
+	//SQLite allows a single writer, sharing one connection avoids "database is locked" errors.
+	db.SetMaxOpenConns(1)
+	store, err := New(db)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return store, nil
+}
+
+// New(db) uses an already open database, and migrates its schema. Close() closes db.
+func New(db *sql.DB) (*Store, error) {
+	store := &Store{db: db}
+	if err := store.migrate(); err != nil {
+		return nil, fmt.Errorf("failed to migrate the database: %w", err)
+	}
+	return store, nil
+}
+
+// Close closes the database.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Version returns the version of the schema of the database, which is the number of migrations applied.
+func (s *Store) Version() (int, error) {
+	var version int
+	err := s.db.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&version)
+	return version, err
+}
+
+// migrate applies the migrations the database doesn't have yet, each one in its own transaction.
+func (s *Store) migrate() error {
+	_, err := s.db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (version INTEGER PRIMARY KEY, applied INTEGER NOT NULL)`)
+	if err != nil {
+		return err
+	}
+	version, err := s.Version()
+	if err != nil {
+		return err
+	}
+	if version > len(migrations) {
+		return fmt.Errorf("database has schema version %v, this version of gobalt only knows %v", version, len(migrations))
+	}
+	for i := version; i < len(migrations); i++ {
+		tx, err := s.db.Begin()
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(migrations[i]); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migration %v: %w", i+1, err)
+		}
+		if _, err := tx.Exec(`INSERT INTO schema_migrations (version, applied) VALUES (?, ?)`, i+1, time.Now().Unix()); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Store) Lookup(key string) (*gobalt.HistoryEntry, bool, error) {
+	entry := gobalt.HistoryEntry{Key: key}
+	var unix int64
+	var metadata sql.NullString
+	err := s.db.QueryRow(`SELECT source, path, size, time, metadata FROM history WHERE key = ?`, key).Scan(&entry.Source, &entry.Path, &entry.Size, &unix, &metadata)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	entry.Time = time.UnixMilli(unix)
+	if metadata.Valid {
+		if err := json.Unmarshal([]byte(metadata.String), &entry.Metadata); err != nil {
+			return nil, false, fmt.Errorf("invalid metadata in the database: %w", err)
+		}
+	}
+	return &entry, true, nil
+}
+
+func (s *Store) Add(entry gobalt.HistoryEntry) error {
+	var metadata sql.NullString
+	if entry.Metadata != nil {
+		encoded, err := json.Marshal(entry.Metadata)
+		if err != nil {
+			return err
+		}
+		metadata = sql.NullString{String: string(encoded), Valid: true}
+	}
+	_, err := s.db.Exec(`INSERT OR REPLACE INTO history (key, source, path, size, time, metadata) VALUES (?, ?, ?, ?, ?, ?)`,
+		entry.Key, entry.Source, entry.Path, entry.Size, entry.Time.UnixMilli(), metadata)
+	return err
+}
+
+func (s *Store) SaveJob(job downloader.StoredJob) error {
+	encoded, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(`INSERT INTO jobs (id, job) VALUES (?, ?) ON CONFLICT (id) DO UPDATE SET job = excluded.job`, job.ID, string(encoded))
+	return err
+}
+
+func (s *Store) DeleteJob(id string) error {
+	_, err := s.db.Exec(`DELETE FROM jobs WHERE id = ?`, id)
+	return err
+}
+
+func (s *Store) PendingJobs() ([]downloader.StoredJob, error) {
+	rows, err := s.db.Query(`SELECT job FROM jobs ORDER BY seq`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var jobs []downloader.StoredJob
+	for rows.Next() {
+		var encoded string
+		if err := rows.Scan(&encoded); err != nil {
+			return nil, err
+		}
+		var job downloader.StoredJob
+		if err := json.Unmarshal([]byte(encoded), &job); err != nil {
+			return nil, fmt.Errorf("invalid job in the database: %w", err)
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, rows.Err()
+}
+
+// LoadResumeState returns the state saved for the download to path, or an error wrapping os.ErrNotExist.
+func (s *Store) LoadResumeState(path string) (*gobalt.ResumeState, error) {
+	var encoded string
+	err := s.db.QueryRow(`SELECT state FROM resume_states WHERE path = ?`, path).Scan(&encoded)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("no resume state for %v: %w", path, os.ErrNotExist)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return gobalt.UnmarshalResumeState([]byte(encoded))
+}
+
+func (s *Store) SaveResumeState(state *gobalt.ResumeState) error {
+	encoded, err := state.Marshal()
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(`INSERT OR REPLACE INTO resume_states (path, state) VALUES (?, ?)`, state.Path, string(encoded))
+	return err
+}
+
+func (s *Store) DeleteResumeState(path string) error {
+	_, err := s.db.Exec(`DELETE FROM resume_states WHERE path = ?`, path)
+	return err
+}
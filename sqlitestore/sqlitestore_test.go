@@ -0,0 +1,106 @@
+package sqlitestore
+
+import (
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/lostdusty/gobalt/v2"
+	"github.com/lostdusty/gobalt/v2/downloader"
+)
+
+// openTestStore opens a store in a temporary directory.
+func openTestStore(t *testing.T) *Store {
+	store, err := Open(filepath.Join(t.TempDir(), "gobalt.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func TestStore(t *testing.T) {
+	store := openTestStore(t)
+	if version, err := store.Version(); err != nil || version != len(migrations) {
+		t.Fatalf("expected schema version %v, got %v, %v", len(migrations), version, err)
+	}
+
+	entry := gobalt.HistoryEntry{Key: "youtube:abc", Source: "https://youtu.be/abc", Path: "a.mp4", Size: 5, Time: time.UnixMilli(1000), Metadata: map[string]string{"user": "42"}}
+	if err := store.Add(entry); err != nil {
+		t.Fatal(err)
+	}
+	if found, ok, err := store.Lookup(entry.Key); err != nil || !ok || found.Path != entry.Path || !found.Time.Equal(entry.Time) || found.Metadata["user"] != "42" {
+		t.Fatalf("unexpected lookup %+v, %v, %v", found, ok, err)
+	}
+
+	for _, id := range []string{"job-2", "job-1"} {
+		if err := store.SaveJob(downloader.StoredJob{ID: id, Dir: "."}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	store.DeleteJob("job-2")
+	if jobs, err := store.PendingJobs(); err != nil || len(jobs) != 1 || jobs[0].ID != "job-1" {
+		t.Fatalf("unexpected pending jobs %+v, %v", jobs, err)
+	}
+
+	if _, err := store.LoadResumeState("a.mp4"); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected no resume state, got %v", err)
+	}
+	state := &gobalt.ResumeState{URL: "https://example.com/tunnel", Path: "a.mp4", PartPath: "a.mp4.part", Size: 4}
+	if err := store.SaveResumeState(state); err != nil {
+		t.Fatal(err)
+	}
+	if loaded, err := store.LoadResumeState("a.mp4"); err != nil || loaded.Size != 4 || loaded.PartPath != state.PartPath {
+		t.Fatalf("unexpected resume state %+v, %v", loaded, err)
+	}
+	store.DeleteResumeState("a.mp4")
+	if _, err := store.LoadResumeState("a.mp4"); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("the resume state should be deleted, got %v", err)
+	}
+}
+
+func TestMigrations(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gobalt.db")
+	db, err := sql.Open(DriverName, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	//A database made by the first version of the package.
+	store := &Store{db: db}
+	saved := migrations
+	migrations = saved[:1]
+	err = store.migrate()
+	migrations = saved
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(`INSERT INTO history (key, source, path, size, time) VALUES ('youtube:abc', 'https://youtu.be/abc', 'a.mp4', 5, 1000)`); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	store, err = Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	if version, err := store.Version(); err != nil || version != len(migrations) {
+		t.Fatalf("expected schema version %v, got %v, %v", len(migrations), version, err)
+	}
+	if entry, ok, err := store.Lookup("youtube:abc"); err != nil || !ok || entry.Path != "a.mp4" || entry.Metadata != nil {
+		t.Fatalf("entries should survive the migrations, got %+v, %v, %v", entry, ok, err)
+	}
+	var tables int
+	store.db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE name = 'resume_states'`).Scan(&tables)
+	if tables != 1 {
+		t.Fatal("the resume_states table should be created again")
+	}
+
+	//Opening a migrated database again changes nothing.
+	if err := store.migrate(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,231 @@
+package gobalt
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// AudioTags are the tags written by WriteTags(). Empty fields are left as they are in the file.
+type AudioTags struct {
+	Title       string
+	Artist      string
+	Album       string
+	AlbumArtist string
+	Track       int    //Track number, starting at 1.
+	TrackTotal  int    //How many tracks the album has.
+	Cover       []byte //Cover art as a JPEG, see NormalizeCover() and FetchCover().
+}
+
+// WriteTags(ctx, path, tags) writes tags to the audio file at path, so music players can group and order the files.
+// mp3 files are tagged directly (ID3v2), other formats need ffmpeg (see FFmpegPath).
+func WriteTags(ctx context.Context, path string, tags AudioTags) error {
+	if strings.EqualFold(filepath.Ext(path), ".mp3") {
+		return writeID3(path, tags)
+	}
+	return writeTagsFFmpeg(ctx, path, tags)
+}
+
+// trackNumber formats the track number like "3/12", or "" if there's none.
+func (t AudioTags) trackNumber() string {
+	switch {
+	case t.Track <= 0:
+		return ""
+	case t.TrackTotal > 0:
+		return fmt.Sprintf("%v/%v", t.Track, t.TrackTotal)
+	}
+	return strconv.Itoa(t.Track)
+}
+
+// id3Frames returns the ID3 text frames for the tags, by frame ID.
+func (t AudioTags) id3Frames() map[string]string {
+	frames := map[string]string{
+		"TIT2": t.Title,
+		"TPE1": t.Artist,
+		"TALB": t.Album,
+		"TPE2": t.AlbumArtist,
+		"TRCK": t.trackNumber(),
+	}
+	for id, value := range frames {
+		if value == "" {
+			delete(frames, id)
+		}
+	}
+	return frames
+}
+
+// id3Frame is a frame of an ID3v2 tag, kept as it is when it's not replaced.
+type id3Frame struct {
+	id    string
+	flags [2]byte
+	data  []byte
+}
+
+// writeID3 replaces the frames of the ID3v2 tag of an mp3 file (creating the tag if needed), keeping every other frame.
+func writeID3(path string, tags AudioTags) error {
+	file, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	version, frames, audio, err := readID3(file)
+	if err != nil {
+		return err
+	}
+
+	replaced := tags.id3Frames()
+	kept := frames[:0]
+	for _, frame := range frames {
+		if _, ok := replaced[frame.id]; !ok && (frame.id != "APIC" || tags.Cover == nil) {
+			kept = append(kept, frame)
+		}
+	}
+	for _, id := range []string{"TIT2", "TPE1", "TALB", "TPE2", "TRCK"} {
+		if value, ok := replaced[id]; ok {
+			kept = append(kept, id3Frame{id: id, data: id3Text(value)})
+		}
+	}
+	if tags.Cover != nil {
+		kept = append(kept, id3Frame{id: "APIC", data: id3Picture(tags.Cover)})
+	}
+	return replaceFile(path, append(encodeID3(version, kept), audio...))
+}
+
+// readID3 splits an mp3 file into its ID3v2 tag frames and the audio after it. Files without a tag are ID3v2.3.
+func readID3(file []byte) (version byte, frames []id3Frame, audio []byte, err error) {
+	if len(file) < 10 || string(file[:3]) != "ID3" {
+		return 3, nil, file, nil
+	}
+	version, flags := file[3], file[5]
+	if version != 3 && version != 4 {
+		return 0, nil, nil, fmt.Errorf("ID3v2.%v tags are not supported", version)
+	}
+	if flags&0xc0 != 0 {
+		return 0, nil, nil, errors.New("ID3 tags with unsynchronisation or extended headers are not supported")
+	}
+	size := int(syncsafe(file[6:10]))
+	end := 10 + size
+	if flags&0x10 != 0 {
+		end += 10 //Footer.
+	}
+	if end > len(file) {
+		return 0, nil, nil, io.ErrUnexpectedEOF
+	}
+
+	tag := file[10 : 10+size]
+	for len(tag) >= 10 && tag[0] != 0 {
+		frameSize := int(binary.BigEndian.Uint32(tag[4:8]))
+		if version == 4 {
+			frameSize = int(syncsafe(tag[4:8]))
+		}
+		if 10+frameSize > len(tag) {
+			return 0, nil, nil, errors.New("invalid ID3 frame size")
+		}
+		frames = append(frames, id3Frame{id: string(tag[:4]), flags: [2]byte{tag[8], tag[9]}, data: tag[10 : 10+frameSize]})
+		tag = tag[10+frameSize:]
+	}
+	return version, frames, file[end:], nil
+}
+
+// encodeID3 encodes an ID3v2 tag of version (3 or 4) with frames.
+func encodeID3(version byte, frames []id3Frame) []byte {
+	var body bytes.Buffer
+	for _, frame := range frames {
+		size := make([]byte, 4)
+		if version == 4 {
+			putSyncsafe(size, uint32(len(frame.data)))
+		} else {
+			binary.BigEndian.PutUint32(size, uint32(len(frame.data)))
+		}
+		body.WriteString(frame.id)
+		body.Write(size)
+		body.Write(frame.flags[:])
+		body.Write(frame.data)
+	}
+	header := []byte{'I', 'D', '3', version, 0, 0, 0, 0, 0, 0}
+	putSyncsafe(header[6:], uint32(body.Len()))
+	return append(header, body.Bytes()...)
+}
+
+// id3Text encodes a text frame as UTF-16 with BOM, valid in ID3v2.3 and ID3v2.4.
+func id3Text(value string) []byte {
+	data := []byte{1, 0xff, 0xfe}
+	for _, unit := range utf16.Encode([]rune(value)) {
+		data = binary.LittleEndian.AppendUint16(data, unit)
+	}
+	return data
+}
+
+func syncsafe(b []byte) uint32 {
+	return uint32(b[0]&0x7f)<<21 | uint32(b[1]&0x7f)<<14 | uint32(b[2]&0x7f)<<7 | uint32(b[3]&0x7f)
+}
+
+func putSyncsafe(b []byte, n uint32) {
+	b[0], b[1], b[2], b[3] = byte(n>>21&0x7f), byte(n>>14&0x7f), byte(n>>7&0x7f), byte(n&0x7f)
+}
+
+// writeTagsFFmpeg tags any format supported by ffmpeg, copying the streams without re-encoding.
+func writeTagsFFmpeg(ctx context.Context, path string, tags AudioTags) error {
+	args := []string{"-i", path, "-map", "0", "-c", "copy"}
+	if tags.Cover != nil {
+		cover, err := os.CreateTemp("", "gobalt-cover-*.jpg")
+		if err != nil {
+			return err
+		}
+		defer os.Remove(cover.Name())
+		_, err = cover.Write(tags.Cover)
+		if closeErr := cover.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return err
+		}
+		args = []string{"-i", path, "-i", cover.Name(), "-map", "0:a", "-map", "1", "-c", "copy", "-disposition:v", "attached_pic"}
+	}
+	for _, tag := range [][2]string{
+		{"title", tags.Title},
+		{"artist", tags.Artist},
+		{"album", tags.Album},
+		{"album_artist", tags.AlbumArtist},
+		{"track", tags.trackNumber()},
+	} {
+		if tag[1] != "" {
+			args = append(args, "-metadata", tag[0]+"="+tag[1])
+		}
+	}
+	return ffmpegInPlace(ctx, path, args...)
+}
+
+// ffmpegInPlace runs ffmpeg with args writing to a temporary file next to path, which then replaces path.
+func ffmpegInPlace(ctx context.Context, path string, args ...string) error {
+	tmp := strings.TrimSuffix(path, filepath.Ext(path)) + ".tmp" + filepath.Ext(path)
+	if err := runFFmpeg(ctx, append(args, tmp)...); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// replaceFile writes data to path through a temporary file, keeping the permissions of path.
+func replaceFile(path string, data []byte) error {
+	mode := os.FileMode(0o644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, mode); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp, mode); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, path)
+}
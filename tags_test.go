@@ -0,0 +1,49 @@
+package gobalt
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteID3(t *testing.T) {
+	//An ID3v2.3 tag with a title and an artist, like cobalt sends, followed by the audio.
+	existing := encodeID3(3, []id3Frame{{id: "TIT2", data: id3Text("Song")}, {id: "TPE1", data: id3Text("Uploader")}})
+	audio := []byte{0xff, 0xfb, 0x90, 0x00, 1, 2, 3}
+	path := filepath.Join(t.TempDir(), "01 - Song.mp3")
+	os.WriteFile(path, append(existing, audio...), 0o644)
+
+	cover := []byte{0xff, 0xd8, 0xff, 0xd9}
+	if err := WriteTags(context.Background(), path, AudioTags{Album: "Playlist ♪", Track: 1, TrackTotal: 12, Cover: cover}); err != nil {
+		t.Fatalf("tagging failed: %v", err)
+	}
+	file, _ := os.ReadFile(path)
+	version, frames, rest, err := readID3(file)
+	if err != nil || version != 3 {
+		t.Fatalf("invalid tag after writing (v2.%v): %v", version, err)
+	}
+	if !bytes.Equal(rest, audio) {
+		t.Fatal("audio was changed")
+	}
+	got := map[string]string{}
+	for _, frame := range frames {
+		got[frame.id] = string(frame.data)
+	}
+	want := map[string]string{
+		"TIT2": string(id3Text("Song")),
+		"TPE1": string(id3Text("Uploader")),
+		"TALB": string(id3Text("Playlist ♪")),
+		"TRCK": string(id3Text("1/12")),
+		"APIC": string(id3Picture(cover)),
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %v frames, want %v", len(got), len(want))
+	}
+	for id, value := range want {
+		if got[id] != value {
+			t.Fatalf("frame %v is %q, want %q", id, got[id], value)
+		}
+	}
+}
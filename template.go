@@ -0,0 +1,89 @@
+package gobalt
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Placeholders look like {service}, letters, numbers and underscores only.
+var templatePlaceholder = regexp.MustCompile(`\{([a-zA-Z0-9_]+)\}`)
+
+// Value used when a template placeholder has no value.
+const unknownTemplateValue = "unknown"
+
+// TemplateFields returns the values available to directory templates for this response:
+//
+//   - {service}: service name of the requested url, see DetectService().
+//   - {status}: cobalt response status, like "tunnel" or "picker".
+//   - {mode}: download mode requested, like "auto" or "audio".
+//   - {filename}: name of the file, without extension.
+//   - {ext}: extension of the file, without the dot.
+//   - {uploader}: who uploaded the media, if cobalt provided this information.
+//   - {year}, {month}, {day}: date of the download.
+//
+// Fields that are not known are left out of the map, and expand to "unknown".
+func (c *CobaltResponse) TemplateFields() map[string]string {
+	now := time.Now()
+	fields := map[string]string{
+		"status": c.Status,
+		"year":   strconv.Itoa(now.Year()),
+		"month":  now.Format("01"),
+		"day":    now.Format("02"),
+	}
+	if service := DetectService(c.request.Url); service != "" {
+		fields["service"] = service
+	} else if c.Service != "" {
+		fields["service"] = c.Service
+	}
+	if c.request.Mode != "" {
+		fields["mode"] = string(c.request.Mode)
+	}
+	if c.Filename != "" {
+		ext := filepath.Ext(c.Filename)
+		fields["filename"] = strings.TrimSuffix(c.Filename, ext)
+		if ext != "" {
+			fields["ext"] = strings.TrimPrefix(ext, ".")
+		}
+	}
+	if c.Output != nil && c.Output.Metadata != nil && c.Output.Metadata.Artist != "" {
+		fields["uploader"] = c.Output.Metadata.Artist
+	}
+	return fields
+}
+
+// ExpandDirTemplate(template, fields) replaces every {placeholder} in template with its value from fields.
+//
+// Each value is cleaned so it can't escape its own directory level (no slashes, no "..").
+// Placeholders without a value are replaced by "unknown". The result uses the OS path separator.
+func ExpandDirTemplate(template string, fields map[string]string) string {
+	expanded := templatePlaceholder.ReplaceAllStringFunc(template, func(match string) string {
+		value := strings.TrimSpace(fields[strings.ToLower(match[1:len(match)-1])])
+		value = cleanPathSegment(value)
+		if value == "" {
+			return unknownTemplateValue
+		}
+		return value
+	})
+	return filepath.Clean(filepath.FromSlash(expanded))
+}
+
+// MakeDir(root, template) expands template with the response TemplateFields() and creates the resulting directory
+// (and any missing parents) inside root, returning its path.
+//
+// Example: resp.MakeDir("downloads", "{service}/{year}/") creates and returns "downloads/youtube/2024".
+func (c *CobaltResponse) MakeDir(root, template string) (string, error) {
+	dir := filepath.Join(root, ExpandDirTemplate(template, c.TemplateFields()))
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+// cleanPathSegment makes a template value safe to be used as a single directory or file name, on any system.
+func cleanPathSegment(value string) string {
+	return SanitizeFilenameFor("windows", value)
+}
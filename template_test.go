@@ -0,0 +1,37 @@
+package gobalt
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestExpandDirTemplate(t *testing.T) {
+	got := ExpandDirTemplate("{service}/{uploader}/{year}/", map[string]string{
+		"service":  "youtube",
+		"uploader": "../AC/DC",
+		"year":     "2024",
+	})
+	want := filepath.Join("youtube", "_AC_DC", "2024")
+	if got != want {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	if got := ExpandDirTemplate("{service}/{missing}", nil); got != filepath.Join("unknown", "unknown") {
+		t.Fatalf("expected missing fields to expand to unknown, got %v", got)
+	}
+}
+
+func TestMakeDir(t *testing.T) {
+	root := t.TempDir()
+	resp := &CobaltResponse{Status: "tunnel", Filename: "video.mp4", request: Settings{Url: "https://youtu.be/dQw4w9WgXcQ", Mode: Audio}}
+	dir, err := resp.MakeDir(root, "{service}/{mode}/{ext}")
+	if err != nil {
+		t.Fatalf("failed to create dir: %v", err)
+	}
+	if dir != filepath.Join(root, "youtube", "audio", "mp4") {
+		t.Fatalf("got unexpected dir %v", dir)
+	}
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		t.Fatalf("directory was not created: %v", err)
+	}
+}
@@ -0,0 +1,198 @@
+package gobalt
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+)
+
+// Thumbnail is a media preview downloaded by a ThumbnailCache.
+type Thumbnail struct {
+	URL         string //Url of the thumbnail.
+	ContentType string //Content type sent by the server, like "image/jpeg".
+	Data        []byte //The image itself.
+}
+
+// ThumbnailCache downloads thumbnails (like the Thumb urls of picker items) and keeps the most recently used ones in memory,
+// so previews can be shown right away while the user decides what to download. It's safe for concurrent use.
+//
+// Create one with NewThumbnailCache(), and fill it with Prefetch() or PrefetchPicker().
+type ThumbnailCache struct {
+	MaxEntries  int   //How many thumbnails are kept, the least recently used are removed first. Default: 256.
+	MaxSize     int64 //Largest thumbnail accepted, in bytes. Default: 5 MiB.
+	Concurrency int   //How many thumbnails Prefetch() downloads at the same time. Default: 4.
+
+	client   *Cobalt
+	mu       sync.Mutex
+	order    *list.List //Most recently used first, values are *Thumbnail.
+	entries  map[string]*list.Element
+	inflight map[string]*thumbnailFetch
+}
+
+// thumbnailFetch is a download in progress, so concurrent requests for the same url only download it once.
+type thumbnailFetch struct {
+	done  chan struct{}
+	thumb *Thumbnail
+	err   error
+}
+
+// NewThumbnailCache creates a ThumbnailCache that keeps up to maxEntries thumbnails, downloading them with client.
+// If client is nil, the package-level settings are used (see Client).
+func NewThumbnailCache(client *Cobalt, maxEntries int) *ThumbnailCache {
+	if client == nil {
+		client = defaultClient()
+	}
+	return &ThumbnailCache{MaxEntries: maxEntries, client: client}
+}
+
+// Get(ctx, url) returns the thumbnail at url, downloading it if it's not cached yet.
+// If the same url is already being downloaded (e.g. by Prefetch()), it waits for that download instead.
+func (tc *ThumbnailCache) Get(ctx context.Context, url string) (*Thumbnail, error) {
+	tc.mu.Lock()
+	tc.init()
+	if element, ok := tc.entries[url]; ok {
+		tc.order.MoveToFront(element)
+		tc.mu.Unlock()
+		return element.Value.(*Thumbnail), nil
+	}
+	fetch, ok := tc.inflight[url]
+	if !ok {
+		fetch = &thumbnailFetch{done: make(chan struct{})}
+		tc.inflight[url] = fetch
+		go tc.fetch(url, fetch)
+	}
+	tc.mu.Unlock()
+
+	select {
+	case <-fetch.done:
+		return fetch.thumb, fetch.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Cached(url) returns the thumbnail at url only if it's already in the cache.
+func (tc *ThumbnailCache) Cached(url string) (*Thumbnail, bool) {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+	tc.init()
+	element, ok := tc.entries[url]
+	if !ok {
+		return nil, false
+	}
+	tc.order.MoveToFront(element)
+	return element.Value.(*Thumbnail), true
+}
+
+// Prefetch(ctx, urls...) downloads every url into the cache, Concurrency at a time, and returns once all of them are done.
+// Run it in a goroutine to keep going while thumbnails load. Empty urls are skipped; errors of every failed url are joined.
+func (tc *ThumbnailCache) Prefetch(ctx context.Context, urls ...string) error {
+	limit := make(chan struct{}, tc.concurrency())
+	errs := make([]error, len(urls))
+	var wg sync.WaitGroup
+	for i, url := range urls {
+		if url == "" {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			select {
+			case limit <- struct{}{}:
+			case <-ctx.Done():
+				errs[i] = ctx.Err()
+				return
+			}
+			defer func() { <-limit }()
+			_, errs[i] = tc.Get(ctx, url)
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// PrefetchPicker(ctx, media) prefetches the thumbnails of every picker item of media, see Prefetch().
+func (tc *ThumbnailCache) PrefetchPicker(ctx context.Context, media *CobaltResponse) error {
+	if media == nil || media.Picker == nil {
+		return nil
+	}
+	var urls []string
+	for _, item := range *media.Picker {
+		urls = append(urls, item.Thumb)
+	}
+	return tc.Prefetch(ctx, urls...)
+}
+
+// fetch downloads url and stores it. It's not tied to the context of any caller, since others may be waiting for it.
+func (tc *ThumbnailCache) fetch(url string, fetch *thumbnailFetch) {
+	fetch.thumb, fetch.err = tc.download(url)
+
+	tc.mu.Lock()
+	delete(tc.inflight, url)
+	if fetch.err == nil {
+		tc.entries[url] = tc.order.PushFront(fetch.thumb)
+		for tc.order.Len() > tc.maxEntries() {
+			oldest := tc.order.Back()
+			tc.order.Remove(oldest)
+			delete(tc.entries, oldest.Value.(*Thumbnail).URL)
+		}
+	}
+	tc.mu.Unlock()
+	close(fetch.done)
+}
+
+func (tc *ThumbnailCache) download(url string) (*Thumbnail, error) {
+	res, err := tc.client.doHttpRequest(context.Background(), url, http.MethodGet, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("thumbnail %v: server returned %v", url, res.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(res.Body, tc.maxSize()+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > tc.maxSize() {
+		return nil, fmt.Errorf("thumbnail %v is larger than %v bytes", url, tc.maxSize())
+	}
+	return &Thumbnail{URL: url, ContentType: res.Header.Get("Content-Type"), Data: data}, nil
+}
+
+// init creates the maps, so a ThumbnailCache{} literal works too. Must be called with mu held.
+func (tc *ThumbnailCache) init() {
+	if tc.entries == nil {
+		tc.entries = make(map[string]*list.Element)
+		tc.inflight = make(map[string]*thumbnailFetch)
+		tc.order = list.New()
+	}
+	if tc.client == nil {
+		tc.client = defaultClient()
+	}
+}
+
+func (tc *ThumbnailCache) maxEntries() int {
+	if tc.MaxEntries <= 0 {
+		return 256
+	}
+	return tc.MaxEntries
+}
+
+func (tc *ThumbnailCache) maxSize() int64 {
+	if tc.MaxSize <= 0 {
+		return 5 << 20
+	}
+	return tc.MaxSize
+}
+
+func (tc *ThumbnailCache) concurrency() int {
+	if tc.Concurrency <= 0 {
+		return 4
+	}
+	return tc.Concurrency
+}
@@ -0,0 +1,43 @@
+package gobalt
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestThumbnailPrefetch(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Header().Set("Content-Type", "image/jpeg")
+		w.Write([]byte("thumb " + r.URL.Path))
+	}))
+	defer server.Close()
+
+	cache := NewThumbnailCache(New(), 2)
+	urls := []string{server.URL + "/1", server.URL + "/2", server.URL + "/1"}
+	if err := cache.Prefetch(context.Background(), urls...); err != nil {
+		t.Fatalf("prefetch failed: %v", err)
+	}
+	if requests.Load() != 2 {
+		t.Fatalf("each url should be downloaded once, got %v requests", requests.Load())
+	}
+	thumb, ok := cache.Cached(server.URL + "/1")
+	if !ok || string(thumb.Data) != "thumb /1" || thumb.ContentType != "image/jpeg" {
+		t.Fatalf("thumbnail was not cached, got %+v", thumb)
+	}
+
+	//The cache keeps 2 entries, so /2 (least recently used) is removed.
+	if _, err := cache.Get(context.Background(), server.URL+"/3"); err != nil {
+		t.Fatalf("get failed: %v", err)
+	}
+	if _, ok := cache.Cached(server.URL + "/2"); ok {
+		t.Fatal("least recently used thumbnail should have been removed")
+	}
+	if _, ok := cache.Cached(server.URL + "/1"); !ok {
+		t.Fatal("recently used thumbnail should still be cached")
+	}
+}
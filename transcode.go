@@ -0,0 +1,118 @@
+package gobalt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Cobalt error codes after which SaveMedia() tries the local transcode fallback, see WithLocalTranscode().
+var transcodeFallbackErrors = map[string]bool{
+	"error.api.fetch.critical": true,
+	"error.api.fetch.fail":     true,
+	"error.api.fetch.empty":    true,
+}
+
+// ffmpeg encoders used to convert audio locally, per format.
+var audioEncoders = map[audioCodec][]string{
+	MP3:  {"-c:a", "libmp3lame"},
+	Opus: {"-c:a", "libopus"},
+	Ogg:  {"-c:a", "libvorbis"},
+	Wav:  {"-c:a", "pcm_s16le"},
+}
+
+// WithLocalTranscode makes SaveMedia() convert the audio locally with ffmpeg (see FFmpegPath) when cobalt fails to convert it:
+// if an audio request with an AudioFormat other than Best fails (because of a server-side fetch error, or a broken tunnel),
+// the audio is requested again as Best and converted to the format that was asked for. Hooks and history see the converted file.
+func WithLocalTranscode() DownloadOption {
+	return func(c *downloadConfig) {
+		c.localTranscode = true
+	}
+}
+
+// SaveMedia(ctx, options, dir) sends options to cobalt and saves the media to dir, see SaveTo().
+func SaveMedia(ctx context.Context, options Settings, dir string, opts ...DownloadOption) (*DownloadResult, error) {
+	return defaultClient().SaveMedia(ctx, options, dir, opts...)
+}
+
+// SaveMedia(ctx, options, dir) is the same as SaveMedia(), using c.
+func (c *Cobalt) SaveMedia(ctx context.Context, options Settings, dir string, opts ...DownloadOption) (*DownloadResult, error) {
+	config := newDownloadConfig(opts)
+	canTranscode := config.localTranscode && options.Mode == Audio && audioEncoders[options.AudioFormat] != nil
+
+	media, err := c.Run(ctx, options)
+	if err == nil {
+		var result *DownloadResult
+		result, err = media.SaveToContext(ctx, dir, opts...)
+		if err == nil || !canTranscode || !isTunnelFailure(ctx, err) {
+			return result, err
+		}
+	} else if !canTranscode || !transcodeFallbackErrors[strings.SplitN(err.Error(), ":", 2)[0]] {
+		return nil, err
+	}
+	c.warn(Warning{
+		Code:     WarningLocalTranscode,
+		Message:  fmt.Sprintf("cobalt failed to convert the audio of %v to %v (%v), converting it locally", options.Url, options.AudioFormat, err),
+		Instance: c.api,
+	})
+
+	best := options
+	best.AudioFormat = Best
+	media, err = c.Run(ctx, best)
+	if err != nil {
+		return nil, err
+	}
+	//Hooks and history run once the file is converted.
+	result, err := media.SaveToContext(ctx, dir, append(opts[:len(opts):len(opts)], func(c *downloadConfig) {
+		c.hooks, c.history = nil, nil
+	})...)
+	if err != nil {
+		return nil, err
+	}
+	if err := transcodeAudio(ctx, result, options); err != nil {
+		os.Remove(result.Path)
+		return nil, err
+	}
+	if err := config.recordHistory(result); err != nil {
+		return result, err
+	}
+	if err := config.runHooks(ctx, result); err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+// isTunnelFailure reports if a SaveTo() error comes from the download itself, and not from the caller (skips, scanners, cancellation).
+func isTunnelFailure(ctx context.Context, err error) bool {
+	return ctx.Err() == nil && !errors.Is(err, ErrSkipped) && !errors.Is(err, ErrRejectedByScanner)
+}
+
+// transcodeAudio converts the file of result to the AudioFormat of options, replacing it, and updates result.
+func transcodeAudio(ctx context.Context, result *DownloadResult, options Settings) error {
+	target := strings.TrimSuffix(result.Path, filepath.Ext(result.Path)) + "." + string(options.AudioFormat)
+	tmp := target + ".tmp." + string(options.AudioFormat)
+	args := append([]string{"-i", result.Path, "-vn"}, audioEncoders[options.AudioFormat]...)
+	if options.AudioFormat != Wav && options.AudioBitrate > 0 {
+		args = append(args, "-b:a", fmt.Sprintf("%vk", options.AudioBitrate))
+	}
+	if err := runFFmpeg(ctx, append(args, tmp)...); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, target); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if target != result.Path {
+		os.Remove(result.Path)
+	}
+	info, err := os.Stat(target)
+	if err != nil {
+		return err
+	}
+	result.Path, result.Size = target, info.Size()
+	return nil
+}
@@ -0,0 +1,68 @@
+//go:build !windows
+
+package gobalt
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// fakeFFmpeg makes FFmpegPath a script that writes "converted" to its output file.
+func fakeFFmpeg(t *testing.T) {
+	t.Helper()
+	script := filepath.Join(t.TempDir(), "ffmpeg")
+	os.WriteFile(script, []byte("#!/bin/sh\nfor arg; do out=$arg; done\necho converted > \"$out\"\n"), 0o755)
+	previous := FFmpegPath
+	FFmpegPath = script
+	t.Cleanup(func() { FFmpegPath = previous })
+}
+
+func TestLocalTranscodeFallback(t *testing.T) {
+	fakeFFmpeg(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/file":
+			w.Write([]byte("best audio"))
+		case r.Method == http.MethodGet:
+			w.Write([]byte(`{"cobalt":{"version":"10.5.0","services":["youtube"]}}`))
+		default:
+			var body map[string]any
+			json.NewDecoder(r.Body).Decode(&body)
+			if body["audioFormat"] != "best" {
+				w.Write([]byte(`{"status":"error","error":{"code":"error.api.fetch.critical"}}`))
+				return
+			}
+			w.Write([]byte(`{"status":"tunnel","url":"http://` + r.Host + `/file","filename":"song.webm"}`))
+		}
+	}))
+	defer server.Close()
+
+	options := CreateDefaultSettings()
+	options.Url = "https://youtu.be/dQw4w9WgXcQ"
+	options.Mode = Audio
+	options.AudioFormat = MP3
+	client := New(WithInstance(server.URL))
+	dir := t.TempDir()
+
+	if _, err := client.SaveMedia(context.Background(), options, dir); err == nil {
+		t.Fatal("without WithLocalTranscode() the cobalt error should be returned")
+	}
+	result, err := client.SaveMedia(context.Background(), options, dir, WithLocalTranscode())
+	if err != nil {
+		t.Fatalf("fallback failed: %v", err)
+	}
+	if result.Path != filepath.Join(dir, "song.mp3") {
+		t.Fatalf("unexpected path %v", result.Path)
+	}
+	if data, _ := os.ReadFile(result.Path); string(data) != "converted\n" {
+		t.Fatalf("file was not converted, got %q", data)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "song.webm")); !os.IsNotExist(err) {
+		t.Fatal("the unconverted file should be removed")
+	}
+}
@@ -0,0 +1,69 @@
+package gobalt
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// doTransfer sends the request of a file transfer (a download, or a read of a MediaFS file) like do(), but without the total
+// Timeout of the HTTP client, which would cut any download taking longer. The Timeout is used instead to wait for the headers
+// of the response, then as the longest time the body may go without sending anything. Transfers are canceled with their ctx.
+func (c *Cobalt) doTransfer(req *http.Request) (*http.Response, error) {
+	timeout := c.httpClient.Timeout
+	if timeout > 0 && timeout < OnionTimeout && IsOnion(req.URL.Host) {
+		timeout = OnionTimeout
+	}
+	client := *c.httpClient
+	client.Timeout = 0
+	if timeout <= 0 {
+		return c.send(&client, req)
+	}
+
+	ctx, cancel := context.WithCancel(req.Context())
+	idle := time.AfterFunc(timeout, cancel)
+	res, err := c.send(&client, req.WithContext(ctx))
+	if err != nil {
+		idle.Stop()
+		cancel()
+		return nil, err
+	}
+	idle.Reset(timeout)
+	res.Body = &idleBody{ReadCloser: res.Body, timer: idle, timeout: timeout, cancel: cancel}
+	return res, nil
+}
+
+// send sends req with client through the middleware of c.
+func (c *Cobalt) send(client *http.Client, req *http.Request) (*http.Response, error) {
+	if len(c.middleware) == 0 {
+		return client.Do(req)
+	}
+	return c.handler(client.Do)(req)
+}
+
+// idleBody cancels the request of a transfer when its body doesn't send anything for timeout.
+type idleBody struct {
+	io.ReadCloser
+	timer   *time.Timer
+	timeout time.Duration
+	cancel  context.CancelFunc
+	once    sync.Once
+}
+
+func (b *idleBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 {
+		b.timer.Reset(b.timeout)
+	}
+	return n, err
+}
+
+func (b *idleBody) Close() error {
+	b.once.Do(func() {
+		b.timer.Stop()
+		b.cancel()
+	})
+	return b.ReadCloser.Close()
+}
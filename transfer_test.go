@@ -0,0 +1,45 @@
+package gobalt
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// slowServer sends chunks of 1 byte, waiting for pause between them.
+func slowServer(t *testing.T, chunks int, pause time.Duration) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for i := 0; i < chunks; i++ {
+			w.Write([]byte("a"))
+			w.(http.Flusher).Flush()
+			time.Sleep(pause)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestDownloadOutlivesClientTimeout(t *testing.T) {
+	client := New(WithHTTPClient(&http.Client{Timeout: 100 * time.Millisecond}))
+	server := slowServer(t, 8, 40*time.Millisecond)
+
+	path := filepath.Join(t.TempDir(), "slow.bin")
+	result, err := client.Download(context.Background(), server.URL, path)
+	if err != nil {
+		t.Fatalf("a download taking longer than the client timeout should not fail while it makes progress: %v", err)
+	}
+	if content, _ := os.ReadFile(path); result.Size != 8 || string(content) != strings.Repeat("a", 8) {
+		t.Fatalf("got %q (%v bytes)", content, result.Size)
+	}
+
+	stalled := slowServer(t, 2, 400*time.Millisecond)
+	if _, err := client.Download(context.Background(), stalled.URL, filepath.Join(t.TempDir(), "stalled.bin")); err == nil {
+		t.Fatal("a download sending nothing for longer than the client timeout should fail")
+	}
+}
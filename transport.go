@@ -0,0 +1,172 @@
+package gobalt
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TransportOptions tunes the connection pool used by Client. Zero values keep the defaults of http.DefaultTransport.
+// Useful for bots doing a lot of downloads, where reusing connections to the same instance saves a lot of handshakes.
+type TransportOptions struct {
+	MaxIdleConns        int           //Maximum idle (keep-alive) connections across all hosts.
+	MaxIdleConnsPerHost int           //Maximum idle connections kept per host. Go defaults to 2, which is low for busy instances.
+	MaxConnsPerHost     int           //Maximum connections per host, including active ones. 0 means no limit.
+	IdleConnTimeout     time.Duration //How long an idle connection is kept before being closed.
+	DisableKeepAlives   bool          //Use a new connection for every request.
+	DisableCompression  bool          //Don't ask for gzip responses. Media is already compressed, so this saves CPU on big transfers.
+	DNSCache            *DNSCache     //Cache DNS lookups of instance and tunnel hosts, see DNSCache. nil means no caching.
+
+	//Proxy is used for every request, like socks5://127.0.0.1:9050 to go through Tor. With a SOCKS5 proxy hostnames
+	//are resolved by the proxy, so .onion instances work (see IsOnion()) and DNSCache is only used to reach the proxy.
+	//nil means the proxy from the environment (HTTP_PROXY, HTTPS_PROXY), like http.DefaultTransport.
+	Proxy *url.URL
+
+	//Resolve connects to a fixed address for some hosts, like curl --resolve: host -> "ip" or "ip:port". Requests still use
+	//the hostname for the Host header and TLS (SNI and certificate checks), so instances listed as nodomain by trackers,
+	//reachable only by IP, can be used with the name their certificate was made for.
+	Resolve map[string]string
+}
+
+// NewTransport creates a copy of http.DefaultTransport with opts applied.
+func NewTransport(opts TransportOptions) *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if opts.MaxIdleConns > 0 {
+		transport.MaxIdleConns = opts.MaxIdleConns
+	}
+	if opts.MaxIdleConnsPerHost > 0 {
+		transport.MaxIdleConnsPerHost = opts.MaxIdleConnsPerHost
+	}
+	if opts.MaxConnsPerHost > 0 {
+		transport.MaxConnsPerHost = opts.MaxConnsPerHost
+	}
+	if opts.IdleConnTimeout > 0 {
+		transport.IdleConnTimeout = opts.IdleConnTimeout
+	}
+	transport.DisableKeepAlives = opts.DisableKeepAlives
+	transport.DisableCompression = opts.DisableCompression
+	if opts.DNSCache != nil {
+		transport.DialContext = opts.DNSCache.DialContext
+	}
+	if opts.Proxy != nil {
+		transport.Proxy = http.ProxyURL(opts.Proxy)
+	}
+	if len(opts.Resolve) > 0 {
+		transport.DialContext = resolveOverrides(opts.Resolve, transport.DialContext)
+	}
+	transport.DialContext = refuseOnion(transport.DialContext)
+	return transport
+}
+
+// ConfigureTransport replaces the transport of Client with NewTransport(opts).
+// If you also want HTTP/3, call EnableHTTP3() after this, so it falls back to the tuned transport.
+func ConfigureTransport(opts TransportOptions) {
+	Client.Transport = NewTransport(opts)
+}
+
+// ErrHTTP3Unavailable is returned by EnableHTTP3() when no HTTP/3 transport was registered, see RegisterHTTP3().
+var ErrHTTP3Unavailable = errors.New("gobalt has no HTTP/3 support, import github.com/lostdusty/gobalt/v2/gobalthttp3 to enable it")
+
+var (
+	http3Mu              sync.Mutex
+	newHTTP3RoundTripper func() http.RoundTripper //Registered with RegisterHTTP3(), <NIL> if there's no HTTP/3 support.
+)
+
+// RegisterHTTP3(newTransport) makes EnableHTTP3() send requests with the HTTP/3 transports made by newTransport.
+// It's called by the gobalthttp3 package when imported, like database/sql drivers, so programs that don't use HTTP/3
+// don't depend on a QUIC implementation.
+func RegisterHTTP3(newTransport func() http.RoundTripper) {
+	http3Mu.Lock()
+	defer http3Mu.Unlock()
+	newHTTP3RoundTripper = newTransport
+}
+
+// EnableHTTP3 makes Client send requests over HTTP/3 (QUIC), which can cut latency on lossy links (like mobile networks).
+// Servers that don't speak HTTP/3 are remembered and contacted with the previous transport of Client instead.
+//
+// HTTP/3 support is opt-in so the default dependency set stays small: import the gobalthttp3 package (its own module)
+// in your program, otherwise this returns ErrHTTP3Unavailable and Client is left unchanged.
+func EnableHTTP3() error {
+	http3Mu.Lock()
+	newTransport := newHTTP3RoundTripper
+	http3Mu.Unlock()
+	if newTransport == nil {
+		return ErrHTTP3Unavailable
+	}
+	h3 := newTransport()
+	fallback := Client.Transport
+	if fallback == nil {
+		fallback = http.DefaultTransport
+	}
+	Client.Transport = &http3FallbackTransport{h3: h3, fallback: fallback}
+	return nil
+}
+
+// http3FallbackTransport tries HTTP/3 first, and uses fallback for hosts where HTTP/3 failed.
+type http3FallbackTransport struct {
+	h3       http.RoundTripper
+	fallback http.RoundTripper
+
+	mu      sync.Mutex
+	noHTTP3 map[string]bool //Hosts that failed to answer over HTTP/3.
+}
+
+func (t *http3FallbackTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.mu.Lock()
+	skip := t.noHTTP3[req.URL.Host]
+	t.mu.Unlock()
+	if skip || req.URL.Scheme != "https" {
+		return t.fallback.RoundTrip(req)
+	}
+
+	res, err := t.h3.RoundTrip(req)
+	if err == nil {
+		return res, nil
+	}
+	//Can't retry if the request body was already consumed and can't be recreated.
+	if req.Body != nil && req.GetBody == nil {
+		return nil, err
+	}
+	if req.Context().Err() != nil {
+		return nil, err
+	}
+
+	t.mu.Lock()
+	if t.noHTTP3 == nil {
+		t.noHTTP3 = make(map[string]bool)
+	}
+	t.noHTTP3[req.URL.Host] = true
+	t.mu.Unlock()
+
+	retry := req.Clone(req.Context())
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		retry.Body = body
+	}
+	return t.fallback.RoundTrip(retry)
+}
+
+// resolveOverrides wraps a dial function so hosts found in overrides are dialed at the given address instead.
+func resolveOverrides(overrides map[string]string, dial func(ctx context.Context, network, address string) (net.Conn, error)) func(ctx context.Context, network, address string) (net.Conn, error) {
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(address)
+		if err != nil {
+			return dial(ctx, network, address)
+		}
+		if target, ok := overrides[strings.ToLower(host)]; ok {
+			if _, _, err := net.SplitHostPort(target); err != nil {
+				target = net.JoinHostPort(target, port)
+			}
+			address = target
+		}
+		return dial(ctx, network, address)
+	}
+}
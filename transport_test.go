@@ -0,0 +1,94 @@
+package gobalt
+
+import (
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+func TestHTTP3FallbackTransport(t *testing.T) {
+	h3Calls := 0
+	transport := &http3FallbackTransport{
+		h3: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			h3Calls++
+			io.ReadAll(req.Body) //Consume the body, like a real transport would.
+			return nil, errors.New("no QUIC here")
+		}),
+		fallback: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			body, _ := io.ReadAll(req.Body)
+			return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(string(body)))}, nil
+		}),
+	}
+
+	for range 2 {
+		req, _ := http.NewRequest(http.MethodPost, "https://cobalt.example/", strings.NewReader(`{"url":"x"}`))
+		res, err := transport.RoundTrip(req)
+		if err != nil {
+			t.Fatalf("expected the fallback to answer, got %v", err)
+		}
+		if body, _ := io.ReadAll(res.Body); string(body) != `{"url":"x"}` {
+			t.Fatalf("the request body was not sent again to the fallback, got %q", body)
+		}
+	}
+	if h3Calls != 1 {
+		t.Fatalf("expected HTTP/3 to be tried only once for the host, got %v", h3Calls)
+	}
+}
+
+func TestNewTransport(t *testing.T) {
+	transport := NewTransport(TransportOptions{MaxIdleConnsPerHost: 32, IdleConnTimeout: time.Minute, DisableCompression: true})
+	if transport.MaxIdleConnsPerHost != 32 || transport.IdleConnTimeout != time.Minute || !transport.DisableCompression {
+		t.Fatalf("options were not applied: %+v", transport)
+	}
+	if transport.MaxIdleConns != http.DefaultTransport.(*http.Transport).MaxIdleConns {
+		t.Fatal("unset options should keep the defaults")
+	}
+}
+
+func TestResolveOverride(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Host + " " + r.TLS.ServerName))
+	}))
+	defer server.Close()
+	_, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+
+	//The test certificate is made for example.com, which is reached at the IP of the server.
+	transport := NewTransport(TransportOptions{Resolve: map[string]string{"example.com": "127.0.0.1"}})
+	transport.TLSClientConfig = server.Client().Transport.(*http.Transport).TLSClientConfig.Clone()
+	res, err := (&http.Client{Transport: transport}).Get("https://example.com:" + port + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	body, _ := io.ReadAll(res.Body)
+	if string(body) != "example.com:"+port+" example.com" {
+		t.Fatalf("expected the hostname in Host and SNI, got %q", body)
+	}
+}
+
+func TestEnableHTTP3(t *testing.T) {
+	previous := Client.Transport
+	defer func() { Client.Transport = previous }()
+	defer RegisterHTTP3(nil)
+
+	if err := EnableHTTP3(); !errors.Is(err, ErrHTTP3Unavailable) || Client.Transport != previous {
+		t.Fatalf("expected ErrHTTP3Unavailable and Client left unchanged, got %v", err)
+	}
+	h3 := roundTripFunc(func(*http.Request) (*http.Response, error) { return nil, errors.New("no quic here") })
+	RegisterHTTP3(func() http.RoundTripper { return h3 })
+	if err := EnableHTTP3(); err != nil {
+		t.Fatalf("EnableHTTP3 failed with a registered transport: %v", err)
+	}
+	if transport, ok := Client.Transport.(*http3FallbackTransport); !ok || transport.fallback == nil {
+		t.Fatalf("Client should try HTTP/3 and fall back, got %T", Client.Transport)
+	}
+}
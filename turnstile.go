@@ -0,0 +1,44 @@
+package gobalt
+
+import (
+	"context"
+	"fmt"
+)
+
+// TurnstileProvider solves the Turnstile challenge of a cobalt instance, e.g. with a headless browser or a token service,
+// and returns the response token. It's called with the instance url and its sitekey (see CobaltServerInformation.TurnstileSitekey)
+// every time the client needs a new session (see Sessions), since tokens can only be used once.
+type TurnstileProvider interface {
+	TurnstileToken(ctx context.Context, instance, sitekey string) (string, error)
+}
+
+// TurnstileFunc is a function used as TurnstileProvider.
+type TurnstileFunc func(ctx context.Context, instance, sitekey string) (string, error)
+
+func (f TurnstileFunc) TurnstileToken(ctx context.Context, instance, sitekey string) (string, error) {
+	return f(ctx, instance, sitekey)
+}
+
+// WithTurnstile sets the provider used to pass the Turnstile challenge of instances that have one. Without it,
+// those instances answer error.api.auth.turnstile.missing. Sessions must be enabled, see WithSessions().
+func WithTurnstile(provider TurnstileProvider) Option {
+	return func(c *Cobalt) {
+		c.turnstile = provider
+	}
+}
+
+// turnstileToken solves the challenge of the instance at api, returns "" if there's no provider or the instance doesn't use Turnstile.
+func (c *Cobalt) turnstileToken(ctx context.Context, api string) (string, error) {
+	if c.turnstile == nil {
+		return "", nil
+	}
+	info, err := c.serverInfoCache.get(ctx, c, api)
+	if err != nil || info.Cobalt.TurnstileSitekey == "" {
+		return "", nil
+	}
+	token, err := c.turnstile.TurnstileToken(ctx, api, info.Cobalt.TurnstileSitekey)
+	if err != nil {
+		return "", fmt.Errorf("failed to solve the turnstile challenge: %w", err)
+	}
+	return token, nil
+}
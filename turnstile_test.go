@@ -0,0 +1,47 @@
+package gobalt
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTurnstile(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/session" && r.Header.Get("cf-turnstile-response") != "solved":
+			w.Write([]byte(`{"status":"error","error":{"code":"error.api.auth.turnstile.missing"}}`))
+		case r.URL.Path == "/session":
+			w.Write([]byte(`{"token":"jwt-1","exp":100}`))
+		case r.Method == http.MethodGet:
+			w.Write([]byte(`{"cobalt":{"version":"10.5.0","services":["youtube"],"turnstileSitekey":"0x4AAA"}}`))
+		case r.Header.Get("Authorization") != "Bearer jwt-1":
+			w.Write([]byte(`{"status":"error","error":{"code":"error.api.auth.jwt.missing"}}`))
+		default:
+			w.Write([]byte(`{"status":"tunnel","url":"https://example.com/tunnel"}`))
+		}
+	}))
+	defer server.Close()
+	options := CreateDefaultSettings()
+	options.Url = "https://youtu.be/dQw4w9WgXcQ"
+
+	_, err := New(WithInstance(server.URL), WithSessions(&Sessions{})).Run(context.Background(), options)
+	if !errors.Is(err, ErrAuthRequired) {
+		t.Fatalf("expected the turnstile error without a provider, got %v", err)
+	}
+
+	var sitekey string
+	provider := TurnstileFunc(func(ctx context.Context, instance, key string) (string, error) {
+		sitekey = key
+		return "solved", nil
+	})
+	client := New(WithInstance(server.URL), WithSessions(&Sessions{}), WithTurnstile(provider))
+	if _, err := client.Run(context.Background(), options); err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	if sitekey != "0x4AAA" {
+		t.Fatalf("the provider should get the sitekey of the instance, got %q", sitekey)
+	}
+}
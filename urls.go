@@ -0,0 +1,125 @@
+package gobalt
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+// MaxURLLength is the longest url accepted by NormalizeMediaURL(). Real media links are way shorter than this.
+const MaxURLLength = 2048
+
+// ErrInvalidURL is returned (wrapped with the reason) by NormalizeMediaURL() for urls that can't be sent to cobalt.
+var ErrInvalidURL = errors.New("invalid url")
+
+// NormalizeMediaURL(url) checks and cleans an url typed by an user before it's sent to cobalt, so malformed input
+// can't produce weird requests. It:
+//
+//   - trims spaces, and adds "https://" if the url has no scheme (like "youtu.be/dQw4w9WgXcQ");
+//   - only accepts http and https urls (no data:, file:, javascript:, ...);
+//   - rejects urls with embedded credentials (user:password@host), control characters, or longer than MaxURLLength;
+//   - converts unicode hostnames to their ASCII (punycode) form, and lowercases the host;
+//   - removes the fragment (#...), which is never sent to servers anyway.
+//
+// Errors wrap ErrInvalidURL.
+func NormalizeMediaURL(raw string) (string, error) {
+	parsed, err := parseHTTPURL(raw)
+	if err != nil {
+		return "", err
+	}
+	if parsed.User != nil {
+		return "", fmt.Errorf("%w: urls with credentials are not allowed", ErrInvalidURL)
+	}
+	parsed.Fragment = ""
+	parsed.RawFragment = ""
+	normalized := parsed.String()
+	if len(normalized) > MaxURLLength {
+		return "", fmt.Errorf("%w: url is longer than %v characters", ErrInvalidURL, MaxURLLength)
+	}
+	return normalized, nil
+}
+
+// normalizeInstanceURL checks and cleans the url of a cobalt instance, adding "http://" if it has no scheme like gobalt always
+// did, since instances without one are usually local ("localhost:9000") or onion services, which rarely have certificates.
+// Unlike media urls, credentials are kept, since some private instances use basic auth.
+func normalizeInstanceURL(raw string) (string, error) {
+	if trimmed := strings.TrimSpace(raw); !strings.Contains(trimmed, "://") {
+		if scheme, _, found := strings.Cut(trimmed, ":"); !found || !isBlockedScheme(scheme) {
+			raw = "http://" + trimmed
+		}
+	}
+	parsed, err := parseHTTPURL(raw)
+	if err != nil {
+		return "", err
+	}
+	return parsed.String(), nil
+}
+
+// parseHTTPURL does the checks shared by media and instance urls.
+func parseHTTPURL(raw string) (*url.URL, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, fmt.Errorf("%w: url is empty", ErrInvalidURL)
+	}
+	if len(raw) > MaxURLLength {
+		return nil, fmt.Errorf("%w: url is longer than %v characters", ErrInvalidURL, MaxURLLength)
+	}
+	if strings.ContainsFunc(raw, func(r rune) bool { return r < 0x20 || r == 0x7f }) {
+		return nil, fmt.Errorf("%w: url contains control characters", ErrInvalidURL)
+	}
+	if !strings.Contains(raw, "://") {
+		//Without a scheme, url.Parse would take "youtube.com/watch" as a path, or "localhost:80" as a scheme.
+		if scheme, _, found := strings.Cut(raw, ":"); found && isBlockedScheme(scheme) {
+			return nil, fmt.Errorf("%w: only http and https urls are allowed", ErrInvalidURL)
+		}
+		raw = "https://" + raw
+	}
+
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidURL, err)
+	}
+	parsed.Scheme = strings.ToLower(parsed.Scheme)
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return nil, fmt.Errorf("%w: only http and https urls are allowed", ErrInvalidURL)
+	}
+	if parsed.Opaque != "" {
+		return nil, fmt.Errorf("%w: url has no host", ErrInvalidURL)
+	}
+
+	host := parsed.Hostname()
+	if host == "" {
+		return nil, fmt.Errorf("%w: url has no host", ErrInvalidURL)
+	}
+	if net.ParseIP(host) == nil {
+		ascii, err := idna.Lookup.ToASCII(host)
+		if err == nil {
+			//Some inputs turn into punycode that isn't valid itself, check it again.
+			_, err = idna.Lookup.ToASCII(ascii)
+		}
+		if err != nil || ascii == "" {
+			return nil, fmt.Errorf("%w: invalid hostname %q", ErrInvalidURL, host)
+		}
+		host = strings.ToLower(ascii)
+	} else if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+	if port := parsed.Port(); port != "" {
+		host = host + ":" + port
+	}
+	parsed.Host = host
+	return parsed, nil
+}
+
+// isBlockedScheme reports if scheme (from an url without "://", like "data:text/plain,hi") is a known non-http scheme.
+func isBlockedScheme(scheme string) bool {
+	switch strings.ToLower(scheme) {
+	case "data", "javascript", "file", "mailto", "about", "blob", "vbscript", "tel":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,226 @@
+// Package vcr records the HTTP traffic of gobalt to files and replays it, so test suites don't depend on live
+// cobalt instances, which change behavior (and go offline) all the time.
+//
+// Record once against a real instance, commit the files, and replay them in every run after that:
+//
+//	rec := vcr.New("testdata/cassettes", vcr.ModeAuto)
+//	client := gobalt.New(gobalt.WithHTTPClient(rec.Client()))
+//
+// Request headers are never saved, so api keys don't end up in the recordings.
+package vcr
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// Mode tells a Recorder where responses come from.
+type Mode int
+
+const (
+	ModeReplay Mode = iota //Only serve recorded responses, requests without a recording fail with ErrNotRecorded.
+	ModeRecord             //Send every request and save the response, replacing older recordings.
+	ModeAuto               //Replay requests that have a recording, send and record the others.
+)
+
+// ErrNotRecorded is returned (wrapped) in ModeReplay for a request that has no recording.
+var ErrNotRecorded = errors.New("no recording for request")
+
+// Recorder is an http.RoundTripper recording or replaying requests, depending on its Mode.
+// Each distinct request (method, url and body) is saved to its own file in Dir. When the same request is sent many times,
+// every response is recorded, and replayed in the same order (the last one repeats), so retries can be tested too.
+type Recorder struct {
+	Dir       string            //Directory of the recordings.
+	Mode      Mode              //Where the responses come from.
+	Transport http.RoundTripper //Used to send requests that are recorded. Default: http.DefaultTransport.
+
+	mu    sync.Mutex
+	seen  map[string]int           //Responses served (or recorded) per request, in this session.
+	tapes map[string][]Interaction //Recordings loaded or made in this session.
+}
+
+// Interaction is a recorded request and its response, the content of the recording files.
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+// RecordedRequest is the part of a request used to find its recording. Headers are left out on purpose.
+type RecordedRequest struct {
+	Method string `json:"method"`
+	URL    string `json:"url"`
+	Body   string `json:"body,omitempty"`
+}
+
+// RecordedResponse is a saved response, replayed as-is.
+type RecordedResponse struct {
+	StatusCode int         `json:"status"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body,omitempty"`       //Body, if it's text.
+	BodyBase64 []byte      `json:"bodyBase64,omitempty"` //Body, if it's binary (like media).
+}
+
+// New creates a Recorder saving to (or reading from) dir.
+func New(dir string, mode Mode) *Recorder {
+	return &Recorder{Dir: dir, Mode: mode}
+}
+
+// Client returns an http.Client using r as transport, to pass to gobalt.WithHTTPClient().
+func (r *Recorder) Client() *http.Client {
+	return &http.Client{Transport: r}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	key := requestKey(req.Method, req.URL.String(), body)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.seen == nil {
+		r.seen, r.tapes = make(map[string]int), make(map[string][]Interaction)
+	}
+	tape, err := r.load(key)
+	if err != nil {
+		return nil, err
+	}
+	index := r.seen[key]
+	r.seen[key]++
+
+	switch {
+	case r.Mode == ModeRecord, r.Mode == ModeAuto && len(tape) == 0:
+		if r.Mode == ModeRecord && index == 0 {
+			tape = nil //Replace the recording of an older session.
+		}
+		return r.record(req, key, body, tape)
+	case len(tape) == 0:
+		return nil, fmt.Errorf("%w: %v %v", ErrNotRecorded, req.Method, req.URL)
+	}
+	return tape[min(index, len(tape)-1)].Response.toResponse(req), nil
+}
+
+// record sends req and appends its response to tape.
+func (r *Recorder) record(req *http.Request, key string, body []byte, tape []Interaction) (*http.Response, error) {
+	transport := r.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	res, err := transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	resBody, err := io.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	recorded := RecordedResponse{StatusCode: res.StatusCode, Header: res.Header.Clone()}
+	recorded.Header.Del("Set-Cookie")
+	if utf8.Valid(resBody) {
+		recorded.Body = string(resBody)
+	} else {
+		recorded.BodyBase64 = resBody
+	}
+	tape = append(tape, Interaction{
+		Request:  RecordedRequest{Method: req.Method, URL: req.URL.String(), Body: string(body)},
+		Response: recorded,
+	})
+	if err := r.save(key, tape); err != nil {
+		return nil, err
+	}
+	return recorded.toResponse(req), nil
+}
+
+// load returns the recording of key, reading it from Dir the first time. Missing recordings are empty.
+func (r *Recorder) load(key string) ([]Interaction, error) {
+	if tape, ok := r.tapes[key]; ok {
+		return tape, nil
+	}
+	data, err := os.ReadFile(r.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var tape []Interaction
+	if err := json.Unmarshal(data, &tape); err != nil {
+		return nil, fmt.Errorf("invalid recording %v: %w", r.path(key), err)
+	}
+	r.tapes[key] = tape
+	return tape, nil
+}
+
+func (r *Recorder) save(key string, tape []Interaction) error {
+	data, err := json.MarshalIndent(tape, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(r.Dir, 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(r.path(key), data, 0644); err != nil {
+		return err
+	}
+	r.tapes[key] = tape
+	return nil
+}
+
+func (r *Recorder) path(key string) string {
+	return filepath.Join(r.Dir, key+".json")
+}
+
+// requestKey names the recording of a request: the host (to keep the directory readable) and a hash of the request.
+func requestKey(method, url string, body []byte) string {
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%v %v\n", method, url)
+	hash.Write(body)
+	host := url
+	if _, after, ok := strings.Cut(url, "://"); ok {
+		host, _, _ = strings.Cut(after, "/")
+	}
+	host = strings.NewReplacer(":", "_", "[", "", "]", "").Replace(host)
+	return host + "-" + hex.EncodeToString(hash.Sum(nil))[:16]
+}
+
+func (r RecordedResponse) toResponse(req *http.Request) *http.Response {
+	body := []byte(r.Body)
+	if r.BodyBase64 != nil {
+		body = r.BodyBase64
+	}
+	header := r.Header.Clone()
+	if header == nil {
+		header = make(http.Header)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%v %v", r.StatusCode, http.StatusText(r.StatusCode)),
+		StatusCode:    r.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
@@ -0,0 +1,59 @@
+package vcr
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/lostdusty/gobalt/v2"
+)
+
+func TestRecordReplay(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if r.Method == http.MethodGet {
+			w.Write([]byte(`{"cobalt":{"version":"10.5.0","services":["youtube"]}}`))
+			return
+		}
+		w.Write([]byte(`{"status":"tunnel","url":"https://example.com/tunnel","filename":"video.mp4"}`))
+	}))
+	dir := t.TempDir()
+	options := gobalt.CreateDefaultSettings()
+	options.Url = "https://youtu.be/dQw4w9WgXcQ"
+
+	recorder := New(dir, ModeRecord)
+	client := gobalt.New(gobalt.WithInstance(server.URL), gobalt.WithAPIKey("secret"), gobalt.WithHTTPClient(recorder.Client()))
+	if _, err := client.Run(context.Background(), options); err != nil {
+		t.Fatal(err)
+	}
+	recorded := requests.Load()
+	server.Close()
+
+	replayer := New(dir, ModeReplay)
+	client = gobalt.New(gobalt.WithInstance(server.URL), gobalt.WithHTTPClient(replayer.Client()))
+	media, err := client.Run(context.Background(), options)
+	if err != nil {
+		t.Fatalf("replay failed: %v", err)
+	}
+	if media.Filename != "video.mp4" || requests.Load() != recorded {
+		t.Fatalf("unexpected replay %+v, %v requests", media, requests.Load())
+	}
+
+	if _, err := replayer.Client().Get(server.URL + "/other"); !errors.Is(err, ErrNotRecorded) {
+		t.Fatalf("expected ErrNotRecorded, got %v", err)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	for _, file := range files {
+		if data, _ := os.ReadFile(file); strings.Contains(string(data), "secret") {
+			t.Fatalf("the api key was saved in %v", file)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package gobalt
+
+import "strings"
+
+// WarningCode identifies the kind of a Warning.
+type WarningCode string
+
+const (
+	WarningFieldsDropped    WarningCode = "fields_dropped"    //Request fields the instance doesn't support were left out, see requestFields.
+	WarningLocalTranscode   WarningCode = "local_transcode"   //cobalt failed to convert the audio, it was converted locally instead. See WithLocalTranscode().
+	WarningInstanceFallback WarningCode = "instance_fallback" //The instance of the client failed, another one answered. See WithFailover().
+)
+
+// Warning is something that went differently than asked, but didn't make the call fail, like a setting the instance
+// doesn't support. Warnings are added to CobaltResponse.Warnings and sent to the handler set with WithWarningHandler().
+type Warning struct {
+	Code     WarningCode //Kind of warning.
+	Message  string      //Human-readable description.
+	Instance string      //Instance the warning is about, if any.
+	Fields   []string    //Request fields concerned, if any.
+}
+
+func (w Warning) String() string {
+	message := string(w.Code) + ": " + w.Message
+	if len(w.Fields) > 0 {
+		message += " (" + strings.Join(w.Fields, ", ") + ")"
+	}
+	return message
+}
+
+// WithWarningHandler calls fn for every Warning of the client, as they happen. fn may be called from several goroutines.
+func WithWarningHandler(fn func(Warning)) Option {
+	return func(c *Cobalt) {
+		c.onWarning = fn
+	}
+}
+
+// warn logs w and sends it to the warning handler, if any.
+func (c *Cobalt) warn(w Warning) {
+	c.logger.Warn(w.Message, "code", w.Code, "instance", w.Instance, "fields", w.Fields)
+	if c.onWarning != nil {
+		c.onWarning(w)
+	}
+}
@@ -0,0 +1,88 @@
+package gobalt
+
+import (
+	"context"
+	"slices"
+	"time"
+)
+
+// ServerInfoChange is sent by WatchServerInfo() when the information of an instance changed since the previous refresh.
+type ServerInfoChange struct {
+	Instance        string      //Url of the instance.
+	Old             *ServerInfo //Previous information, <NIL> if the instance was offline.
+	New             *ServerInfo //Current information, <NIL> if the instance is now offline.
+	Err             error       //Why the refresh failed, when the instance went offline.
+	VersionChanged  bool        //If the cobalt version changed.
+	AddedServices   []string    //Services enabled since the previous refresh.
+	RemovedServices []string    //Services disabled since the previous refresh, e.g. YouTube being turned off overnight.
+}
+
+// WatchServerInfo(ctx, interval, fn) refreshes the server information of the instance of c every interval, until ctx is done,
+// keeping the server info cache warm so Run() never waits for it. fn is called when the version or the services of the
+// instance change, or when it goes offline or back online, so bots can react before users hit errors.
+// It blocks, run it in its own goroutine or use WithServerInfoWatch().
+func (c *Cobalt) WatchServerInfo(ctx context.Context, interval time.Duration, fn func(ServerInfoChange)) {
+	instance, err := normalizeInstanceURL(c.api)
+	if err != nil {
+		return
+	}
+	var previous *ServerInfo
+	online := true //So an instance that is offline from the start is reported.
+	for first := true; ; first = false {
+		info, err := c.fetchServerInfo(ctx, instance)
+		if ctx.Err() != nil {
+			return
+		}
+		c.metrics.InstanceHealth(instance, err == nil)
+		switch {
+		case err != nil:
+			//Only report going offline once, not on every failed refresh.
+			if online && fn != nil {
+				fn(ServerInfoChange{Instance: instance, Old: previous, Err: err})
+			}
+			online = false
+		default:
+			c.serverInfoCache.store(instance, info)
+			change, changed := compareServerInfo(instance, previous, info)
+			if !online {
+				change.Old, changed = nil, true
+			}
+			if changed && !first && fn != nil {
+				fn(change)
+			}
+			previous, online = info, true
+		}
+		if c.clock.Sleep(ctx, interval) != nil {
+			return
+		}
+	}
+}
+
+// WithServerInfoWatch makes New() start WatchServerInfo() in the background, stopping when ctx is done.
+func WithServerInfoWatch(ctx context.Context, interval time.Duration, fn func(ServerInfoChange)) Option {
+	return func(c *Cobalt) {
+		c.watches = append(c.watches, func(client *Cobalt) {
+			go client.WatchServerInfo(ctx, interval, fn)
+		})
+	}
+}
+
+// compareServerInfo describes what changed between two refreshes of instance.
+func compareServerInfo(instance string, old, new *ServerInfo) (change ServerInfoChange, changed bool) {
+	change = ServerInfoChange{Instance: instance, Old: old, New: new}
+	if old == nil {
+		return change, false
+	}
+	change.VersionChanged = old.Cobalt.Version != new.Cobalt.Version
+	for _, service := range new.Cobalt.Services {
+		if !slices.Contains(old.Cobalt.Services, service) {
+			change.AddedServices = append(change.AddedServices, service)
+		}
+	}
+	for _, service := range old.Cobalt.Services {
+		if !slices.Contains(new.Cobalt.Services, service) {
+			change.RemovedServices = append(change.RemovedServices, service)
+		}
+	}
+	return change, change.VersionChanged || len(change.AddedServices) > 0 || len(change.RemovedServices) > 0
+}
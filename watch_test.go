@@ -0,0 +1,45 @@
+package gobalt
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWatchServerInfo(t *testing.T) {
+	var refreshes atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if refreshes.Add(1) < 3 {
+			w.Write([]byte(`{"cobalt":{"version":"10.5.0","services":["youtube","tiktok"]}}`))
+			return
+		}
+		w.Write([]byte(`{"cobalt":{"version":"10.5.1","services":["tiktok"]}}`))
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	changes := make(chan ServerInfoChange, 1)
+	clock := &fakeClock{}
+	New(WithInstance(server.URL), WithClock(clock), WithServerInfoWatch(ctx, time.Hour, func(change ServerInfoChange) {
+		changes <- change
+		cancel()
+	}))
+
+	select {
+	case change := <-changes:
+		if !change.VersionChanged || len(change.RemovedServices) != 1 || change.RemovedServices[0] != "youtube" || len(change.AddedServices) != 0 {
+			t.Fatalf("unexpected change %+v", change)
+		}
+	case <-ctx.Done():
+		t.Fatal("no change was reported")
+	}
+	clock.mu.Lock()
+	defer clock.mu.Unlock()
+	if len(clock.slept) < 2 || clock.slept[0] != time.Hour {
+		t.Fatalf("expected the refreshes to wait for the interval, got %v", clock.slept)
+	}
+}